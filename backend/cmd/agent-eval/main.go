@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"syscall"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/eval"
+	"github.com/webide/ide/backend/internal/ai/provider"
+	"github.com/webide/ide/backend/internal/config"
+)
+
+func main() {
+	tasksPath := flag.String("tasks", "eval/tasks", "task file or directory of *.json task files")
+	mode := flag.String("mode", eval.ModeReplay, "live, record or replay")
+	cassettes := flag.String("cassettes", "", "cassette directory (default: <tasks>/cassettes)")
+	model := flag.String("model", "", "model name (default: MINIMAX_MODEL)")
+	baseURL := flag.String("base-url", "", "provider base URL (default: MINIMAX_URL)")
+	apiKey := flag.String("api-key", "", "provider API key (default: MINIMAX_API_KEY)")
+	outDir := flag.String("out", "", "directory for report.json and report.md")
+	filter := flag.String("run", "", "only run tasks whose name matches this regexp")
+	timeout := flag.Duration("timeout", 10*time.Minute, "per-task timeout")
+	keep := flag.Bool("keep", false, "keep task workspaces for inspection")
+	flag.Parse()
+
+	switch *mode {
+	case eval.ModeLive, eval.ModeRecord, eval.ModeReplay:
+	default:
+		log.Fatalf("unknown mode %q", *mode)
+	}
+
+	tasks, err := eval.LoadTasks(*tasksPath)
+	if err != nil {
+		log.Fatalf("Failed to load tasks: %v", err)
+	}
+	if *filter != "" {
+		re, err := regexp.Compile(*filter)
+		if err != nil {
+			log.Fatalf("Invalid -run pattern: %v", err)
+		}
+		var selected []*eval.Task
+		for _, t := range tasks {
+			if re.MatchString(t.Name) {
+				selected = append(selected, t)
+			}
+		}
+		tasks = selected
+	}
+	if len(tasks) == 0 {
+		log.Fatalf("No tasks to run")
+	}
+
+	opts := eval.Options{
+		Mode:        *mode,
+		Model:       *model,
+		CassetteDir: *cassettes,
+		Timeout:     *timeout,
+		KeepWorkDir: *keep,
+	}
+
+	if *mode != eval.ModeReplay {
+		cfg, err := config.Load()
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+		if *apiKey == "" {
+			*apiKey = cfg.MiniMaxAPIKey
+		}
+		if *baseURL == "" {
+			*baseURL = cfg.MiniMaxURL
+		}
+		if opts.Model == "" {
+			opts.Model = cfg.MiniMaxModel
+		}
+		opts.Provider = provider.NewAnthropic(*apiKey, *baseURL)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report := eval.Run(ctx, tasks, opts)
+
+	if *outDir == "" {
+		*outDir = filepath.Join("eval", "results", report.StartedAt.Format("20060102-150405"))
+	}
+	if err := report.Write(*outDir); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+
+	fmt.Print(report.Markdown())
+	fmt.Printf("\nReport written to %s\n", *outDir)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -35,6 +36,9 @@ func main() {
 	}
 	defer db.Close()
 
+	ai.RecoverChatRuns(context.Background())
+	go ai.StartScheduler(context.Background())
+
 	if err := bootstrapUser(cfg); err != nil {
 		log.Printf("Warning: bootstrap user failed: %v", err)
 	}
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/provider"
+	"github.com/webide/ide/backend/internal/ai/redact"
 )
 
 type AgentSession struct {
@@ -18,6 +20,12 @@ type AgentSession struct {
 	PendingCalls map[string]*PendingToolCall
 	RunningCmds  map[string]*CommandProcess
 	Config       AgentConfig
+	workDirs     map[string]bool
+	toolCache    *ToolCache
+	verifier     *Verifier
+	budget       *Budget
+	redactor     *redact.Redactor
+	usage        provider.TokenUsage
 	mu           sync.RWMutex
 }
 
@@ -45,6 +53,24 @@ func NewSession(projectID, userID, chatID uuid.UUID, config AgentConfig) *AgentS
 	if config.Mode == "" {
 		config.Mode = ModeSafe
 	}
+	if (config.Hooks == nil || config.Checks == nil || config.Budget == nil || config.Summarize == nil || config.Redact == nil) && config.ProjectRoot != "" {
+		projectCfg := LoadProjectConfig(config.ProjectRoot)
+		if config.Hooks == nil {
+			config.Hooks = projectCfg.Hooks
+		}
+		if config.Checks == nil {
+			config.Checks = &projectCfg.Checks
+		}
+		if config.Budget == nil {
+			config.Budget = &projectCfg.Budget
+		}
+		if config.Summarize == nil {
+			config.Summarize = &projectCfg.Summarize
+		}
+		if config.Redact == nil {
+			config.Redact = &projectCfg.Redact
+		}
+	}
 	return &AgentSession{
 		ID:           uuid.New(),
 		ProjectID:    projectID,
@@ -55,6 +81,7 @@ func NewSession(projectID, userID, chatID uuid.UUID, config AgentConfig) *AgentS
 		PendingCalls: make(map[string]*PendingToolCall),
 		RunningCmds:  make(map[string]*CommandProcess),
 		Config:       config,
+		workDirs:     make(map[string]bool),
 	}
 }
 
@@ -154,6 +181,149 @@ func (s *AgentSession) GetConfig() AgentConfig {
 	return s.Config
 }
 
+func (s *AgentSession) Hooks() *HookRunner {
+	cfg := s.GetConfig()
+	if len(cfg.Hooks) == 0 {
+		return nil
+	}
+	return NewHookRunner(cfg.ProjectRoot, cfg.Hooks, s.Redactor())
+}
+
+// Verifier returns the session's checks. It is created once so a run that
+// resumes after an approval keeps its state.
+func (s *AgentSession) Verifier() *Verifier {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.verifier == nil && s.Config.Checks != nil {
+		s.verifier = NewVerifier(s.Config.ProjectRoot, *s.Config.Checks)
+	}
+	return s.verifier
+}
+
+// Budget returns the session's budget, created once like Verifier.
+func (s *AgentSession) Budget() *Budget {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.budget == nil && s.Config.Budget != nil {
+		s.budget = NewBudget(*s.Config.Budget, s.Config.Model)
+	}
+	return s.budget
+}
+
+// Summarizer condenses large tool outputs with llm. Sessions without a
+// project config get the defaults.
+func (s *AgentSession) Summarizer(llm provider.Provider) *Summarizer {
+	cfg := s.GetConfig()
+	var summarize SummarizeConfig
+	if cfg.Summarize != nil {
+		summarize = *cfg.Summarize
+	}
+	return NewSummarizer(summarize, llm, provider.Config{Model: cfg.Model})
+}
+
+// Redactor returns the session's secret redactor. It is created once so
+// placeholders stay restorable for the whole session.
+func (s *AgentSession) Redactor() *redact.Redactor {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.redactor == nil {
+		var cfg redact.Config
+		if s.Config.Redact != nil {
+			cfg = *s.Config.Redact
+		}
+		s.redactor = redact.New(s.Config.ProjectRoot, cfg)
+	}
+	return s.redactor
+}
+
+// ToolCache returns the session's cache of read-only tool results. Sessions
+// of a chat share the chat's cache.
+func (s *AgentSession) ToolCache() *ToolCache {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.toolCache == nil {
+		if s.ChatID != uuid.Nil {
+			s.toolCache = ChatToolCache(s.ChatID, s.Config.ProjectRoot)
+		} else {
+			s.toolCache = NewToolCache(s.Config.ProjectRoot)
+		}
+	}
+	return s.toolCache
+}
+
+func (s *AgentSession) AddUsage(u provider.TokenUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage.PromptTokens += u.PromptTokens
+	s.usage.CompletionTokens += u.CompletionTokens
+	s.usage.TotalTokens += u.TotalTokens
+}
+
+func (s *AgentSession) Usage() provider.TokenUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.usage
+}
+
+// TouchWorkDirs records directories the agent has worked in and reports
+// whether any of them is new.
+func (s *AgentSession) TouchWorkDirs(dirs []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := false
+	for _, d := range dirs {
+		if !s.workDirs[d] {
+			s.workDirs[d] = true
+			added = true
+		}
+	}
+	return added
+}
+
+func (s *AgentSession) WorkDirs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dirs := make([]string, 0, len(s.workDirs))
+	for d := range s.workDirs {
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
+
+// RefreshSystemPrompt rebuilds the system message at the start of the
+// conversation from the base prompt, project facts and instruction files.
+func (s *AgentSession) RefreshSystemPrompt() SystemPrompt {
+	prompt := s.SystemPrompt()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.Messages) > 0 && s.Messages[0].Role == RoleSystem {
+		s.Messages[0].Content = prompt.Text
+	} else {
+		s.Messages = append([]ModelMessage{NewSystemMessage(prompt.Text)}, s.Messages...)
+	}
+	return prompt
+}
+
+// SystemPrompt builds the system prompt for the session's root, work
+// directories and latest user message without changing the conversation.
+func (s *AgentSession) SystemPrompt() SystemPrompt {
+	cfg := s.GetConfig()
+	memories := LoadMemories(context.Background(), s.ProjectID, cfg.ProjectRoot, s.lastUserMessage())
+	return BuildSystemPrompt(cfg.SystemPrompt, cfg.ProjectRoot, s.WorkDirs(), memories)
+}
+
+func (s *AgentSession) lastUserMessage() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].Role == RoleUser {
+			return s.Messages[i].Content
+		}
+	}
+	return ""
+}
+
 func (s *AgentSession) Context() context.Context {
 	return context.WithValue(context.Background(), "session", s)
 }
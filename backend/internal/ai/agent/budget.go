@@ -0,0 +1,269 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/provider"
+)
+
+const (
+	BudgetTokens   = "tokens"
+	BudgetWallTime = "wall_time"
+	BudgetCost     = "cost"
+	BudgetCommands = "commands"
+
+	ErrCodeBudgetExceeded = "BUDGET_EXCEEDED"
+	EventBudgetWarning    = "budget.warning"
+
+	defaultBudgetWarnAt = 0.8
+)
+
+// CommandTools count against the commands budget.
+var CommandTools = map[string]bool{
+	"run_command":      true,
+	"run_tests":        true,
+	"send_to_terminal": true,
+}
+
+// BudgetConfig is the "budget" section of .webide/config.json. A chat can
+// override individual fields; zero means no limit.
+type BudgetConfig struct {
+	MaxTokens     int     `json:"max_tokens,omitempty"`
+	MaxWallTimeMs int64   `json:"max_wall_time_ms,omitempty"`
+	MaxCostUSD    float64 `json:"max_cost_usd,omitempty"`
+	MaxCommands   int     `json:"max_commands,omitempty"`
+	// WarnAt is the fraction of a budget at which the model is asked to
+	// wrap up. Defaults to 0.8.
+	WarnAt float64 `json:"warn_at,omitempty"`
+	// Prices in USD per million tokens; the model's list price is used when
+	// unset.
+	InputPricePerMTok  float64 `json:"input_price_per_mtok,omitempty"`
+	OutputPricePerMTok float64 `json:"output_price_per_mtok,omitempty"`
+}
+
+// Merge returns c with every non-zero field of override applied.
+func (c BudgetConfig) Merge(override *BudgetConfig) BudgetConfig {
+	if override == nil {
+		return c
+	}
+	if override.MaxTokens != 0 {
+		c.MaxTokens = override.MaxTokens
+	}
+	if override.MaxWallTimeMs != 0 {
+		c.MaxWallTimeMs = override.MaxWallTimeMs
+	}
+	if override.MaxCostUSD != 0 {
+		c.MaxCostUSD = override.MaxCostUSD
+	}
+	if override.MaxCommands != 0 {
+		c.MaxCommands = override.MaxCommands
+	}
+	if override.WarnAt != 0 {
+		c.WarnAt = override.WarnAt
+	}
+	if override.InputPricePerMTok != 0 {
+		c.InputPricePerMTok = override.InputPricePerMTok
+	}
+	if override.OutputPricePerMTok != 0 {
+		c.OutputPricePerMTok = override.OutputPricePerMTok
+	}
+	return c
+}
+
+func (c BudgetConfig) limited() bool {
+	return c.MaxTokens > 0 || c.MaxWallTimeMs > 0 || c.MaxCostUSD > 0 || c.MaxCommands > 0
+}
+
+type modelPrice struct {
+	prefix        string
+	input, output float64
+}
+
+// modelPrices are list prices in USD per million tokens, matched by model
+// name prefix. The last entry is the fallback.
+var modelPrices = []modelPrice{
+	{"claude-opus", 15, 75},
+	{"claude-sonnet", 3, 15},
+	{"claude-haiku", 0.8, 4},
+	{"claude-3-5-haiku", 0.8, 4},
+	{"minimax", 0.3, 1.2},
+	{"", 3, 15},
+}
+
+func priceFor(model string) (float64, float64) {
+	model = strings.ToLower(model)
+	for _, p := range modelPrices {
+		if strings.HasPrefix(model, p.prefix) {
+			return p.input, p.output
+		}
+	}
+	return 0, 0
+}
+
+type BudgetUsage struct {
+	Tokens     int     `json:"tokens"`
+	WallTimeMs int64   `json:"wall_time_ms"`
+	CostUSD    float64 `json:"cost_usd"`
+	Commands   int     `json:"commands"`
+}
+
+// BudgetExceeded reports the first budget a run went over.
+type BudgetExceeded struct {
+	Budget string  `json:"budget"`
+	Limit  float64 `json:"limit"`
+	Used   float64 `json:"used"`
+}
+
+func (e *BudgetExceeded) Error() string {
+	return fmt.Sprintf("%s budget exceeded: used %s of %s", e.Budget, formatBudget(e.Budget, e.Used), formatBudget(e.Budget, e.Limit))
+}
+
+type BudgetSummary struct {
+	Limits   BudgetConfig    `json:"limits"`
+	Used     BudgetUsage     `json:"used"`
+	Exceeded *BudgetExceeded `json:"exceeded,omitempty"`
+}
+
+// Budget tracks one run against its BudgetConfig. A nil Budget is valid and
+// never warns or stops.
+type Budget struct {
+	cfg           BudgetConfig
+	start         time.Time
+	input, output float64
+
+	mu       sync.Mutex
+	usage    provider.TokenUsage
+	commands int
+	warned   bool
+	exceeded *BudgetExceeded
+}
+
+func NewBudget(cfg BudgetConfig, model string) *Budget {
+	if !cfg.limited() {
+		return nil
+	}
+	if cfg.WarnAt <= 0 || cfg.WarnAt >= 1 {
+		cfg.WarnAt = defaultBudgetWarnAt
+	}
+
+	b := &Budget{cfg: cfg, start: time.Now()}
+	b.input, b.output = priceFor(model)
+	if cfg.InputPricePerMTok > 0 {
+		b.input = cfg.InputPricePerMTok
+	}
+	if cfg.OutputPricePerMTok > 0 {
+		b.output = cfg.OutputPricePerMTok
+	}
+	return b
+}
+
+func (b *Budget) AddUsage(u provider.TokenUsage) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.usage.PromptTokens += u.PromptTokens
+	b.usage.CompletionTokens += u.CompletionTokens
+	b.usage.TotalTokens += u.TotalTokens
+}
+
+// BeforeTool counts command tools and refuses the one that would go over
+// the commands budget.
+func (b *Budget) BeforeTool(name string) *BudgetExceeded {
+	if b == nil || !CommandTools[name] {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.cfg.MaxCommands > 0 && b.commands >= b.cfg.MaxCommands {
+		b.exceeded = &BudgetExceeded{Budget: BudgetCommands, Limit: float64(b.cfg.MaxCommands), Used: float64(b.commands + 1)}
+		return b.exceeded
+	}
+	b.commands++
+	return nil
+}
+
+// Check compares usage with the limits. It returns a wrap-up message the
+// first time any budget passes WarnAt, and the exceeded budget once one is
+// used up.
+func (b *Budget) Check() (string, *BudgetExceeded) {
+	if b == nil {
+		return "", nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	used := b.used()
+	type check struct {
+		name        string
+		used, limit float64
+	}
+	checks := []check{
+		{BudgetTokens, float64(used.Tokens), float64(b.cfg.MaxTokens)},
+		{BudgetWallTime, float64(used.WallTimeMs), float64(b.cfg.MaxWallTimeMs)},
+		{BudgetCost, used.CostUSD, b.cfg.MaxCostUSD},
+		{BudgetCommands, float64(used.Commands), float64(b.cfg.MaxCommands)},
+	}
+
+	var warn *check
+	for i, c := range checks {
+		if c.limit <= 0 {
+			continue
+		}
+		// Commands are refused one at a time in BeforeTool, so reaching the
+		// limit is not an overrun.
+		if c.used >= c.limit && c.name != BudgetCommands {
+			b.exceeded = &BudgetExceeded{Budget: c.name, Limit: c.limit, Used: c.used}
+			return "", b.exceeded
+		}
+		if warn == nil && c.used >= c.limit*b.cfg.WarnAt {
+			warn = &checks[i]
+		}
+	}
+
+	if warn == nil || b.warned {
+		return "", nil
+	}
+	b.warned = true
+	return fmt.Sprintf("Budget notice: this run has used %s of its %s budget (%s). Wrap up now: finish or revert the change in progress, avoid starting new work, and give your final answer.",
+		formatBudget(warn.name, warn.used), strings.ReplaceAll(warn.name, "_", " "), formatBudget(warn.name, warn.limit)), nil
+}
+
+func (b *Budget) used() BudgetUsage {
+	return BudgetUsage{
+		Tokens:     b.usage.TotalTokens,
+		WallTimeMs: time.Since(b.start).Milliseconds(),
+		CostUSD:    (float64(b.usage.PromptTokens)*b.input + float64(b.usage.CompletionTokens)*b.output) / 1e6,
+		Commands:   b.commands,
+	}
+}
+
+func (b *Budget) Summary() *BudgetSummary {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &BudgetSummary{Limits: b.cfg, Used: b.used(), Exceeded: b.exceeded}
+}
+
+func formatBudget(name string, v float64) string {
+	switch name {
+	case BudgetWallTime:
+		return (time.Duration(v) * time.Millisecond).Round(time.Second).String()
+	case BudgetCost:
+		return fmt.Sprintf("$%.2f", v)
+	case BudgetTokens:
+		return fmt.Sprintf("%.0f tokens", v)
+	}
+	return fmt.Sprintf("%.0f", v)
+}
+
+// BudgetErrorPayload is the agent.error payload for a budget stop.
+func BudgetErrorPayload(e *BudgetExceeded) AgentErrorPayload {
+	return AgentErrorPayload{Code: ErrCodeBudgetExceeded, Message: e.Error(), Budget: e}
+}
@@ -0,0 +1,401 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/tools/builtin"
+)
+
+const (
+	ChecksAfterWrite  = "after_write"
+	ChecksBeforeDone  = "before_done"
+	ChecksStatusPass  = "passed"
+	ChecksStatusFail  = "failed"
+	ChecksStatusSkip  = "skipped"
+	EventChecksResult = "checks.result"
+
+	defaultCheckAttempts  = 2
+	defaultCheckTimeout   = 5 * time.Minute
+	maxCheckOutputBytes   = 256 * 1024
+	maxCheckFeedbackLines = 60
+	maxCheckFeedbackBytes = 6 * 1024
+)
+
+// WriteTools are the tools whose successful execution makes the workspace
+// dirty and due for a check run.
+var WriteTools = map[string]bool{
+	"apply_patch": true,
+	"write_file":  true,
+	"str_replace": true,
+	"move_path":   true,
+	"copy_path":   true,
+	"delete_path": true,
+	"make_dir":    true,
+}
+
+// ChecksConfig is the "checks" section of .webide/config.json. Commands
+// overrides detected commands by name; an empty command disables that check.
+type ChecksConfig struct {
+	Disabled    bool              `json:"disabled,omitempty"`
+	Run         string            `json:"run,omitempty"`
+	MaxAttempts int               `json:"max_attempts,omitempty"`
+	TimeoutMs   int               `json:"timeout_ms,omitempty"`
+	Commands    map[string]string `json:"commands,omitempty"`
+}
+
+type CheckCommand struct {
+	Name   string `json:"name"`
+	Cmd    string `json:"cmd"`
+	Source string `json:"source"`
+}
+
+type CheckRunResult struct {
+	Name       string `json:"name"`
+	Cmd        string `json:"cmd"`
+	Passed     bool   `json:"passed"`
+	ExitCode   int    `json:"exit_code"`
+	TimedOut   bool   `json:"timed_out,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Output     string `json:"output,omitempty"`
+}
+
+type CheckOutcome struct {
+	Passed  bool             `json:"passed"`
+	Attempt int              `json:"attempt"`
+	Results []CheckRunResult `json:"results"`
+}
+
+// ChecksSummary is reported on agent.done.
+type ChecksSummary struct {
+	Status   string           `json:"status"`
+	Runs     int              `json:"runs"`
+	Attempts int              `json:"fix_attempts"`
+	Results  []CheckRunResult `json:"results,omitempty"`
+}
+
+var checkOrder = []string{"build", "lint", "test"}
+
+// DetectChecks infers build, lint and test commands from the project files.
+// A Makefile target wins over package.json scripts, which win over go.mod.
+func DetectChecks(projectRoot string) []CheckCommand {
+	found := make(map[string]CheckCommand)
+
+	if _, err := os.Stat(filepath.Join(projectRoot, "go.mod")); err == nil {
+		found["build"] = CheckCommand{Name: "build", Cmd: "go build ./...", Source: "go.mod"}
+		found["lint"] = CheckCommand{Name: "lint", Cmd: "go vet ./...", Source: "go.mod"}
+		found["test"] = CheckCommand{Name: "test", Cmd: "go test ./...", Source: "go.mod"}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(projectRoot, "package.json")); err == nil {
+		var pkg struct {
+			Scripts map[string]string `json:"scripts"`
+		}
+		if json.Unmarshal(data, &pkg) == nil {
+			runner := nodeRunner(projectRoot)
+			for _, name := range checkOrder {
+				script, ok := pkg.Scripts[name]
+				if !ok || strings.Contains(script, "no test specified") {
+					continue
+				}
+				found[name] = CheckCommand{Name: name, Cmd: runner + " run " + name, Source: "package.json"}
+			}
+		}
+	}
+
+	for _, name := range []string{"Makefile", "makefile", "GNUmakefile"} {
+		data, err := os.ReadFile(filepath.Join(projectRoot, name))
+		if err != nil {
+			continue
+		}
+		for _, target := range checkOrder {
+			if regexp.MustCompile(`(?m)^` + target + `\s*:`).Match(data) {
+				found[target] = CheckCommand{Name: target, Cmd: "make " + target, Source: name}
+			}
+		}
+		break
+	}
+
+	var checks []CheckCommand
+	for _, name := range checkOrder {
+		if c, ok := found[name]; ok {
+			checks = append(checks, c)
+		}
+	}
+	return checks
+}
+
+func nodeRunner(projectRoot string) string {
+	switch {
+	case fileExists(filepath.Join(projectRoot, "pnpm-lock.yaml")):
+		return "pnpm"
+	case fileExists(filepath.Join(projectRoot, "yarn.lock")):
+		return "yarn"
+	case fileExists(filepath.Join(projectRoot, "bun.lockb")):
+		return "bun"
+	}
+	return "npm"
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ResolveChecks applies config overrides on top of the detected commands.
+func ResolveChecks(projectRoot string, cfg ChecksConfig) []CheckCommand {
+	if cfg.Disabled {
+		return nil
+	}
+
+	checks := DetectChecks(projectRoot)
+	if len(cfg.Commands) == 0 {
+		return checks
+	}
+
+	var out []CheckCommand
+	for _, c := range checks {
+		if cmd, ok := cfg.Commands[c.Name]; ok {
+			if strings.TrimSpace(cmd) == "" {
+				continue
+			}
+			c.Cmd, c.Source = cmd, ProjectConfigPath
+		}
+		out = append(out, c)
+	}
+
+	var extra []string
+	for name, cmd := range cfg.Commands {
+		if strings.TrimSpace(cmd) == "" || containsCheck(out, name) {
+			continue
+		}
+		extra = append(extra, name)
+	}
+	sort.Slice(extra, func(i, j int) bool {
+		ri, rj := checkRank(extra[i]), checkRank(extra[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return extra[i] < extra[j]
+	})
+	for _, name := range extra {
+		out = append(out, CheckCommand{Name: name, Cmd: cfg.Commands[name], Source: ProjectConfigPath})
+	}
+	return out
+}
+
+func containsCheck(checks []CheckCommand, name string) bool {
+	for _, c := range checks {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func checkRank(name string) int {
+	for i, n := range checkOrder {
+		if n == name {
+			return i
+		}
+	}
+	return len(checkOrder)
+}
+
+// RunChecks runs every check in order and stops at the first failure, since
+// later checks rarely add information when the build is broken.
+func RunChecks(ctx context.Context, projectRoot string, checks []CheckCommand, timeout time.Duration) CheckOutcome {
+	outcome := CheckOutcome{Passed: true}
+	for _, c := range checks {
+		start := time.Now()
+		res := CheckRunResult{Name: c.Name, Cmd: c.Cmd}
+
+		proc, err := builtin.ExecCommand(ctx, builtin.ExecSpec{
+			Cmd:            c.Cmd,
+			Dir:            projectRoot,
+			Env:            os.Environ(),
+			Timeout:        timeout,
+			MaxOutputBytes: maxCheckOutputBytes,
+			Stream:         true,
+		})
+		res.DurationMs = time.Since(start).Milliseconds()
+		if err != nil {
+			res.ExitCode = -1
+			res.Output = err.Error()
+		} else {
+			res.ExitCode = proc.ExitCode
+			res.TimedOut = proc.Cancelled
+			res.Passed = proc.ExitCode == 0 && !proc.Cancelled
+			if !res.Passed {
+				res.Output = CompactCheckOutput(proc.Output.Text())
+			}
+		}
+
+		log.Printf("[Checks] %s: exit=%d passed=%v (%dms)", c.Name, res.ExitCode, res.Passed, res.DurationMs)
+		outcome.Results = append(outcome.Results, res)
+		if !res.Passed {
+			outcome.Passed = false
+			break
+		}
+	}
+	return outcome
+}
+
+var checkSignalRe = regexp.MustCompile(`(?i)(error|fail|panic|undefined|cannot|expected|warning|\w+\.\w+:\d+)`)
+
+// CompactCheckOutput keeps the lines that carry signal (errors, failing
+// tests, file:line references) and falls back to the tail of the output.
+func CompactCheckOutput(output string) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+
+	var keep []string
+	for _, l := range lines {
+		if checkSignalRe.MatchString(l) {
+			keep = append(keep, l)
+		}
+	}
+	if len(keep) == 0 {
+		keep = lines
+	}
+	if len(keep) > maxCheckFeedbackLines {
+		omitted := len(keep) - maxCheckFeedbackLines
+		keep = append([]string{fmt.Sprintf("... (%d lines omitted)", omitted)}, keep[omitted:]...)
+	}
+
+	out := strings.Join(keep, "\n")
+	if len(out) > maxCheckFeedbackBytes {
+		out = "..." + out[len(out)-maxCheckFeedbackBytes:]
+	}
+	return out
+}
+
+// Verifier drives the check-and-fix loop for one agent run. A nil Verifier
+// is valid and never runs anything.
+type Verifier struct {
+	root        string
+	checks      []CheckCommand
+	run         string
+	maxAttempts int
+	timeout     time.Duration
+
+	dirty    bool
+	runs     int
+	attempts int
+	last     *CheckOutcome
+}
+
+func NewVerifier(projectRoot string, cfg ChecksConfig) *Verifier {
+	checks := ResolveChecks(projectRoot, cfg)
+	if projectRoot == "" || len(checks) == 0 {
+		return nil
+	}
+
+	v := &Verifier{
+		root:        projectRoot,
+		checks:      checks,
+		run:         cfg.Run,
+		maxAttempts: cfg.MaxAttempts,
+		timeout:     defaultCheckTimeout,
+	}
+	if v.run != ChecksAfterWrite {
+		v.run = ChecksBeforeDone
+	}
+	if v.maxAttempts <= 0 {
+		v.maxAttempts = defaultCheckAttempts
+	}
+	if cfg.TimeoutMs > 0 {
+		v.timeout = time.Duration(cfg.TimeoutMs) * time.Millisecond
+	}
+	return v
+}
+
+func (v *Verifier) Checks() []CheckCommand {
+	if v == nil {
+		return nil
+	}
+	return v.checks
+}
+
+// NoteTool marks the workspace dirty after a successful write tool.
+func (v *Verifier) NoteTool(name string, ok bool) {
+	if v != nil && ok && WriteTools[name] {
+		v.dirty = true
+	}
+}
+
+// AfterStep runs the checks at the end of a step that wrote files, when the
+// project asks for after_write checks. canRetry tells whether the loop has
+// room for another step.
+func (v *Verifier) AfterStep(ctx context.Context, canRetry bool) (*CheckOutcome, string) {
+	if v == nil || v.run != ChecksAfterWrite || !v.dirty {
+		return nil, ""
+	}
+	return v.check(ctx, canRetry)
+}
+
+// BeforeDone runs the checks when the model wants to finish with unchecked
+// edits. A non-empty feedback string means the model should get another turn.
+func (v *Verifier) BeforeDone(ctx context.Context, canRetry bool) (*CheckOutcome, string) {
+	if v == nil || !v.dirty {
+		return nil, ""
+	}
+	return v.check(ctx, canRetry)
+}
+
+func (v *Verifier) check(ctx context.Context, canRetry bool) (*CheckOutcome, string) {
+	outcome := RunChecks(ctx, v.root, v.checks, v.timeout)
+	v.runs++
+	outcome.Attempt = v.attempts
+	v.last = &outcome
+
+	if outcome.Passed {
+		v.dirty = false
+		return &outcome, ""
+	}
+	if !canRetry || v.attempts >= v.maxAttempts || ctx.Err() != nil {
+		v.dirty = false
+		return &outcome, ""
+	}
+
+	v.attempts++
+	return &outcome, checkFeedback(outcome, v.attempts, v.maxAttempts)
+}
+
+func checkFeedback(outcome CheckOutcome, attempt, max int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Project checks failed after your changes (fix attempt %d of %d). Fix the problems below, then finish.\n", attempt, max)
+	for _, r := range outcome.Results {
+		if r.Passed {
+			continue
+		}
+		status := fmt.Sprintf("exit %d", r.ExitCode)
+		if r.TimedOut {
+			status = "timed out"
+		}
+		fmt.Fprintf(&b, "\n$ %s (%s)\n%s\n", r.Cmd, status, r.Output)
+	}
+	return b.String()
+}
+
+func (v *Verifier) Summary() *ChecksSummary {
+	if v == nil {
+		return nil
+	}
+	s := &ChecksSummary{Status: ChecksStatusSkip, Runs: v.runs, Attempts: v.attempts}
+	if v.last != nil {
+		s.Results = v.last.Results
+		s.Status = ChecksStatusFail
+		if v.last.Passed {
+			s.Status = ChecksStatusPass
+		}
+	}
+	return s
+}
@@ -1,6 +1,10 @@
 package agent
 
-import "time"
+import (
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/redact"
+)
 
 type AgentMode string
 
@@ -25,6 +29,18 @@ type AgentConfig struct {
 	SystemPrompt string
 	ProjectRoot  string
 	ChatID       string
+	Hooks        []HookConfig
+	Checks       *ChecksConfig
+	Budget       *BudgetConfig
+	Summarize    *SummarizeConfig
+	Redact       *redact.Config
+	Model        string
+	// AutoApprove runs tools that would need confirmation without asking,
+	// for headless runs.
+	AutoApprove bool
+	// DenyConfirm refuses tools that would need confirmation instead, for
+	// unattended runs that nobody can approve.
+	DenyConfirm bool
 }
 
 func DefaultConfig() AgentConfig {
@@ -1,6 +1,10 @@
 package agent
 
-import "time"
+import (
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/provider"
+)
 
 type WSEvent struct {
 	Type      string      `json:"type"`
@@ -20,8 +24,38 @@ const (
 	EventCommandDone          = "command.done"
 	EventAgentDone            = "agent.done"
 	EventAgentError           = "agent.error"
+	EventHookResult           = "hook.result"
+	EventStepStart            = "step.start"
+	EventStepEnd              = "step.end"
+	EventAssistantDelta       = "assistant.delta"
+	EventThinkingDelta        = "assistant.thinking"
+	EventAssistantMessage     = "assistant.message"
+	EventAssistantFinal       = "assistant.final"
+	EventInputDelivered       = "input.delivered"
+	EventInputDropped         = "input.dropped"
 )
 
+// StepPayload numbers a step of the run, starting at 1.
+type StepPayload struct {
+	Step int `json:"step"`
+}
+
+// AssistantMessagePayload is the model's complete response in one step.
+type AssistantMessagePayload struct {
+	Step      int        `json:"step"`
+	Content   string     `json:"content"`
+	Thinking  string     `json:"thinking,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// InputPayload reports what happened to a UserInput handed over by
+// RunHandler.Inputs.
+type InputPayload struct {
+	ID     string `json:"id"`
+	Step   int    `json:"step,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
 type ToolCallPayload struct {
 	ToolCallID string                 `json:"id"`
 	Name       string                 `json:"name"`
@@ -34,6 +68,7 @@ type ToolApprovalPayload struct {
 	Arguments  map[string]interface{} `json:"arguments"`
 	Summary    string                 `json:"summary"`
 	Policy     string                 `json:"policy"`
+	Preview    string                 `json:"preview,omitempty"`
 }
 
 type ToolResultPayload struct {
@@ -64,13 +99,17 @@ type CommandDonePayload struct {
 }
 
 type AgentDonePayload struct {
-	Steps    int    `json:"steps"`
-	FinalMsg string `json:"final_message"`
+	Steps    int                 `json:"steps"`
+	FinalMsg string              `json:"final_message"`
+	Usage    provider.TokenUsage `json:"usage"`
+	Checks   *ChecksSummary      `json:"checks,omitempty"`
+	Budget   *BudgetSummary      `json:"budget,omitempty"`
 }
 
 type AgentErrorPayload struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code    string          `json:"code"`
+	Message string          `json:"message"`
+	Budget  *BudgetExceeded `json:"budget,omitempty"`
 }
 
 func NewToolCallEvent(sessionID, projectID string, payload ToolCallPayload) WSEvent {
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrApprovalPending ends a run at a tool call that needs approval instead of
+// waiting for it. HandleApproval resumes the run once the user answers.
+var ErrApprovalPending = errors.New("tool call is waiting for approval")
+
+// UserInput is a user message for the model.
+type UserInput struct {
+	ID string
+	// Message is what the user typed; user_message hooks see this.
+	Message string
+	// Prompt is what the model gets, e.g. the message with the editor
+	// context in front. Empty means Message.
+	Prompt string
+}
+
+func (in UserInput) prompt() string {
+	if in.Prompt != "" {
+		return in.Prompt
+	}
+	return in.Message
+}
+
+// RunHandler connects a run to whoever started it. The orchestrator reports
+// every event of the run to it and asks it for what the loop cannot decide
+// by itself.
+type RunHandler interface {
+	// Event receives the run's events in the order they happen.
+	Event(ev WSEvent)
+	// AwaitApproval shows a tool call that needs confirmation to the user
+	// and blocks until they answer or ctx is done. Returning
+	// ErrApprovalPending ends the run instead.
+	AwaitApproval(ctx context.Context, req ToolApprovalPayload) (approved bool, reason string, err error)
+	// Inputs returns the user messages sent since the last call. final is
+	// set when the run would otherwise finish, so the handler can stop
+	// accepting messages when there are none.
+	Inputs(final bool) []UserInput
+	// Review runs once the model is done and returns feedback that sends it
+	// back to work, or "" to finish. Feedback given when the run is out of
+	// steps only ends up in the session, so handlers persist it themselves.
+	Review(ctx context.Context) string
+}
+
+// senderHandler adapts a WebSocketSender. Approvals end the run and resume
+// through HandleApproval; there are no queued messages or reviews.
+type senderHandler struct {
+	session *AgentSession
+	send    WebSocketSender
+}
+
+func (h senderHandler) Event(ev WSEvent) {
+	h.send(ev)
+}
+
+func (h senderHandler) AwaitApproval(ctx context.Context, req ToolApprovalPayload) (bool, string, error) {
+	h.send(NewToolApprovalRequiredEvent(h.session.ID.String(), h.session.ProjectID.String(), req))
+	return false, "", ErrApprovalPending
+}
+
+func (h senderHandler) Inputs(final bool) []UserInput {
+	return nil
+}
+
+func (h senderHandler) Review(ctx context.Context) string {
+	return ""
+}
+
+// event builds a session event stamped with the current time.
+func event(session *AgentSession, typ, id string, payload interface{}) WSEvent {
+	return WSEvent{
+		Type:      typ,
+		SessionID: session.ID.String(),
+		ProjectID: session.ProjectID.String(),
+		TS:        time.Now(),
+		ID:        id,
+		Payload:   payload,
+	}
+}
@@ -0,0 +1,348 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/redact"
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/ai/tools/builtin"
+)
+
+type HookEvent string
+
+const (
+	HookPreTool     HookEvent = "pre_tool"
+	HookPostTool    HookEvent = "post_tool"
+	HookUserMessage HookEvent = "on_user_message"
+	HookStop        HookEvent = "on_stop"
+)
+
+const (
+	HookDecisionAllow = "allow"
+	HookDecisionAsk   = "ask"
+	HookDecisionBlock = "block"
+)
+
+const (
+	DefaultHookTimeout = 30 * time.Second
+	MaxHookTimeout     = 10 * time.Minute
+	maxHookOutputBytes = 64 * 1024
+	maxHookOutputShown = 4096
+)
+
+const HookStopPrompt = "A stop hook asked you to continue before finishing."
+
+type HookConfig struct {
+	Name      string    `json:"name,omitempty"`
+	Event     HookEvent `json:"event"`
+	Matcher   string    `json:"matcher,omitempty"`
+	Command   string    `json:"command"`
+	TimeoutMs int       `json:"timeout_ms,omitempty"`
+}
+
+type HookPayload struct {
+	Event       HookEvent              `json:"event"`
+	SessionID   string                 `json:"session_id,omitempty"`
+	ProjectID   string                 `json:"project_id,omitempty"`
+	ChatID      string                 `json:"chat_id,omitempty"`
+	ProjectRoot string                 `json:"project_root"`
+	ToolName    string                 `json:"tool_name,omitempty"`
+	ToolCallID  string                 `json:"tool_call_id,omitempty"`
+	Arguments   map[string]interface{} `json:"arguments,omitempty"`
+	Result      *tools.ToolResult      `json:"result,omitempty"`
+	Message     string                 `json:"message,omitempty"`
+	StopReason  string                 `json:"stop_reason,omitempty"`
+}
+
+type HookResult struct {
+	Hook       string    `json:"hook"`
+	Event      HookEvent `json:"event"`
+	ToolName   string    `json:"tool_name,omitempty"`
+	ToolCallID string    `json:"tool_call_id,omitempty"`
+	ExitCode   int       `json:"exit_code"`
+	Output     string    `json:"output,omitempty"`
+	Decision   string    `json:"decision,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Feedback   string    `json:"feedback,omitempty"`
+	TimedOut   bool      `json:"timed_out,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+type HookOutcome struct {
+	Results  []HookResult
+	Decision string
+	Reason   string
+	Feedback string
+}
+
+func (o HookOutcome) Blocked() bool {
+	return o.Decision == HookDecisionBlock
+}
+
+// hookResponse is the optional JSON object a hook may print on stdout.
+type hookResponse struct {
+	Decision string `json:"decision"`
+	Reason   string `json:"reason"`
+	Feedback string `json:"feedback"`
+}
+
+type HookRunner struct {
+	projectRoot string
+	hooks       []HookConfig
+	redactor    *redact.Redactor
+}
+
+// NewHookRunner runs hooks in projectRoot. Hook output is shown to the model,
+// so it goes through redactor like any tool result; redactor may be nil.
+func NewHookRunner(projectRoot string, hooks []HookConfig, redactor *redact.Redactor) *HookRunner {
+	return &HookRunner{
+		projectRoot: projectRoot,
+		hooks:       hooks,
+		redactor:    redactor,
+	}
+}
+
+func (r *HookRunner) Has(event HookEvent) bool {
+	if r == nil {
+		return false
+	}
+	for _, h := range r.hooks {
+		if h.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *HookRunner) Run(ctx context.Context, payload HookPayload) HookOutcome {
+	var outcome HookOutcome
+	if r == nil {
+		return outcome
+	}
+
+	payload.ProjectRoot = r.projectRoot
+	stdin, _ := json.Marshal(payload)
+
+	var reasons, feedback []string
+	for _, h := range r.hooks {
+		if h.Event != payload.Event || h.Command == "" {
+			continue
+		}
+		if payload.ToolName != "" && !matchHook(h.Matcher, payload.ToolName) {
+			continue
+		}
+
+		result := r.runHook(ctx, h, payload, stdin)
+		result.Output = r.redactor.String(result.Output)
+		result.Reason = r.redactor.String(result.Reason)
+		result.Feedback = r.redactor.String(result.Feedback)
+		outcome.Results = append(outcome.Results, result)
+
+		if hookDecisionRank(result.Decision) > hookDecisionRank(outcome.Decision) {
+			outcome.Decision = result.Decision
+		}
+		if result.Reason != "" {
+			reasons = append(reasons, result.Reason)
+		}
+		if result.Feedback != "" {
+			feedback = append(feedback, result.Feedback)
+		}
+
+		if result.Decision == HookDecisionBlock && payload.Event == HookPreTool {
+			break
+		}
+	}
+
+	outcome.Reason = strings.Join(reasons, "\n")
+	outcome.Feedback = strings.Join(feedback, "\n")
+	return outcome
+}
+
+func (r *HookRunner) runHook(ctx context.Context, h HookConfig, payload HookPayload, stdin []byte) HookResult {
+	timeout := DefaultHookTimeout
+	if h.TimeoutMs > 0 {
+		timeout = time.Duration(h.TimeoutMs) * time.Millisecond
+	}
+	if timeout > MaxHookTimeout {
+		timeout = MaxHookTimeout
+	}
+
+	name := h.Name
+	if name == "" {
+		name = truncateString(h.Command, 60)
+	}
+
+	result := HookResult{
+		Hook:       name,
+		Event:      payload.Event,
+		ToolName:   payload.ToolName,
+		ToolCallID: payload.ToolCallID,
+	}
+
+	env := append(os.Environ(),
+		"WEBIDE_HOOK_EVENT="+string(payload.Event),
+		"WEBIDE_PROJECT_ROOT="+r.projectRoot,
+		"WEBIDE_TOOL_NAME="+payload.ToolName,
+	)
+
+	start := time.Now()
+	proc, err := builtin.ExecCommand(ctx, builtin.ExecSpec{
+		Cmd:            h.Command,
+		Dir:            r.projectRoot,
+		Env:            env,
+		Stdin:          bytes.NewReader(stdin),
+		Timeout:        timeout,
+		MaxOutputBytes: maxHookOutputBytes,
+		Stream:         true,
+	})
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		log.Printf("[Hooks] %s hook %q failed to start: %v", payload.Event, name, err)
+		result.ExitCode = -1
+		result.Decision = HookDecisionBlock
+		result.Reason = "hook failed to start: " + err.Error()
+		return result
+	}
+
+	output := proc.Output.Text()
+	result.ExitCode = proc.ExitCode
+	result.TimedOut = proc.Cancelled
+	result.Output = truncateString(output, maxHookOutputShown)
+
+	if resp, ok := parseHookResponse(output); ok {
+		result.Decision = normalizeHookDecision(resp.Decision)
+		result.Reason = resp.Reason
+		result.Feedback = resp.Feedback
+	}
+
+	if result.ExitCode != 0 && result.Decision == "" {
+		result.Decision = HookDecisionBlock
+		if result.TimedOut {
+			result.Reason = "hook timed out after " + timeout.String()
+		} else {
+			result.Reason = strings.TrimSpace(result.Output)
+		}
+	}
+
+	log.Printf("[Hooks] %s hook %q: exit=%d decision=%q (%dms)", payload.Event, name, result.ExitCode, result.Decision, result.DurationMs)
+	return result
+}
+
+func parseHookResponse(output string) (hookResponse, bool) {
+	var resp hookResponse
+	trimmed := strings.TrimSpace(output)
+	if !strings.HasPrefix(trimmed, "{") {
+		return resp, false
+	}
+	if err := json.Unmarshal([]byte(trimmed), &resp); err != nil {
+		return resp, false
+	}
+	return resp, true
+}
+
+func normalizeHookDecision(decision string) string {
+	switch strings.ToLower(strings.TrimSpace(decision)) {
+	case "block", "deny":
+		return HookDecisionBlock
+	case "ask", "confirm":
+		return HookDecisionAsk
+	case "allow", "approve":
+		return HookDecisionAllow
+	}
+	return ""
+}
+
+func hookDecisionRank(decision string) int {
+	switch decision {
+	case HookDecisionBlock:
+		return 3
+	case HookDecisionAsk:
+		return 2
+	case HookDecisionAllow:
+		return 1
+	}
+	return 0
+}
+
+func matchHook(matcher, toolName string) bool {
+	if matcher == "" || matcher == "*" {
+		return true
+	}
+	for _, alt := range strings.Split(matcher, "|") {
+		if ok, _ := path.Match(strings.TrimSpace(alt), toolName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyHookDecision lets a pre_tool hook tighten the policy decision: ask
+// turns an allowed call into a confirmation and block denies it. A hook's
+// allow is ignored, since hooks come from a file the agent can edit.
+func ApplyHookDecision(decision PolicyDecision, outcome HookOutcome) PolicyDecision {
+	switch {
+	case outcome.Decision == HookDecisionBlock:
+		return DecisionDeny
+	case outcome.Decision == HookDecisionAsk && decision == DecisionAllow:
+		return DecisionConfirm
+	}
+	return decision
+}
+
+// configPathArgs are the arguments of write tools that name a path.
+var configPathArgs = map[string][]string{
+	"write_file":  {"path"},
+	"str_replace": {"path"},
+	"delete_path": {"path"},
+	"make_dir":    {"path"},
+	"move_path":   {"source", "destination"},
+	"copy_path":   {"destination"},
+}
+
+// ProtectedWrite returns an approval reason when name(args) would change the
+// project config, which defines the hooks and the agent's own limits.
+func ProtectedWrite(projectRoot, name string, args map[string]interface{}) (string, bool) {
+	reason := ProjectConfigPath + " holds the project's hooks and agent settings; changing it needs approval"
+	if name == "apply_patch" {
+		patch, _ := args["patch"].(string)
+		return reason, strings.Contains(patch, ProjectConfigPath)
+	}
+	for _, arg := range configPathArgs[name] {
+		p, _ := args[arg].(string)
+		if p == "" {
+			continue
+		}
+		if filepath.IsAbs(p) {
+			rel, err := filepath.Rel(projectRoot, p)
+			if err != nil {
+				continue
+			}
+			p = rel
+		}
+		p = filepath.ToSlash(filepath.Clean(p))
+		if p == ProjectConfigPath || strings.HasPrefix(ProjectConfigPath, p+"/") {
+			return reason, true
+		}
+	}
+	return "", false
+}
+
+func AppendHookFeedback(content string, outcome HookOutcome) string {
+	text := outcome.Feedback
+	if text == "" && outcome.Blocked() {
+		text = outcome.Reason
+	}
+	if text == "" {
+		return content
+	}
+	return content + "\n\nHook feedback:\n" + text
+}
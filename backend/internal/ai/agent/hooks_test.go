@@ -0,0 +1,145 @@
+package agent_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/agent"
+)
+
+func TestApplyHookDecision(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy agent.PolicyDecision
+		hook   string
+		want   agent.PolicyDecision
+	}{
+		{name: "no hook keeps allow", policy: agent.DecisionAllow, hook: "", want: agent.DecisionAllow},
+		{name: "no hook keeps confirm", policy: agent.DecisionConfirm, hook: "", want: agent.DecisionConfirm},
+		{name: "ask tightens allow", policy: agent.DecisionAllow, hook: agent.HookDecisionAsk, want: agent.DecisionConfirm},
+		{name: "ask keeps confirm", policy: agent.DecisionConfirm, hook: agent.HookDecisionAsk, want: agent.DecisionConfirm},
+		{name: "ask keeps deny", policy: agent.DecisionDeny, hook: agent.HookDecisionAsk, want: agent.DecisionDeny},
+		{name: "block denies allow", policy: agent.DecisionAllow, hook: agent.HookDecisionBlock, want: agent.DecisionDeny},
+		{name: "block denies confirm", policy: agent.DecisionConfirm, hook: agent.HookDecisionBlock, want: agent.DecisionDeny},
+		{name: "allow cannot skip confirm", policy: agent.DecisionConfirm, hook: agent.HookDecisionAllow, want: agent.DecisionConfirm},
+		{name: "allow cannot lift deny", policy: agent.DecisionDeny, hook: agent.HookDecisionAllow, want: agent.DecisionDeny},
+		{name: "allow keeps allow", policy: agent.DecisionAllow, hook: agent.HookDecisionAllow, want: agent.DecisionAllow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := agent.ApplyHookDecision(tt.policy, agent.HookOutcome{Decision: tt.hook})
+			if got != tt.want {
+				t.Errorf("ApplyHookDecision(%s, %q) = %s, want %s", tt.policy, tt.hook, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProtectedWrite(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name      string
+		tool      string
+		args      map[string]interface{}
+		protected bool
+	}{
+		{name: "write config", tool: "write_file", args: map[string]interface{}{"path": ".webide/config.json"}, protected: true},
+		{name: "write config unclean path", tool: "write_file", args: map[string]interface{}{"path": "./src/../.webide/config.json"}, protected: true},
+		{name: "write config absolute", tool: "write_file", args: map[string]interface{}{"path": filepath.Join(root, ".webide", "config.json")}, protected: true},
+		{name: "edit config", tool: "str_replace", args: map[string]interface{}{"path": ".webide/config.json"}, protected: true},
+		{name: "move over config", tool: "move_path", args: map[string]interface{}{"source": "tmp.json", "destination": ".webide/config.json"}, protected: true},
+		{name: "move config dir", tool: "move_path", args: map[string]interface{}{"source": ".webide", "destination": "old"}, protected: true},
+		{name: "copy over config", tool: "copy_path", args: map[string]interface{}{"source": "x.json", "destination": ".webide/config.json"}, protected: true},
+		{name: "delete config dir", tool: "delete_path", args: map[string]interface{}{"path": ".webide"}, protected: true},
+		{name: "patch config", tool: "apply_patch", args: map[string]interface{}{"patch": "--- a/.webide/config.json\n+++ b/.webide/config.json\n"}, protected: true},
+		{name: "write skill", tool: "write_file", args: map[string]interface{}{"path": ".webide/skills/go/SKILL.md"}, protected: false},
+		{name: "write source", tool: "write_file", args: map[string]interface{}{"path": "config.json"}, protected: false},
+		{name: "copy config elsewhere", tool: "copy_path", args: map[string]interface{}{"source": ".webide/config.json", "destination": "backup.json"}, protected: false},
+		{name: "patch source", tool: "apply_patch", args: map[string]interface{}{"patch": "--- a/main.go\n+++ b/main.go\n"}, protected: false},
+		{name: "read config", tool: "read_file", args: map[string]interface{}{"path": ".webide/config.json"}, protected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, protected := agent.ProtectedWrite(root, tt.tool, tt.args)
+			if protected != tt.protected {
+				t.Errorf("ProtectedWrite(%s, %v) = %v, want %v", tt.tool, tt.args, protected, tt.protected)
+			}
+		})
+	}
+}
+
+func TestHookRunner_Decisions(t *testing.T) {
+	tests := []struct {
+		name     string
+		hooks    []agent.HookConfig
+		tool     string
+		decision string
+		reason   string
+	}{
+		{
+			name:     "exit zero without output",
+			hooks:    []agent.HookConfig{{Event: agent.HookPreTool, Command: "true"}},
+			tool:     "write_file",
+			decision: "",
+		},
+		{
+			name:     "non-zero exit blocks",
+			hooks:    []agent.HookConfig{{Event: agent.HookPreTool, Command: "echo no writes on friday; exit 1"}},
+			tool:     "write_file",
+			decision: agent.HookDecisionBlock,
+			reason:   "no writes on friday",
+		},
+		{
+			name:     "json ask",
+			hooks:    []agent.HookConfig{{Event: agent.HookPreTool, Command: `echo '{"decision":"confirm","reason":"touches prod"}'`}},
+			tool:     "write_file",
+			decision: agent.HookDecisionAsk,
+			reason:   "touches prod",
+		},
+		{
+			name: "strictest hook wins",
+			hooks: []agent.HookConfig{
+				{Event: agent.HookPreTool, Command: `echo '{"decision":"allow"}'`},
+				{Event: agent.HookPreTool, Command: `echo '{"decision":"block","reason":"denied"}'`},
+			},
+			tool:     "write_file",
+			decision: agent.HookDecisionBlock,
+			reason:   "denied",
+		},
+		{
+			name:     "matcher skips other tools",
+			hooks:    []agent.HookConfig{{Event: agent.HookPreTool, Matcher: "run_*|delete_path", Command: "exit 1"}},
+			tool:     "write_file",
+			decision: "",
+		},
+		{
+			name:     "matcher alternative",
+			hooks:    []agent.HookConfig{{Event: agent.HookPreTool, Matcher: "run_*|delete_path", Command: "exit 1"}},
+			tool:     "run_command",
+			decision: agent.HookDecisionBlock,
+		},
+		{
+			name:     "timeout blocks",
+			hooks:    []agent.HookConfig{{Event: agent.HookPreTool, Command: "sleep 5", TimeoutMs: 100}},
+			tool:     "write_file",
+			decision: agent.HookDecisionBlock,
+			reason:   "hook timed out after 100ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hooks := agent.NewHookRunner(t.TempDir(), tt.hooks, nil)
+			outcome := hooks.Run(t.Context(), agent.HookPayload{Event: agent.HookPreTool, ToolName: tt.tool})
+			if outcome.Decision != tt.decision {
+				t.Errorf("decision = %q, want %q", outcome.Decision, tt.decision)
+			}
+			if tt.reason != "" && outcome.Reason != tt.reason {
+				t.Errorf("reason = %q, want %q", outcome.Reason, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package agent
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/memory"
+	"github.com/webide/ide/backend/internal/ai/skills"
+	"github.com/webide/ide/backend/internal/git"
+	"github.com/webide/ide/backend/internal/models"
+)
+
+const (
+	MaxInstructionBytes = 32 * 1024
+	maxLanguageScan     = 5000
+)
+
+// InstructionFileNames are looked up at the project root. Nested directories
+// only contribute AGENTS.md once the agent works inside them.
+var InstructionFileNames = []string{"AGENTS.md", ".webide/instructions.md"}
+
+var languageByExt = map[string]string{
+	".go":    "Go",
+	".ts":    "TypeScript",
+	".tsx":   "TypeScript",
+	".vue":   "Vue",
+	".js":    "JavaScript",
+	".jsx":   "JavaScript",
+	".mjs":   "JavaScript",
+	".py":    "Python",
+	".rs":    "Rust",
+	".java":  "Java",
+	".kt":    "Kotlin",
+	".rb":    "Ruby",
+	".php":   "PHP",
+	".cs":    "C#",
+	".c":     "C",
+	".h":     "C",
+	".cpp":   "C++",
+	".cc":    "C++",
+	".hpp":   "C++",
+	".swift": "Swift",
+	".scala": "Scala",
+	".sh":    "Shell",
+	".sql":   "SQL",
+}
+
+var skipScanDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	".next":        true,
+	"__pycache__":  true,
+	".venv":        true,
+	"target":       true,
+}
+
+type InstructionFile struct {
+	Path      string `json:"path"`
+	Bytes     int    `json:"bytes"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Skipped   bool   `json:"skipped,omitempty"`
+	content   string
+}
+
+type ProjectFacts struct {
+	Branch       string   `json:"branch,omitempty"`
+	Dirty        bool     `json:"dirty"`
+	ChangedFiles int      `json:"changed_files"`
+	Languages    []string `json:"languages,omitempty"`
+	OS           string   `json:"os"`
+}
+
+type SystemPrompt struct {
+	Text         string                 `json:"text"`
+	Bytes        int                    `json:"bytes"`
+	Instructions []InstructionFile      `json:"instructions"`
+	Facts        ProjectFacts           `json:"facts"`
+	Memories     []models.ProjectMemory `json:"memories"`
+	Skills       []skills.Skill         `json:"skills"`
+}
+
+// BuildSystemPrompt combines the base prompt with auto-detected project facts,
+// the instruction files that apply to workDirs (relative to projectRoot) and
+// the project memories selected for this conversation.
+func BuildSystemPrompt(base, projectRoot string, workDirs []string, memories []models.ProjectMemory) SystemPrompt {
+	if base == "" {
+		base = DefaultSystemPrompt
+	}
+
+	prompt := SystemPrompt{Instructions: []InstructionFile{}, Memories: []models.ProjectMemory{}, Skills: []skills.Skill{}}
+	if projectRoot == "" {
+		prompt.Text = base
+		prompt.Bytes = len(base)
+		return prompt
+	}
+
+	prompt.Facts = DetectProjectFacts(projectRoot)
+	prompt.Instructions = DiscoverInstructions(projectRoot, workDirs, MaxInstructionBytes)
+	if memories != nil {
+		prompt.Memories = memories
+	}
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(base, "\n"))
+	b.WriteString("\n\n### Environment\n")
+	b.WriteString(formatFacts(prompt.Facts))
+
+	for _, f := range prompt.Instructions {
+		if f.Skipped {
+			continue
+		}
+		b.WriteString("\n\n### Project instructions (")
+		b.WriteString(f.Path)
+		b.WriteString(")\n")
+		b.WriteString(strings.TrimSpace(f.content))
+		if f.Truncated {
+			b.WriteString("\n[truncated: instruction budget exceeded]")
+		}
+	}
+
+	if len(prompt.Memories) > 0 {
+		b.WriteString("\n\n### Project memory\n")
+		b.WriteString("Facts saved in earlier chats. Use the memory tool to update or delete entries that are wrong or outdated.\n")
+		b.WriteString(strings.TrimRight(memory.Render(prompt.Memories), "\n"))
+	}
+
+	if list := skills.List(projectRoot); len(list) > 0 {
+		if len(list) > skills.MaxListed {
+			list = list[:skills.MaxListed]
+		}
+		prompt.Skills = list
+		b.WriteString("\n\n### Skills\n")
+		b.WriteString("Packaged workflows for this project. When a task matches one, call use_skill with its name before starting.")
+		for _, s := range list {
+			b.WriteString("\n- ")
+			b.WriteString(s.Name)
+			if s.Description != "" {
+				b.WriteString(": ")
+				b.WriteString(s.Description)
+			}
+		}
+	}
+	b.WriteString("\n")
+
+	prompt.Text = b.String()
+	prompt.Bytes = len(prompt.Text)
+	return prompt
+}
+
+// LoadMemories selects the project memories for the system prompt, ranked
+// against query (usually the latest user message).
+func LoadMemories(ctx context.Context, projectID uuid.UUID, projectRoot, query string) []models.ProjectMemory {
+	if LoadProjectConfig(projectRoot).Memory.Disabled {
+		return nil
+	}
+	return memory.Relevant(ctx, projectID, query, memory.PromptBudget)
+}
+
+// DiscoverInstructions returns root instruction files followed by AGENTS.md
+// files from every directory between the root and each work dir, keeping the
+// total within budget bytes.
+func DiscoverInstructions(projectRoot string, workDirs []string, budget int) []InstructionFile {
+	candidates := append([]string{}, InstructionFileNames...)
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		seen[c] = true
+	}
+
+	dirs := append([]string{}, workDirs...)
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		dir = filepath.ToSlash(filepath.Clean(dir))
+		if dir == "." || dir == "" || strings.HasPrefix(dir, "..") {
+			continue
+		}
+		parts := strings.Split(dir, "/")
+		for i := range parts {
+			candidate := strings.Join(parts[:i+1], "/") + "/AGENTS.md"
+			if !seen[candidate] {
+				seen[candidate] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	var files []InstructionFile
+	remaining := budget
+	for _, rel := range candidates {
+		data, err := os.ReadFile(filepath.Join(projectRoot, filepath.FromSlash(rel)))
+		if err != nil || len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+
+		f := InstructionFile{Path: rel, Bytes: len(data), content: string(data)}
+		switch {
+		case remaining <= 0:
+			f.Skipped = true
+			f.content = ""
+		case len(data) > remaining:
+			f.Truncated = true
+			f.content = string(data[:remaining])
+		}
+		remaining -= len(f.content)
+		files = append(files, f)
+	}
+	return files
+}
+
+func DetectProjectFacts(projectRoot string) ProjectFacts {
+	facts := ProjectFacts{OS: runtime.GOOS + "/" + runtime.GOARCH}
+
+	if git.IsGitRepo(projectRoot) {
+		if res, err := git.RunGit(projectRoot, 5*time.Second, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && res.ExitCode == 0 {
+			facts.Branch = strings.TrimSpace(res.Stdout)
+		}
+		if res, err := git.RunGit(projectRoot, 10*time.Second, "status", "--porcelain"); err == nil && res.ExitCode == 0 {
+			for _, line := range strings.Split(res.Stdout, "\n") {
+				if strings.TrimSpace(line) != "" {
+					facts.ChangedFiles++
+				}
+			}
+			facts.Dirty = facts.ChangedFiles > 0
+		}
+	}
+
+	facts.Languages = detectLanguages(projectRoot, 3)
+	return facts
+}
+
+func detectLanguages(projectRoot string, limit int) []string {
+	counts := make(map[string]int)
+	scanned := 0
+
+	filepath.WalkDir(projectRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != projectRoot && (skipScanDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		scanned++
+		if scanned > maxLanguageScan {
+			return filepath.SkipAll
+		}
+		if lang, ok := languageByExt[strings.ToLower(filepath.Ext(path))]; ok {
+			counts[lang]++
+		}
+		return nil
+	})
+
+	langs := make([]string, 0, len(counts))
+	for lang := range counts {
+		langs = append(langs, lang)
+	}
+	sort.Slice(langs, func(i, j int) bool {
+		if counts[langs[i]] != counts[langs[j]] {
+			return counts[langs[i]] > counts[langs[j]]
+		}
+		return langs[i] < langs[j]
+	})
+	if len(langs) > limit {
+		langs = langs[:limit]
+	}
+	return langs
+}
+
+func formatFacts(f ProjectFacts) string {
+	var b strings.Builder
+	b.WriteString("- OS: " + f.OS + "\n")
+	if f.Branch != "" {
+		b.WriteString("- Git branch: " + f.Branch + "\n")
+		if f.Dirty {
+			b.WriteString("- Working tree: dirty (" + strconv.Itoa(f.ChangedFiles) + " changed files)\n")
+		} else {
+			b.WriteString("- Working tree: clean\n")
+		}
+	} else {
+		b.WriteString("- Git: not a repository\n")
+	}
+	if len(f.Languages) > 0 {
+		b.WriteString("- Primary languages: " + strings.Join(f.Languages, ", ") + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// WorkDirsFromArgs returns the project-relative directories referenced by the
+// path-like arguments of a tool call.
+func WorkDirsFromArgs(projectRoot string, args map[string]interface{}) []string {
+	var dirs []string
+	for _, key := range []string{"path", "file_path", "dir", "directory", "source", "target", "destination"} {
+		p, ok := args[key].(string)
+		if !ok || p == "" {
+			continue
+		}
+		if filepath.IsAbs(p) {
+			rel, err := filepath.Rel(projectRoot, p)
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			p = rel
+		}
+		p = filepath.Clean(p)
+		if info, err := os.Stat(filepath.Join(projectRoot, p)); err != nil || !info.IsDir() {
+			p = filepath.Dir(p)
+		}
+		if p != "." && !strings.HasPrefix(p, "..") {
+			dirs = append(dirs, filepath.ToSlash(p))
+		}
+	}
+	return dirs
+}
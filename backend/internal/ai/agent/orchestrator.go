@@ -3,7 +3,9 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,32 +18,76 @@ type WebSocketSender func(event WSEvent) error
 
 type AgentOrchestrator struct {
 	toolRegistry *tools.ToolRegistry
-	anthropic    *provider.Anthropic
+	llm          provider.Provider
+	llmConfig    provider.Config
 	policy       *PolicyEngine
 	mu           sync.RWMutex
 	sessions     map[uuid.UUID]*AgentSession
 }
 
-func NewOrchestrator(registry *tools.ToolRegistry, anthropic *provider.Anthropic) *AgentOrchestrator {
+func NewOrchestrator(registry *tools.ToolRegistry, llm provider.Provider) *AgentOrchestrator {
 	return &AgentOrchestrator{
 		toolRegistry: registry,
-		anthropic:    anthropic,
-		policy:       NewPolicyEngine(),
+		llm:          llm,
+		llmConfig:    provider.Config{MaxTokens: 4096, Temperature: 0.7},
+		policy:       NewPolicyEngine(registry),
 		sessions:     make(map[uuid.UUID]*AgentSession),
 	}
 }
 
+// SetPolicy replaces the policy engine, e.g. with NewScheduledPolicyEngine
+// for unattended runs.
+func (o *AgentOrchestrator) SetPolicy(policy *PolicyEngine) {
+	o.policy = policy
+}
+
+// SetProviderConfig replaces the settings for model calls. Without a model
+// the session's model is used.
+func (o *AgentOrchestrator) SetProviderConfig(cfg provider.Config) {
+	o.llmConfig = cfg
+}
+
+func (o *AgentOrchestrator) providerConfig(session *AgentSession) provider.Config {
+	cfg := o.llmConfig
+	if cfg.Model == "" {
+		cfg.Model = session.Config.Model
+	}
+	if cfg.Model == "" {
+		cfg.Model = "minimax"
+	}
+	return cfg
+}
+
+// Run runs the agent loop and reports events through send. A tool call that
+// needs approval ends the run; HandleApproval continues it.
 func (o *AgentOrchestrator) Run(ctx context.Context, session *AgentSession, userContent string, send WebSocketSender) error {
-	session.AddUserMessage(userContent)
+	return o.RunWith(ctx, session, UserInput{Message: userContent}, senderHandler{session: session, send: send})
+}
 
-	if len(session.Messages) == 0 {
-		systemPrompt := session.Config.SystemPrompt
-		if systemPrompt == "" {
-			systemPrompt = DefaultSystemPrompt
+// RunWith runs the agent loop until the model is done or a limit is reached,
+// starting with input if it has a message.
+func (o *AgentOrchestrator) RunWith(ctx context.Context, session *AgentSession, input UserInput, h RunHandler) error {
+	hooks := session.Hooks()
+	verifier := session.Verifier()
+	budget := session.Budget()
+
+	if input.Message != "" {
+		outcome := o.runHooks(ctx, session, hooks, HookPayload{
+			Event:   HookUserMessage,
+			Message: input.Message,
+		}, h)
+		if outcome.Blocked() {
+			h.Event(event(session, EventAgentError, "", AgentErrorPayload{
+				Code:    tools.ErrCodeHookBlocked,
+				Message: "Message blocked by hook: " + outcome.Reason,
+			}))
+			return nil
 		}
-		session.AddSystemMessage(systemPrompt)
+		session.AddUserMessage(AppendHookFeedback(input.prompt(), outcome))
 	}
 
+	session.RefreshSystemPrompt()
+
 	o.mu.Lock()
 	o.sessions[session.ID] = session
 	o.mu.Unlock()
@@ -64,6 +110,18 @@ func (o *AgentOrchestrator) Run(ctx context.Context, session *AgentSession, user
 			return ctx.Err()
 		default:
 		}
+		session.ToolCache().BeginStep(step + 1)
+		o.deliverInputs(ctx, session, hooks, h.Inputs(false), step+1, h)
+
+		warning, exceeded := budget.Check()
+		if exceeded != nil {
+			o.stopForBudget(ctx, session, hooks, exceeded, h)
+			return nil
+		}
+		if warning != "" {
+			session.AddUserMessage(warning)
+			o.sendBudgetWarning(session, budget, warning, h)
+		}
 
 		toolDefs := o.toolRegistry.ListForModel()
 		messages := o.sessionToProviderMessages(session)
@@ -78,205 +136,400 @@ func (o *AgentOrchestrator) Run(ctx context.Context, session *AgentSession, user
 		}
 
 		providerTools := convertToProviderTools(toolDefs)
-		stream, err := o.anthropic.StreamWithTools(ctx, messages, provider.Config{
-			APIKey:      "",
-			Model:       "minimax",
-			MaxTokens:   4096,
-			Temperature: 0.7,
-		}, providerTools, toolChoice)
+
+		h.Event(event(session, EventStepStart, "", StepPayload{Step: step + 1}))
+		stream, err := o.llm.StreamWithTools(ctx, messages, o.providerConfig(session), providerTools, toolChoice)
 
 		if err != nil {
 			log.Printf("[Agent] Stream error: %v", err)
-			send(WSEvent{
-				Type:      EventAgentError,
-				SessionID: session.ID.String(),
-				ProjectID: session.ProjectID.String(),
-				Payload: AgentErrorPayload{
-					Code:    "STREAM_ERROR",
-					Message: err.Error(),
-				},
-			})
+			o.runHooks(ctx, session, hooks, HookPayload{Event: HookStop, StopReason: "error", Message: err.Error()}, h)
+			h.Event(event(session, EventAgentError, "", AgentErrorPayload{
+				Code:    "STREAM_ERROR",
+				Message: err.Error(),
+			}))
 			return err
 		}
 
-		assistantText := ""
+		var text, thinking strings.Builder
 		var toolCalls []provider.ToolCall
 
 		for chunk := range stream {
+			if chunk.Thinking != "" {
+				thinking.WriteString(chunk.Thinking)
+				h.Event(event(session, EventThinkingDelta, "", map[string]interface{}{
+					"content": chunk.Thinking,
+					"done":    false,
+				}))
+			}
 			if chunk.Content != "" {
-				assistantText += chunk.Content
-				send(WSEvent{
-					Type:      "assistant.delta",
-					SessionID: session.ID.String(),
-					ProjectID: session.ProjectID.String(),
-					Payload: map[string]interface{}{
-						"content": chunk.Content,
-						"done":    false,
-					},
-				})
+				text.WriteString(chunk.Content)
+				h.Event(event(session, EventAssistantDelta, "", map[string]interface{}{
+					"content": chunk.Content,
+					"done":    false,
+				}))
 			}
 
-			for _, tc := range chunk.ToolCalls {
-				toolCalls = append(toolCalls, tc)
+			toolCalls = append(toolCalls, chunk.ToolCalls...)
+
+			if chunk.Usage != nil {
+				session.AddUsage(*chunk.Usage)
+				budget.AddUsage(*chunk.Usage)
 			}
 
 			if chunk.Done {
 				break
 			}
 		}
-
-		if len(toolCalls) == 0 && assistantText != "" {
-			session.AddAssistantMessage(assistantText, nil)
-			send(WSEvent{
-				Type:      "assistant.final",
-				SessionID: session.ID.String(),
-				ProjectID: session.ProjectID.String(),
-				Payload: map[string]interface{}{
-					"content": assistantText,
+		assistantText := text.String()
+
+		var agentToolCalls []ToolCall
+		for _, tc := range toolCalls {
+			agentToolCalls = append(agentToolCalls, ToolCall{
+				ID:   tc.ID,
+				Type: tc.Type,
+				Function: ToolCallFunction{
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
 				},
 			})
-			return nil
 		}
+		h.Event(event(session, EventAssistantMessage, "", AssistantMessagePayload{
+			Step:      step + 1,
+			Content:   assistantText,
+			Thinking:  thinking.String(),
+			ToolCalls: agentToolCalls,
+		}))
+
+		if len(toolCalls) == 0 {
+			session.AddAssistantMessage(assistantText, nil)
+			h.Event(event(session, EventAssistantFinal, "", map[string]interface{}{
+				"content": assistantText,
+			}))
 
-		if len(toolCalls) > 0 {
-			var agentToolCalls []ToolCall
-			for _, tc := range toolCalls {
-				agentToolCalls = append(agentToolCalls, ToolCall{
-					ID:   tc.ID,
-					Type: tc.Type,
-					Function: ToolCallFunction{
-						Name:      tc.Function.Name,
-						Arguments: tc.Function.Arguments,
-					},
-				})
+			if o.beforeDone(ctx, session, hooks, verifier, assistantText, step+1 < maxSteps, step+2, h) {
+				step++
+				continue
 			}
-			session.AddAssistantMessage(assistantText, agentToolCalls)
-
-			for _, tc := range toolCalls {
-				_, ok := o.toolRegistry.Get(tc.Function.Name)
-				if !ok {
-					send(WSEvent{
-						Type:      EventToolError,
-						SessionID: session.ID.String(),
-						ProjectID: session.ProjectID.String(),
-						ID:        tc.ID,
-						Payload: map[string]interface{}{
-							"error": map[string]interface{}{
-								"code":    "UNKNOWN_TOOL",
-								"message": "Unknown tool: " + tc.Function.Name,
-							},
-						},
-					})
-
-					result := tools.ToolResult{
-						OK: false,
-						Error: &tools.ToolError{
-							Code:    tools.ErrCodeNotFound,
-							Message: "Tool not found: " + tc.Function.Name,
-						},
-					}
-					session.AddToolResult(tc.ID, tc.Function.Name, formatToolResult(result))
-
-					continue
-				}
 
-				var args map[string]interface{}
-				json.Unmarshal([]byte(tc.Function.Arguments), &args)
-
-				decision := o.policy.Decide(tc.Function.Name, session, args)
-
-				summary := GenerateToolSummary(tc.Function.Name, args)
-
-				switch decision {
-				case DecisionAllow:
-					result := o.executeTool(session, tc.Function.Name, args)
-					send(WSEvent{
-						Type:      EventToolResult,
-						SessionID: session.ID.String(),
-						ProjectID: session.ProjectID.String(),
-						ID:        tc.ID,
-						Payload: map[string]interface{}{
-							"id":       tc.ID,
-							"name":     tc.Function.Name,
-							"ok":       result.OK,
-							"result":   result.Data,
-							"error":    result.Error,
-							"duration": result.Meta.DurationMs,
-						},
-					})
-					session.AddToolResult(tc.ID, tc.Function.Name, formatToolResult(result))
-
-				case DecisionConfirm:
-					send(WSEvent{
-						Type:      EventToolApprovalRequired,
-						SessionID: session.ID.String(),
-						ProjectID: session.ProjectID.String(),
-						ID:        tc.ID,
-						Payload: map[string]interface{}{
-							"id":        tc.ID,
-							"name":      tc.Function.Name,
-							"arguments": args,
-							"summary":   summary,
-							"policy":    "confirm",
-						},
-					})
-
-					session.SetPendingToolCall(tc.ID, &PendingToolCall{
-						ToolCall: ToolCall{
-							ID:   tc.ID,
-							Type: tc.Type,
-							Function: ToolCallFunction{
-								Name:      tc.Function.Name,
-								Arguments: tc.Function.Arguments,
-							},
-						},
-						Args:      args,
-						CreatedAt: time.Now(),
-					})
+			h.Event(event(session, EventAgentDone, "", AgentDonePayload{
+				Steps:    step + 1,
+				FinalMsg: assistantText,
+				Usage:    session.Usage(),
+				Checks:   verifier.Summary(),
+				Budget:   budget.Summary(),
+			}))
+			return nil
+		}
 
-					return nil
+		session.AddAssistantMessage(assistantText, agentToolCalls)
 
-				case DecisionDeny:
-					result := tools.ToolResult{
-						OK: false,
-						Error: &tools.ToolError{
-							Code:    tools.ErrCodePermission,
-							Message: "Tool blocked by policy",
-						},
-					}
-					send(WSEvent{
-						Type:      EventToolResult,
-						SessionID: session.ID.String(),
-						ProjectID: session.ProjectID.String(),
-						ID:        tc.ID,
-						Payload: map[string]interface{}{
-							"id":    tc.ID,
-							"name":  tc.Function.Name,
-							"ok":    false,
-							"error": result.Error,
-						},
-					})
-					session.AddToolResult(tc.ID, tc.Function.Name, formatToolResult(result))
+		var overBudget *BudgetExceeded
+		for _, tc := range toolCalls {
+			if overBudget == nil {
+				overBudget = budget.BeforeTool(tc.Function.Name)
+			}
+			if overBudget != nil {
+				result := tools.NewErrorResult(ErrCodeBudgetExceeded, overBudget.Error(), nil)
+				h.Event(toolResultEvent(session, tc.ID, tc.Function.Name, result, HookOutcome{}, nil))
+				session.AddToolResult(tc.ID, tc.Function.Name, formatToolResult(result))
+				continue
+			}
+
+			if err := o.callTool(ctx, session, hooks, verifier, tc, h); err != nil {
+				if errors.Is(err, ErrApprovalPending) {
+					return nil
 				}
+				return err
+			}
+		}
+		h.Event(event(session, EventStepEnd, "", StepPayload{Step: step + 1}))
+
+		if overBudget != nil {
+			o.stopForBudget(ctx, session, hooks, overBudget, h)
+			return nil
+		}
+
+		if checks, feedback := verifier.AfterStep(ctx, step+1 < maxSteps); checks != nil {
+			o.sendChecks(session, checks, h)
+			if feedback != "" {
+				session.AddUserMessage(feedback)
 			}
 		}
 
 		step++
 	}
 
-	send(WSEvent{
-		Type:      EventAgentDone,
-		SessionID: session.ID.String(),
-		ProjectID: session.ProjectID.String(),
-		Payload: AgentDonePayload{
-			Steps:    step,
-			FinalMsg: "Agent stopped: maximum steps reached",
-		},
-	})
+	o.runHooks(ctx, session, hooks, HookPayload{Event: HookStop, StopReason: "max_steps"}, h)
+
+	h.Event(event(session, EventAgentDone, "", AgentDonePayload{
+		Steps:    step,
+		FinalMsg: "Agent stopped: maximum steps reached",
+		Usage:    session.Usage(),
+		Checks:   verifier.Summary(),
+		Budget:   budget.Summary(),
+	}))
 
 	return nil
 }
 
+// callTool decides, runs and records one tool call. It returns
+// ErrApprovalPending when the handler leaves the call for HandleApproval.
+func (o *AgentOrchestrator) callTool(ctx context.Context, session *AgentSession, hooks *HookRunner, verifier *Verifier, tc provider.ToolCall, h RunHandler) error {
+	name := tc.Function.Name
+	if _, ok := o.toolRegistry.Get(name); !ok {
+		h.Event(event(session, EventToolError, tc.ID, map[string]interface{}{
+			"name": name,
+			"error": map[string]interface{}{
+				"code":    "UNKNOWN_TOOL",
+				"message": "Unknown tool: " + name,
+			},
+		}))
+
+		result := tools.NewErrorResult(tools.ErrCodeNotFound, "Tool not found: "+name, nil)
+		session.AddToolResult(tc.ID, name, formatToolResult(result))
+		return nil
+	}
+
+	var args map[string]interface{}
+	json.Unmarshal([]byte(tc.Function.Arguments), &args)
+
+	if session.TouchWorkDirs(WorkDirsFromArgs(session.Config.ProjectRoot, args)) {
+		session.RefreshSystemPrompt()
+	}
+
+	h.Event(NewToolCallEvent(session.ID.String(), session.ProjectID.String(), ToolCallPayload{
+		ToolCallID: tc.ID,
+		Name:       name,
+		Arguments:  args,
+	}))
+
+	pre := o.runHooks(ctx, session, hooks, HookPayload{
+		Event:      HookPreTool,
+		ToolName:   name,
+		ToolCallID: tc.ID,
+		Arguments:  args,
+	}, h)
+
+	decision, reason := o.decide(session, name, args, pre)
+
+	var approval map[string]interface{}
+	switch decision {
+	case DecisionConfirm:
+		var err error
+		if approval, err = o.approve(ctx, session, tc, args, reason, h); err != nil || approval["decision"] == "rejected" {
+			return err
+		}
+
+	case DecisionDeny:
+		result := tools.NewErrorResult(tools.ErrCodePermission, "Tool blocked by policy", nil)
+		approval = map[string]interface{}{"decision": "blocked", "by": "policy"}
+		if pre.Blocked() {
+			result.Error.Code = tools.ErrCodeHookBlocked
+			result.Error.Message = "Tool blocked by hook: " + pre.Reason
+			approval = map[string]interface{}{"decision": "blocked", "by": "hook", "reason": pre.Reason}
+		}
+		h.Event(toolResultEvent(session, tc.ID, name, result, HookOutcome{}, approval))
+		session.AddToolResult(tc.ID, name, formatToolResult(result))
+		return nil
+	}
+
+	// A call a person approved may read protected files.
+	result := o.runTool(ctx, session, name, args, approval != nil)
+	if approval == nil && result.Error != nil && result.Error.Code == tools.ErrCodeProtected &&
+		!session.Config.AutoApprove && !session.Config.DenyConfirm {
+		// The tool refused a file that may hold secrets; ask before running
+		// it again without the guard.
+		var err error
+		if approval, err = o.approve(ctx, session, tc, args, result.Error.Message, h); err != nil || approval["decision"] == "rejected" {
+			return err
+		}
+		result = o.runTool(ctx, session, name, args, true)
+	}
+	post := o.runHooks(ctx, session, hooks, HookPayload{
+		Event:      HookPostTool,
+		ToolName:   name,
+		ToolCallID: tc.ID,
+		Arguments:  args,
+		Result:     &result,
+	}, h)
+
+	verifier.NoteTool(name, result.OK)
+	h.Event(toolResultEvent(session, tc.ID, name, result, post, approval))
+	session.AddToolResult(tc.ID, name, AppendHookFeedback(formatToolResult(result), post))
+	return nil
+}
+
+// approve asks the handler to approve a call. A rejection is recorded as the
+// call's result. ErrApprovalPending leaves the call for HandleApproval.
+func (o *AgentOrchestrator) approve(ctx context.Context, session *AgentSession, tc provider.ToolCall, args map[string]interface{}, reason string, h RunHandler) (map[string]interface{}, error) {
+	name := tc.Function.Name
+	approved, answer, err := h.AwaitApproval(ctx, ToolApprovalPayload{
+		ToolCallID: tc.ID,
+		Name:       name,
+		Arguments:  args,
+		Summary:    reason,
+		Policy:     string(DecisionConfirm),
+		Preview:    o.toolPreview(session, name, args),
+	})
+	if errors.Is(err, ErrApprovalPending) {
+		session.SetPendingToolCall(tc.ID, &PendingToolCall{
+			ToolCall: ToolCall{
+				ID:   tc.ID,
+				Type: tc.Type,
+				Function: ToolCallFunction{
+					Name:      name,
+					Arguments: tc.Function.Arguments,
+				},
+			},
+			Args:      args,
+			CreatedAt: time.Now(),
+		})
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	approval := map[string]interface{}{"decision": "approved", "by": "user", "reason": answer}
+	if !approved {
+		approval["decision"] = "rejected"
+		result := tools.NewErrorResult(tools.ErrCodeUserRejected, "User rejected: "+answer, nil)
+		h.Event(toolResultEvent(session, tc.ID, name, result, HookOutcome{}, approval))
+		session.AddToolResult(tc.ID, name, formatToolResult(result))
+	}
+	return approval, nil
+}
+
+// beforeDone runs what has to happen before the run may finish: the stop
+// hook, the project checks, messages the user sent meanwhile and the
+// handler's review. It reports whether the model has more to do.
+func (o *AgentOrchestrator) beforeDone(ctx context.Context, session *AgentSession, hooks *HookRunner, verifier *Verifier, final string, canContinue bool, nextStep int, h RunHandler) bool {
+	outcome := o.runHooks(ctx, session, hooks, HookPayload{
+		Event:      HookStop,
+		StopReason: "completed",
+		Message:    final,
+	}, h)
+	if outcome.Blocked() && canContinue {
+		session.AddUserMessage(AppendHookFeedback(HookStopPrompt, outcome))
+		return true
+	}
+
+	if checks, feedback := verifier.BeforeDone(ctx, canContinue); checks != nil {
+		o.sendChecks(session, checks, h)
+		if feedback != "" {
+			session.AddUserMessage(feedback)
+			return true
+		}
+	}
+
+	if canContinue && o.deliverInputs(ctx, session, hooks, h.Inputs(false), nextStep, h) > 0 {
+		return true
+	}
+	if feedback := h.Review(ctx); feedback != "" {
+		// A run out of steps cannot act on the feedback, but it stays in the
+		// conversation so a resumed session starts from it.
+		session.AddUserMessage(feedback)
+		if canContinue {
+			return true
+		}
+		log.Printf("[Agent] Session %s is out of steps; review feedback kept for the next run", session.ID)
+	}
+	// The handler keeps accepting messages during the review, so one sent
+	// meanwhile is delivered here instead of being left for a new run.
+	return canContinue && o.deliverInputs(ctx, session, hooks, h.Inputs(true), nextStep, h) > 0
+}
+
+// deliverInputs adds user messages that arrived during the run as user
+// turns, unless a user_message hook blocks them. It returns how many were
+// delivered.
+func (o *AgentOrchestrator) deliverInputs(ctx context.Context, session *AgentSession, hooks *HookRunner, inputs []UserInput, step int, h RunHandler) int {
+	delivered := 0
+	for _, in := range inputs {
+		outcome := o.runHooks(ctx, session, hooks, HookPayload{
+			Event:   HookUserMessage,
+			Message: in.Message,
+		}, h)
+		if outcome.Blocked() {
+			h.Event(event(session, EventInputDropped, "", InputPayload{
+				ID:     in.ID,
+				Reason: "message blocked by hook: " + outcome.Reason,
+			}))
+			continue
+		}
+		session.AddUserMessage(AppendHookFeedback(in.prompt(), outcome))
+		h.Event(event(session, EventInputDelivered, "", InputPayload{ID: in.ID, Step: step}))
+		delivered++
+	}
+	return delivered
+}
+
+// decide combines the policy, the pre-tool hook, which can only tighten it,
+// and the protected file check for writes. Tools refuse to return the
+// content of protected files themselves; callTool asks about those. The
+// reason is shown with a confirmation.
+func (o *AgentOrchestrator) decide(session *AgentSession, name string, args map[string]interface{}, pre HookOutcome) (PolicyDecision, string) {
+	decision := ApplyHookDecision(o.policy.Decide(name, session, args), pre)
+	reason := GenerateToolSummary(name, args)
+	if pre.Decision == HookDecisionAsk && pre.Reason != "" {
+		reason = pre.Reason
+	}
+
+	protectedReason, protected := ProtectedWrite(session.Config.ProjectRoot, name, args)
+	if protected && decision != DecisionDeny {
+		decision = DecisionConfirm
+		reason = protectedReason
+	}
+	if decision == DecisionConfirm && (session.Config.AutoApprove || session.Config.DenyConfirm) {
+		decision = DecisionAllow
+		// Protected files are never changed without a person saying so.
+		if protected || session.Config.DenyConfirm {
+			decision = DecisionDeny
+		}
+	}
+	return decision, reason
+}
+
+// toolResultEvent reports a finished tool call with what the post-tool hooks
+// told the model and who allowed or refused the call beyond the policy.
+func toolResultEvent(session *AgentSession, id, name string, result tools.ToolResult, post HookOutcome, approval map[string]interface{}) WSEvent {
+	payload := map[string]interface{}{
+		"id":     id,
+		"name":   name,
+		"ok":     result.OK,
+		"result": result.Data,
+		"error":  result.Error,
+	}
+	if result.Meta != nil {
+		payload["duration"] = result.Meta.DurationMs
+	}
+	if feedback := AppendHookFeedback("", post); feedback != "" {
+		payload["feedback"] = strings.TrimSpace(feedback)
+	}
+	if approval != nil {
+		payload["approval"] = approval
+	}
+	return event(session, EventToolResult, id, payload)
+}
+
+// stopForBudget ends the run without another model call and reports which
+// budget was used up.
+func (o *AgentOrchestrator) stopForBudget(ctx context.Context, session *AgentSession, hooks *HookRunner, exceeded *BudgetExceeded, h RunHandler) {
+	log.Printf("[Agent] Stopping run: %s", exceeded.Error())
+	o.runHooks(ctx, session, hooks, HookPayload{Event: HookStop, StopReason: "budget", Message: exceeded.Error()}, h)
+	h.Event(event(session, EventAgentError, "", BudgetErrorPayload(exceeded)))
+}
+
+func (o *AgentOrchestrator) sendBudgetWarning(session *AgentSession, budget *Budget, message string, h RunHandler) {
+	h.Event(event(session, EventBudgetWarning, "", map[string]interface{}{
+		"message": message,
+		"budget":  budget.Summary(),
+	}))
+}
+
+func (o *AgentOrchestrator) sendChecks(session *AgentSession, outcome *CheckOutcome, h RunHandler) {
+	h.Event(event(session, EventChecksResult, "", outcome))
+}
+
 func (o *AgentOrchestrator) HandleApproval(ctx context.Context, sessionID uuid.UUID, toolCallID string, approved bool, reason string, send WebSocketSender) error {
 	o.mu.RLock()
 	session, ok := o.sessions[sessionID]
@@ -291,36 +544,20 @@ func (o *AgentOrchestrator) HandleApproval(ctx context.Context, sessionID uuid.U
 		return nil
 	}
 
+	h := senderHandler{session: session, send: send}
+	name := pending.ToolCall.Function.Name
 	var result tools.ToolResult
+	var post HookOutcome
 	if approved {
-		result = o.executeTool(session, pending.ToolCall.Function.Name, pending.Args)
+		result, post = o.executeWithHooks(ctx, session, session.Hooks(), toolCallID, name, pending.Args, h)
 	} else {
-		result = tools.ToolResult{
-			OK: false,
-			Error: &tools.ToolError{
-				Code:    tools.ErrCodeUserRejected,
-				Message: "User rejected: " + reason,
-			},
-		}
+		result = tools.NewErrorResult(tools.ErrCodeUserRejected, "User rejected: "+reason, nil)
 	}
 
 	session.RemovePendingToolCall(toolCallID)
 
-	send(WSEvent{
-		Type:      EventToolResult,
-		SessionID: session.ID.String(),
-		ProjectID: session.ProjectID.String(),
-		ID:        toolCallID,
-		Payload: map[string]interface{}{
-			"id":     toolCallID,
-			"name":   pending.ToolCall.Function.Name,
-			"ok":     result.OK,
-			"result": result.Data,
-			"error":  result.Error,
-		},
-	})
-
-	session.AddToolResult(toolCallID, pending.ToolCall.Function.Name, formatToolResult(result))
+	h.Event(toolResultEvent(session, toolCallID, name, result, post, nil))
+	session.AddToolResult(toolCallID, name, AppendHookFeedback(formatToolResult(result), post))
 
 	go func() {
 		runCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
@@ -331,26 +568,56 @@ func (o *AgentOrchestrator) HandleApproval(ctx context.Context, sessionID uuid.U
 	return nil
 }
 
-func (o *AgentOrchestrator) executeTool(session *AgentSession, toolName string, args map[string]interface{}) tools.ToolResult {
-	start := time.Now()
+// executeWithHooks runs a tool a person approved, then the post-tool hooks.
+func (o *AgentOrchestrator) executeWithHooks(ctx context.Context, session *AgentSession, hooks *HookRunner, toolCallID, toolName string, args map[string]interface{}, h RunHandler) (tools.ToolResult, HookOutcome) {
+	result := o.runTool(ctx, session, toolName, args, true)
 
-	tool, ok := o.toolRegistry.Get(toolName)
-	if !ok {
-		return tools.ToolResult{
-			OK: false,
-			Error: &tools.ToolError{
-				Code:    tools.ErrCodeNotFound,
-				Message: "Tool not found: " + toolName,
-			},
-		}
+	outcome := o.runHooks(ctx, session, hooks, HookPayload{
+		Event:      HookPostTool,
+		ToolName:   toolName,
+		ToolCallID: toolCallID,
+		Arguments:  args,
+		Result:     &result,
+	}, h)
+
+	return result, outcome
+}
+
+// runTool runs a tool through the cache, redaction and summarizer. Unless
+// allowProtected, the tool refuses files matching the deny globs.
+func (o *AgentOrchestrator) runTool(ctx context.Context, session *AgentSession, toolName string, args map[string]interface{}, allowProtected bool) tools.ToolResult {
+	return session.ToolCache().Execute(toolName, args, func() tools.ToolResult {
+		redactor := session.Redactor()
+		result := RedactResult(redactor, o.executeTool(ctx, session, toolName, RestoreArgs(redactor, args), allowProtected))
+		return session.Summarizer(o.llm).Apply(ctx, session.ProjectID, toolName, result)
+	})
+}
+
+func (o *AgentOrchestrator) runHooks(ctx context.Context, session *AgentSession, hooks *HookRunner, payload HookPayload, h RunHandler) HookOutcome {
+	if !hooks.Has(payload.Event) {
+		return HookOutcome{}
 	}
 
-	tc := tools.ToolContext{
+	payload.SessionID = session.ID.String()
+	payload.ProjectID = session.ProjectID.String()
+	payload.ChatID = session.ChatID.String()
+
+	outcome := hooks.Run(ctx, payload)
+	for _, r := range outcome.Results {
+		h.Event(event(session, EventHookResult, r.ToolCallID, r))
+	}
+	return outcome
+}
+
+func toolContext(session *AgentSession) tools.ToolContext {
+	return tools.ToolContext{
 		SessionID:   session.ID,
 		ProjectID:   session.ProjectID,
 		UserID:      session.UserID,
 		ProjectRoot: session.Config.ProjectRoot,
 		Mode:        string(session.Mode),
+		Denied:      session.Redactor().Denied,
+		Redact:      session.Redactor().String,
 		Limits: tools.ToolLimits{
 			MaxFileBytes:     session.Config.Limits.MaxFileBytes,
 			MaxOutputBytes:   session.Config.Limits.MaxOutputBytes,
@@ -359,8 +626,40 @@ func (o *AgentOrchestrator) executeTool(session *AgentSession, toolName string,
 			MaxToolTime:      session.Config.Limits.MaxToolTimeMs,
 		},
 	}
+}
+
+// toolPreview is the redacted preview of a call awaiting approval.
+func (o *AgentOrchestrator) toolPreview(session *AgentSession, toolName string, args map[string]interface{}) string {
+	tool, ok := o.toolRegistry.Get(toolName)
+	if !ok || tool.Preview == nil {
+		return ""
+	}
+	// The preview is shown to a person, not the model.
+	tc := toolContext(session)
+	tc.Denied = nil
+	r := session.Redactor()
+	return r.String(tool.Preview(RestoreArgs(r, args), tc))
+}
 
-	result, err := tool.Execute(context.Background(), args, tc)
+func (o *AgentOrchestrator) executeTool(ctx context.Context, session *AgentSession, toolName string, args map[string]interface{}, allowProtected bool) tools.ToolResult {
+	start := time.Now()
+
+	tool, ok := o.toolRegistry.Get(toolName)
+	if !ok {
+		return tools.ToolResult{
+			OK: false,
+			Error: &tools.ToolError{
+				Code:    tools.ErrCodeNotFound,
+				Message: "Tool not found: " + toolName,
+			},
+		}
+	}
+
+	tc := toolContext(session)
+	if allowProtected {
+		tc.Denied = nil
+	}
+	result, err := tool.Execute(ctx, args, tc)
 
 	if err != nil {
 		return tools.ToolResult{
@@ -427,21 +726,22 @@ func formatToolResult(result tools.ToolResult) string {
 const DefaultSystemPrompt = `You are an AI assistant inside a WebIDE.
 
 ### IMPORTANT: You MUST always specify arguments for tools!
-If you call a tool without arguments like {"name": "list_dir"} or {"name": "apply_patch" with empty arguments {}, THE TOOL WILL FAIL!
+If you call a tool without arguments like {"name": "list_dir"} or {"name": "write_file" with empty arguments {}, THE TOOL WILL FAIL!
 
 Examples of CORRECT tool calls:
 - {"name": "list_dir", "arguments": {"path": ".", "depth": 1}}
 - {"name": "read_file", "arguments": {"path": "main.go", "start_line": 1, "end_line": 50}}
 - {"name": "search_in_files", "arguments": {"query": "func main", "max_results": 20}}
-- {"name": "apply_patch", "arguments": {"patch": "--- /dev/null\n+++ hello.go\n@@ -0,0 +1,5 @@\n+package main\n+", "dry_run": true}}
+- {"name": "write_file", "arguments": {"path": "hello.go", "content": "package main\n"}}
+- {"name": "str_replace", "arguments": {"path": "main.go", "old_string": "func main() {\n}", "new_string": "func main() {\n\trun()\n}"}}
 - {"name": "run_command", "arguments": {"cmd": "ls -la", "timeout_ms": 60000}}
 
 NEVER call a tool without all required arguments!
 
 ### Core rules
 1. DO use tools to interact with files and run commands.
-2. When asked to create a file, use apply_patch with a unified diff OR run_command with 'cat > file'.
-3. When asked to modify a file, use apply_patch with a unified diff.
+2. When asked to create a file, use write_file.
+3. When asked to modify a file, use str_replace; use write_file to rewrite it completely. Never write files with run_command.
 4. Once a file is created, STOP - do not call list_dir again or try to create the same file!
 5. After successful tool execution, check the RESULT. If the file was created successfully, respond to the user with confirmation.
 6. Never tell users you "cannot" do something - use the tools available to you.
@@ -449,21 +749,27 @@ NEVER call a tool without all required arguments!
 ### Tool descriptions
 - list_dir: List directory contents. Required args: path, depth
 - read_file: Read file contents. Required args: path, optional: start_line, end_line
-- search_in_files: Search for text patterns. Required args: query, optional: max_results
-- apply_patch: Create or modify files using unified diffs. Required args: patch, optional: dry_run
+- find_files: Locate files by fuzzy path or glob. Args: query and/or globs, optional: limit
+- search_in_files: Search file contents. Required args: query, optional: mode (literal or regex), case_sensitive, whole_word, context, globs, max_results
+- write_file: Create a file or replace its whole content. Required args: path, content, optional: expected_sha (the sha from read_file)
+- str_replace: Replace an exact, unique string in a file. Required args: path, old_string, new_string, optional: replace_all
+- apply_patch: Apply a unified diff, for changes spanning many hunks or files. Required args: patch, optional: dry_run
 - run_command: Execute shell commands. Required args: cmd, optional: timeout_ms
+- run_tests: Run tests (go, jest, vitest, pytest) with per-test results. Optional args: path, test, framework
+- read_terminal: List the user's terminals and read recent output of one. Optional args: terminal, lines
+- send_to_terminal: Type a command into the user's terminal. Required args: command, optional: terminal, interrupt, wait_ms
+- memory: Remember a project fact for future chats. Required args: action (save, update or delete), optional: id, content
 
 ### How to create a NEW file
-Choose ONE method:
-- Use apply_patch with {"patch": "--- /dev/null\n+++ filename.go\n@@ -0,0 +1,3 @@\n+package main\n+"} and {"dry_run": false}
-- OR use run_command: {"cmd": "cat > filename.go << 'EOF'\npackage main\nEOF"}
+Use write_file with {"path": "filename.go", "content": "package main\n"}.
 
 ### How to modify an EXISTING file
-Use apply_patch with {"patch": "diff content"} showing the changes.
+Read it first, then use str_replace with an old_string copied exactly from the file, including indentation and a few surrounding lines so it is unique.
+To rewrite the whole file, use write_file with the sha from read_file as expected_sha.
 
 ### Workflow for creating files
 1. Call list_dir ONCE to check current structure.
-2. Create the file with ONE tool call (apply_patch OR run_command).
+2. Create the file with ONE write_file call.
 3. Check the tool result - if successful, the file exists!
 4. STOP - do NOT call list_dir again or try to create the file again!
 5. Respond to the user with confirmation that the file was created.
@@ -0,0 +1,208 @@
+package agent_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/ai/provider"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+// approvalHandler records events and blocks tool calls that need approval
+// until the test answers them.
+type approvalHandler struct {
+	asked  chan agent.ToolApprovalPayload
+	answer chan bool
+
+	mu     sync.Mutex
+	events []agent.WSEvent
+}
+
+func (h *approvalHandler) Event(ev agent.WSEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, ev)
+}
+
+func (h *approvalHandler) AwaitApproval(ctx context.Context, req agent.ToolApprovalPayload) (bool, string, error) {
+	h.asked <- req
+	select {
+	case approved := <-h.answer:
+		return approved, "", nil
+	case <-ctx.Done():
+		return false, "", ctx.Err()
+	}
+}
+
+func (h *approvalHandler) Inputs(final bool) []agent.UserInput { return nil }
+
+func (h *approvalHandler) Review(ctx context.Context) string { return "" }
+
+func (h *approvalHandler) result(id string) map[string]interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, ev := range h.events {
+		if ev.Type == agent.EventToolResult && ev.ID == id {
+			p, _ := ev.Payload.(map[string]interface{})
+			return p
+		}
+	}
+	return nil
+}
+
+func toolCallCassette(name, args string) *provider.Cassette {
+	tc := provider.ToolCall{ID: "call_1", Type: "function"}
+	tc.Function.Name = name
+	tc.Function.Arguments = args
+	return &provider.Cassette{Interactions: []provider.Interaction{
+		{Chunks: []provider.StreamChunk{{ToolCalls: []provider.ToolCall{tc}}, {Done: true}}},
+		{Chunks: []provider.StreamChunk{{Content: "done"}, {Done: true}}},
+	}}
+}
+
+func TestOrchestrator_RunWithApprovals(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    string
+		args    string
+		ask     bool
+		approve bool
+		ran     bool
+		code    string
+	}{
+		{name: "allowed tool runs at once", tool: "find_files", args: `{}`, ran: true},
+		{name: "confirm tool waits for approval", tool: "fetch_url", args: `{}`, ask: true, approve: true, ran: true},
+		{name: "rejected tool does not run", tool: "fetch_url", args: `{}`, ask: true, code: tools.ErrCodeUserRejected},
+		{name: "denied tool does not run", tool: "drop_db", args: `{}`, code: tools.ErrCodePermission},
+		{name: "protected read waits for approval", tool: "read_file", args: `{"path":"certs/server.key"}`, ask: true, approve: true, ran: true},
+		{name: "rejected protected read", tool: "read_file", args: `{"path":"certs/server.key"}`, ask: true, code: tools.ErrCodeUserRejected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			ran := false
+			registry := tools.NewRegistry()
+			for name, policy := range map[string]tools.ToolPolicy{
+				"find_files": tools.PolicyAllow,
+				"read_file":  tools.PolicyAllow,
+				"fetch_url":  tools.PolicyConfirm,
+				"drop_db":    tools.PolicyDeny,
+			} {
+				registry.Register(tools.Tool{
+					Name:   name,
+					Policy: policy,
+					Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+						path, _ := args["path"].(string)
+						if glob, denied := tc.Protected(path); denied {
+							return tools.NewProtectedResult(path, glob), nil
+						}
+						mu.Lock()
+						ran = true
+						mu.Unlock()
+						return tools.ToolResult{OK: true}, nil
+					},
+				})
+			}
+			hasRun := func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return ran
+			}
+
+			o := agent.NewOrchestrator(registry, provider.NewReplayer(toolCallCassette(tt.tool, tt.args)))
+			session := agent.NewSession(uuid.Nil, uuid.Nil, uuid.Nil, agent.AgentConfig{Mode: agent.ModeWrite, ProjectRoot: t.TempDir()})
+			h := &approvalHandler{asked: make(chan agent.ToolApprovalPayload, 1), answer: make(chan bool)}
+
+			done := make(chan error, 1)
+			go func() {
+				done <- o.RunWith(t.Context(), session, agent.UserInput{Message: "go"}, h)
+			}()
+
+			if tt.ask {
+				select {
+				case req := <-h.asked:
+					if req.ToolCallID != "call_1" {
+						t.Errorf("approval asked for %q, want call_1", req.ToolCallID)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatal("no approval was requested")
+				}
+				select {
+				case <-done:
+					t.Fatal("run finished before anyone approved the call")
+				case <-time.After(50 * time.Millisecond):
+				}
+				if hasRun() {
+					t.Fatal("tool ran before it was approved")
+				}
+				h.answer <- tt.approve
+			}
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("RunWith: %v", err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("RunWith did not return")
+			}
+			if !tt.ask && len(h.asked) > 0 {
+				t.Error("an approval was requested for a call that needs none")
+			}
+			if hasRun() != tt.ran {
+				t.Errorf("tool ran = %v, want %v", hasRun(), tt.ran)
+			}
+
+			result := h.result("call_1")
+			if result == nil {
+				t.Fatal("no tool.result event")
+			}
+			code := ""
+			if e, ok := result["error"].(*tools.ToolError); ok && e != nil {
+				code = e.Code
+			}
+			if code != tt.code {
+				t.Errorf("result error code = %q, want %q", code, tt.code)
+			}
+		})
+	}
+}
+
+// reviewHandler answers every review with the same feedback.
+type reviewHandler struct {
+	approvalHandler
+	feedback string
+	reviews  int
+}
+
+func (h *reviewHandler) Review(ctx context.Context) string {
+	h.reviews++
+	return h.feedback
+}
+
+func TestOrchestrator_ReviewFeedbackOutOfSteps(t *testing.T) {
+	cassette := &provider.Cassette{Interactions: []provider.Interaction{
+		{Chunks: []provider.StreamChunk{{Content: "done"}, {Done: true}}},
+	}}
+	o := agent.NewOrchestrator(tools.NewRegistry(), provider.NewReplayer(cassette))
+	cfg := agent.AgentConfig{Mode: agent.ModeWrite, ProjectRoot: t.TempDir()}
+	cfg.Limits.MaxSteps = 1
+	session := agent.NewSession(uuid.Nil, uuid.Nil, uuid.Nil, cfg)
+	h := &reviewHandler{feedback: "Rejected main.go: keep the old name"}
+
+	if err := o.RunWith(t.Context(), session, agent.UserInput{Message: "go"}, h); err != nil {
+		t.Fatalf("RunWith: %v", err)
+	}
+	if h.reviews != 1 {
+		t.Errorf("reviews = %d, want 1", h.reviews)
+	}
+	messages := session.GetMessages()
+	if last := messages[len(messages)-1]; last.Role != agent.RoleUser || last.Content != h.feedback {
+		t.Errorf("last message = %s %q, want the review feedback", last.Role, last.Content)
+	}
+}
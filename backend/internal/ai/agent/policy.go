@@ -1,7 +1,11 @@
 package agent
 
 import (
+	"fmt"
 	"strings"
+
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/ai/tools/builtin"
 )
 
 type PolicyDecision string
@@ -20,9 +24,13 @@ type ToolPolicy struct {
 
 type PolicyEngine struct {
 	policies []ToolPolicy
+	// fallback decides tools without a policy; nil means confirm.
+	fallback func(toolName string) PolicyDecision
 }
 
-func NewPolicyEngine() *PolicyEngine {
+// NewPolicyEngine is the policy for interactive runs. Tools without a rule
+// of their own follow the policy they were registered with.
+func NewPolicyEngine(registry *tools.ToolRegistry) *PolicyEngine {
 	return &PolicyEngine{
 		policies: []ToolPolicy{
 			{
@@ -53,6 +61,51 @@ func NewPolicyEngine() *PolicyEngine {
 					return DecisionConfirm
 				},
 			},
+			{
+				Name:     "write_file_default",
+				ToolName: "write_file",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					return DecisionConfirm
+				},
+			},
+			{
+				Name:     "str_replace_default",
+				ToolName: "str_replace",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					return DecisionConfirm
+				},
+			},
+			{
+				Name:     "move_path_default",
+				ToolName: "move_path",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					return DecisionConfirm
+				},
+			},
+			{
+				Name:     "copy_path_default",
+				ToolName: "copy_path",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					if overwrite, _ := args["overwrite"].(bool); overwrite {
+						return DecisionConfirm
+					}
+					return DecisionAllow
+				},
+			},
+			{
+				Name:     "delete_path_default",
+				ToolName: "delete_path",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					return DecisionConfirm
+				},
+			},
+			{
+				Name:     "make_dir_default",
+				ToolName: "make_dir",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					return DecisionAllow
+				},
+			},
 			{
 				Name:     "run_command_default",
 				ToolName: "run_command",
@@ -67,8 +120,211 @@ func NewPolicyEngine() *PolicyEngine {
 					return DecisionConfirm
 				},
 			},
+			{
+				Name:     "run_tests_default",
+				ToolName: "run_tests",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					return DecisionConfirm
+				},
+			},
+			{
+				Name:     "send_to_terminal_default",
+				ToolName: "send_to_terminal",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					return DecisionConfirm
+				},
+			},
+		},
+		fallback: func(toolName string) PolicyDecision {
+			tool, ok := registry.Get(toolName)
+			if !ok {
+				return DecisionConfirm
+			}
+			switch tool.Policy {
+			case tools.PolicyAllow:
+				return DecisionAllow
+			case tools.PolicyDeny:
+				return DecisionDeny
+			}
+			return DecisionConfirm
+		},
+	}
+}
+
+// NewBatchReviewPolicyEngine is the policy for runs in batch review mode.
+// Edits land in a shadow copy and the user reviews them together at the
+// end, so write tools run without asking; everything else is decided as in
+// NewPolicyEngine.
+func NewBatchReviewPolicyEngine(registry *tools.ToolRegistry) *PolicyEngine {
+	e := NewPolicyEngine(registry)
+	policies := e.policies[:0]
+	for _, p := range e.policies {
+		if !WriteTools[p.ToolName] {
+			policies = append(policies, p)
+		}
+	}
+	e.policies = policies
+	fallback := e.fallback
+	e.fallback = func(toolName string) PolicyDecision {
+		if WriteTools[toolName] {
+			return DecisionAllow
+		}
+		return fallback(toolName)
+	}
+	return e
+}
+
+// ScheduleConfig is the "schedule" section of .webide/config.json.
+type ScheduleConfig struct {
+	// AllowedCommands are the run_command and run_tests commands scheduled
+	// runs may use.
+	// A "*" matches any text, e.g. "go vet *" or "npm run lint".
+	AllowedCommands []string `json:"allowed_commands,omitempty"`
+}
+
+// NewScheduledPolicyEngine is the policy for scheduled runs. They work in a
+// shadow copy, so edits are allowed, but tools that would need confirmation
+// are denied. Commands are denied unless the project allowlists them, since
+// a shell command can reach anything outside the copy.
+func NewScheduledPolicyEngine(registry *tools.ToolRegistry, cfg ScheduleConfig) *PolicyEngine {
+	return &PolicyEngine{
+		policies: []ToolPolicy{
+			{
+				Name:     "run_command_scheduled",
+				ToolName: "run_command",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					cmd, ok := args["cmd"].(string)
+					if !ok || !isAllowedCommand(cmd, cfg.AllowedCommands) || isDangerousCommand(cmd) || isGitWriteCommand(cmd) {
+						return DecisionDeny
+					}
+					return DecisionAllow
+				},
+			},
+			{
+				// run_tests runs the project's own test scripts, so it is held
+				// to the same allowlist as run_command. Its arguments are quoted
+				// by the tool itself, so only the pattern has to match.
+				Name:     "run_tests_scheduled",
+				ToolName: "run_tests",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					if s == nil {
+						return DecisionDeny
+					}
+					cmd, err := builtin.TestCommand(s.Config.ProjectRoot, args)
+					if err != nil || !matchesAllowed(cmd, cfg.AllowedCommands) {
+						return DecisionDeny
+					}
+					return DecisionAllow
+				},
+			},
+			{
+				// Deletes always need a person, and nobody attends these runs.
+				Name:     "delete_path_scheduled",
+				ToolName: "delete_path",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					return DecisionDeny
+				},
+			},
+			{
+				// Terminals are the user's shells, outside the shadow copy.
+				Name:     "send_to_terminal_scheduled",
+				ToolName: "send_to_terminal",
+				Condition: func(s *AgentSession, args map[string]interface{}) PolicyDecision {
+					return DecisionDeny
+				},
+			},
 		},
+		fallback: func(toolName string) PolicyDecision {
+			if WriteTools[toolName] {
+				return DecisionAllow
+			}
+			if tool, ok := registry.Get(toolName); ok && tool.Policy == tools.PolicyAllow {
+				return DecisionAllow
+			}
+			return DecisionDeny
+		},
+	}
+}
+
+// shellMetaChars would let an allowlisted prefix run further commands.
+const shellMetaChars = ";&|<>$`(){}\\\n\r"
+
+// isAllowedCommand reports whether cmd is a single simple command matching
+// one of the allowlist patterns.
+func isAllowedCommand(cmd string, allowed []string) bool {
+	cmd = strings.TrimSpace(cmd)
+	if cmd == "" || strings.ContainsAny(cmd, shellMetaChars) {
+		return false
 	}
+	return matchesAllowed(cmd, allowed)
+}
+
+// matchesAllowed reports whether cmd matches one of the allowed patterns,
+// ignoring differences in whitespace.
+func matchesAllowed(cmd string, allowed []string) bool {
+	cmd = strings.Join(strings.Fields(cmd), " ")
+	for _, pattern := range allowed {
+		if matchCommand(strings.Join(strings.Fields(pattern), " "), cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCommand matches cmd against pattern, where "*" stands for any text.
+func matchCommand(pattern, cmd string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == cmd
+	}
+	if !strings.HasPrefix(cmd, parts[0]) {
+		return false
+	}
+	cmd = cmd[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(cmd, part)
+		if i < 0 {
+			return false
+		}
+		cmd = cmd[i+len(part):]
+	}
+	return strings.HasSuffix(cmd, parts[len(parts)-1])
+}
+
+var gitWriteSubcommands = []string{
+	"push", "commit", "reset", "checkout", "switch", "restore", "rebase", "merge",
+	"cherry-pick", "revert", "am", "apply", "stash", "tag", "branch", "clean",
+	"rm", "mv", "add", "remote", "config", "gc", "prune", "fetch", "pull",
+}
+
+// isGitWriteCommand reports git invocations that change refs, the index or
+// configuration, or talk to a remote. It is a second check on allowlisted
+// commands, not a sandbox.
+func isGitWriteCommand(cmd string) bool {
+	fields := strings.Fields(strings.ToLower(cmd))
+	for i, f := range fields {
+		if f != "git" && !strings.HasSuffix(f, "/git") {
+			continue
+		}
+		args := fields[i+1:]
+		for j := 0; j < len(args); j++ {
+			arg := args[j]
+			if strings.HasPrefix(arg, "-") {
+				// Global options that take a separate value.
+				if arg == "-c" || arg == "-C" || arg == "--git-dir" || arg == "--work-tree" {
+					j++
+				}
+				continue
+			}
+			for _, sub := range gitWriteSubcommands {
+				if arg == sub {
+					return true
+				}
+			}
+			break
+		}
+	}
+	return false
 }
 
 func isDangerousCommand(cmd string) bool {
@@ -99,6 +355,9 @@ func (e *PolicyEngine) Decide(toolName string, session *AgentSession, args map[s
 			return p.Condition(session, args)
 		}
 	}
+	if e.fallback != nil {
+		return e.fallback(toolName)
+	}
 	return DecisionConfirm
 }
 
@@ -120,11 +379,80 @@ func GenerateToolSummary(toolName string, args map[string]interface{}) string {
 	case "search_in_files":
 		query, _ := args["query"].(string)
 		return "Search for: " + query
+	case "find_files":
+		if query, _ := args["query"].(string); query != "" {
+			return "Find files: " + query
+		}
+		return "Find files"
 	case "apply_patch":
 		return "Apply code changes"
+	case "write_file":
+		path, _ := args["path"].(string)
+		return "Write file: " + path
+	case "str_replace":
+		path, _ := args["path"].(string)
+		return "Edit file: " + path
+	case "move_path", "copy_path":
+		src, _ := args["source"].(string)
+		dst, _ := args["destination"].(string)
+		verb := "Move "
+		if toolName == "copy_path" {
+			verb = "Copy "
+		}
+		return verb + src + " to " + dst
+	case "delete_path":
+		path, _ := args["path"].(string)
+		return "Delete: " + path
+	case "make_dir":
+		path, _ := args["path"].(string)
+		return "Create directory: " + path
+	case "git_status":
+		return "Git status"
+	case "git_diff", "git_log", "git_show", "git_blame":
+		if path, _ := args["path"].(string); path != "" {
+			return "Git " + strings.TrimPrefix(toolName, "git_") + ": " + path
+		}
+		return "Git " + strings.TrimPrefix(toolName, "git_")
+	case "list_symbols":
+		path, _ := args["path"].(string)
+		return "List symbols: " + path
+	case "package_api":
+		path, _ := args["path"].(string)
+		return "Package API: " + path
+	case "find_definition", "find_references":
+		target, _ := args["symbol"].(string)
+		if target == "" {
+			path, _ := args["path"].(string)
+			line, _ := args["line"].(float64)
+			target = fmt.Sprintf("%s:%d", path, int(line))
+		}
+		if toolName == "find_definition" {
+			return "Find definition: " + target
+		}
+		return "Find references: " + target
 	case "run_command":
 		cmd, _ := args["cmd"].(string)
 		return "Run command: " + truncateString(cmd, 50)
+	case "run_tests":
+		target, _ := args["path"].(string)
+		if target == "" {
+			target = "."
+		}
+		if test, _ := args["test"].(string); test != "" {
+			target += " " + test
+		}
+		return "Run tests: " + truncateString(target, 50)
+	case "read_terminal":
+		if name, _ := args["terminal"].(string); name != "" {
+			return "Read terminal: " + name
+		}
+		return "Read terminal"
+	case "send_to_terminal":
+		cmd, _ := args["command"].(string)
+		if interrupt, _ := args["interrupt"].(bool); interrupt {
+			cmd = strings.TrimSpace("^C " + cmd)
+		}
+		return "Type in terminal: " + truncateString(cmd, 50)
 	}
 	return "Tool: " + toolName
 }
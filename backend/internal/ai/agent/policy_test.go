@@ -0,0 +1,149 @@
+package agent_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+func TestScheduledPolicy_RunCommand(t *testing.T) {
+	engine := agent.NewScheduledPolicyEngine(tools.NewRegistry(), agent.ScheduleConfig{
+		AllowedCommands: []string{"go vet ./...", "go test *", "npm run lint"},
+	})
+
+	tests := []struct {
+		name string
+		cmd  string
+		want agent.PolicyDecision
+	}{
+		{name: "exact match", cmd: "go vet ./...", want: agent.DecisionAllow},
+		{name: "extra spaces", cmd: "  go   vet ./... ", want: agent.DecisionAllow},
+		{name: "wildcard", cmd: "go test -run TestFoo ./pkg/...", want: agent.DecisionAllow},
+		{name: "not allowlisted", cmd: "make build", want: agent.DecisionDeny},
+		{name: "prefix of allowed", cmd: "npm run lint:fix", want: agent.DecisionDeny},
+		{name: "chained with &&", cmd: "go test ./... && git push", want: agent.DecisionDeny},
+		{name: "chained with ;", cmd: "npm run lint; curl x", want: agent.DecisionDeny},
+		{name: "command substitution", cmd: "go test $(git push)", want: agent.DecisionDeny},
+		{name: "backticks", cmd: "go test `git push`", want: agent.DecisionDeny},
+		{name: "pipe", cmd: "go test ./... | sh", want: agent.DecisionDeny},
+		{name: "newline", cmd: "go vet ./...\ngit push", want: agent.DecisionDeny},
+		{name: "sh -c", cmd: "sh -c 'git push'", want: agent.DecisionDeny},
+		{name: "absolute git", cmd: "/usr/bin/git push", want: agent.DecisionDeny},
+		{name: "git write behind wildcard", cmd: "go test -exec git push", want: agent.DecisionDeny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := engine.Decide("run_command", nil, map[string]interface{}{"cmd": tt.cmd})
+			if got != tt.want {
+				t.Errorf("Decide(%q) = %s, want %s", tt.cmd, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduledPolicy_DefaultDeniesCommands(t *testing.T) {
+	engine := agent.NewScheduledPolicyEngine(tools.NewRegistry(), agent.ScheduleConfig{})
+
+	for _, cmd := range []string{"ls", "go test ./...", "echo hi"} {
+		if got := engine.Decide("run_command", nil, map[string]interface{}{"cmd": cmd}); got != agent.DecisionDeny {
+			t.Errorf("Decide(%q) = %s without an allowlist, want deny", cmd, got)
+		}
+	}
+}
+
+func TestScheduledPolicy_Tools(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(tools.Tool{Name: "read_file", Policy: tools.PolicyAllow})
+	registry.Register(tools.Tool{Name: "fetch_url", Policy: tools.PolicyConfirm})
+	engine := agent.NewScheduledPolicyEngine(registry, agent.ScheduleConfig{})
+
+	tests := []struct {
+		tool string
+		want agent.PolicyDecision
+	}{
+		{tool: "read_file", want: agent.DecisionAllow},
+		{tool: "write_file", want: agent.DecisionAllow},
+		{tool: "str_replace", want: agent.DecisionAllow},
+		{tool: "run_tests", want: agent.DecisionDeny},
+		{tool: "delete_path", want: agent.DecisionDeny},
+		{tool: "send_to_terminal", want: agent.DecisionDeny},
+		{tool: "fetch_url", want: agent.DecisionDeny},
+		{tool: "unknown_tool", want: agent.DecisionDeny},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			if got := engine.Decide(tt.tool, nil, map[string]interface{}{}); got != tt.want {
+				t.Errorf("Decide(%s) = %s, want %s", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduledPolicy_RunTests(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/m\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	session := agent.NewSession(uuid.Nil, uuid.Nil, uuid.Nil, agent.AgentConfig{ProjectRoot: root})
+
+	tests := []struct {
+		name    string
+		allowed []string
+		want    agent.PolicyDecision
+	}{
+		{name: "no allowlist", want: agent.DecisionDeny},
+		{name: "other command allowlisted", allowed: []string{"npm test"}, want: agent.DecisionDeny},
+		{name: "go test allowlisted", allowed: []string{"go test *"}, want: agent.DecisionAllow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := agent.NewScheduledPolicyEngine(tools.NewRegistry(), agent.ScheduleConfig{AllowedCommands: tt.allowed})
+			if got := engine.Decide("run_tests", session, map[string]interface{}{"framework": "go"}); got != tt.want {
+				t.Errorf("Decide(run_tests) = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyEngine_Tools(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(tools.Tool{Name: "find_files", Policy: tools.PolicyAllow})
+	registry.Register(tools.Tool{Name: "fetch_url", Policy: tools.PolicyConfirm})
+	registry.Register(tools.Tool{Name: "drop_db", Policy: tools.PolicyDeny})
+
+	tests := []struct {
+		name   string
+		engine *agent.PolicyEngine
+		tool   string
+		args   map[string]interface{}
+		want   agent.PolicyDecision
+	}{
+		{name: "registered allow", engine: agent.NewPolicyEngine(registry), tool: "find_files", want: agent.DecisionAllow},
+		{name: "registered confirm", engine: agent.NewPolicyEngine(registry), tool: "fetch_url", want: agent.DecisionConfirm},
+		{name: "registered deny", engine: agent.NewPolicyEngine(registry), tool: "drop_db", want: agent.DecisionDeny},
+		{name: "unknown tool", engine: agent.NewPolicyEngine(registry), tool: "mystery", want: agent.DecisionConfirm},
+		{name: "patch needs confirmation", engine: agent.NewPolicyEngine(registry), tool: "apply_patch", want: agent.DecisionConfirm},
+		{name: "write needs confirmation", engine: agent.NewPolicyEngine(registry), tool: "write_file", want: agent.DecisionConfirm},
+		{name: "command needs confirmation", engine: agent.NewPolicyEngine(registry), tool: "run_command", args: map[string]interface{}{"cmd": "ls"}, want: agent.DecisionConfirm},
+		{name: "batch review allows patches", engine: agent.NewBatchReviewPolicyEngine(registry), tool: "apply_patch", want: agent.DecisionAllow},
+		{name: "batch review allows writes", engine: agent.NewBatchReviewPolicyEngine(registry), tool: "write_file", want: agent.DecisionAllow},
+		{name: "batch review allows deletes", engine: agent.NewBatchReviewPolicyEngine(registry), tool: "delete_path", want: agent.DecisionAllow},
+		{name: "batch review confirms commands", engine: agent.NewBatchReviewPolicyEngine(registry), tool: "run_command", args: map[string]interface{}{"cmd": "ls"}, want: agent.DecisionConfirm},
+		{name: "batch review keeps registered policy", engine: agent.NewBatchReviewPolicyEngine(registry), tool: "fetch_url", want: agent.DecisionConfirm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.engine.Decide(tt.tool, nil, tt.args); got != tt.want {
+				t.Errorf("Decide(%s) = %s, want %s", tt.tool, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/webide/ide/backend/internal/ai/memory"
+	"github.com/webide/ide/backend/internal/ai/redact"
+)
+
+const ProjectConfigPath = ".webide/config.json"
+
+type ProjectConfig struct {
+	Hooks     []HookConfig    `json:"hooks,omitempty"`
+	Checks    ChecksConfig    `json:"checks,omitempty"`
+	Budget    BudgetConfig    `json:"budget,omitempty"`
+	Memory    memory.Config   `json:"memory,omitempty"`
+	Review    ReviewConfig    `json:"review,omitempty"`
+	Summarize SummarizeConfig `json:"summarize,omitempty"`
+	Redact    redact.Config   `json:"redact,omitempty"`
+	Schedule  ScheduleConfig  `json:"schedule,omitempty"`
+}
+
+func LoadProjectConfig(projectRoot string) ProjectConfig {
+	var cfg ProjectConfig
+	if projectRoot == "" {
+		return cfg
+	}
+
+	data, err := os.ReadFile(filepath.Join(projectRoot, ProjectConfigPath))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("[Agent] Failed to read project config: %v", err)
+		}
+		return cfg
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Printf("[Agent] Invalid project config %s: %v", ProjectConfigPath, err)
+		return ProjectConfig{}
+	}
+
+	return cfg
+}
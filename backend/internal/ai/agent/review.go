@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ReviewModeImmediate = "immediate"
+	ReviewModeBatch     = "batch"
+
+	EventReviewRequired = "review.required"
+	EventReviewResolved = "review.resolved"
+)
+
+// ReviewConfig is the "review" section of .webide/config.json. In batch mode
+// a run edits a shadow copy and the user reviews every change at the end.
+type ReviewConfig struct {
+	Mode string `json:"mode,omitempty"`
+}
+
+type ReviewRequest struct {
+	ID    string         `json:"id"`
+	Files []ShadowChange `json:"files"`
+	Diff  string         `json:"diff"`
+}
+
+// ReviewDecision is the user's answer. Accept lists the files to keep; with
+// AcceptAll every file is kept and Accept is ignored.
+type ReviewDecision struct {
+	AcceptAll bool     `json:"accept_all,omitempty"`
+	Accept    []string `json:"accept,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+}
+
+// ReviewOutcome records what happened to each file. Conflicts were accepted
+// but not applied because the user changed them in the meantime.
+type ReviewOutcome struct {
+	ID        string   `json:"id"`
+	Accepted  []string `json:"accepted"`
+	Rejected  []string `json:"rejected"`
+	Conflicts []string `json:"conflicts,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Split sorts the reviewed files into accepted and rejected paths.
+func (d ReviewDecision) Split(files []ShadowChange) ([]string, []string) {
+	keep := make(map[string]bool, len(d.Accept))
+	for _, p := range d.Accept {
+		keep[p] = true
+	}
+
+	accepted, rejected := []string{}, []string{}
+	for _, f := range files {
+		if d.AcceptAll || keep[f.Path] {
+			accepted = append(accepted, f.Path)
+		} else {
+			rejected = append(rejected, f.Path)
+		}
+	}
+	return accepted, rejected
+}
+
+// Status summarises the outcome as a changeset status.
+func (o ReviewOutcome) Status() string {
+	switch {
+	case len(o.Rejected) == 0 && len(o.Conflicts) == 0:
+		return "accepted"
+	case len(o.Accepted) == 0:
+		return "rejected"
+	}
+	return "partial"
+}
+
+// Feedback tells the model which of its changes were discarded. It is empty
+// when everything was applied.
+func (o ReviewOutcome) Feedback() string {
+	if len(o.Rejected) == 0 && len(o.Conflicts) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	if len(o.Rejected) > 0 {
+		b.WriteString("The user reviewed your changes and rejected the edits to:\n")
+		for _, p := range o.Rejected {
+			b.WriteString("- " + p + "\n")
+		}
+		if o.Reason != "" {
+			fmt.Fprintf(&b, "Reason: %s\n", o.Reason)
+		}
+		b.WriteString("Those files were restored to their previous content.")
+		b.WriteString(" Do not redo the rejected edits unless the user asks; address the reason if one was given.\n")
+	}
+	if len(o.Conflicts) > 0 {
+		b.WriteString("The user edited these files while you were working, so your changes to them were not applied:\n")
+		for _, p := range o.Conflicts {
+			b.WriteString("- " + p + "\n")
+		}
+		b.WriteString("They now have the user's content. Read them again before making any further change.\n")
+	}
+	if len(o.Accepted) > 0 {
+		b.WriteString("The other changes were applied.")
+	}
+	return strings.TrimSpace(b.String())
+}
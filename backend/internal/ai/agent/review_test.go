@@ -0,0 +1,52 @@
+package agent_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/agent"
+)
+
+func TestReviewOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcome  agent.ReviewOutcome
+		status   string
+		feedback []string
+	}{
+		{
+			name:    "all accepted",
+			outcome: agent.ReviewOutcome{Accepted: []string{"a.go"}, Rejected: []string{}},
+			status:  "accepted",
+		},
+		{
+			name:     "partly rejected",
+			outcome:  agent.ReviewOutcome{Accepted: []string{"a.go"}, Rejected: []string{"b.go"}, Reason: "keep b"},
+			status:   "partial",
+			feedback: []string{"rejected the edits to:\n- b.go", "Reason: keep b", "The other changes were applied."},
+		},
+		{
+			name:     "conflict",
+			outcome:  agent.ReviewOutcome{Accepted: []string{}, Rejected: []string{}, Conflicts: []string{"a.go"}},
+			status:   "rejected",
+			feedback: []string{"edited these files while you were working", "- a.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.outcome.Status(); got != tt.status {
+				t.Errorf("Status() = %q, want %q", got, tt.status)
+			}
+			feedback := tt.outcome.Feedback()
+			if len(tt.feedback) == 0 && feedback != "" {
+				t.Errorf("expected no feedback, got %q", feedback)
+			}
+			for _, want := range tt.feedback {
+				if !strings.Contains(feedback, want) {
+					t.Errorf("feedback missing %q:\n%s", want, feedback)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"github.com/webide/ide/backend/internal/ai/redact"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+// RedactResult hides secrets in a tool result before the model or the chat
+// history sees it.
+func RedactResult(r *redact.Redactor, result tools.ToolResult) tools.ToolResult {
+	result.Data = r.Data(result.Data)
+	if result.Error != nil {
+		e := *result.Error
+		e.Message = r.String(e.Message)
+		e.Details = r.Data(e.Details)
+		result.Error = &e
+	}
+	return result
+}
+
+// RestoreArgs puts the real values back for placeholders in tool arguments.
+func RestoreArgs(r *redact.Redactor, args map[string]interface{}) map[string]interface{} {
+	if restored, ok := r.Restore(args).(map[string]interface{}); ok {
+		return restored
+	}
+	return args
+}
@@ -0,0 +1,82 @@
+package agent_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/ai/redact"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+const testSecret = "sk_live_4f9a8b7c6d5e4f3a2b1c"
+
+type outputEntry struct {
+	Stream string `json:"stream"`
+	Text   string `json:"text"`
+}
+
+func TestRedactResult(t *testing.T) {
+	projectDir := t.TempDir()
+	os.WriteFile(filepath.Join(projectDir, ".env"), []byte("API_TOKEN="+testSecret+"\n"), 0644)
+	r := redact.New(projectDir, redact.Config{})
+
+	tests := []struct {
+		name   string
+		result tools.ToolResult
+	}{
+		{
+			name:   "map data",
+			result: tools.NewSuccessResult(map[string]interface{}{"content": "API_TOKEN=" + testSecret}),
+		},
+		{
+			name:   "typed entries",
+			result: tools.NewSuccessResult([]outputEntry{{Stream: "stdout", Text: "token " + testSecret}}),
+		},
+		{
+			name:   "error message and details",
+			result: tools.NewErrorResult(tools.ErrCodeExecution, "failed with "+testSecret, []outputEntry{{Text: testSecret}}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := agent.RedactResult(r, tt.result)
+			raw, _ := json.Marshal(out)
+			if strings.Contains(string(raw), testSecret) {
+				t.Errorf("secret reached the result: %s", raw)
+			}
+			if !strings.Contains(string(raw), "[REDACTED:API_TOKEN]") {
+				t.Errorf("expected placeholder in %s", raw)
+			}
+		})
+	}
+}
+
+func TestHookRunner_RedactsOutput(t *testing.T) {
+	projectDir := t.TempDir()
+	os.WriteFile(filepath.Join(projectDir, ".env"), []byte("API_TOKEN="+testSecret+"\n"), 0644)
+	r := redact.New(projectDir, redact.Config{})
+
+	hooks := agent.NewHookRunner(projectDir, []agent.HookConfig{{
+		Event:   agent.HookPreTool,
+		Command: `echo "env has $(cat .env)"; exit 2`,
+	}}, r)
+
+	outcome := hooks.Run(t.Context(), agent.HookPayload{Event: agent.HookPreTool, ToolName: "read_file"})
+	if len(outcome.Results) != 1 {
+		t.Fatalf("expected one hook result, got %d", len(outcome.Results))
+	}
+	res := outcome.Results[0]
+	for _, text := range []string{res.Output, res.Reason, outcome.Reason} {
+		if strings.Contains(text, testSecret) {
+			t.Errorf("secret reached hook output: %q", text)
+		}
+	}
+	if !strings.Contains(res.Output, "[REDACTED:API_TOKEN]") {
+		t.Errorf("expected placeholder in %q", res.Output)
+	}
+}
@@ -0,0 +1,530 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	ChangeAdded    = "added"
+	ChangeModified = "modified"
+	ChangeDeleted  = "deleted"
+
+	maxShadowDiffBytes = 256 * 1024
+)
+
+// shadowLinkDirs are linked into the shadow copy instead of copied. They
+// hold dependencies or caches the agent should not be editing, and copying
+// them would make every run as slow as a fresh install. Writes below them go
+// straight to the real tree and never show up in Changes.
+var shadowLinkDirs = map[string]bool{
+	"node_modules": true,
+	".venv":        true,
+	"venv":         true,
+	"__pycache__":  true,
+	".next":        true,
+	"target":       true,
+	".gradle":      true,
+}
+
+// Shadow is a scratch copy of a project. Tools run against Root while the
+// real tree at Source stays untouched until the user accepts the changes.
+// The user can keep editing Source meanwhile, so changes are measured
+// against the content hashes taken when the copy was made, not against the
+// live tree. Only regular files are tracked: directories and symlinks the
+// run creates or removes are not reported or applied, and every shadow is
+// a full copy of the project apart from the git objects.
+type Shadow struct {
+	Root   string
+	Source string
+
+	base map[string][sha256.Size]byte
+}
+
+// ShadowChange is one file the run changed. Conflict is set when the file
+// also changed in the real tree since the shadow was created; Apply will not
+// overwrite it.
+type ShadowChange struct {
+	Path     string `json:"path"`
+	Status   string `json:"status"`
+	Diff     string `json:"diff,omitempty"`
+	Binary   bool   `json:"binary,omitempty"`
+	Conflict bool   `json:"conflict,omitempty"`
+}
+
+func NewShadow(projectRoot string) (*Shadow, error) {
+	root, err := os.MkdirTemp("", "webide-shadow-*")
+	if err != nil {
+		return nil, err
+	}
+	s := &Shadow{Root: root, Source: projectRoot, base: make(map[string][sha256.Size]byte)}
+
+	err = filepath.WalkDir(projectRoot, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(projectRoot, path)
+		if rel == "." {
+			return nil
+		}
+		dst := filepath.Join(root, rel)
+
+		if d.Name() == ".git" {
+			if d.IsDir() {
+				if err := copyGitDir(path, dst); err != nil {
+					return err
+				}
+				return filepath.SkipDir
+			}
+			// A .git file points at a git directory elsewhere, which
+			// commands in the shadow would then write to.
+			return nil
+		}
+		if d.IsDir() && shadowLinkDirs[d.Name()] {
+			if err := os.Symlink(path, dst); err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			sum, err := copyFileHash(path, dst, d)
+			if err != nil {
+				return err
+			}
+			s.base[filepath.ToSlash(rel)] = sum
+			return nil
+		}
+		return copyEntry(path, dst, d)
+	})
+	if err != nil {
+		os.RemoveAll(root)
+		return nil, fmt.Errorf("create shadow copy: %w", err)
+	}
+	return s, nil
+}
+
+// copyGitDir gives the shadow its own git directory, so git commands run
+// there cannot move the project's refs, index or config. Objects are never
+// rewritten in place, so instead of copying them the copy borrows the
+// project's object store as an alternate and writes new objects locally.
+func copyGitDir(src, dst string) error {
+	objects := filepath.Join(src, "objects")
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == objects {
+			return filepath.SkipDir
+		}
+		rel, _ := filepath.Rel(src, path)
+		return copyEntry(path, filepath.Join(dst, rel), d)
+	})
+	if err != nil {
+		return err
+	}
+
+	info := filepath.Join(dst, "objects", "info")
+	if err := os.MkdirAll(info, 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(dst, "objects", "pack"), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(info, "alternates"), []byte(objects+"\n"), 0644)
+}
+
+func copyEntry(src, dst string, d fs.DirEntry) error {
+	info, err := d.Info()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case d.IsDir():
+		return os.MkdirAll(dst, info.Mode().Perm())
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	case !info.Mode().IsRegular():
+		return nil
+	}
+	return copyFile(src, dst, info.Mode().Perm())
+}
+
+func copyFileHash(src, dst string, d fs.DirEntry) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	info, err := d.Info()
+	if err != nil {
+		return sum, err
+	}
+	h := sha256.New()
+	if err := copyFileTo(src, dst, info.Mode().Perm(), h); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	return copyFileTo(src, dst, mode, io.Discard)
+}
+
+// copyFileTo copies src to dst and also writes the content to tee.
+func copyFileTo(src, dst string, mode os.FileMode, tee io.Writer) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.MultiWriter(out, tee), in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// readFileState returns a file's content and hash, with ok false when it
+// does not exist.
+func readFileState(path string) ([]byte, [sha256.Size]byte, bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, [sha256.Size]byte{}, false, nil
+	}
+	if err != nil {
+		return nil, [sha256.Size]byte{}, false, err
+	}
+	return data, sha256.Sum256(data), true, nil
+}
+
+// Changes returns one entry per file the run added, modified or deleted,
+// with a unified diff against the file as it is now in the real tree. Files
+// the user edited during the run but the agent did not touch are not
+// reported.
+func (s *Shadow) Changes() ([]ShadowChange, error) {
+	shadowFiles, err := regularFiles(s.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []ShadowChange
+	for rel := range shadowFiles {
+		after, afterSum, _, err := readFileState(filepath.Join(s.Root, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		baseSum, inBase := s.base[rel]
+		if inBase && baseSum == afterSum {
+			continue
+		}
+		before, sourceSum, inSource, err := readFileState(filepath.Join(s.Source, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		if inSource && sourceSum == afterSum {
+			continue
+		}
+		status := ChangeModified
+		if !inBase {
+			status = ChangeAdded
+		}
+		c := newShadowChange(rel, status, before, after)
+		c.Conflict = inSource != inBase || (inBase && sourceSum != baseSum)
+		changes = append(changes, c)
+	}
+	for rel, baseSum := range s.base {
+		if shadowFiles[rel] {
+			continue
+		}
+		before, sourceSum, inSource, err := readFileState(filepath.Join(s.Source, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		if !inSource {
+			continue
+		}
+		c := newShadowChange(rel, ChangeDeleted, before, nil)
+		c.Conflict = sourceSum != baseSum
+		changes = append(changes, c)
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// regularFiles lists project-relative paths of regular files, skipping .git
+// and the linked directories in both trees.
+func regularFiles(root string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != root && (d.Name() == ".git" || shadowLinkDirs[d.Name()]) {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
+
+func newShadowChange(rel, status string, before, after []byte) ShadowChange {
+	c := ShadowChange{Path: rel, Status: status}
+	if isBinary(before) || isBinary(after) {
+		c.Binary = true
+		return c
+	}
+
+	fromFile, toFile := "a/"+rel, "b/"+rel
+	if status == ChangeAdded {
+		fromFile = "/dev/null"
+	}
+	if status == ChangeDeleted {
+		toFile = "/dev/null"
+	}
+	c.Diff, _ = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        diffLines(before),
+		B:        diffLines(after),
+		FromFile: fromFile,
+		ToFile:   toFile,
+		Context:  3,
+	})
+	if len(c.Diff) > maxShadowDiffBytes {
+		c.Diff = c.Diff[:maxShadowDiffBytes] + "\n... (diff truncated)\n"
+	}
+	return c
+}
+
+// diffLines splits content for difflib without the trailing empty line
+// difflib.SplitLines adds, so added and deleted files diff cleanly.
+func diffLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
+
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// CombinedDiff joins the per-file diffs into one patch.
+func CombinedDiff(changes []ShadowChange) string {
+	var b strings.Builder
+	for _, c := range changes {
+		if c.Binary {
+			fmt.Fprintf(&b, "Binary file %s %s\n", c.Path, c.Status)
+			continue
+		}
+		b.WriteString(c.Diff)
+	}
+	return b.String()
+}
+
+// Apply copies the given shadow paths over the real tree, deleting files the
+// shadow no longer has. Paths whose real file changed since the shadow was
+// created are left alone and returned, so the user's own edits are never
+// overwritten.
+func (s *Shadow) Apply(paths []string) ([]string, error) {
+	var apply, conflicts []string
+	for _, rel := range paths {
+		_, sourceSum, inSource, err := readFileState(filepath.Join(s.Source, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		baseSum, inBase := s.base[rel]
+		if inSource != inBase || (inBase && sourceSum != baseSum) {
+			conflicts = append(conflicts, rel)
+			continue
+		}
+		apply = append(apply, rel)
+	}
+	if err := syncPaths(s.Root, s.Source, apply); err != nil {
+		return conflicts, err
+	}
+	return conflicts, s.rebase(apply)
+}
+
+// Discard resets the given paths in the shadow to the real tree so later
+// edits and checks start from what the user kept.
+func (s *Shadow) Discard(paths []string) error {
+	if err := syncPaths(s.Source, s.Root, paths); err != nil {
+		return err
+	}
+	return s.rebase(paths)
+}
+
+// rebase records the current real content of paths as the new baseline once
+// both trees agree on them.
+func (s *Shadow) rebase(paths []string) error {
+	for _, rel := range paths {
+		_, sum, ok, err := readFileState(filepath.Join(s.Source, filepath.FromSlash(rel)))
+		if err != nil {
+			return err
+		}
+		if ok {
+			s.base[rel] = sum
+		} else {
+			delete(s.base, rel)
+		}
+	}
+	return nil
+}
+
+// ShadowFile is what a change leaves in the shadow, kept so the change can
+// be applied after the shadow is gone. BaseSHA is the hex sha256 of the file
+// when the shadow was created, empty if it did not exist.
+type ShadowFile struct {
+	Path    string
+	Status  string
+	Content []byte
+	Mode    os.FileMode
+	BaseSHA string
+}
+
+// Files captures the shadow content of each change.
+func (s *Shadow) Files(changes []ShadowChange) ([]ShadowFile, error) {
+	files := make([]ShadowFile, 0, len(changes))
+	for _, c := range changes {
+		f := ShadowFile{Path: c.Path, Status: c.Status}
+		if sum, ok := s.base[c.Path]; ok {
+			f.BaseSHA = hex.EncodeToString(sum[:])
+		}
+		if c.Status != ChangeDeleted {
+			path := filepath.Join(s.Root, filepath.FromSlash(c.Path))
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if f.Content, err = os.ReadFile(path); err != nil {
+				return nil, err
+			}
+			f.Mode = info.Mode().Perm()
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// ApplyFiles writes captured shadow files into root. If any file no longer
+// has its base content, nothing is written and the changed paths are
+// returned.
+func ApplyFiles(root string, files []ShadowFile) ([]string, error) {
+	var conflicts []string
+	for _, f := range files {
+		path, err := joinRel(root, f.Path)
+		if err != nil {
+			return nil, err
+		}
+		_, sum, ok, err := readFileState(path)
+		if err != nil {
+			return nil, err
+		}
+		current := ""
+		if ok {
+			current = hex.EncodeToString(sum[:])
+		}
+		if current != f.BaseSHA {
+			conflicts = append(conflicts, f.Path)
+		}
+	}
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+
+	for _, f := range files {
+		path, _ := joinRel(root, f.Path)
+		if f.Status == ChangeDeleted {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		mode := f.Mode
+		if mode == 0 {
+			mode = 0644
+		}
+		if err := os.WriteFile(path, f.Content, mode); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// joinRel joins a project-relative path onto root, refusing paths that
+// leave it.
+func joinRel(root, rel string) (string, error) {
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if !strings.HasPrefix(path, filepath.Clean(root)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("path escapes project: %s", rel)
+	}
+	return path, nil
+}
+
+func syncPaths(from, to string, paths []string) error {
+	for _, rel := range paths {
+		src := filepath.Join(from, filepath.FromSlash(rel))
+		dst, err := joinRel(to, rel)
+		if err != nil {
+			return err
+		}
+
+		info, err := os.Stat(src)
+		switch {
+		case os.IsNotExist(err):
+			if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if err := copyFile(src, dst, info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Shadow) Close() {
+	if s != nil {
+		os.RemoveAll(s.Root)
+	}
+}
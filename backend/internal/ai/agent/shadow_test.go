@@ -0,0 +1,226 @@
+package agent_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/agent"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "<missing>"
+	}
+	return string(data)
+}
+
+func TestShadow_Changes(t *testing.T) {
+	type change struct {
+		status   string
+		conflict bool
+	}
+
+	tests := []struct {
+		name      string
+		agentEdit func(root string)
+		userEdit  func(root string)
+		want      map[string]change
+	}{
+		{
+			name: "agent edits",
+			agentEdit: func(root string) {
+				writeFiles(t, root, map[string]string{"a.txt": "agent\n", "new.txt": "new\n"})
+				os.Remove(filepath.Join(root, "b.txt"))
+			},
+			want: map[string]change{
+				"a.txt":   {status: agent.ChangeModified},
+				"new.txt": {status: agent.ChangeAdded},
+				"b.txt":   {status: agent.ChangeDeleted},
+			},
+		},
+		{
+			name:     "user edits only",
+			userEdit: func(root string) { writeFiles(t, root, map[string]string{"a.txt": "user\n", "user.txt": "mine\n"}) },
+			want:     map[string]change{},
+		},
+		{
+			name:      "both edit the same file",
+			agentEdit: func(root string) { writeFiles(t, root, map[string]string{"a.txt": "agent\n"}) },
+			userEdit:  func(root string) { writeFiles(t, root, map[string]string{"a.txt": "user\n"}) },
+			want:      map[string]change{"a.txt": {status: agent.ChangeModified, conflict: true}},
+		},
+		{
+			name:      "agent deletes a file the user edited",
+			agentEdit: func(root string) { os.Remove(filepath.Join(root, "a.txt")) },
+			userEdit:  func(root string) { writeFiles(t, root, map[string]string{"a.txt": "user\n"}) },
+			want:      map[string]change{"a.txt": {status: agent.ChangeDeleted, conflict: true}},
+		},
+		{
+			name:      "both add the same path",
+			agentEdit: func(root string) { writeFiles(t, root, map[string]string{"c.txt": "agent\n"}) },
+			userEdit:  func(root string) { writeFiles(t, root, map[string]string{"c.txt": "user\n"}) },
+			want:      map[string]change{"c.txt": {status: agent.ChangeAdded, conflict: true}},
+		},
+		{
+			name:      "both make the same edit",
+			agentEdit: func(root string) { writeFiles(t, root, map[string]string{"a.txt": "same\n"}) },
+			userEdit:  func(root string) { writeFiles(t, root, map[string]string{"a.txt": "same\n"}) },
+			want:      map[string]change{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := t.TempDir()
+			writeFiles(t, project, map[string]string{"a.txt": "base\n", "b.txt": "keep\n"})
+
+			shadow, err := agent.NewShadow(project)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer shadow.Close()
+
+			if tt.agentEdit != nil {
+				tt.agentEdit(shadow.Root)
+			}
+			if tt.userEdit != nil {
+				tt.userEdit(project)
+			}
+
+			changes, err := shadow.Changes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]change)
+			for _, c := range changes {
+				got[c.Path] = change{status: c.Status, conflict: c.Conflict}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("changes = %v, want %v", got, tt.want)
+			}
+			for path, want := range tt.want {
+				if got[path] != want {
+					t.Errorf("%s: got %+v, want %+v", path, got[path], want)
+				}
+			}
+		})
+	}
+}
+
+func TestShadow_ApplySkipsConflicts(t *testing.T) {
+	project := t.TempDir()
+	writeFiles(t, project, map[string]string{"a.txt": "base\n", "b.txt": "base\n", "c.txt": "base\n"})
+
+	shadow, err := agent.NewShadow(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shadow.Close()
+
+	writeFiles(t, shadow.Root, map[string]string{"a.txt": "agent\n", "b.txt": "agent\n"})
+	writeFiles(t, project, map[string]string{"b.txt": "user\n", "c.txt": "user\n"})
+
+	conflicts, err := shadow.Apply([]string{"a.txt", "b.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0] != "b.txt" {
+		t.Fatalf("conflicts = %v, want [b.txt]", conflicts)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "a.txt", want: "agent\n"},
+		{path: "b.txt", want: "user\n"},
+		{path: "c.txt", want: "user\n"},
+	}
+	for _, tt := range tests {
+		if got := readFile(t, filepath.Join(project, tt.path)); got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	if err := shadow.Discard(conflicts); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := shadow.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes after apply and discard, got %+v", changes)
+	}
+}
+
+func TestShadow_GitDirIsNotShared(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	project := t.TempDir()
+	writeFiles(t, project, map[string]string{"a.txt": "base\n"})
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git(project, "init", "-q")
+	git(project, "add", ".")
+	git(project, "commit", "-qm", "base")
+	head := git(project, "rev-parse", "HEAD")
+
+	shadow, err := agent.NewShadow(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shadow.Close()
+
+	if info, err := os.Lstat(filepath.Join(shadow.Root, ".git")); err != nil || info.Mode()&os.ModeSymlink != 0 {
+		t.Fatalf(".git in the shadow must be a directory, got %v %v", info, err)
+	}
+	writeFiles(t, shadow.Root, map[string]string{"a.txt": "agent\n"})
+	git(shadow.Root, "commit", "-qam", "agent commit")
+	git(shadow.Root, "branch", "agent-branch")
+
+	if got := git(project, "rev-parse", "HEAD"); got != head {
+		t.Errorf("project HEAD moved to %s", got)
+	}
+	if got := git(project, "branch", "--list", "agent-branch"); got != "" {
+		t.Errorf("branch leaked into the project: %q", got)
+	}
+	if got := git(shadow.Root, "log", "--format=%s"); got != "agent commit\nbase" {
+		t.Errorf("shadow history = %q", got)
+	}
+
+	changes, err := shadow.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "a.txt" {
+		t.Errorf("changes = %+v, want only a.txt", changes)
+	}
+}
@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/provider"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+const (
+	defaultSummarizeThreshold = 16 * 1024
+	// maxSummarizeInputBytes is how much of an output the summary model sees:
+	// the head and the tail, where build and test failures usually are.
+	maxSummarizeInputBytes = 48 * 1024
+	maxKeyLines            = 60
+	summarizeTimeout       = 60 * time.Second
+)
+
+// SummarizedOutputs maps tools to the result field that holds their output.
+var SummarizedOutputs = map[string]string{
+	"run_command": "output",
+	"read_file":   "content",
+}
+
+// SummarizeConfig is the "summarize" section of .webide/config.json.
+type SummarizeConfig struct {
+	Disabled       bool   `json:"disabled,omitempty"`
+	ThresholdBytes int    `json:"threshold_bytes,omitempty"`
+	Model          string `json:"model,omitempty"`
+}
+
+const summarizePrompt = `You condense tool output for a coding agent that cannot see the original.
+Summarize the output below in at most 30 lines. Keep verbatim:
+- every error and warning line,
+- the names of failing tests and their assertion messages,
+- every file:line (or file:line:col) reference.
+State the overall result (succeeded, failed, how many passed/failed) first. Drop progress bars, repeated lines and passing noise. Do not add advice.`
+
+// keyLinePattern matches lines worth keeping whatever the summary says.
+var keyLinePattern = regexp.MustCompile(`(?i)(\berror\b|\bfail(ed|ure)?\b|\bpanic\b|\bexception\b|\bwarning\b|traceback|assert|[\w./-]+\.\w+:\d+)`)
+
+// Summarizer replaces outputs above the threshold with a summary from a
+// fast model. The full output stays available through read_output. A nil
+// Summarizer leaves results alone.
+type Summarizer struct {
+	threshold int
+	llm       provider.Provider
+	cfg       provider.Config
+}
+
+func NewSummarizer(cfg SummarizeConfig, llm provider.Provider, providerCfg provider.Config) *Summarizer {
+	if cfg.Disabled {
+		return nil
+	}
+	s := &Summarizer{threshold: cfg.ThresholdBytes, llm: llm, cfg: providerCfg}
+	if s.threshold <= 0 {
+		s.threshold = defaultSummarizeThreshold
+	}
+	if cfg.Model != "" {
+		s.cfg.Model = cfg.Model
+	}
+	s.cfg.MaxTokens = 1024
+	return s
+}
+
+// Apply summarizes the output field of result when it is too large. result
+// must already be redacted, since the full output is stored as given.
+func (s *Summarizer) Apply(ctx context.Context, projectID uuid.UUID, name string, result tools.ToolResult) tools.ToolResult {
+	field, ok := SummarizedOutputs[name]
+	if s == nil || !ok || !result.OK {
+		return result
+	}
+	data, ok := result.Data.(map[string]interface{})
+	if !ok {
+		return result
+	}
+	text, _ := data[field].(string)
+	if len(text) <= s.threshold {
+		return result
+	}
+
+	// Only summarized outputs are kept for read_output, and only as the
+	// caller passes them in, which is after redaction. run_command's own
+	// handle is reused so both refer to the same output.
+	handle, _ := data["handle"].(string)
+	handle = tools.Outputs.Put(projectID, name, handle, text)
+
+	keyLines := KeyLines(text)
+	summary, err := s.summarize(ctx, name, text)
+	if err != nil {
+		log.Printf("[Agent] Output summary failed, keeping key lines only: %v", err)
+		summary = fallbackSummary(text)
+	}
+
+	out := make(map[string]interface{}, len(data)+5)
+	for k, v := range data {
+		out[k] = v
+	}
+	out[field] = summary
+	out["summarized"] = true
+	out["output_handle"] = handle
+	out["output_bytes"] = len(text)
+	out["output_lines"] = strings.Count(text, "\n") + 1
+	if len(keyLines) > 0 {
+		out["key_lines"] = keyLines
+	}
+	result.Data = out
+	if result.Meta == nil {
+		result.Meta = &tools.ResultMeta{}
+	}
+	result.Meta.Truncated = true
+	return result
+}
+
+func (s *Summarizer) summarize(ctx context.Context, name, text string) (string, error) {
+	if s.llm == nil {
+		return "", fmt.Errorf("no summary model configured")
+	}
+	ctx, cancel := context.WithTimeout(ctx, summarizeTimeout)
+	defer cancel()
+
+	resp, err := s.llm.Complete(ctx, []provider.Message{
+		{Role: "system", Content: summarizePrompt},
+		{Role: "user", Content: fmt.Sprintf("Output of %s (%d bytes):\n\n%s", name, len(text), clipMiddle(text, maxSummarizeInputBytes))},
+	}, s.cfg)
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
+
+// KeyLines returns error, failure and file:line lines from text, capped at
+// maxKeyLines.
+func KeyLines(text string) []string {
+	var lines []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] || !keyLinePattern.MatchString(line) {
+			continue
+		}
+		seen[line] = true
+		if len(line) > 300 {
+			line = line[:300] + "..."
+		}
+		lines = append(lines, line)
+		if len(lines) >= maxKeyLines {
+			break
+		}
+	}
+	return lines
+}
+
+func fallbackSummary(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) <= 40 {
+		return clipMiddle(text, defaultSummarizeThreshold)
+	}
+	head := strings.Join(lines[:20], "\n")
+	tail := strings.Join(lines[len(lines)-20:], "\n")
+	return fmt.Sprintf("%s\n... (%d lines omitted, see key_lines or read_output) ...\n%s", head, len(lines)-40, tail)
+}
+
+func clipMiddle(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	half := max / 2
+	return text[:half] + fmt.Sprintf("\n... (%d bytes omitted) ...\n", len(text)-max) + text[len(text)-half:]
+}
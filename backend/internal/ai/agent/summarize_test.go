@@ -0,0 +1,53 @@
+package agent_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/ai/provider"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+func TestSummarizer_StoresOnlySummarizedOutputs(t *testing.T) {
+	summarizer := agent.NewSummarizer(agent.SummarizeConfig{ThresholdBytes: 100}, nil, provider.Config{})
+	projectID := uuid.New()
+
+	tests := []struct {
+		name   string
+		output string
+		stored bool
+	}{
+		{name: "short output", output: "ok\n"},
+		{name: "long output", output: strings.Repeat("line of build output\n", 20), stored: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle := uuid.New().String()
+			result := summarizer.Apply(t.Context(), projectID, "run_command", tools.NewSuccessResult(map[string]interface{}{
+				"handle": handle,
+				"output": tt.output,
+			}))
+
+			out, stored := tools.Outputs.Get(handle)
+			if stored != tt.stored {
+				t.Fatalf("output stored = %v, want %v", stored, tt.stored)
+			}
+			data, _ := result.Data.(map[string]interface{})
+			if !tt.stored {
+				if data["summarized"] != nil {
+					t.Errorf("short output was summarized: %v", data)
+				}
+				return
+			}
+			if data["output_handle"] != handle {
+				t.Errorf("output_handle = %v, want the command's handle %s", data["output_handle"], handle)
+			}
+			if out.ProjectID != projectID || out.Bytes != len(tt.output) {
+				t.Errorf("stored output = %+v, want %d bytes for project %s", out, len(tt.output), projectID)
+			}
+		})
+	}
+}
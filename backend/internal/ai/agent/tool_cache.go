@@ -0,0 +1,375 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/ignore"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+// CacheableTools are read-only tools whose results are reused within a
+// session while the files they looked at are unchanged.
+var CacheableTools = map[string]bool{
+	"read_file":       true,
+	"list_dir":        true,
+	"search_in_files": true,
+}
+
+// maxCacheTreeFiles bounds the stat walk used to fingerprint a directory.
+// Larger trees are not cached.
+const maxCacheTreeFiles = 20000
+
+// ToolCache remembers read-only tool results for one session, or for every
+// run of a chat. A repeated call whose inputs are unchanged gets a short note
+// pointing at the earlier result instead of the full output. A nil ToolCache
+// caches nothing.
+type ToolCache struct {
+	root string
+
+	mu      sync.Mutex
+	run     int
+	step    int
+	entries map[string]*toolCacheEntry
+}
+
+type toolCacheEntry struct {
+	run  int
+	step int
+	// noted is set once the entry has been served as a note. The next
+	// identical call runs the tool again, in case the model lost the
+	// original result.
+	noted bool
+
+	file  string
+	stamp fileStamp
+
+	dir   string
+	depth int
+	// search fingerprints only what search_in_files reads: .git and, unless
+	// includeIgnored, ignored paths are left out.
+	search         bool
+	includeIgnored bool
+	tree           string
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	sha     string
+}
+
+func NewToolCache(projectRoot string) *ToolCache {
+	return &ToolCache{root: projectRoot, entries: make(map[string]*toolCacheEntry)}
+}
+
+// chatToolCaches holds the cache each chat's runs share, so a later run can
+// point at results an earlier one already put in the chat history.
+var chatToolCaches = struct {
+	sync.Mutex
+	byChat map[uuid.UUID]*ToolCache
+}{byChat: make(map[uuid.UUID]*ToolCache)}
+
+// ChatToolCache returns the cache shared by the runs of chatID in root and
+// starts a new run in it. A chat that moves to another root, such as a new
+// batch review shadow, gets a fresh cache.
+func ChatToolCache(chatID uuid.UUID, root string) *ToolCache {
+	chatToolCaches.Lock()
+	defer chatToolCaches.Unlock()
+	c := chatToolCaches.byChat[chatID]
+	if c == nil || c.root != root {
+		c = NewToolCache(root)
+		chatToolCaches.byChat[chatID] = c
+	}
+	c.mu.Lock()
+	c.run++
+	c.mu.Unlock()
+	return c
+}
+
+// ForgetChatToolCache drops the cache of a deleted chat.
+func ForgetChatToolCache(chatID uuid.UUID) {
+	chatToolCaches.Lock()
+	delete(chatToolCaches.byChat, chatID)
+	chatToolCaches.Unlock()
+}
+
+// BeginStep sets the step number recorded with new results.
+func (c *ToolCache) BeginStep(step int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.step = step
+	c.mu.Unlock()
+}
+
+// Invalidate drops every cached result.
+func (c *ToolCache) Invalidate() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.entries = make(map[string]*toolCacheEntry)
+	c.mu.Unlock()
+}
+
+// Execute serves name(args) from the cache or runs exec. Write and command
+// tools clear the cache since they may change any file.
+func (c *ToolCache) Execute(name string, args map[string]interface{}, exec func() tools.ToolResult) tools.ToolResult {
+	if c == nil || c.root == "" {
+		return exec()
+	}
+	if !CacheableTools[name] {
+		result := exec()
+		if WriteTools[name] || CommandTools[name] {
+			c.Invalidate()
+		}
+		return result
+	}
+
+	key := c.key(name, args)
+	c.mu.Lock()
+	entry := c.entries[key]
+	c.mu.Unlock()
+
+	if entry != nil && !entry.noted && c.fresh(entry) {
+		c.mu.Lock()
+		entry.noted = true
+		since := fmt.Sprintf("step %d", entry.step)
+		if entry.run != c.run {
+			since += " of an earlier run in this chat"
+		}
+		c.mu.Unlock()
+		return tools.NewSuccessResultWithMeta(map[string]interface{}{
+			"cached": true,
+			"note":   "Unchanged since " + since + "; use the result returned then. Call again to get the full output.",
+		}, tools.ResultMeta{CachedStep: entry.step})
+	}
+
+	result := exec()
+	if !result.OK {
+		return result
+	}
+
+	c.mu.Lock()
+	run, step := c.run, c.step
+	c.mu.Unlock()
+	if entry := c.newEntry(name, args, result, step); entry != nil {
+		entry.run = run
+		c.mu.Lock()
+		c.entries[key] = entry
+		c.mu.Unlock()
+	}
+	return result
+}
+
+// key identifies a call by tool name and arguments, with paths made
+// relative to the project so "./a.go", "a.go" and "/root/a.go" match.
+func (c *ToolCache) key(name string, args map[string]interface{}) string {
+	normalized := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		normalized[k] = v
+	}
+	if p, ok := args["path"].(string); ok {
+		normalized["path"] = c.rel(p)
+	}
+	data, _ := json.Marshal(normalized)
+	return name + ":" + string(data)
+}
+
+func (c *ToolCache) rel(p string) string {
+	abs := c.abs(p)
+	if rel, err := filepath.Rel(c.root, abs); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return abs
+}
+
+func (c *ToolCache) abs(p string) string {
+	if p == "" {
+		p = "."
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(c.root, p)
+	}
+	return filepath.Clean(p)
+}
+
+func (c *ToolCache) newEntry(name string, args map[string]interface{}, result tools.ToolResult, step int) *toolCacheEntry {
+	entry := &toolCacheEntry{step: step}
+	path, _ := args["path"].(string)
+
+	switch name {
+	case "read_file":
+		entry.file = c.abs(path)
+		stamp, err := statFile(entry.file)
+		if err != nil {
+			return nil
+		}
+		if data, ok := result.Data.(map[string]interface{}); ok {
+			stamp.sha, _ = data["sha"].(string)
+		}
+		if stamp.sha == "" {
+			if stamp.sha, err = hashFile(entry.file); err != nil {
+				return nil
+			}
+		}
+		entry.stamp = stamp
+	case "list_dir":
+		entry.dir = c.abs(path)
+		entry.depth = 1
+		if d, ok := args["depth"].(float64); ok && d > 1 {
+			entry.depth = int(d)
+		}
+	default:
+		entry.dir = c.root
+		entry.depth = -1
+		entry.search = true
+		entry.includeIgnored, _ = args["include_ignored"].(bool)
+	}
+
+	if entry.dir != "" {
+		tree, ok := entry.fingerprint()
+		if !ok {
+			return nil
+		}
+		entry.tree = tree
+	}
+	return entry
+}
+
+// fresh reports whether the files behind entry are unchanged. A file whose
+// mtime moved is re-hashed so a touch does not defeat the cache.
+func (c *ToolCache) fresh(entry *toolCacheEntry) bool {
+	if entry.file != "" {
+		stamp, err := statFile(entry.file)
+		if err != nil {
+			return false
+		}
+		if stamp.size != entry.stamp.size {
+			return false
+		}
+		if !stamp.modTime.Equal(entry.stamp.modTime) {
+			sha, err := hashFile(entry.file)
+			if err != nil || sha != entry.stamp.sha {
+				return false
+			}
+			c.mu.Lock()
+			entry.stamp.modTime = stamp.modTime
+			c.mu.Unlock()
+		}
+		return true
+	}
+
+	tree, ok := entry.fingerprint()
+	return ok && tree == entry.tree
+}
+
+func (e *toolCacheEntry) fingerprint() (string, bool) {
+	if !e.search {
+		return treeFingerprint(e.dir, e.depth, nil)
+	}
+	tree, ok := treeFingerprint(e.dir, e.depth, searchSkipper(e.dir, e.includeIgnored))
+	// The exclude file is an ignore file too, though it lives under .git.
+	if info, err := os.Stat(filepath.Join(e.dir, ".git", "info", "exclude")); ok && err == nil {
+		tree += fmt.Sprintf(":%d:%d", info.Size(), info.ModTime().UnixNano())
+	}
+	return tree, ok
+}
+
+// searchSkipper skips the paths search_in_files does not read, so the
+// fingerprint neither stats dependency trees nor changes when they do.
+func searchSkipper(root string, includeIgnored bool) func(rel string, d fs.DirEntry) bool {
+	matchers := map[string]*ignore.Matcher{".": ignore.Load(root)}
+	return func(rel string, d fs.DirEntry) bool {
+		if d.IsDir() && d.Name() == ".git" {
+			return true
+		}
+		if includeIgnored {
+			return false
+		}
+		rel = filepath.ToSlash(rel)
+		m := matchers[path.Dir(rel)]
+		if d.IsDir() {
+			if ignore.DefaultDirs[d.Name()] || m.Match(rel, true) {
+				return true
+			}
+			matchers[rel] = m.Child(root, rel)
+			return false
+		}
+		return m.Match(rel, false)
+	}
+}
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// treeFingerprint hashes the names, sizes and mtimes under dir down to depth
+// levels (-1 for no limit), leaving out entries skip reports. It reports
+// false for trees too large to stat on every call.
+func treeFingerprint(dir string, depth int, skip func(rel string, d fs.DirEntry) bool) (string, bool) {
+	h := sha256.New()
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if skip != nil && rel != "." && skip(rel, d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if depth >= 0 && rel != "." && strings.Count(rel, string(os.PathSeparator)) >= depth {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		count++
+		if count > maxCacheTreeFiles {
+			return filepath.SkipAll
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00%d\n", rel, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil || count > maxCacheTreeFiles {
+		return "", false
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
@@ -0,0 +1,158 @@
+package agent_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+func TestToolCache_SearchFingerprint(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   map[string]interface{}
+		change map[string]string
+		cached bool
+	}{
+		{name: "nothing changed", args: map[string]interface{}{"query": "x"}, cached: true},
+		{name: "source file changed", args: map[string]interface{}{"query": "x"}, change: map[string]string{"src/a.go": "package a // edit\n"}, cached: false},
+		{name: "new source file", args: map[string]interface{}{"query": "x"}, change: map[string]string{"src/b.go": "package a\n"}, cached: false},
+		{name: "git internals changed", args: map[string]interface{}{"query": "x"}, change: map[string]string{".git/index": "new index"}, cached: true},
+		{name: "node_modules changed", args: map[string]interface{}{"query": "x"}, change: map[string]string{"node_modules/dep/index.js": "changed"}, cached: true},
+		{name: "gitignored file changed", args: map[string]interface{}{"query": "x"}, change: map[string]string{"build/out.txt": "changed"}, cached: true},
+		{name: "gitignore changed", args: map[string]interface{}{"query": "x"}, change: map[string]string{".gitignore": "dist/\n"}, cached: false},
+		{name: "include_ignored sees node_modules", args: map[string]interface{}{"query": "x", "include_ignored": true}, change: map[string]string{"node_modules/dep/index.js": "changed"}, cached: false},
+		{name: "include_ignored still skips .git", args: map[string]interface{}{"query": "x", "include_ignored": true}, change: map[string]string{".git/index": "new index"}, cached: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, map[string]string{
+				"src/a.go":                  "package a\n",
+				".gitignore":                "build/\n",
+				"build/out.txt":             "generated",
+				".git/index":                "index",
+				"node_modules/dep/index.js": "module.exports = 1",
+			})
+
+			cache := agent.NewToolCache(root)
+			runs := 0
+			exec := func() tools.ToolResult {
+				runs++
+				return tools.NewSuccessResult(map[string]interface{}{"matches": []interface{}{}})
+			}
+
+			cache.Execute("search_in_files", tt.args, exec)
+			if len(tt.change) > 0 {
+				// Make sure a rewrite of the same size still moves the mtime.
+				time.Sleep(10 * time.Millisecond)
+				writeFiles(t, root, tt.change)
+			}
+			result := cache.Execute("search_in_files", tt.args, exec)
+
+			if cached := runs == 1; cached != tt.cached {
+				t.Errorf("cached = %v, want %v", cached, tt.cached)
+			}
+			if data, _ := result.Data.(map[string]interface{}); tt.cached && data["cached"] != true {
+				t.Errorf("expected a cache note, got %+v", result.Data)
+			}
+		})
+	}
+}
+
+func TestToolCache_ReadFile(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.txt": "hello\n"})
+
+	cache := agent.NewToolCache(root)
+	runs := 0
+	exec := func() tools.ToolResult {
+		runs++
+		return tools.NewSuccessResult(map[string]interface{}{"content": "hello\n"})
+	}
+
+	steps := []struct {
+		name   string
+		before func()
+		tool   string
+		args   map[string]interface{}
+		runs   int
+	}{
+		{name: "first read runs", tool: "read_file", args: map[string]interface{}{"path": "a.txt"}, runs: 1},
+		{name: "same path in another form is served from cache", tool: "read_file", args: map[string]interface{}{"path": "./a.txt"}, runs: 1},
+		{name: "a third read runs again after the note", tool: "read_file", args: map[string]interface{}{"path": "a.txt"}, runs: 2},
+		{
+			name: "touch without a content change stays cached",
+			before: func() {
+				later := time.Now().Add(time.Minute)
+				os.Chtimes(filepath.Join(root, "a.txt"), later, later)
+			},
+			tool: "read_file", args: map[string]interface{}{"path": "a.txt"}, runs: 2,
+		},
+		{
+			name:   "content change runs again",
+			before: func() { writeFiles(t, root, map[string]string{"a.txt": "changed\n"}) },
+			tool:   "read_file", args: map[string]interface{}{"path": "a.txt"}, runs: 3,
+		},
+		{name: "write tool clears the cache", tool: "write_file", args: map[string]interface{}{"path": "b.txt"}, runs: 4},
+		{name: "read after a write runs", tool: "read_file", args: map[string]interface{}{"path": "a.txt"}, runs: 5},
+	}
+
+	for _, step := range steps {
+		if step.before != nil {
+			step.before()
+		}
+		cache.Execute(step.tool, step.args, exec)
+		if runs != step.runs {
+			t.Fatalf("%s: tool ran %d times, want %d", step.name, runs, step.runs)
+		}
+	}
+}
+
+func TestChatToolCache(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.txt": "hello\n"})
+	chatID := uuid.New()
+	defer agent.ForgetChatToolCache(chatID)
+
+	runs := 0
+	exec := func() tools.ToolResult {
+		runs++
+		return tools.NewSuccessResult(map[string]interface{}{"content": "hello\n"})
+	}
+	read := map[string]interface{}{"path": "a.txt"}
+
+	first := agent.NewSession(uuid.Nil, uuid.Nil, chatID, agent.AgentConfig{ProjectRoot: root})
+	first.ToolCache().Execute("read_file", read, exec)
+
+	second := agent.NewSession(uuid.Nil, uuid.Nil, chatID, agent.AgentConfig{ProjectRoot: root})
+	result := second.ToolCache().Execute("read_file", read, exec)
+	data, _ := result.Data.(map[string]interface{})
+	if runs != 1 || data["cached"] != true {
+		t.Fatalf("second run: tool ran %d times, result %v; want the first run's result noted", runs, result.Data)
+	}
+	if note, _ := data["note"].(string); !strings.Contains(note, "earlier run") {
+		t.Errorf("note = %q, want it to point at the earlier run", note)
+	}
+
+	second.ToolCache().Execute("write_file", map[string]interface{}{"path": "b.txt"}, exec)
+	third := agent.NewSession(uuid.Nil, uuid.Nil, chatID, agent.AgentConfig{ProjectRoot: root})
+	third.ToolCache().Execute("read_file", read, exec)
+	if runs != 3 {
+		t.Errorf("read after a write in another run: tool ran %d times, want 3", runs)
+	}
+
+	otherID := uuid.New()
+	defer agent.ForgetChatToolCache(otherID)
+	other := agent.NewSession(uuid.Nil, uuid.Nil, otherID, agent.AgentConfig{ProjectRoot: root})
+	other.ToolCache().Execute("read_file", read, exec)
+	if runs != 4 {
+		t.Errorf("read in another chat: tool ran %d times, want 4", runs)
+	}
+}
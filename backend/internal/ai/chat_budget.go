@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/db"
+	"github.com/webide/ide/backend/internal/models"
+	"github.com/webide/ide/backend/internal/projects"
+)
+
+// loadChatBudget returns the per-chat budget override, or nil when the chat
+// uses the project defaults.
+func loadChatBudget(ctx context.Context, chatID uuid.UUID) *agent.BudgetConfig {
+	var row struct {
+		BudgetJSON string `db:"budget_json"`
+	}
+	if err := db.Get(ctx, &row, "SELECT budget_json FROM chats WHERE id = $1", chatID.String()); err != nil || row.BudgetJSON == "" {
+		return nil
+	}
+
+	var cfg agent.BudgetConfig
+	if err := json.Unmarshal([]byte(row.BudgetJSON), &cfg); err != nil {
+		log.Printf("[Budget] Invalid budget for chat %s: %v", chatID, err)
+		return nil
+	}
+	return &cfg
+}
+
+func HandleGetChatBudget(c *fiber.Ctx) error {
+	ctx := c.Context()
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+	chatID, err := uuid.Parse(c.Params("chatId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid chat_id"})
+	}
+
+	var chat models.Chat
+	if err := db.Get(ctx, &chat, "SELECT id, project_id, title, status, created_at, updated_at FROM chats WHERE id = $1", chatID.String()); err != nil || chat.ProjectID != projectID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "chat not found"})
+	}
+
+	project, err := projects.GetProject(projectID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	}
+
+	defaults := agent.LoadProjectConfig(project.RootPath).Budget
+	override := loadChatBudget(ctx, chatID)
+	return c.JSON(fiber.Map{
+		"project":   defaults,
+		"chat":      override,
+		"effective": defaults.Merge(override),
+	})
+}
+
+func HandleUpdateChatBudget(c *fiber.Ctx) error {
+	ctx := c.Context()
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+	chatID, err := uuid.Parse(c.Params("chatId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid chat_id"})
+	}
+
+	var chat models.Chat
+	if err := db.Get(ctx, &chat, "SELECT id, project_id, title, status, created_at, updated_at FROM chats WHERE id = $1", chatID.String()); err != nil || chat.ProjectID != projectID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "chat not found"})
+	}
+
+	var req agent.BudgetConfig
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if req.MaxTokens < 0 || req.MaxWallTimeMs < 0 || req.MaxCostUSD < 0 || req.MaxCommands < 0 ||
+		req.WarnAt < 0 || req.WarnAt >= 1 || req.InputPricePerMTok < 0 || req.OutputPricePerMTok < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "budget values must be positive and warn_at below 1"})
+	}
+
+	raw := ""
+	if req != (agent.BudgetConfig{}) {
+		data, _ := json.Marshal(req)
+		raw = string(data)
+	}
+
+	if _, err := db.Exec(ctx, "UPDATE chats SET budget_json = $1 WHERE id = $2", raw, chatID.String()); err != nil {
+		log.Printf("[HandleUpdateChatBudget] Failed to update: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update budget"})
+	}
+
+	return c.JSON(req)
+}
@@ -0,0 +1,501 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/ai/redact"
+	"github.com/webide/ide/backend/internal/db"
+	"github.com/webide/ide/backend/internal/models"
+	"github.com/webide/ide/backend/internal/projects"
+)
+
+// maxExportBlockBytes caps tool arguments and results in the Markdown and
+// HTML renderings. The JSON export always carries the full values.
+const maxExportBlockBytes = 16 * 1024
+
+type ChatTranscript struct {
+	Chat       models.Chat            `json:"chat"`
+	ExportedAt time.Time              `json:"exported_at"`
+	Thinking   bool                   `json:"thinking"`
+	Entries    []TranscriptEntry      `json:"entries"`
+	Changesets []models.ChatChangeSet `json:"changesets"`
+}
+
+type TranscriptEntry struct {
+	ID        string               `json:"id"`
+	Role      string               `json:"role"`
+	Content   string               `json:"content,omitempty"`
+	Tool      *TranscriptTool      `json:"tool,omitempty"`
+	Hook      *agent.HookResult    `json:"hook,omitempty"`
+	Context   *EditorContext       `json:"context,omitempty"`
+	Review    *agent.ReviewOutcome `json:"review,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+type TranscriptTool struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	OK        *bool                  `json:"ok,omitempty"`
+	Result    interface{}            `json:"result,omitempty"`
+	Error     interface{}            `json:"error,omitempty"`
+	Approval  map[string]interface{} `json:"approval,omitempty"`
+	Feedback  string                 `json:"feedback,omitempty"`
+	Patch     string                 `json:"patch,omitempty"`
+}
+
+func HandleExportChat(c *fiber.Ctx) error {
+	ctx := c.Context()
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+	chatID, err := uuid.Parse(c.Params("chatId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid chat_id"})
+	}
+
+	format := c.Query("format", "md")
+	if format != "md" && format != "json" && format != "html" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "format must be md, json or html"})
+	}
+
+	var chat models.Chat
+	if err := db.Get(ctx, &chat, "SELECT id, project_id, title, status, created_at, updated_at FROM chats WHERE id = $1", chatID.String()); err != nil || chat.ProjectID != projectID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "chat not found"})
+	}
+
+	project, err := projects.GetProject(projectID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	}
+	// The project's redactor also knows its .env values and its entropy
+	// setting, which the pattern rules alone would miss.
+	r := redact.New(project.RootPath, agent.LoadProjectConfig(project.RootPath).Redact)
+
+	transcript, err := BuildChatTranscript(ctx, chat, r, c.QueryBool("thinking"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to build transcript"})
+	}
+
+	var body []byte
+	switch format {
+	case "json":
+		body, err = json.MarshalIndent(transcript, "", "  ")
+	case "html":
+		body, err = transcript.HTML()
+	default:
+		body = []byte(transcript.Markdown())
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to render transcript"})
+	}
+
+	c.Type(format)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="chat-%s.%s"`, chatID.String()[:8], format))
+	return c.Send(body)
+}
+
+// BuildChatTranscript assembles a transcript redacted with r from
+// chat_messages and chat_changesets. Tool calls and results are stored cumulatively per model
+// turn, so both are de-duplicated by tool call ID.
+func BuildChatTranscript(ctx context.Context, chat models.Chat, r *redact.Redactor, includeThinking bool) (*ChatTranscript, error) {
+	rows, err := db.Query(ctx, "SELECT id, role, COALESCE(content, ''), COALESCE(tool_calls_json, ''), COALESCE(tool_results_json, ''), created_at FROM chat_messages WHERE chat_id = $1 ORDER BY created_at ASC", chat.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	t := &ChatTranscript{
+		Chat:       chat,
+		ExportedAt: time.Now(),
+		Thinking:   includeThinking,
+		Entries:    []TranscriptEntry{},
+		Changesets: []models.ChatChangeSet{},
+	}
+	t.Chat.Title = r.String(chat.Title)
+
+	toolsByID := make(map[string]*TranscriptTool)
+	for rows.Next() {
+		var id, role, content, toolCallsJSON, toolResultsJSON string
+		var createdAt time.Time
+		if err := rows.Scan(&id, &role, &content, &toolCallsJSON, &toolResultsJSON, &createdAt); err != nil {
+			continue
+		}
+		entry := TranscriptEntry{ID: id, Role: role, Content: r.String(content), CreatedAt: createdAt}
+
+		switch role {
+		case "user":
+			t.Entries = append(t.Entries, entry)
+
+		case "context":
+			var ec EditorContext
+			if err := json.Unmarshal([]byte(toolResultsJSON), &ec); err == nil {
+				for i := range ec.Items {
+					ec.Items[i].Content = r.String(ec.Items[i].Content)
+				}
+				entry.Context = &ec
+				entry.Content = ec.Summary()
+			}
+			t.Entries = append(t.Entries, entry)
+
+		case "thinking":
+			if includeThinking && strings.TrimSpace(content) != "" {
+				t.Entries = append(t.Entries, entry)
+			}
+
+		case "assistant":
+			if strings.TrimSpace(content) != "" {
+				t.Entries = append(t.Entries, entry)
+			}
+			for _, call := range parseStoredToolCalls(toolCallsJSON, r) {
+				if call.ID == "" || toolsByID[call.ID] != nil {
+					continue
+				}
+				toolsByID[call.ID] = call
+				t.Entries = append(t.Entries, TranscriptEntry{
+					ID:        id + ":" + call.ID,
+					Role:      "tool",
+					Tool:      call,
+					CreatedAt: createdAt,
+				})
+			}
+
+		case "tool":
+			var results []map[string]interface{}
+			if err := json.Unmarshal([]byte(toolResultsJSON), &results); err != nil {
+				continue
+			}
+			for _, res := range results {
+				callID, _ := res["id"].(string)
+				if call := toolsByID[callID]; call != nil {
+					call.applyResult(res, r)
+				}
+			}
+
+		case "review":
+			var outcome agent.ReviewOutcome
+			if err := json.Unmarshal([]byte(toolResultsJSON), &outcome); err == nil {
+				outcome.Reason = r.String(outcome.Reason)
+				entry.Review = &outcome
+				entry.Content = reviewSummary(outcome)
+			}
+			t.Entries = append(t.Entries, entry)
+
+		case "hook":
+			var hook agent.HookResult
+			if err := json.Unmarshal([]byte(toolResultsJSON), &hook); err == nil {
+				hook.Output = r.String(hook.Output)
+				hook.Reason = r.String(hook.Reason)
+				hook.Feedback = r.String(hook.Feedback)
+				entry.Hook = &hook
+			}
+			t.Entries = append(t.Entries, entry)
+		}
+	}
+
+	csRows, err := db.Query(ctx, "SELECT id, chat_id, title, COALESCE(diff, ''), status, COALESCE(summary_text, ''), created_at FROM chat_changesets WHERE chat_id = $1 ORDER BY created_at ASC", chat.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer csRows.Close()
+	for csRows.Next() {
+		var cs models.ChatChangeSet
+		if err := csRows.Scan(&cs.ID, &cs.ChatID, &cs.Title, &cs.Diff, &cs.Status, &cs.SummaryText, &cs.CreatedAt); err != nil {
+			continue
+		}
+		cs.Diff = r.String(cs.Diff)
+		cs.SummaryText = r.String(cs.SummaryText)
+		t.Changesets = append(t.Changesets, cs)
+	}
+
+	return t, nil
+}
+
+// parseStoredToolCalls accepts both the provider format the chat loop stores
+// ({id, function: {name, arguments}}) and the flattened frontend format.
+func parseStoredToolCalls(raw string, r *redact.Redactor) []*TranscriptTool {
+	if raw == "" {
+		return nil
+	}
+	var stored []struct {
+		ID        string                 `json:"id"`
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+		Function  *struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal([]byte(raw), &stored); err != nil {
+		return nil
+	}
+
+	calls := make([]*TranscriptTool, 0, len(stored))
+	for _, s := range stored {
+		call := &TranscriptTool{ID: s.ID, Name: s.Name, Arguments: s.Arguments}
+		if s.Function != nil {
+			call.Name = s.Function.Name
+			json.Unmarshal([]byte(s.Function.Arguments), &call.Arguments)
+		}
+		if args, ok := r.Value(call.Arguments).(map[string]interface{}); ok {
+			call.Arguments = args
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+func (t *TranscriptTool) applyResult(res map[string]interface{}, r *redact.Redactor) {
+	if ok, isBool := res["ok"].(bool); isBool {
+		t.OK = &ok
+	}
+	t.Result = r.Value(res["result"])
+	t.Error = r.Value(res["error"])
+	if approval, ok := r.Value(res["approval"]).(map[string]interface{}); ok {
+		t.Approval = approval
+	}
+	if feedback, ok := res["feedback"].(string); ok {
+		t.Feedback = r.String(feedback)
+	}
+
+	if t.Name == "apply_patch" && t.OK != nil && *t.OK {
+		dryRun, _ := t.Arguments["dry_run"].(bool)
+		patch, _ := t.Arguments["patch"].(string)
+		if !dryRun && patch != "" {
+			t.Patch = patch
+		}
+	}
+	if (t.Name == "write_file" || t.Name == "str_replace") && t.OK != nil && *t.OK {
+		if result, ok := t.Result.(map[string]interface{}); ok {
+			t.Patch, _ = result["diff"].(string)
+		}
+	}
+}
+
+func (t *TranscriptTool) Status() string {
+	switch {
+	case t.OK == nil:
+		return "no result"
+	case *t.OK:
+		return "ok"
+	}
+	return "error"
+}
+
+func (t *TranscriptTool) ApprovalText() string {
+	if t.Approval == nil {
+		return ""
+	}
+	decision, _ := t.Approval["decision"].(string)
+	by, _ := t.Approval["by"].(string)
+	reason, _ := t.Approval["reason"].(string)
+	text := decision
+	if by != "" {
+		text += " by " + by
+	}
+	if reason != "" {
+		text += ": " + reason
+	}
+	return text
+}
+
+func (t *TranscriptTool) ErrorText() string {
+	if t.Error == nil {
+		return ""
+	}
+	if m, ok := t.Error.(map[string]interface{}); ok {
+		code, _ := m["code"].(string)
+		msg, _ := m["message"].(string)
+		if code != "" || msg != "" {
+			return strings.TrimSpace(code + " " + msg)
+		}
+	}
+	return exportJSON(t.Error)
+}
+
+func exportJSON(v interface{}) string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	s := string(data)
+	if len(s) > maxExportBlockBytes {
+		s = s[:maxExportBlockBytes] + fmt.Sprintf("\n... (%d bytes truncated)", len(s)-maxExportBlockBytes)
+	}
+	return s
+}
+
+func (t *ChatTranscript) Markdown() string {
+	var b strings.Builder
+
+	title := t.Chat.Title
+	if title == "" {
+		title = "Untitled chat"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- Chat: `%s`\n", t.Chat.ID)
+	fmt.Fprintf(&b, "- Started: %s\n", t.Chat.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Exported: %s\n", t.ExportedAt.Format(time.RFC3339))
+
+	for _, e := range t.Entries {
+		switch e.Role {
+		case "user":
+			fmt.Fprintf(&b, "\n## User\n\n%s\n", e.Content)
+		case "assistant":
+			fmt.Fprintf(&b, "\n## Assistant\n\n%s\n", e.Content)
+		case "context":
+			fmt.Fprintf(&b, "\n<details><summary>Editor context: %s</summary>\n", e.Content)
+			if e.Context != nil {
+				for _, item := range e.Context.Items {
+					fmt.Fprintf(&b, "\n**%s**\n\n```\n%s\n```\n", item.Label, item.Content)
+				}
+			}
+			b.WriteString("\n</details>\n")
+		case "review":
+			fmt.Fprintf(&b, "\n**Review**: %s\n", e.Content)
+		case "thinking":
+			fmt.Fprintf(&b, "\n<details><summary>Thinking</summary>\n\n%s\n\n</details>\n", e.Content)
+		case "hook":
+			b.WriteString("\n**Hook**")
+			if e.Hook != nil {
+				fmt.Fprintf(&b, " `%s` on %s", e.Hook.Hook, e.Hook.Event)
+				if e.Hook.Decision != "" {
+					fmt.Fprintf(&b, ": %s", e.Hook.Decision)
+				}
+			}
+			b.WriteString("\n")
+			if e.Content != "" {
+				fmt.Fprintf(&b, "\n```\n%s\n```\n", strings.TrimRight(e.Content, "\n"))
+			}
+		case "tool":
+			writeMarkdownTool(&b, e.Tool)
+		}
+	}
+
+	if len(t.Changesets) > 0 {
+		b.WriteString("\n## Changes\n")
+		for _, cs := range t.Changesets {
+			fmt.Fprintf(&b, "\n### %s (%s)\n", cs.Title, cs.Status)
+			if cs.SummaryText != "" {
+				fmt.Fprintf(&b, "\n%s\n", cs.SummaryText)
+			}
+			if cs.Diff != "" {
+				fmt.Fprintf(&b, "\n```diff\n%s\n```\n", strings.TrimRight(cs.Diff, "\n"))
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func writeMarkdownTool(b *strings.Builder, t *TranscriptTool) {
+	fmt.Fprintf(b, "\n### Tool: `%s` (%s)\n", t.Name, t.Status())
+	if approval := t.ApprovalText(); approval != "" {
+		fmt.Fprintf(b, "\nApproval: %s\n", approval)
+	}
+
+	if t.Patch != "" {
+		fmt.Fprintf(b, "\n```diff\n%s\n```\n", strings.TrimRight(t.Patch, "\n"))
+	} else if len(t.Arguments) > 0 {
+		fmt.Fprintf(b, "\nArguments:\n\n```json\n%s\n```\n", exportJSON(t.Arguments))
+	}
+
+	if errText := t.ErrorText(); errText != "" {
+		fmt.Fprintf(b, "\nError: %s\n", errText)
+	} else if t.Result != nil {
+		fmt.Fprintf(b, "\nResult:\n\n```json\n%s\n```\n", exportJSON(t.Result))
+	}
+	if t.Feedback != "" {
+		fmt.Fprintf(b, "\nHook feedback: %s\n", t.Feedback)
+	}
+}
+
+var transcriptHTML = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"json": exportJSON,
+	"time": func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{if .Chat.Title}}{{.Chat.Title}}{{else}}Untitled chat{{end}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; max-width: 960px; margin: 2rem auto; padding: 0 1rem; color: #1f2328; }
+pre { background: #f6f8fa; padding: .75rem; overflow-x: auto; border-radius: 6px; font-size: 12px; }
+.entry { border-left: 3px solid #d0d7de; padding: .25rem 1rem; margin: 1rem 0; }
+.user { border-color: #0969da; } .assistant { border-color: #8250df; } .tool { border-color: #9a6700; } .hook { border-color: #57606a; }
+.role { font-weight: 600; font-size: 13px; text-transform: uppercase; color: #57606a; }
+.content { white-space: pre-wrap; }
+.ok { color: #1a7f37; } .error { color: #cf222e; }
+.meta { color: #57606a; font-size: 13px; }
+</style>
+</head>
+<body>
+<h1>{{if .Chat.Title}}{{.Chat.Title}}{{else}}Untitled chat{{end}}</h1>
+<p class="meta">Chat {{.Chat.ID}} &middot; started {{time .Chat.CreatedAt}} &middot; exported {{time .ExportedAt}}</p>
+{{range .Entries}}
+{{if eq .Role "tool"}}{{with .Tool}}
+<div class="entry tool">
+<div class="role">Tool <code>{{.Name}}</code> <span class="{{if eq .Status "ok"}}ok{{else}}error{{end}}">{{.Status}}</span></div>
+{{with .ApprovalText}}<p class="meta">Approval: {{.}}</p>{{end}}
+{{if .Patch}}<pre>{{.Patch}}</pre>{{else if .Arguments}}<details><summary>Arguments</summary><pre>{{json .Arguments}}</pre></details>{{end}}
+{{with .ErrorText}}<p class="error">{{.}}</p>{{else}}{{if .Result}}<details><summary>Result</summary><pre>{{json .Result}}</pre></details>{{end}}{{end}}
+{{with .Feedback}}<p class="meta">Hook feedback: {{.}}</p>{{end}}
+</div>
+{{end}}{{else if eq .Role "thinking"}}
+<details class="entry"><summary class="role">Thinking</summary><div class="content">{{.Content}}</div></details>
+{{else if eq .Role "context"}}
+<details class="entry"><summary class="role">Editor context: {{.Content}}</summary>
+{{with .Context}}{{range .Items}}<p class="meta">{{.Label}}</p><pre>{{.Content}}</pre>{{end}}{{end}}
+</details>
+{{else if eq .Role "hook"}}
+<div class="entry hook">
+<div class="role">Hook{{with .Hook}} <code>{{.Hook}}</code> on {{.Event}}{{with .Decision}}: {{.}}{{end}}{{end}}</div>
+{{with .Content}}<pre>{{.}}</pre>{{end}}
+</div>
+{{else}}
+<div class="entry {{.Role}}">
+<div class="role">{{.Role}}</div>
+<div class="content">{{.Content}}</div>
+</div>
+{{end}}
+{{end}}
+{{if .Changesets}}
+<h2>Changes</h2>
+{{range .Changesets}}
+<h3>{{.Title}} <span class="meta">({{.Status}})</span></h3>
+{{with .SummaryText}}<p>{{.}}</p>{{end}}
+{{with .Diff}}<pre>{{.}}</pre>{{end}}
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+func (t *ChatTranscript) HTML() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := transcriptHTML.Execute(&buf, t); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func reviewSummary(o agent.ReviewOutcome) string {
+	summary := fmt.Sprintf("%s, %d accepted, %d rejected", o.Status(), len(o.Accepted), len(o.Rejected))
+	if len(o.Rejected) > 0 {
+		summary += " (" + strings.Join(o.Rejected, ", ") + ")"
+	}
+	if o.Reason != "" {
+		summary += ": " + o.Reason
+	}
+	return summary
+}
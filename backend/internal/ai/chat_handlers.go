@@ -7,8 +7,10 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
 	"github.com/webide/ide/backend/internal/db"
 	"github.com/webide/ide/backend/internal/models"
+	"github.com/webide/ide/backend/internal/projects"
 )
 
 func RegisterChatRoutes(router fiber.Router) {
@@ -28,6 +30,10 @@ func RegisterChatRoutes(router fiber.Router) {
 	chat.Put("/title", HandleUpdateChatTitle)
 	chat.Post("/generate-title", HandleGenerateTitle)
 	chat.Delete("", HandleDeleteChat)
+	chat.Get("/system-prompt", HandleGetChatSystemPrompt)
+	chat.Get("/export", HandleExportChat)
+	chat.Get("/budget", HandleGetChatBudget)
+	chat.Put("/budget", HandleUpdateChatBudget)
 
 	chatMessages := chat.Group("/messages")
 	chatMessages.Get("", HandleListChatMessages)
@@ -36,6 +42,38 @@ func RegisterChatRoutes(router fiber.Router) {
 	chatChangesets := chat.Group("/changesets")
 	chatChangesets.Get("", HandleListChatChangeSets)
 
+	router.Get("/projects/:id/ai/checks", HandleListProjectChecks)
+	router.Get("/projects/:id/ai/outputs/:handle", HandleGetToolOutput)
+	router.Get("/projects/:id/ai/skills", HandleListSkills)
+	router.Get("/projects/:id/ai/skills/:name", HandleGetSkill)
+
+	code := router.Group("/projects/:id/ai/code")
+	code.Get("/symbols", HandleCodeSymbols)
+	code.Get("/definition", HandleCodeDefinition)
+	code.Get("/references", HandleCodeReferences)
+	code.Get("/package", HandleCodePackage)
+	code.Get("/files", HandleFindFiles)
+
+	memories := router.Group("/projects/:id/ai/memories")
+	memories.Get("", HandleListMemories)
+	memories.Post("", HandleCreateMemory)
+	memories.Put("/:memoryId", HandleUpdateMemory)
+	memories.Delete("/:memoryId", HandleDeleteMemory)
+
+	schedules := router.Group("/projects/:id/ai/schedules")
+	schedules.Get("", HandleListSchedules)
+	schedules.Post("", HandleCreateSchedule)
+	schedules.Get("/:scheduleId", HandleGetSchedule)
+	schedules.Put("/:scheduleId", HandleUpdateSchedule)
+	schedules.Delete("/:scheduleId", HandleDeleteSchedule)
+	schedules.Post("/:scheduleId/run", HandleRunSchedule)
+	schedules.Get("/:scheduleId/runs", HandleListScheduleRuns)
+
+	runs := router.Group("/projects/:id/ai/runs")
+	runs.Get("", HandleListChatRuns)
+	runs.Get("/:runId", HandleGetChatRun)
+	runs.Post("/:runId/cancel", HandleCancelChatRun)
+
 	log.Println("RegisterChatRoutes: all routes registered")
 }
 
@@ -184,6 +222,7 @@ func HandleDeleteChat(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete chat"})
 	}
+	agent.ForgetChatToolCache(chatID)
 
 	return c.SendStatus(fiber.StatusOK)
 }
@@ -298,3 +337,75 @@ func HandleListChatChangeSets(c *fiber.Ctx) error {
 
 	return c.JSON(changesets)
 }
+
+func HandleGetChatSystemPrompt(c *fiber.Ctx) error {
+	ctx := c.Context()
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+	chatID, err := uuid.Parse(c.Params("chatId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid chat_id"})
+	}
+
+	var chat models.Chat
+	if err := db.Get(ctx, &chat, "SELECT id, project_id, title, status, created_at, updated_at FROM chats WHERE id = $1", chatID.String()); err != nil || chat.ProjectID != projectID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "chat not found"})
+	}
+
+	project, err := projects.GetProject(projectID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	}
+
+	// A running agent's shadow copy is gone once the run ends, so only an
+	// active run is asked for its prompt.
+	if run := ChatRuns.ForChat(chatID); run != nil && run.Active() {
+		if prompt, ok := run.SystemPrompt(); ok {
+			return c.JSON(prompt)
+		}
+	}
+
+	// Otherwise build what the next run starts from: the history as execute
+	// loads it, with editor context folded into its user message. A batch
+	// review shadow is a fresh copy of the project, so the project root gives
+	// the same facts and instructions.
+	history, err := loadChatMessages(ctx, chatID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load chat messages"})
+	}
+	var lastUser string
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == "user" {
+			lastUser = history[i].Content
+			break
+		}
+	}
+	dirs := chatWorkDirs(ctx, chatID, project.RootPath)
+	memories := agent.LoadMemories(ctx, projectID, project.RootPath, lastUser)
+
+	return c.JSON(agent.BuildSystemPrompt("", project.RootPath, dirs, memories))
+}
+
+func HandleListProjectChecks(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+
+	project, err := projects.GetProject(projectID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	}
+
+	cfg := agent.LoadProjectConfig(project.RootPath).Checks
+	checks := agent.ResolveChecks(project.RootPath, cfg)
+	if checks == nil {
+		checks = []agent.CheckCommand{}
+	}
+	return c.JSON(fiber.Map{
+		"checks": checks,
+		"config": cfg,
+	})
+}
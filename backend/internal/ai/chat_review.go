@@ -0,0 +1,188 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/db"
+	"github.com/webide/ide/backend/internal/models"
+)
+
+type chatReview struct {
+	request *agent.ReviewRequest
+	ch      chan agent.ReviewDecision
+}
+
+func (r *ChatRun) batchReviewEnabled(cfg agent.ProjectConfig) bool {
+	if r.batchReview != nil {
+		return *r.batchReview
+	}
+	return cfg.Review.Mode == agent.ReviewModeBatch
+}
+
+// reviewShadow asks the user to review everything the run changed in the
+// shadow copy, applies the accepted files to the real tree and resets the
+// rejected ones. It returns nil when there was nothing to review or the run
+// was cancelled while waiting.
+func (r *ChatRun) reviewShadow(shadow *agent.Shadow) *agent.ReviewOutcome {
+	changes, err := shadow.Changes()
+	if err != nil {
+		log.Printf("[RUN] Failed to collect shadow changes: %v", err)
+		return nil
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	req := &agent.ReviewRequest{
+		ID:    uuid.New().String(),
+		Files: changes,
+		Diff:  agent.CombinedDiff(changes),
+	}
+
+	changeset := &models.ChatChangeSet{
+		ID:        uuid.New(),
+		ChatID:    r.ChatID,
+		Title:     fmt.Sprintf("Review of %d file(s)", len(changes)),
+		Diff:      req.Diff,
+		Status:    "pending",
+		CreatedAt: time.Now(),
+	}
+	if err := db.Insert(r.ctx, "chat_changesets", changeset); err != nil {
+		log.Printf("[RUN] Failed to save review changeset: %v", err)
+	}
+
+	decision, ok := r.awaitReview(req)
+	if !ok {
+		return nil
+	}
+
+	accepted, rejected := decision.Split(changes)
+	outcome := &agent.ReviewOutcome{
+		ID:       req.ID,
+		Accepted: accepted,
+		Rejected: rejected,
+		Reason:   decision.Reason,
+	}
+	conflicts, err := shadow.Apply(accepted)
+	if err != nil {
+		log.Printf("[RUN] Failed to apply reviewed changes: %v", err)
+		outcome.Error = err.Error()
+	}
+	if len(conflicts) > 0 {
+		outcome.Accepted = without(accepted, conflicts)
+		outcome.Conflicts = conflicts
+		rejected = append(rejected, conflicts...)
+	}
+	if err := shadow.Discard(rejected); err != nil {
+		log.Printf("[RUN] Failed to discard rejected changes: %v", err)
+		outcome.Error = err.Error()
+	}
+	log.Printf("[RUN] Review %s: %d accepted, %d rejected, %d conflicts", req.ID, len(outcome.Accepted), len(outcome.Rejected), len(outcome.Conflicts))
+
+	changeset.Status = outcome.Status()
+	changeset.SummaryText = fmt.Sprintf("%d accepted, %d rejected", len(outcome.Accepted), len(outcome.Rejected))
+	if len(outcome.Conflicts) > 0 {
+		changeset.SummaryText += fmt.Sprintf(", %d not applied because the file changed", len(outcome.Conflicts))
+	}
+	if decision.Reason != "" {
+		changeset.SummaryText += ": " + decision.Reason
+	}
+	if _, err := db.Exec(r.ctx, "UPDATE chat_changesets SET status = $1, summary_text = $2 WHERE id = $3",
+		changeset.Status, changeset.SummaryText, changeset.ID.String()); err != nil {
+		log.Printf("[RUN] Failed to update review changeset: %v", err)
+	}
+
+	// The feedback is stored as the message content so later runs see it too.
+	outcomeJSON, _ := json.Marshal(outcome)
+	msg := &models.ChatMessage{
+		ID:              uuid.New(),
+		ChatID:          r.ChatID,
+		Role:            "review",
+		Content:         outcome.Feedback(),
+		ToolResultsJSON: string(outcomeJSON),
+		CreatedAt:       time.Now(),
+	}
+	if err := db.Insert(r.ctx, "chat_messages", msg); err != nil {
+		log.Printf("[RUN] Failed to save review outcome: %v", err)
+	}
+
+	r.emit(ChatWSMessage{Type: agent.EventReviewResolved, Payload: outcome})
+	return outcome
+}
+
+// awaitReview pauses the run until a client answers the review or the run is
+// cancelled.
+func (r *ChatRun) awaitReview(req *agent.ReviewRequest) (agent.ReviewDecision, bool) {
+	ch := make(chan agent.ReviewDecision, 1)
+
+	r.mu.Lock()
+	r.review = &chatReview{request: req, ch: ch}
+	r.mu.Unlock()
+
+	r.setStatus(RunStatusWaitingApproval)
+	r.emit(ChatWSMessage{Type: agent.EventReviewRequired, Payload: req})
+	BroadcastJobUpdate(r.ProjectID.String(), r.ID.String(), RunStatusWaitingApproval, "", map[string]interface{}{
+		"type":    ChatRunJobType,
+		"chat_id": r.ChatID,
+		"review":  req.ID,
+	})
+
+	var decision agent.ReviewDecision
+	ok := true
+	select {
+	case decision = <-ch:
+	case <-r.ctx.Done():
+		ok = false
+	}
+
+	r.mu.Lock()
+	r.review = nil
+	r.mu.Unlock()
+
+	if r.ctx.Err() == nil {
+		r.setStatus(RunStatusRunning)
+	}
+	return decision, ok
+}
+
+func (r *ChatRun) ResolveReview(id string, decision agent.ReviewDecision) bool {
+	r.mu.Lock()
+	review := r.review
+	r.mu.Unlock()
+	if review == nil || review.request.ID != id {
+		return false
+	}
+	select {
+	case review.ch <- decision:
+		return true
+	default:
+		return false
+	}
+}
+
+// without returns paths minus the ones in drop.
+func without(paths, drop []string) []string {
+	skip := make(map[string]bool, len(drop))
+	for _, p := range drop {
+		skip[p] = true
+	}
+	kept := []string{}
+	for _, p := range paths {
+		if !skip[p] {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+func (r *ChatRun) pendingReview() *agent.ReviewRequest {
+	if r.review == nil {
+		return nil
+	}
+	return r.review.request
+}
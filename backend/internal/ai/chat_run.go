@@ -0,0 +1,835 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/ai/provider"
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/config"
+	"github.com/webide/ide/backend/internal/db"
+	"github.com/webide/ide/backend/internal/models"
+	"github.com/webide/ide/backend/internal/projects"
+)
+
+const (
+	ChatRunJobType = "agent_run"
+
+	RunStatusRunning         = "running"
+	RunStatusWaitingApproval = "waiting_approval"
+	RunStatusSucceeded       = "succeeded"
+	RunStatusFailed          = "failed"
+	RunStatusCancelled       = "cancelled"
+
+	maxRunEvents     = 20000
+	finishedRunTTL   = 10 * time.Minute
+	chatRunCancelMsg = "cancelled by user"
+)
+
+var ErrChatRunActive = errors.New("chat already has an active run")
+
+type chatRunEvent struct {
+	seq  int64
+	data []byte
+}
+
+type chatApproval struct {
+	approved bool
+	reason   string
+}
+
+// QueuedMessage is a user message sent while a run is in progress. It is
+// delivered to the model as a user turn at the next step boundary.
+type QueuedMessage struct {
+	ID       uuid.UUID      `json:"id"`
+	RunID    uuid.UUID      `json:"run_id"`
+	Content  string         `json:"content"`
+	Context  *EditorContext `json:"context,omitempty"`
+	QueuedAt time.Time      `json:"queued_at"`
+}
+
+// ChatRun is an agent run for a chat. It lives on the server independently of
+// any websocket; clients attach to it and replay the events they missed.
+type ChatRun struct {
+	ID        uuid.UUID
+	ChatID    uuid.UUID
+	ProjectID uuid.UUID
+	UserID    uuid.UUID
+	StartedAt time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	status      string
+	errText     string
+	finishedAt  *time.Time
+	steps       int
+	seq         int64
+	events      []chatRunEvent
+	subscribers map[*ChatWSClient]struct{}
+	approvals   map[string]chan chatApproval
+	pending     *agent.ToolApprovalPayload
+	session     *agent.AgentSession
+	checks      *agent.ChecksSummary
+	budget      *agent.BudgetSummary
+	review      *chatReview
+	// batchReview overrides the project's review mode for this run.
+	batchReview *bool
+
+	// queueMu guards queue and closed. It is held while emitting
+	// message.queued so that event always precedes message.delivered.
+	queueMu sync.Mutex
+	queue   []QueuedMessage
+	closed  bool
+}
+
+type ChatRunInfo struct {
+	ID              uuid.UUID                  `json:"id"`
+	ChatID          uuid.UUID                  `json:"chat_id"`
+	ProjectID       uuid.UUID                  `json:"project_id"`
+	UserID          uuid.UUID                  `json:"user_id"`
+	Status          string                     `json:"status"`
+	Error           string                     `json:"error,omitempty"`
+	Steps           int                        `json:"steps"`
+	LastSeq         int64                      `json:"last_seq"`
+	Attached        int                        `json:"attached"`
+	PendingApproval *agent.ToolApprovalPayload `json:"pending_approval,omitempty"`
+	PendingReview   *agent.ReviewRequest       `json:"pending_review,omitempty"`
+	Queued          int                        `json:"queued"`
+	StartedAt       time.Time                  `json:"started_at"`
+	FinishedAt      *time.Time                 `json:"finished_at,omitempty"`
+}
+
+type ChatRunManager struct {
+	mu     sync.RWMutex
+	runs   map[uuid.UUID]*ChatRun
+	byChat map[uuid.UUID]*ChatRun
+}
+
+var ChatRuns = &ChatRunManager{
+	runs:   make(map[uuid.UUID]*ChatRun),
+	byChat: make(map[uuid.UUID]*ChatRun),
+}
+
+func (m *ChatRunManager) Start(chatID, projectID, userID uuid.UUID, content string, editorCtx *EditorContext, batchReview *bool) (*ChatRun, error) {
+	m.mu.Lock()
+	if existing, ok := m.byChat[chatID]; ok && existing.AcceptsMessages() {
+		m.mu.Unlock()
+		return nil, ErrChatRunActive
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &ChatRun{
+		ID:          uuid.New(),
+		ChatID:      chatID,
+		ProjectID:   projectID,
+		UserID:      userID,
+		StartedAt:   time.Now(),
+		ctx:         ctx,
+		cancel:      cancel,
+		status:      RunStatusRunning,
+		subscribers: make(map[*ChatWSClient]struct{}),
+		approvals:   make(map[string]chan chatApproval),
+		batchReview: batchReview,
+	}
+	m.runs[run.ID] = run
+	m.byChat[chatID] = run
+	m.mu.Unlock()
+
+	job := &models.Job{
+		ID:        run.ID,
+		ProjectID: projectID,
+		Type:      ChatRunJobType,
+		Status:    RunStatusRunning,
+		PayloadJSON: mustMarshal(map[string]interface{}{
+			"chat_id": chatID,
+			"user_id": userID,
+			"content": content,
+		}),
+		CreatedAt: run.StartedAt,
+		StartedAt: &run.StartedAt,
+	}
+	if err := db.Insert(ctx, "jobs", job); err != nil {
+		log.Printf("[RUN] Failed to create job for run %s: %v", run.ID, err)
+	}
+	BroadcastJobUpdate(projectID.String(), run.ID.String(), RunStatusRunning, "", map[string]interface{}{
+		"type":    ChatRunJobType,
+		"chat_id": chatID,
+	})
+
+	go run.start(content, editorCtx)
+
+	return run, nil
+}
+
+func (m *ChatRunManager) Get(id uuid.UUID) *ChatRun {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.runs[id]
+}
+
+func (m *ChatRunManager) ForChat(chatID uuid.UUID) *ChatRun {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.byChat[chatID]
+}
+
+func (m *ChatRunManager) ListProject(projectID uuid.UUID, activeOnly bool) []ChatRunInfo {
+	m.mu.RLock()
+	var infos []ChatRunInfo
+	for _, run := range m.runs {
+		if run.ProjectID != projectID || (activeOnly && !run.Active()) {
+			continue
+		}
+		infos = append(infos, run.Info())
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].StartedAt.After(infos[j].StartedAt)
+	})
+	return infos
+}
+
+func (m *ChatRunManager) Detach(client *ChatWSClient) {
+	m.mu.RLock()
+	run := m.byChat[client.chatID]
+	m.mu.RUnlock()
+	if run != nil {
+		run.Detach(client)
+	}
+}
+
+func (m *ChatRunManager) remove(run *ChatRun) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.runs, run.ID)
+	if m.byChat[run.ChatID] == run {
+		delete(m.byChat, run.ChatID)
+	}
+}
+
+// RecoverChatRuns marks agent runs left over from a previous server process
+// as failed, since their goroutines are gone.
+func RecoverChatRuns(ctx context.Context) {
+	res, err := db.Exec(ctx,
+		"UPDATE jobs SET status = $1, error_text = $2, finished_at = $3 WHERE type = $4 AND status IN ($5, $6)",
+		RunStatusFailed, "interrupted by server restart", time.Now(), ChatRunJobType, RunStatusRunning, RunStatusWaitingApproval)
+	if err != nil {
+		log.Printf("[RUN] Failed to recover interrupted runs: %v", err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("[RUN] Marked %d interrupted agent runs as failed", n)
+	}
+}
+
+func (r *ChatRun) Status() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+func (r *ChatRun) Active() bool {
+	status := r.Status()
+	return status == RunStatusRunning || status == RunStatusWaitingApproval
+}
+
+// AcceptsMessages reports whether new user messages can still be queued on
+// the run. It turns false once the loop has decided to finish.
+func (r *ChatRun) AcceptsMessages() bool {
+	r.queueMu.Lock()
+	closed := r.closed
+	r.queueMu.Unlock()
+	return !closed && r.Active()
+}
+
+func (r *ChatRun) Info() ChatRunInfo {
+	r.queueMu.Lock()
+	queued := len(r.queue)
+	r.queueMu.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return ChatRunInfo{
+		ID:              r.ID,
+		ChatID:          r.ChatID,
+		ProjectID:       r.ProjectID,
+		UserID:          r.UserID,
+		Status:          r.status,
+		Error:           r.errText,
+		Steps:           r.steps,
+		LastSeq:         r.seq,
+		Attached:        len(r.subscribers),
+		PendingApproval: r.pending,
+		PendingReview:   r.pendingReview(),
+		Queued:          queued,
+		StartedAt:       r.StartedAt,
+		FinishedAt:      r.finishedAt,
+	}
+}
+
+func (r *ChatRun) Cancel() {
+	log.Printf("[RUN] Cancel requested for run %s", r.ID)
+	r.cancel()
+}
+
+// Attach subscribes the client to future events after replaying every event
+// with a sequence number greater than sinceSeq.
+func (r *ChatRun) Attach(client *ChatWSClient, sinceSeq int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	truncated := len(r.events) > 0 && r.events[0].seq > sinceSeq+1
+	replay := 0
+	for _, ev := range r.events {
+		if ev.seq <= sinceSeq {
+			continue
+		}
+		if !client.trySend(ev.data) {
+			break
+		}
+		replay++
+	}
+
+	attached, _ := json.Marshal(ChatWSMessage{
+		Type: "run.attached",
+		Payload: map[string]interface{}{
+			"run_id":           r.ID,
+			"status":           r.status,
+			"since_seq":        sinceSeq,
+			"last_seq":         r.seq,
+			"replayed":         replay,
+			"truncated":        truncated,
+			"pending_approval": r.pending,
+			"pending_review":   r.pendingReview(),
+		},
+	})
+	client.trySend(attached)
+
+	if r.status == RunStatusRunning || r.status == RunStatusWaitingApproval {
+		r.subscribers[client] = struct{}{}
+	}
+	log.Printf("[RUN] Client attached to run %s (since=%d, replayed=%d)", r.ID, sinceSeq, replay)
+}
+
+func (r *ChatRun) Detach(client *ChatWSClient) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.subscribers[client]; ok {
+		delete(r.subscribers, client)
+		log.Printf("[RUN] Client detached from run %s", r.ID)
+	}
+}
+
+func (r *ChatRun) emit(msg ChatWSMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	msg.Seq = r.seq
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("[RUN] Failed to marshal event: %v", err)
+		return
+	}
+
+	r.events = append(r.events, chatRunEvent{seq: r.seq, data: data})
+	if len(r.events) > maxRunEvents {
+		r.events = r.events[len(r.events)-maxRunEvents:]
+	}
+
+	for client := range r.subscribers {
+		if !client.trySend(data) {
+			log.Printf("[RUN] Client buffer full, detaching from run %s", r.ID)
+			delete(r.subscribers, client)
+		}
+	}
+}
+
+func (r *ChatRun) setStatus(status string) {
+	r.mu.Lock()
+	r.status = status
+	r.mu.Unlock()
+
+	if _, err := db.Exec(r.ctx, "UPDATE jobs SET status = $1 WHERE id = $2", status, r.ID.String()); err != nil {
+		log.Printf("[RUN] Failed to update job status: %v", err)
+	}
+}
+
+// awaitApproval pauses the run until a client approves or rejects the tool
+// call, or the run is cancelled.
+func (r *ChatRun) awaitApproval(payload agent.ToolApprovalPayload) (bool, string) {
+	ch := make(chan chatApproval, 1)
+	toolCallID := payload.ToolCallID
+
+	r.mu.Lock()
+	r.approvals[toolCallID] = ch
+	r.pending = &payload
+	r.mu.Unlock()
+
+	r.setStatus(RunStatusWaitingApproval)
+	r.emit(ChatWSMessage{Type: agent.EventToolApprovalRequired, Payload: payload})
+	BroadcastJobUpdate(r.ProjectID.String(), r.ID.String(), RunStatusWaitingApproval, "", map[string]interface{}{
+		"type":     ChatRunJobType,
+		"chat_id":  r.ChatID,
+		"approval": payload,
+	})
+
+	var decision chatApproval
+	select {
+	case decision = <-ch:
+	case <-r.ctx.Done():
+		decision = chatApproval{reason: chatRunCancelMsg}
+	}
+
+	r.mu.Lock()
+	delete(r.approvals, toolCallID)
+	r.pending = nil
+	r.mu.Unlock()
+
+	if r.ctx.Err() == nil {
+		r.setStatus(RunStatusRunning)
+	}
+	return decision.approved, decision.reason
+}
+
+func (r *ChatRun) ResolveApproval(toolCallID string, approved bool, reason string) bool {
+	r.mu.Lock()
+	ch, ok := r.approvals[toolCallID]
+	r.mu.Unlock()
+	if !ok {
+		return false
+	}
+	if reason == "" && !approved {
+		reason = "rejected"
+	}
+	select {
+	case ch <- chatApproval{approved: approved, reason: reason}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Enqueue queues a user message for delivery at the next step boundary. It
+// returns false when the run is no longer accepting messages.
+func (r *ChatRun) Enqueue(content string, editorCtx *EditorContext) (QueuedMessage, bool) {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	if r.closed || !r.Active() {
+		return QueuedMessage{}, false
+	}
+
+	msg := QueuedMessage{
+		ID:       uuid.New(),
+		RunID:    r.ID,
+		Content:  content,
+		Context:  editorCtx,
+		QueuedAt: time.Now(),
+	}
+	r.queue = append(r.queue, msg)
+	r.emit(ChatWSMessage{Type: "message.queued", Payload: msg})
+	log.Printf("[RUN] Queued message %s on run %s", msg.ID, r.ID)
+	return msg, true
+}
+
+// takeQueued drains the queue. With closeIfEmpty the run stops accepting
+// messages when nothing is pending, so a message sent after that point
+// starts a new run instead of being lost.
+func (r *ChatRun) takeQueued(closeIfEmpty bool) []QueuedMessage {
+	r.queueMu.Lock()
+	defer r.queueMu.Unlock()
+	msgs := r.queue
+	r.queue = nil
+	if closeIfEmpty && len(msgs) == 0 {
+		r.closed = true
+	}
+	return msgs
+}
+
+// deliverQueued persists a queued message the model was given at step and
+// tells clients it left the queue.
+func (r *ChatRun) deliverQueued(q QueuedMessage, step int) {
+	now := time.Now()
+	r.saveEditorContext(q.Context, now.Add(-time.Millisecond))
+	msg := &models.ChatMessage{
+		ID:        q.ID,
+		ChatID:    r.ChatID,
+		Role:      "user",
+		Content:   q.Content,
+		CreatedAt: now,
+	}
+	if err := db.Insert(r.ctx, "chat_messages", msg); err != nil {
+		log.Printf("[RUN] Failed to save queued message %s: %v", q.ID, err)
+	}
+
+	r.emit(ChatWSMessage{
+		Type: "message_created",
+		Payload: MessageCreatedPayload{
+			ID:        msg.ID.String(),
+			ChatID:    r.ChatID.String(),
+			Role:      "user",
+			Content:   msg.Content,
+			CreatedAt: msg.CreatedAt,
+		},
+	})
+	r.emit(ChatWSMessage{
+		Type: "message.delivered",
+		Payload: map[string]interface{}{
+			"id":     q.ID,
+			"run_id": r.ID,
+			"step":   step,
+		},
+	})
+}
+
+// saveEditorContext stores the attached editor context as a "context" message
+// right before the user message it belongs to, so the transcript shows
+// exactly what the model was given.
+func (r *ChatRun) saveEditorContext(editorCtx *EditorContext, createdAt time.Time) {
+	if editorCtx == nil {
+		return
+	}
+
+	itemsJSON, _ := json.Marshal(editorCtx)
+	msg := &models.ChatMessage{
+		ID:              uuid.New(),
+		ChatID:          r.ChatID,
+		Role:            "context",
+		Content:         editorCtx.Render(),
+		ToolResultsJSON: string(itemsJSON),
+		CreatedAt:       createdAt,
+	}
+	if err := db.Insert(r.ctx, "chat_messages", msg); err != nil {
+		log.Printf("[RUN] Failed to save editor context: %v", err)
+		return
+	}
+
+	r.emit(ChatWSMessage{
+		Type: "message_created",
+		Payload: MessageCreatedPayload{
+			ID:              msg.ID.String(),
+			ChatID:          r.ChatID.String(),
+			Role:            "context",
+			Content:         editorCtx.Summary(),
+			ToolResultsJSON: msg.ToolResultsJSON,
+			CreatedAt:       msg.CreatedAt,
+		},
+	})
+}
+
+func withEditorContext(editorCtx *EditorContext, content string) string {
+	if editorCtx == nil {
+		return content
+	}
+	return editorCtx.Render() + "\n\n" + content
+}
+
+func (r *ChatRun) dropQueued(reason string) {
+	for _, q := range r.takeQueued(true) {
+		r.dropMessage(q, reason)
+	}
+}
+
+func (r *ChatRun) dropMessage(q QueuedMessage, reason string) {
+	r.emit(ChatWSMessage{
+		Type: "message.dropped",
+		Payload: map[string]interface{}{
+			"id":      q.ID,
+			"run_id":  r.ID,
+			"content": q.Content,
+			"reason":  reason,
+		},
+	})
+}
+
+func (r *ChatRun) start(content string, editorCtx *EditorContext) {
+	err := r.execute(content, editorCtx)
+	r.dropQueued("run finished before the message could be delivered")
+
+	status := RunStatusSucceeded
+	errText := ""
+	switch {
+	case r.ctx.Err() != nil:
+		status = RunStatusCancelled
+		errText = chatRunCancelMsg
+	case err != nil:
+		status = RunStatusFailed
+		errText = err.Error()
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	r.status = status
+	r.errText = errText
+	r.finishedAt = &now
+	steps := r.steps
+	checks := r.checks
+	budget := r.budget
+	r.mu.Unlock()
+
+	result := map[string]interface{}{
+		"type":    ChatRunJobType,
+		"chat_id": r.ChatID,
+		"steps":   steps,
+	}
+	if checks != nil {
+		result["checks"] = checks
+	}
+	if budget != nil {
+		result["budget"] = budget
+	}
+
+	ctx := context.Background()
+	if _, err := db.Exec(ctx,
+		"UPDATE jobs SET status = $1, error_text = $2, result_json = $3, finished_at = $4 WHERE id = $5",
+		status, errText, mustMarshal(result), now, r.ID.String()); err != nil {
+		log.Printf("[RUN] Failed to finish job %s: %v", r.ID, err)
+	}
+
+	if errText != "" {
+		r.emit(ChatWSMessage{
+			Type: "run.error",
+			Payload: map[string]interface{}{
+				"run_id": r.ID,
+				"status": status,
+				"error":  errText,
+			},
+		})
+	}
+	r.emit(ChatWSMessage{
+		Type: "run.finished",
+		Payload: map[string]interface{}{
+			"run_id": r.ID,
+			"status": status,
+			"steps":  steps,
+			"checks": checks,
+			"budget": budget,
+		},
+	})
+	r.emit(ChatWSMessage{
+		Type: "status",
+		Payload: map[string]interface{}{
+			"status": "idle",
+		},
+	})
+	BroadcastJobUpdate(r.ProjectID.String(), r.ID.String(), status, errText, result)
+
+	r.mu.Lock()
+	r.subscribers = make(map[*ChatWSClient]struct{})
+	r.mu.Unlock()
+
+	r.cancel()
+	log.Printf("[RUN] Run %s finished: status=%s steps=%d", r.ID, status, steps)
+
+	time.AfterFunc(finishedRunTTL, func() {
+		ChatRuns.remove(r)
+	})
+}
+
+func (r *ChatRun) execute(content string, editorCtx *EditorContext) error {
+	ctx := r.ctx
+	now := time.Now()
+
+	projectRoot := ""
+	project, err := projects.GetProject(r.ProjectID)
+	if err != nil {
+		log.Printf("[WS-CHAT] Failed to get project %s: %v, using empty root", r.ProjectID, err)
+	} else {
+		projectRoot = project.RootPath
+		log.Printf("[WS-CHAT] Project root: %s", projectRoot)
+	}
+
+	projectCfg := agent.LoadProjectConfig(projectRoot)
+
+	// In batch review mode tools, hooks and checks work on a shadow copy and
+	// the user accepts or rejects the combined changes at the end.
+	workRoot := projectRoot
+	var shadow *agent.Shadow
+	if projectRoot != "" && r.batchReviewEnabled(projectCfg) {
+		shadow, err = agent.NewShadow(projectRoot)
+		if err != nil {
+			log.Printf("[WS-CHAT] Failed to create shadow copy: %v", err)
+			return err
+		}
+		defer shadow.Close()
+		workRoot = shadow.Root
+		log.Printf("[WS-CHAT] Batch review: working in %s", workRoot)
+	}
+
+	history, err := loadChatMessages(ctx, r.ChatID)
+	if err != nil {
+		log.Printf("[WS-CHAT] Failed to get chat messages: %v", err)
+		return err
+	}
+	log.Printf("[WS-CHAT] Got %d messages for context", len(history))
+
+	r.saveEditorContext(editorCtx, now.Add(-time.Millisecond))
+
+	userMsg := &models.ChatMessage{
+		ID:        uuid.New(),
+		ChatID:    r.ChatID,
+		Role:      "user",
+		Content:   content,
+		CreatedAt: now,
+	}
+
+	log.Printf("[WS-CHAT] Saving user message to DB...")
+	if err := db.Insert(ctx, "chat_messages", userMsg); err != nil {
+		log.Printf("[WS-CHAT] Failed to save user message: %v", err)
+		return err
+	}
+	log.Printf("[WS-CHAT] User message saved: %s", userMsg.ID)
+
+	r.emit(ChatWSMessage{
+		Type: "message_created",
+		Payload: MessageCreatedPayload{
+			ID:        userMsg.ID.String(),
+			ChatID:    r.ChatID.String(),
+			Role:      "user",
+			Content:   userMsg.Content,
+			CreatedAt: userMsg.CreatedAt,
+		},
+	})
+
+	cfg, err := config.Load()
+	if err != nil || cfg == nil {
+		log.Printf("Failed to load config: %v", err)
+		return errors.New("failed to load config")
+	}
+
+	budget := projectCfg.Budget.Merge(loadChatBudget(ctx, r.ChatID))
+	summarize := projectCfg.Summarize
+	if summarize.Model == "" {
+		summarize.Model = cfg.SummaryModel
+	}
+	agentCfg := agent.DefaultConfig()
+	agentCfg.Mode = agent.ModeWrite
+	agentCfg.ProjectRoot = workRoot
+	agentCfg.Model = cfg.MiniMaxModel
+	agentCfg.Hooks = projectCfg.Hooks
+	agentCfg.Checks = &projectCfg.Checks
+	agentCfg.Budget = &budget
+	agentCfg.Summarize = &summarize
+	agentCfg.Redact = &projectCfg.Redact
+
+	session := agent.NewSession(r.ProjectID, r.UserID, r.ChatID, agentCfg)
+	session.ID = r.ID
+	for _, m := range history {
+		session.AddMessage(agent.MessageRole(m.Role), m.Content)
+	}
+	session.TouchWorkDirs(chatWorkDirs(ctx, r.ChatID, projectRoot))
+	r.mu.Lock()
+	r.session = session
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.checks = session.Verifier().Summary()
+		r.budget = session.Budget().Summary()
+		r.mu.Unlock()
+	}()
+
+	orchestrator := agent.NewOrchestrator(tools.GlobalRegistry, provider.NewAnthropic(cfg.MiniMaxAPIKey, cfg.MiniMaxURL))
+	orchestrator.SetProviderConfig(provider.Config{
+		URL:    cfg.MiniMaxURL,
+		APIKey: cfg.MiniMaxAPIKey,
+		Model:  cfg.MiniMaxModel,
+	})
+	if shadow != nil {
+		orchestrator.SetPolicy(agent.NewBatchReviewPolicyEngine(tools.GlobalRegistry))
+	}
+
+	handler := newChatRunHandler(r, shadow)
+	log.Printf("[WS-CHAT] Starting AI response processing...")
+	err = orchestrator.RunWith(ctx, session, agent.UserInput{
+		ID:      userMsg.ID.String(),
+		Message: content,
+		Prompt:  withEditorContext(editorCtx, content),
+	}, handler)
+
+	// Runs that stop on limits still hand their edits over for review.
+	if shadow != nil && ctx.Err() == nil {
+		r.reviewShadow(shadow)
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	return handler.err
+}
+
+// SystemPrompt returns the system prompt the run's agent works with, built
+// from its own root (the shadow copy in batch review mode) and messages.
+func (r *ChatRun) SystemPrompt() (agent.SystemPrompt, bool) {
+	r.mu.Lock()
+	session := r.session
+	r.mu.Unlock()
+	if session == nil {
+		return agent.SystemPrompt{}, false
+	}
+	return session.SystemPrompt(), true
+}
+
+// WorkDirs lists the directories the run's agent has worked in.
+func (r *ChatRun) WorkDirs() []string {
+	r.mu.Lock()
+	session := r.session
+	r.mu.Unlock()
+	if session == nil {
+		return nil
+	}
+	return session.WorkDirs()
+}
+
+// chatWorkDirs collects the directories touched by earlier tool calls in the
+// chat so nested instruction files stay in the prompt across runs.
+func chatWorkDirs(ctx context.Context, chatID uuid.UUID, projectRoot string) []string {
+	if projectRoot == "" {
+		return nil
+	}
+
+	rows, err := db.Query(ctx, "SELECT COALESCE(tool_calls_json, '') FROM chat_messages WHERE chat_id = $1 AND role = 'assistant'", chatID.String())
+	if err != nil {
+		log.Printf("[WS-CHAT] Failed to load tool calls for chat %s: %v", chatID, err)
+		return nil
+	}
+	defer rows.Close()
+
+	seen := make(map[string]bool)
+	var dirs []string
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil || raw == "" {
+			continue
+		}
+		var calls []provider.ToolCall
+		if err := json.Unmarshal([]byte(raw), &calls); err != nil {
+			continue
+		}
+		for _, tc := range calls {
+			var args map[string]interface{}
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				continue
+			}
+			for _, d := range agent.WorkDirsFromArgs(projectRoot, args) {
+				if !seen[d] {
+					seen[d] = true
+					dirs = append(dirs, d)
+				}
+			}
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,329 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/ai/provider"
+	"github.com/webide/ide/backend/internal/db"
+	"github.com/webide/ide/backend/internal/models"
+)
+
+// chatRunHandler connects the orchestrator to a ChatRun. It turns the run's
+// events into the chat's websocket messages and transcript rows, asks the
+// run's clients for approvals and hands over messages queued on the run.
+type chatRunHandler struct {
+	run    *ChatRun
+	shadow *agent.Shadow
+
+	// queued holds messages handed to the orchestrator until it reports
+	// whether they were delivered.
+	queued map[string]QueuedMessage
+
+	thinking    *models.ChatMessage
+	assistant   *models.ChatMessage
+	toolResults []map[string]interface{}
+
+	// err is an agent error that ended the run without failing RunWith,
+	// e.g. a blocked user message.
+	err error
+}
+
+func newChatRunHandler(run *ChatRun, shadow *agent.Shadow) *chatRunHandler {
+	return &chatRunHandler{
+		run:    run,
+		shadow: shadow,
+		queued: make(map[string]QueuedMessage),
+	}
+}
+
+func (h *chatRunHandler) Event(ev agent.WSEvent) {
+	r := h.run
+	switch ev.Type {
+	case agent.EventStepStart:
+		if p, ok := ev.Payload.(agent.StepPayload); ok {
+			r.mu.Lock()
+			r.steps = p.Step
+			r.mu.Unlock()
+		}
+		h.startStep()
+
+	case agent.EventThinkingDelta:
+		content := deltaContent(ev)
+		h.thinking.Content += content
+		db.Update(r.ctx, "chat_messages", h.thinking)
+		h.chunk(h.thinking, content, false)
+
+	case agent.EventAssistantDelta:
+		h.chunk(h.assistant, deltaContent(ev), false)
+
+	case agent.EventAssistantMessage:
+		p, _ := ev.Payload.(agent.AssistantMessagePayload)
+		h.finishMessage(p)
+
+	case agent.EventToolCall:
+		p, _ := ev.Payload.(agent.ToolCallPayload)
+		r.emit(ChatWSMessage{
+			Type: "tool_call",
+			Payload: map[string]interface{}{
+				"id":               p.ToolCallID,
+				"name":             p.Name,
+				"arguments":        p.Arguments,
+				"assistant_msg_id": h.assistant.ID.String(),
+			},
+		})
+
+	case agent.EventToolResult:
+		if p, ok := ev.Payload.(map[string]interface{}); ok {
+			h.toolResult(p)
+		}
+
+	case agent.EventToolError:
+		p, _ := ev.Payload.(map[string]interface{})
+		h.toolResult(map[string]interface{}{
+			"id":    ev.ID,
+			"name":  p["name"],
+			"ok":    false,
+			"error": p["error"],
+		})
+
+	case agent.EventStepEnd:
+		h.saveToolResults()
+
+	case agent.EventHookResult:
+		if res, ok := ev.Payload.(agent.HookResult); ok {
+			h.saveHookResult(res)
+		}
+
+	case agent.EventChecksResult, agent.EventBudgetWarning:
+		r.emit(ChatWSMessage{Type: ev.Type, Payload: ev.Payload})
+
+	case agent.EventAgentError:
+		p, _ := ev.Payload.(agent.AgentErrorPayload)
+		// Clients show agent.error as the budget that ended the run; any
+		// other error fails the run.
+		if p.Code == agent.ErrCodeBudgetExceeded {
+			log.Printf("[RUN] Run %s stopped: %s", r.ID, p.Message)
+			r.emit(ChatWSMessage{Type: ev.Type, Payload: p})
+			return
+		}
+		h.err = errors.New(p.Message)
+
+	case agent.EventInputDelivered:
+		p, _ := ev.Payload.(agent.InputPayload)
+		if q, ok := h.queued[p.ID]; ok {
+			delete(h.queued, p.ID)
+			r.deliverQueued(q, p.Step)
+		}
+
+	case agent.EventInputDropped:
+		p, _ := ev.Payload.(agent.InputPayload)
+		if q, ok := h.queued[p.ID]; ok {
+			delete(h.queued, p.ID)
+			r.dropMessage(q, p.Reason)
+		}
+	}
+}
+
+func (h *chatRunHandler) AwaitApproval(ctx context.Context, req agent.ToolApprovalPayload) (bool, string, error) {
+	approved, reason := h.run.awaitApproval(req)
+	if err := ctx.Err(); err != nil {
+		return false, reason, err
+	}
+	return approved, reason, nil
+}
+
+func (h *chatRunHandler) Inputs(final bool) []agent.UserInput {
+	var inputs []agent.UserInput
+	for _, q := range h.run.takeQueued(final) {
+		id := q.ID.String()
+		h.queued[id] = q
+		inputs = append(inputs, agent.UserInput{
+			ID:      id,
+			Message: q.Content,
+			Prompt:  withEditorContext(q.Context, q.Content),
+		})
+	}
+	if len(inputs) > 0 {
+		log.Printf("[WS-CHAT] Delivering %d queued messages", len(inputs))
+	}
+	return inputs
+}
+
+// Review hands the shadow copy's changes to the user. Rejected files go back
+// to the model as feedback.
+func (h *chatRunHandler) Review(ctx context.Context) string {
+	if h.shadow == nil {
+		return ""
+	}
+	outcome := h.run.reviewShadow(h.shadow)
+	if outcome == nil {
+		return ""
+	}
+	if feedback := outcome.Feedback(); feedback != "" {
+		log.Printf("[WS-CHAT] Review rejected %d files, returning feedback to the model", len(outcome.Rejected)+len(outcome.Conflicts))
+		return feedback
+	}
+	return ""
+}
+
+// startStep creates the thinking and assistant rows the step streams into.
+func (h *chatRunHandler) startStep() {
+	r := h.run
+	h.toolResults = nil
+
+	h.thinking = &models.ChatMessage{
+		ID:        uuid.New(),
+		ChatID:    r.ChatID,
+		Role:      "thinking",
+		CreatedAt: time.Now().Add(-time.Millisecond),
+	}
+	if err := db.Insert(r.ctx, "chat_messages", h.thinking); err != nil {
+		log.Printf("[WS-CHAT] Failed to create thinking message: %v", err)
+	}
+	h.created(h.thinking, "")
+
+	r.emit(ChatWSMessage{
+		Type: "status",
+		Payload: map[string]interface{}{
+			"status": "thinking",
+		},
+	})
+
+	h.assistant = &models.ChatMessage{
+		ID:        uuid.New(),
+		ChatID:    r.ChatID,
+		Role:      "assistant",
+		CreatedAt: time.Now(),
+	}
+	if err := db.Insert(r.ctx, "chat_messages", h.assistant); err != nil {
+		log.Printf("[WS-CHAT] Failed to create AI message: %v", err)
+	}
+	h.created(h.assistant, "")
+}
+
+// finishMessage saves the step's response and closes both streams.
+func (h *chatRunHandler) finishMessage(p agent.AssistantMessagePayload) {
+	var calls []provider.ToolCall
+	for _, tc := range p.ToolCalls {
+		call := provider.ToolCall{ID: tc.ID, Type: tc.Type}
+		call.Function.Name = tc.Function.Name
+		call.Function.Arguments = tc.Function.Arguments
+		calls = append(calls, call)
+	}
+
+	h.assistant.Content = p.Content
+	h.assistant.Thinking = p.Thinking
+	if len(calls) > 0 {
+		callsJSON, _ := json.Marshal(calls)
+		h.assistant.ToolCallsJSON = string(callsJSON)
+	}
+	db.Update(h.run.ctx, "chat_messages", h.assistant)
+
+	h.chunk(h.thinking, "", true)
+	h.chunk(h.assistant, "", true)
+
+	frontendCallsJSON := ""
+	if len(calls) > 0 {
+		b, _ := json.Marshal(transformToolCallsToFrontend(calls))
+		frontendCallsJSON = string(b)
+	}
+	h.created(h.assistant, frontendCallsJSON)
+
+	log.Printf("[WS-CHAT] AI response %d done: content_len=%d, thinking_len=%d, tool_calls=%d",
+		p.Step, len(p.Content), len(p.Thinking), len(calls))
+}
+
+func (h *chatRunHandler) toolResult(result map[string]interface{}) {
+	h.toolResults = append(h.toolResults, result)
+
+	payload := make(map[string]interface{}, len(result)+1)
+	for k, v := range result {
+		payload[k] = v
+	}
+	payload["assistant_msg_id"] = h.assistant.ID.String()
+	h.run.emit(ChatWSMessage{Type: "tool.result", Payload: payload})
+}
+
+// saveToolResults stores the step's tool results as one "tool" row, the way
+// the transcript shows them and the model reads them on the next run.
+func (h *chatRunHandler) saveToolResults() {
+	if len(h.toolResults) == 0 {
+		return
+	}
+	toolResultsJSON, _ := json.Marshal(h.toolResults)
+	toolMsg := &models.ChatMessage{
+		ID:              uuid.New(),
+		ChatID:          h.run.ChatID,
+		Role:            "tool",
+		Content:         combineToolResults(h.toolResults),
+		ToolResultsJSON: string(toolResultsJSON),
+		CreatedAt:       time.Now(),
+	}
+	if err := db.Insert(h.run.ctx, "chat_messages", toolMsg); err != nil {
+		log.Printf("[WS-CHAT] Failed to save tool results message: %v", err)
+	}
+	h.toolResults = nil
+}
+
+func (h *chatRunHandler) saveHookResult(res agent.HookResult) {
+	r := h.run
+	resultJSON, _ := json.Marshal(res)
+	content := res.Output
+	if content == "" {
+		content = res.Reason
+	}
+	hookMsg := &models.ChatMessage{
+		ID:              uuid.New(),
+		ChatID:          r.ChatID,
+		Role:            "hook",
+		Content:         content,
+		ToolCallID:      res.ToolCallID,
+		ToolResultsJSON: string(resultJSON),
+		CreatedAt:       time.Now(),
+	}
+	if err := db.Insert(r.ctx, "chat_messages", hookMsg); err != nil {
+		log.Printf("[WS-CHAT] Failed to save hook result: %v", err)
+	}
+
+	r.emit(ChatWSMessage{
+		Type:    agent.EventHookResult,
+		Payload: res,
+	})
+}
+
+func (h *chatRunHandler) created(msg *models.ChatMessage, toolCallsJSON string) {
+	h.run.emit(ChatWSMessage{
+		Type: "message_created",
+		Payload: MessageCreatedPayload{
+			ID:            msg.ID.String(),
+			ChatID:        h.run.ChatID.String(),
+			Role:          msg.Role,
+			Content:       msg.Content,
+			ToolCallsJSON: toolCallsJSON,
+			CreatedAt:     msg.CreatedAt,
+		},
+	})
+}
+
+func (h *chatRunHandler) chunk(msg *models.ChatMessage, content string, done bool) {
+	h.run.emit(ChatWSMessage{
+		Type: "chunk",
+		Payload: MessageChunkPayload{
+			MessageID: msg.ID.String(),
+			Content:   content,
+			Done:      done,
+		},
+	})
+}
+
+func deltaContent(ev agent.WSEvent) string {
+	p, _ := ev.Payload.(map[string]interface{})
+	content, _ := p["content"].(string)
+	return content
+}
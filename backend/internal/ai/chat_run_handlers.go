@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func HandleListChatRuns(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+
+	runs := ChatRuns.ListProject(projectID, c.Query("all") != "true")
+	if runs == nil {
+		runs = []ChatRunInfo{}
+	}
+	return c.JSON(runs)
+}
+
+func HandleGetChatRun(c *fiber.Ctx) error {
+	run, status, msg := chatRunFromParams(c)
+	if run == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	return c.JSON(run.Info())
+}
+
+func HandleCancelChatRun(c *fiber.Ctx) error {
+	run, status, msg := chatRunFromParams(c)
+	if run == nil {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if !run.Active() {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "run is not active"})
+	}
+
+	run.Cancel()
+	return c.JSON(fiber.Map{
+		"run_id": run.ID,
+		"status": "cancelling",
+	})
+}
+
+func chatRunFromParams(c *fiber.Ctx) (*ChatRun, int, string) {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "invalid project_id"
+	}
+	runID, err := uuid.Parse(c.Params("runId"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, "invalid run_id"
+	}
+
+	run := ChatRuns.Get(runID)
+	if run == nil || run.ProjectID != projectID {
+		return nil, fiber.StatusNotFound, "run not found"
+	}
+	return run, 0, ""
+}
@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/db"
+)
+
+func TestChatRun_QueueStaysOpenUntilClosed(t *testing.T) {
+	run := &ChatRun{status: RunStatusWaitingApproval}
+
+	tests := []struct {
+		name         string
+		enqueue      bool
+		closeIfEmpty bool
+		taken        int
+		accepts      bool
+	}{
+		{name: "message during review is queued", enqueue: true, closeIfEmpty: false, taken: 1, accepts: true},
+		{name: "draining without closing keeps the run open", closeIfEmpty: false, taken: 0, accepts: true},
+		{name: "closing with a pending message delivers it", enqueue: true, closeIfEmpty: true, taken: 1, accepts: true},
+		{name: "closing an empty queue stops accepting", closeIfEmpty: true, taken: 0, accepts: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.enqueue {
+				if _, ok := run.Enqueue("follow-up", nil); !ok {
+					t.Fatal("Enqueue refused a message on an open run")
+				}
+			}
+			if got := len(run.takeQueued(tt.closeIfEmpty)); got != tt.taken {
+				t.Errorf("takeQueued returned %d messages, want %d", got, tt.taken)
+			}
+			if got := run.AcceptsMessages(); got != tt.accepts {
+				t.Errorf("AcceptsMessages() = %v, want %v", got, tt.accepts)
+			}
+		})
+	}
+
+	if _, ok := run.Enqueue("late", nil); ok {
+		t.Error("Enqueue accepted a message after the run closed")
+	}
+}
+
+func TestChatRunHandler_AwaitApproval(t *testing.T) {
+	if err := db.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		approve bool
+		cancel  bool
+		want    bool
+	}{
+		{name: "approved", approve: true, want: true},
+		{name: "rejected"},
+		{name: "cancelled run", cancel: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			run := &ChatRun{
+				ID:          uuid.New(),
+				ctx:         ctx,
+				cancel:      cancel,
+				status:      RunStatusRunning,
+				subscribers: make(map[*ChatWSClient]struct{}),
+				approvals:   make(map[string]chan chatApproval),
+			}
+			h := newChatRunHandler(run, nil)
+
+			type outcome struct {
+				approved bool
+				err      error
+			}
+			done := make(chan outcome, 1)
+			go func() {
+				approved, _, err := h.AwaitApproval(ctx, agent.ToolApprovalPayload{ToolCallID: "call_1", Name: "write_file"})
+				done <- outcome{approved, err}
+			}()
+
+			deadline := time.Now().Add(2 * time.Second)
+			for run.Info().PendingApproval == nil {
+				if time.Now().After(deadline) {
+					t.Fatal("no approval was requested")
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			if got := run.Status(); got != RunStatusWaitingApproval {
+				t.Errorf("status = %s, want %s", got, RunStatusWaitingApproval)
+			}
+			select {
+			case <-done:
+				t.Fatal("AwaitApproval returned before anyone answered")
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			if tt.cancel {
+				cancel()
+			} else if !run.ResolveApproval("call_1", tt.approve, "") {
+				t.Fatal("ResolveApproval found no pending call")
+			}
+
+			var got outcome
+			select {
+			case got = <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("AwaitApproval did not return")
+			}
+			if got.approved != tt.want {
+				t.Errorf("approved = %v, want %v", got.approved, tt.want)
+			}
+			if tt.cancel != (got.err != nil) {
+				t.Errorf("err = %v, want an error only for a cancelled run", got.err)
+			}
+			if run.Info().PendingApproval != nil {
+				t.Error("approval still pending after it was answered")
+			}
+		})
+	}
+}
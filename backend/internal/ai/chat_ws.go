@@ -12,10 +12,10 @@ import (
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
 	"github.com/webide/ide/backend/internal/ai/provider"
 	"github.com/webide/ide/backend/internal/ai/tools"
 	_ "github.com/webide/ide/backend/internal/ai/tools/builtin"
-	"github.com/webide/ide/backend/internal/config"
 	"github.com/webide/ide/backend/internal/db"
 	"github.com/webide/ide/backend/internal/models"
 	"github.com/webide/ide/backend/internal/projects"
@@ -54,6 +54,7 @@ type ChatWSClient struct {
 	conn      *websocket.Conn
 	send      chan []byte
 	mu        sync.Mutex
+	closed    bool
 	ctx       context.Context
 	cancel    context.CancelFunc
 }
@@ -86,11 +87,30 @@ func init() {
 
 type ChatWSMessage struct {
 	Type    string      `json:"type"`
+	Seq     int64       `json:"seq,omitempty"`
 	Payload interface{} `json:"payload"`
 }
 
 type SendMessagePayload struct {
-	Content string `json:"content"`
+	Content string                `json:"content"`
+	Context *EditorContextRequest `json:"context,omitempty"`
+	// BatchReview overrides the project's review mode for this run.
+	BatchReview *bool `json:"batch_review,omitempty"`
+}
+
+type AttachPayload struct {
+	RunID    string `json:"run_id,omitempty"`
+	SinceSeq int64  `json:"since_seq"`
+}
+
+type ApprovalPayload struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type ReviewSubmitPayload struct {
+	ID string `json:"id"`
+	agent.ReviewDecision
 }
 
 type MessageChunkPayload struct {
@@ -176,6 +196,9 @@ func ChatWebSocketHandler(c *fiber.Ctx) error {
 		go client.writePump()
 
 		client.readPump(ctx)
+
+		ChatRuns.Detach(client)
+		client.close()
 	}, websocket.Config{
 		HandshakeTimeout: 10 * time.Second,
 	})(c)
@@ -213,9 +236,19 @@ func (c *ChatWSClient) readPump(ctx context.Context) {
 			case "send_message":
 				log.Printf("[WS-CHAT] Processing send_message for chat: %s", c.chatID)
 				c.handleSendMessage(msg.Payload)
+			case "attach":
+				c.handleAttach(msg.Payload)
+			case "tool.approve":
+				c.handleApproval(msg.Payload, true)
+			case "tool.reject":
+				c.handleApproval(msg.Payload, false)
+			case "review.submit":
+				c.handleReviewSubmit(msg.Payload)
 			case "stop":
 				log.Printf("[WS-CHAT] Stop requested for chat: %s", c.chatID)
-				c.cancel()
+				if run := ChatRuns.ForChat(c.chatID); run != nil {
+					run.Cancel()
+				}
 			}
 		}
 	}
@@ -224,15 +257,9 @@ func (c *ChatWSClient) readPump(ctx context.Context) {
 func (c *ChatWSClient) handleSendMessage(payload interface{}) {
 	log.Printf("[WS-CHAT] handleSendMessage called with payload: %v", payload)
 
-	data, err := json.Marshal(payload)
-	if err != nil {
-		log.Printf("[WS-CHAT] Failed to marshal payload: %v", err)
-		return
-	}
-
 	var sendPayload SendMessagePayload
-	if err := json.Unmarshal(data, &sendPayload); err != nil {
-		log.Printf("[WS-CHAT] Failed to unmarshal payload: %v", err)
+	if err := decodePayload(payload, &sendPayload); err != nil {
+		log.Printf("[WS-CHAT] Failed to decode payload: %v", err)
 		return
 	}
 
@@ -243,392 +270,133 @@ func (c *ChatWSClient) handleSendMessage(payload interface{}) {
 		return
 	}
 
-	ctx := c.ctx
-	now := time.Now()
-
-	userMsg := &models.ChatMessage{
-		ID:        uuid.New(),
-		ChatID:    c.chatID,
-		Role:      "user",
-		Content:   sendPayload.Content,
-		CreatedAt: now,
+	var editorCtx *EditorContext
+	if sendPayload.Context != nil && sendPayload.Context.Enabled {
+		if project, err := projects.GetProject(c.projectID); err == nil {
+			editorCtx = BuildEditorContext(c.ctx, c.userID, c.projectID, project.RootPath, sendPayload.Context)
+		}
 	}
 
-	log.Printf("[WS-CHAT] Saving user message to DB...")
-	if err := db.Insert(ctx, "chat_messages", userMsg); err != nil {
-		log.Printf("[WS-CHAT] Failed to save user message: %v", err)
-		return
-	}
-	log.Printf("[WS-CHAT] User message saved: %s", userMsg.ID)
-
-	userMsgJSON, _ := json.Marshal(MessageCreatedPayload{
-		ID:        userMsg.ID.String(),
-		ChatID:    c.chatID.String(),
-		Role:      "user",
-		Content:   userMsg.Content,
-		CreatedAt: userMsg.CreatedAt,
-	})
-	c.send <- userMsgJSON
-
-	aiMsgID := uuid.New()
-	aiMsg := &models.ChatMessage{
-		ID:        aiMsgID,
-		ChatID:    c.chatID,
-		Role:      "assistant",
-		Content:   "",
-		CreatedAt: time.Now(),
-	}
-
-	log.Printf("[WS-CHAT] Creating AI message...")
-	if err := db.Insert(ctx, "chat_messages", aiMsg); err != nil {
-		log.Printf("[WS-CHAT] Failed to create AI message: %v", err)
-		return
+	if active := ChatRuns.ForChat(c.chatID); active != nil {
+		if queued, ok := active.Enqueue(sendPayload.Content, editorCtx); ok {
+			log.Printf("[WS-CHAT] Queued message %s on active run %s", queued.ID, active.ID)
+			return
+		}
 	}
-	log.Printf("[WS-CHAT] AI message created: %s", aiMsgID)
 
-	messages, err := c.getChatMessages()
+	run, err := ChatRuns.Start(c.chatID, c.projectID, c.userID, sendPayload.Content, editorCtx, sendPayload.BatchReview)
 	if err != nil {
-		log.Printf("[WS-CHAT] Failed to get chat messages: %v", err)
+		log.Printf("[WS-CHAT] Failed to start run: %v", err)
+		c.sendJSON(ChatWSMessage{
+			Type: "run.error",
+			Payload: map[string]interface{}{
+				"error": err.Error(),
+			},
+		})
 		return
 	}
-	log.Printf("[WS-CHAT] Got %d messages for context", len(messages))
 
-	projectRoot := ""
-	project, err := projects.GetProject(c.projectID)
-	if err != nil {
-		log.Printf("[WS-CHAT] Failed to get project %s: %v, using empty root", c.projectID, err)
-	} else {
-		projectRoot = project.RootPath
-		log.Printf("[WS-CHAT] Project root: %s", projectRoot)
-	}
+	log.Printf("[WS-CHAT] Started run %s for chat %s", run.ID, c.chatID)
+	run.Attach(c, 0)
+}
 
-	cfg, err := config.Load()
-	if err != nil || cfg == nil {
-		log.Printf("Failed to load config: %v", err)
+func (c *ChatWSClient) handleAttach(payload interface{}) {
+	var attach AttachPayload
+	if err := decodePayload(payload, &attach); err != nil {
+		log.Printf("[WS-CHAT] Failed to decode attach payload: %v", err)
 		return
 	}
 
-	providerCfg := provider.Config{
-		URL:    cfg.MiniMaxURL,
-		APIKey: cfg.MiniMaxAPIKey,
-		Model:  cfg.MiniMaxModel,
-	}
-
-	p := provider.NewAnthropic(cfg.MiniMaxAPIKey, cfg.MiniMaxURL)
-
-	toolsList := tools.GlobalRegistry.ListForModel()
-	log.Printf("[WS-CHAT] Sending %d tools to model", len(toolsList))
-	for i, t := range toolsList {
-		log.Printf("[WS-CHAT] Tool %d: %s", i, t.Function["name"])
-	}
-
-	providerTools := make([]provider.ToolDefinition, len(toolsList))
-	for i, t := range toolsList {
-		providerTools[i] = provider.ToolDefinition{
-			Type:     t.Type,
-			Function: t.Function,
+	run := ChatRuns.ForChat(c.chatID)
+	if attach.RunID != "" {
+		if id, err := uuid.Parse(attach.RunID); err == nil {
+			run = ChatRuns.Get(id)
 		}
 	}
-
-	log.Printf("[WS-CHAT] Starting AI response processing...")
-
-	var allToolCalls []provider.ToolCall
-	var toolResults []map[string]interface{}
-
-	aiResponseIndex := 0
-	maxIterations := 5
-
-	for aiResponseIndex < maxIterations {
-		aiResponseIndex++
-
-		newToolCalls := []provider.ToolCall{}
-
-		thinkingTime := time.Now().Add(-time.Millisecond)
-		currentThinkingMsgID := uuid.New()
-		currentThinkingMsg := &models.ChatMessage{
-			ID:        currentThinkingMsgID,
-			ChatID:    c.chatID,
-			Role:      "thinking",
-			Content:   "",
-			CreatedAt: thinkingTime,
-		}
-		if err := db.Insert(ctx, "chat_messages", currentThinkingMsg); err != nil {
-			log.Printf("[WS-CHAT] Failed to create thinking message: %v", err)
-		}
-		thinkingMsgJSON, _ := json.Marshal(ChatWSMessage{
-			Type: "message_created",
-			Payload: MessageCreatedPayload{
-				ID:        currentThinkingMsgID.String(),
-				ChatID:    c.chatID.String(),
-				Role:      "thinking",
-				Content:   "",
-				CreatedAt: thinkingTime,
-			},
-		})
-		c.send <- thinkingMsgJSON
-
-		statusJSON, _ := json.Marshal(ChatWSMessage{
-			Type: "status",
+	if run == nil || run.ChatID != c.chatID {
+		c.sendJSON(ChatWSMessage{
+			Type: "run.attached",
 			Payload: map[string]interface{}{
-				"status": "thinking",
-			},
-		})
-		c.send <- statusJSON
-
-		chunks, err := p.StreamWithTools(ctx, messages, providerCfg, providerTools, "auto")
-		if err != nil {
-			log.Printf("[WS-CHAT] Streaming failed: %v", err)
-			break
-		}
-
-		hasNewContent := false
-		hasNewThinking := false
-
-		currentAIMsgID := uuid.New()
-		currentAIMsg := &models.ChatMessage{
-			ID:        currentAIMsgID,
-			ChatID:    c.chatID,
-			Role:      "assistant",
-			Content:   "",
-			CreatedAt: time.Now(),
-		}
-
-		if err := db.Insert(ctx, "chat_messages", currentAIMsg); err != nil {
-			log.Printf("[WS-CHAT] Failed to create AI message: %v", err)
-			return
-		}
-
-		aiMsgJSON, _ := json.Marshal(ChatWSMessage{
-			Type: "message_created",
-			Payload: MessageCreatedPayload{
-				ID:        currentAIMsg.ID.String(),
-				ChatID:    c.chatID.String(),
-				Role:      "assistant",
-				Content:   "",
-				CreatedAt: currentAIMsg.CreatedAt,
-			},
-		})
-		c.send <- aiMsgJSON
-
-		var contentBuilder strings.Builder
-		var thinkingBuilder strings.Builder
-
-		for chunk := range chunks {
-			log.Printf("[WS-CHAT] Chunk: content_len=%d, thinking_len=%d, done=%v, tool_calls=%d",
-				len(chunk.Content), len(chunk.Thinking), chunk.Done, len(chunk.ToolCalls))
-
-			if len(chunk.ToolCalls) > 0 {
-				log.Printf("[WS-CHAT] Tool calls detected: %d", len(chunk.ToolCalls))
-				for _, tc := range chunk.ToolCalls {
-					log.Printf("[WS-CHAT] Tool call: %s(%s)", tc.Function.Name, tc.Function.Arguments)
-				}
-				newToolCalls = append(newToolCalls, chunk.ToolCalls...)
-				allToolCalls = append(allToolCalls, chunk.ToolCalls...)
-			}
-
-			if chunk.Thinking != "" {
-				hasNewThinking = true
-				thinkingBuilder.WriteString(chunk.Thinking)
-				currentThinkingMsg.Content = thinkingBuilder.String()
-				db.Update(ctx, "chat_messages", currentThinkingMsg)
-				chunkThinkingJSON, _ := json.Marshal(ChatWSMessage{
-					Type: "chunk",
-					Payload: MessageChunkPayload{
-						MessageID: currentThinkingMsgID.String(),
-						Content:   chunk.Thinking,
-						Done:      false,
-					},
-				})
-				c.send <- chunkThinkingJSON
-			}
-
-			if chunk.Content != "" {
-				hasNewContent = true
-				contentBuilder.WriteString(chunk.Content)
-				chunkJSON, _ := json.Marshal(ChatWSMessage{
-					Type: "chunk",
-					Payload: MessageChunkPayload{
-						MessageID: currentAIMsgID.String(),
-						Content:   chunk.Content,
-						Done:      false,
-					},
-				})
-				c.send <- chunkJSON
-			}
-
-			if chunk.Done {
-				break
-			}
-		}
-
-		currentAIMsg.Content = contentBuilder.String()
-		currentAIMsg.Thinking = thinkingBuilder.String()
-		toolCallsJSON, _ := json.Marshal(allToolCalls)
-		currentAIMsg.ToolCallsJSON = string(toolCallsJSON)
-		db.Update(ctx, "chat_messages", currentAIMsg)
-
-		doneThinkingJSON, _ := json.Marshal(ChatWSMessage{
-			Type: "chunk",
-			Payload: MessageChunkPayload{
-				MessageID: currentThinkingMsgID.String(),
-				Content:   "",
-				Done:      true,
-			},
-		})
-		c.send <- doneThinkingJSON
-
-		doneJSON, _ := json.Marshal(ChatWSMessage{
-			Type: "chunk",
-			Payload: MessageChunkPayload{
-				MessageID: currentAIMsgID.String(),
-				Content:   "",
-				Done:      true,
-			},
-		})
-		c.send <- doneJSON
-
-		frontendToolCalls := transformToolCallsToFrontend(newToolCalls)
-		frontendToolCallsJSON, _ := json.Marshal(frontendToolCalls)
-		toolResultsJSON, _ := json.Marshal(toolResults)
-		aiMsgJSON, _ = json.Marshal(ChatWSMessage{
-			Type: "message_created",
-			Payload: MessageCreatedPayload{
-				ID:              currentAIMsg.ID.String(),
-				ChatID:          c.chatID.String(),
-				Role:            "assistant",
-				Content:         currentAIMsg.Content,
-				ToolCallsJSON:   string(frontendToolCallsJSON),
-				ToolResultsJSON: string(toolResultsJSON),
-				CreatedAt:       currentAIMsg.CreatedAt,
+				"run_id": nil,
 			},
 		})
-		c.send <- aiMsgJSON
-
-		log.Printf("[WS-CHAT] AI response %d done: content='%s', thinking='%s', new_tool_calls=%d, total=%d",
-			aiResponseIndex, currentAIMsg.Content, currentAIMsg.Thinking, len(newToolCalls), len(allToolCalls))
-
-		// If no new tool calls were made, we're done
-		if len(newToolCalls) == 0 {
-			log.Printf("[WS-CHAT] No new tool calls, finishing")
-			break
-		}
-
-		// Execute tool calls
-		for _, tc := range newToolCalls {
-			log.Printf("[WS-CHAT] Executing tool: %s", tc.Function.Name)
-
-			var args map[string]interface{}
-			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
-				log.Printf("[WS-CHAT] Failed to parse tool args: %v", err)
-				continue
-			}
+		return
+	}
 
-			toolCallJSON, _ := json.Marshal(ChatWSMessage{
-				Type: "tool_call",
-				Payload: map[string]interface{}{
-					"id":               tc.ID,
-					"name":             tc.Function.Name,
-					"arguments":        args,
-					"assistant_msg_id": currentAIMsgID.String(),
-				},
-			})
-			c.send <- toolCallJSON
-
-			tcToolContext := tools.ToolContext{
-				SessionID:   c.userID,
-				ProjectID:   c.chatID,
-				UserID:      c.userID,
-				ProjectRoot: projectRoot,
-				Mode:        "write",
-				Limits: tools.ToolLimits{
-					MaxFileBytes:     512 * 1024,
-					MaxOutputBytes:   1024 * 1024,
-					MaxSearchResults: 200,
-					MaxPatchFiles:    10,
-					MaxToolTime:      5 * time.Minute,
-				},
-			}
+	run.Attach(c, attach.SinceSeq)
+}
 
-			log.Printf("[WS-CHAT] Executing tool with projectRoot=%s", projectRoot)
-			result := tools.GlobalExecute(ctx, tc.Function.Name, args, tcToolContext)
+func (c *ChatWSClient) handleApproval(payload interface{}, approved bool) {
+	var approval ApprovalPayload
+	if err := decodePayload(payload, &approval); err != nil {
+		log.Printf("[WS-CHAT] Failed to decode approval payload: %v", err)
+		return
+	}
 
-			log.Printf("[WS-CHAT] Tool result: ok=%v", result.OK)
-			if !result.OK && result.Error != nil {
-				log.Printf("[WS-CHAT] Tool error: %s - %s", result.Error.Code, result.Error.Message)
-			}
+	run := ChatRuns.ForChat(c.chatID)
+	if run == nil || !run.ResolveApproval(approval.ID, approved, approval.Reason) {
+		log.Printf("[WS-CHAT] No pending approval %s for chat %s", approval.ID, c.chatID)
+	}
+}
 
-			resultJSON, _ := json.Marshal(ChatWSMessage{
-				Type: "tool.result",
-				Payload: map[string]interface{}{
-					"id":               tc.ID,
-					"name":             tc.Function.Name,
-					"ok":               result.OK,
-					"result":           result.Data,
-					"error":            result.Error,
-					"assistant_msg_id": currentAIMsgID.String(),
-				},
-			})
-			c.send <- resultJSON
-
-			toolResults = append(toolResults, map[string]interface{}{
-				"id":     tc.ID,
-				"name":   tc.Function.Name,
-				"ok":     result.OK,
-				"result": result.Data,
-				"error":  result.Error,
-			})
-		}
+func (c *ChatWSClient) handleReviewSubmit(payload interface{}) {
+	var review ReviewSubmitPayload
+	if err := decodePayload(payload, &review); err != nil {
+		log.Printf("[WS-CHAT] Failed to decode review payload: %v", err)
+		return
+	}
 
-		if len(toolResults) > 0 {
-			toolResultsJSON, _ := json.Marshal(toolResults)
-			toolMsg := &models.ChatMessage{
-				ID:              uuid.New(),
-				ChatID:          c.chatID,
-				Role:            "tool",
-				Content:         string(toolResultsJSON),
-				ToolResultsJSON: string(toolResultsJSON),
-				CreatedAt:       time.Now(),
-			}
-			if err := db.Insert(ctx, "chat_messages", toolMsg); err != nil {
-				log.Printf("[WS-CHAT] Failed to save tool results message: %v", err)
-			}
+	run := ChatRuns.ForChat(c.chatID)
+	if run == nil || !run.ResolveReview(review.ID, review.ReviewDecision) {
+		log.Printf("[WS-CHAT] No pending review %s for chat %s", review.ID, c.chatID)
+	}
+}
 
-			combinedContent := combineToolResults(toolResults)
-			messages = append(messages, provider.Message{
-				Role:       "tool",
-				Content:    combinedContent,
-				ToolCallID: "",
-			})
-		}
+func (c *ChatWSClient) trySend(data []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
 
-		// If no new tool calls were added during this iteration, we're done
-		if len(newToolCalls) == 0 && !hasNewContent && !hasNewThinking {
-			log.Printf("[WS-CHAT] No new tool calls, content, or thinking, stopping iteration")
-			break
-		}
+func (c *ChatWSClient) sendJSON(msg ChatWSMessage) {
+	data, _ := json.Marshal(msg)
+	c.trySend(data)
+}
 
-		log.Printf("[WS-CHAT] Making follow-up AI call %d", aiResponseIndex+1)
+func (c *ChatWSClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.send)
 	}
+}
 
-	// Send final idle status
-	statusIdleJSON, _ := json.Marshal(ChatWSMessage{
-		Type: "status",
-		Payload: map[string]interface{}{
-			"status": "idle",
-		},
-	})
-	c.send <- statusIdleJSON
+func decodePayload(payload interface{}, v interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
 }
 
-func (c *ChatWSClient) getChatMessages() ([]provider.Message, error) {
-	ctx := c.ctx
-	rows, err := db.Query(ctx, "SELECT id, chat_id, role, COALESCE(content, ''), COALESCE(tool_call_id, ''), COALESCE(tool_calls_json, ''), COALESCE(tool_results_json, ''), COALESCE(thinking, ''), created_at FROM chat_messages WHERE chat_id = $1 ORDER BY created_at ASC", c.chatID.String())
+func loadChatMessages(ctx context.Context, chatID uuid.UUID) ([]provider.Message, error) {
+	rows, err := db.Query(ctx, "SELECT id, chat_id, role, COALESCE(content, ''), COALESCE(tool_call_id, ''), COALESCE(tool_calls_json, ''), COALESCE(tool_results_json, ''), COALESCE(thinking, ''), created_at FROM chat_messages WHERE chat_id = $1 ORDER BY created_at ASC", chatID.String())
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var messages []provider.Message
+	pendingContext := ""
 	for rows.Next() {
 		var msg models.ChatMessage
 		var content, toolCallID, toolCallsJSON, toolResultsJSON, thinking string
@@ -643,6 +411,24 @@ func (c *ChatWSClient) getChatMessages() ([]provider.Message, error) {
 		msg.ToolResultsJSON = toolResultsJSON
 		msg.Thinking = thinking
 
+		// Editor context is stored as its own row and folded into the user
+		// message that follows it.
+		if msg.Role == "context" {
+			pendingContext = msg.Content
+			continue
+		}
+		// Review outcomes reach the model as the feedback they carried.
+		if msg.Role == "review" {
+			if msg.Content == "" {
+				continue
+			}
+			msg.Role = "user"
+		}
+		if msg.Role == "user" && pendingContext != "" {
+			msg.Content = pendingContext + "\n\n" + msg.Content
+			pendingContext = ""
+		}
+
 		providerMsg := provider.Message{
 			Role:       msg.Role,
 			Content:    msg.Content,
@@ -734,12 +520,50 @@ func combineToolResults(toolResults []map[string]interface{}) string {
 			result := tr["result"]
 			resultJSON, _ := json.Marshal(result)
 			combined.Write(resultJSON)
-		} else if err, ok := tr["error"].(map[string]interface{}); ok {
-			if msg, ok := err["message"].(string); ok {
-				combined.WriteString("ERROR: ")
-				combined.WriteString(msg)
-			}
+		} else {
+			combined.WriteString(formatToolError(tr["error"]))
+		}
+		if feedback, ok := tr["feedback"].(string); ok {
+			combined.WriteString("\n")
+			combined.WriteString(feedback)
 		}
 	}
 	return combined.String()
 }
+
+// formatToolError writes a failed call for the model: the code and message,
+// then the details (the current sha on a conflict, the closest lines for a
+// str_replace miss) as JSON.
+func formatToolError(v interface{}) string {
+	var toolErr tools.ToolError
+	switch e := v.(type) {
+	case *tools.ToolError:
+		if e == nil {
+			return "ERROR: tool failed"
+		}
+		toolErr = *e
+	case tools.ToolError:
+		toolErr = e
+	case map[string]interface{}:
+		toolErr.Code, _ = e["code"].(string)
+		toolErr.Message, _ = e["message"].(string)
+		toolErr.Details = e["details"]
+	default:
+		return "ERROR: tool failed"
+	}
+
+	var b strings.Builder
+	b.WriteString("ERROR: ")
+	if toolErr.Code != "" {
+		b.WriteString(toolErr.Code)
+		b.WriteString(": ")
+	}
+	b.WriteString(toolErr.Message)
+	if toolErr.Details != nil {
+		if details, err := json.Marshal(toolErr.Details); err == nil {
+			b.WriteString("\nDetails: ")
+			b.Write(details)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+func TestCombineToolResults(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  []map[string]interface{}
+		contains []string
+	}{
+		{
+			name: "success",
+			results: []map[string]interface{}{
+				{"id": "call_1", "ok": true, "result": map[string]interface{}{"content": "hello"}},
+			},
+			contains: []string{`{"content":"hello"}`},
+		},
+		{
+			name: "sha conflict keeps details",
+			results: []map[string]interface{}{
+				{"id": "call_1", "ok": false, "error": &tools.ToolError{
+					Code:    tools.ErrCodeConflict,
+					Message: "file changed since it was read",
+					Details: map[string]interface{}{"current_sha": "abc123"},
+				}},
+			},
+			contains: []string{"ERROR: CONFLICT: file changed since it was read", `"current_sha":"abc123"`},
+		},
+		{
+			name: "str_replace miss keeps closest lines",
+			results: []map[string]interface{}{
+				{"id": "call_1", "ok": false, "error": &tools.ToolError{
+					Code:    tools.ErrCodeValidation,
+					Message: "old_string not found",
+					Details: map[string]interface{}{"closest": []interface{}{map[string]interface{}{"line": 12, "text": "func main() {"}}},
+				}},
+			},
+			contains: []string{"old_string not found", `"line":12`, "func main() {"},
+		},
+		{
+			name: "decoded error map",
+			results: []map[string]interface{}{
+				{"id": "call_1", "ok": false, "error": map[string]interface{}{"code": "USER_REJECTED", "message": "rejected"}},
+			},
+			contains: []string{"ERROR: USER_REJECTED: rejected"},
+		},
+		{
+			name: "hook feedback after error",
+			results: []map[string]interface{}{
+				{"id": "call_1", "ok": false, "error": &tools.ToolError{Code: tools.ErrCodeHookBlocked, Message: "blocked"}, "feedback": "run the linter first"},
+				{"id": "call_2", "ok": true, "result": "done"},
+			},
+			contains: []string{"ERROR: HOOK_BLOCKED: blocked\nrun the linter first", `"done"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := combineToolResults(tt.results)
+			for _, want := range tt.contains {
+				if !strings.Contains(out, want) {
+					t.Errorf("combined results missing %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"errors"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/codenav"
+	"github.com/webide/ide/backend/internal/ai/fileindex"
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/projects"
+)
+
+func HandleCodeSymbols(c *fiber.Ctx) error {
+	if c.Query("path") == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "path is required"})
+	}
+	root, abs, err := codePath(c, c.Query("path"))
+	if err != nil {
+		return err
+	}
+	outline, err := codenav.ListSymbols(root, abs)
+	if err != nil {
+		return codeError(c, err)
+	}
+	return c.JSON(outline)
+}
+
+func HandleCodeDefinition(c *fiber.Ctx) error {
+	root, q, err := codeQuery(c)
+	if err != nil {
+		return err
+	}
+	defs, err := codenav.FindDefinition(root, q)
+	if err != nil {
+		return codeError(c, err)
+	}
+	return c.JSON(defs)
+}
+
+func HandleCodeReferences(c *fiber.Ctx) error {
+	root, q, err := codeQuery(c)
+	if err != nil {
+		return err
+	}
+	refs, err := codenav.FindReferences(root, q, c.QueryBool("include_declaration"))
+	if err != nil {
+		return codeError(c, err)
+	}
+	return c.JSON(refs)
+}
+
+func HandleCodePackage(c *fiber.Ctx) error {
+	p := c.Query("path")
+	if p == "" {
+		p = "."
+	}
+	root, abs, err := codePath(c, p)
+	if err != nil {
+		return err
+	}
+	api, err := codenav.PackageAPI(root, abs)
+	if err != nil {
+		return codeError(c, err)
+	}
+	return c.JSON(api)
+}
+
+// HandleFindFiles ranks project paths like find_files. Repeat glob= to give
+// several patterns.
+func HandleFindFiles(c *fiber.Ctx) error {
+	root, _, err := codePath(c, "")
+	if err != nil {
+		return err
+	}
+	var globs []string
+	for _, g := range c.Context().QueryArgs().PeekMulti("glob") {
+		globs = append(globs, string(g))
+	}
+	res, err := fileindex.For(root).Find(c.Query("query"), globs, c.QueryInt("limit", 50))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(res)
+}
+
+// codePath resolves a project path from a query parameter. Errors are
+// *fiber.Error, rendered as {"error": ...} by the server's error handler.
+func codePath(c *fiber.Ctx, p string) (string, string, error) {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return "", "", fiber.NewError(fiber.StatusBadRequest, "invalid project_id")
+	}
+
+	project, err := projects.GetProject(projectID)
+	if err != nil {
+		return "", "", fiber.NewError(fiber.StatusNotFound, "project not found")
+	}
+
+	if p == "" {
+		return project.RootPath, "", nil
+	}
+	abs, err := tools.NewPathGuard(project.RootPath, tools.ToolLimits{}).ResolveProjectPath(p)
+	if err != nil {
+		return "", "", fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	return project.RootPath, abs, nil
+}
+
+func codeQuery(c *fiber.Ctx) (string, codenav.Query, error) {
+	root, abs, err := codePath(c, c.Query("path"))
+	if err != nil {
+		return "", codenav.Query{}, err
+	}
+	return root, codenav.Query{
+		Symbol: c.Query("symbol"),
+		Path:   abs,
+		Line:   c.QueryInt("line"),
+		Column: c.QueryInt("column"),
+	}, nil
+}
+
+func codeError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, codenav.ErrInvalidQuery):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, codenav.ErrNotFound), errors.Is(err, os.ErrNotExist):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
@@ -0,0 +1,271 @@
+// Package codenav answers code navigation queries: file outlines,
+// definitions, references and the exported API of a package. Go code is
+// parsed and type-checked; other languages fall back to line-based
+// heuristics.
+package codenav
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	MaxSymbols     = 500
+	MaxDefinitions = 20
+	MaxReferences  = 200
+)
+
+var (
+	ErrNotFound     = errors.New("symbol not found")
+	ErrInvalidQuery = errors.New("give a symbol name, or a file path and line")
+)
+
+type Symbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Receiver  string `json:"receiver,omitempty"`
+	Exported  bool   `json:"exported"`
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	EndLine   int    `json:"end_line,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Doc       string `json:"doc,omitempty"`
+}
+
+// Location is a definition or a reference. External definitions live
+// outside the project (the standard library or a dependency) and carry the
+// package path instead of a file.
+type Location struct {
+	Path      string `json:"path,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	External  bool   `json:"external,omitempty"`
+	Package   string `json:"package,omitempty"`
+}
+
+// Query names a symbol either by Symbol ("Name", "pkg.Name", "Type.Method")
+// or by position (Path and Line, optionally Column, with Symbol picking the
+// identifier on that line). Path is absolute; a directory restricts a
+// name lookup to that package.
+type Query struct {
+	Symbol string
+	Path   string
+	Line   int
+	Column int
+}
+
+type Outline struct {
+	Path      string   `json:"path"`
+	Language  string   `json:"language"`
+	Symbols   []Symbol `json:"symbols"`
+	Truncated bool     `json:"truncated"`
+	Heuristic bool     `json:"heuristic"`
+}
+
+type Definitions struct {
+	Definitions []Location `json:"definitions"`
+	Heuristic   bool       `json:"heuristic"`
+}
+
+type References struct {
+	Definitions []Location `json:"definitions"`
+	References  []Location `json:"references"`
+	Total       int        `json:"total"`
+	Truncated   bool       `json:"truncated"`
+	Heuristic   bool       `json:"heuristic"`
+}
+
+type API struct {
+	Path       string   `json:"path"`
+	ImportPath string   `json:"import_path,omitempty"`
+	Name       string   `json:"name,omitempty"`
+	Doc        string   `json:"doc,omitempty"`
+	Symbols    []Symbol `json:"symbols"`
+	Truncated  bool     `json:"truncated"`
+	Heuristic  bool     `json:"heuristic"`
+}
+
+// ListSymbols outlines the file at absPath.
+func ListSymbols(root, absPath string) (*Outline, error) {
+	root = resolveRoot(root)
+	src, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	out := &Outline{Path: relPath(root, absPath), Language: language(absPath)}
+	if out.Language == "go" {
+		out.Symbols, err = goOutline(absPath, src, out.Path)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		out.Heuristic = true
+		out.Symbols = heuristicOutline(absPath, src, out.Path)
+	}
+	if len(out.Symbols) > MaxSymbols {
+		out.Symbols, out.Truncated = out.Symbols[:MaxSymbols], true
+	}
+	return out, nil
+}
+
+// FindDefinition locates where the queried symbol is declared.
+func FindDefinition(root string, q Query) (*Definitions, error) {
+	root = resolveRoot(root)
+	if q.Symbol == "" && (q.Path == "" || q.Line <= 0) {
+		return nil, ErrInvalidQuery
+	}
+	if useGo(root, q.Path) {
+		defs, err := goDefinitions(root, q)
+		if err == nil || !errors.Is(err, ErrNotFound) || q.Symbol == "" {
+			return &Definitions{Definitions: defs}, err
+		}
+	}
+	defs := heuristicDefinitions(root, q)
+	if len(defs) == 0 {
+		return nil, ErrNotFound
+	}
+	return &Definitions{Definitions: defs, Heuristic: true}, nil
+}
+
+// FindReferences lists the uses of the queried symbol across the project.
+func FindReferences(root string, q Query, includeDeclaration bool) (*References, error) {
+	root = resolveRoot(root)
+	if q.Symbol == "" && (q.Path == "" || q.Line <= 0) {
+		return nil, ErrInvalidQuery
+	}
+	var refs *References
+	if useGo(root, q.Path) {
+		var err error
+		refs, err = goReferences(root, q, includeDeclaration)
+		if err != nil && (!errors.Is(err, ErrNotFound) || q.Symbol == "") {
+			return nil, err
+		}
+	}
+	if refs == nil {
+		if q.Symbol == "" {
+			return nil, ErrNotFound
+		}
+		refs = heuristicReferences(root, q)
+		if len(refs.References) == 0 {
+			return nil, ErrNotFound
+		}
+	}
+	refs.Total = len(refs.References)
+	if refs.Total > MaxReferences {
+		refs.References, refs.Truncated = refs.References[:MaxReferences], true
+	}
+	return refs, nil
+}
+
+// PackageAPI lists the exported surface of the package in absDir.
+func PackageAPI(root, absDir string) (*API, error) {
+	root = resolveRoot(root)
+	info, err := os.Stat(absDir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		absDir = filepath.Dir(absDir)
+	}
+	var api *API
+	if hasGoFiles(absDir) {
+		api, err = goPackageAPI(root, absDir)
+	} else {
+		api, err = heuristicPackageAPI(root, absDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(api.Symbols) > MaxSymbols {
+		api.Symbols, api.Truncated = api.Symbols[:MaxSymbols], true
+	}
+	return api, nil
+}
+
+// useGo reports whether a query is answered by the Go index: a Go file or
+// package, or a name lookup in a project that has a go.mod.
+func useGo(root, path string) bool {
+	if path != "" {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return hasGoFiles(path)
+		}
+		return language(path) == "go"
+	}
+	return len(findModules(root)) > 0
+}
+
+func hasGoFiles(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") {
+			return true
+		}
+	}
+	return false
+}
+
+// skipDir reports directories that never hold project source.
+func skipDir(name string) bool {
+	switch name {
+	case "node_modules", "vendor", "testdata", "dist", "build", "target", "__pycache__":
+		return true
+	}
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// resolveRoot follows symlinks in the project root, as the paths given to
+// queries are already resolved.
+func resolveRoot(root string) string {
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		return resolved
+	}
+	return filepath.Clean(root)
+}
+
+func relPath(root, abs string) string {
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// firstSentence shortens a doc comment for listings.
+func firstSentence(doc string) string {
+	doc = strings.Join(strings.Fields(doc), " ")
+	if i := strings.Index(doc, ". "); i >= 0 {
+		doc = doc[:i+1]
+	}
+	if len(doc) > 200 {
+		doc = doc[:200] + "..."
+	}
+	return doc
+}
+
+// lineText returns line n (1-based) of a file, trimmed, using cache to read
+// each file once per query.
+func lineText(cache map[string][]string, path string, n int) string {
+	lines, ok := cache[path]
+	if !ok {
+		data, _ := os.ReadFile(path)
+		lines = strings.Split(string(data), "\n")
+		cache[path] = lines
+	}
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+	text := strings.TrimSpace(lines[n-1])
+	if len(text) > 200 {
+		text = text[:200] + "..."
+	}
+	return text
+}
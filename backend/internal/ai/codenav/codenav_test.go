@@ -0,0 +1,245 @@
+package codenav_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/codenav"
+)
+
+var projectFiles = map[string]string{
+	"go.mod": "module example.com/shop\n\ngo 1.21\n",
+	"shop.go": `// Package shop sells things.
+package shop
+
+// Item is something for sale.
+type Item struct {
+	Name  string
+	price int
+}
+
+// Price returns the price in cents.
+func (i Item) Price() int { return i.price }
+
+// Total adds up the prices.
+func Total(items []Item) int {
+	sum := 0
+	for _, it := range items {
+		sum += it.Price()
+	}
+	return discount(sum)
+}
+
+func discount(n int) int { return n }
+`,
+	"cmd/shop/main.go": `package main
+
+import "example.com/shop"
+
+func main() {
+	_ = shop.Total(nil)
+}
+`,
+	"web/cart.ts": `export interface Cart {
+  items: string[];
+}
+
+export function checkout(cart: Cart): number {
+  return cart.items.length;
+}
+
+const tax = 0.2;
+`,
+	"scripts/report.py": `class Report:
+    def render(self):
+        return ""
+
+def main():
+    Report().render()
+`,
+}
+
+// project writes projectFiles to a new directory and returns its resolved
+// path, as the tools pass it.
+func project(t *testing.T) string {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range projectFiles {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func symbolNames(symbols []codenav.Symbol) []string {
+	names := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		name := s.Name
+		if s.Receiver != "" {
+			name = s.Receiver + "." + name
+		}
+		names = append(names, s.Kind+" "+name)
+	}
+	return names
+}
+
+func TestListSymbols(t *testing.T) {
+	root := project(t)
+
+	tests := []struct {
+		file          string
+		wantLanguage  string
+		wantHeuristic bool
+		want          []string
+	}{
+		{file: "shop.go", wantLanguage: "go", want: []string{"struct Item", "field Item.Name", "method Item.Price", "func Total", "func discount"}},
+		{file: "web/cart.ts", wantLanguage: "javascript", wantHeuristic: true, want: []string{"interface Cart", "func checkout", "const tax"}},
+		{file: "scripts/report.py", wantLanguage: "python", wantHeuristic: true, want: []string{"class Report", "method Report.render", "func main"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			out, err := codenav.ListSymbols(root, filepath.Join(root, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Path != tt.file || out.Language != tt.wantLanguage || out.Heuristic != tt.wantHeuristic {
+				t.Errorf("outline = %s %s heuristic=%v, want %s %s heuristic=%v", out.Path, out.Language, out.Heuristic, tt.file, tt.wantLanguage, tt.wantHeuristic)
+			}
+			got := symbolNames(out.Symbols)
+			for _, want := range tt.want {
+				found := false
+				for _, g := range got {
+					found = found || g == want
+				}
+				if !found {
+					t.Errorf("symbols = %v, missing %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFindDefinition(t *testing.T) {
+	root := project(t)
+
+	tests := []struct {
+		name     string
+		query    codenav.Query
+		wantPath string
+		wantLine int
+		wantErr  error
+	}{
+		{name: "function by name", query: codenav.Query{Symbol: "Total"}, wantPath: "shop.go", wantLine: 14},
+		{name: "qualified name", query: codenav.Query{Symbol: "shop.Total"}, wantPath: "shop.go", wantLine: 14},
+		{name: "method", query: codenav.Query{Symbol: "Item.Price"}, wantPath: "shop.go", wantLine: 11},
+		{name: "by position", query: codenav.Query{Path: "cmd/shop/main.go", Line: 6, Symbol: "Total"}, wantPath: "shop.go", wantLine: 14},
+		{name: "heuristic fallback", query: codenav.Query{Symbol: "checkout"}, wantPath: "web/cart.ts", wantLine: 5},
+		{name: "unknown symbol", query: codenav.Query{Symbol: "Missing"}, wantErr: codenav.ErrNotFound},
+		{name: "empty query", query: codenav.Query{}, wantErr: codenav.ErrInvalidQuery},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.query
+			if q.Path != "" {
+				q.Path = filepath.Join(root, q.Path)
+			}
+			defs, err := codenav.FindDefinition(root, q)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(defs.Definitions) == 0 {
+				t.Fatal("no definitions")
+			}
+			if d := defs.Definitions[0]; d.Path != tt.wantPath || d.Line != tt.wantLine {
+				t.Errorf("definition = %s:%d, want %s:%d", d.Path, d.Line, tt.wantPath, tt.wantLine)
+			}
+		})
+	}
+}
+
+func TestFindReferences(t *testing.T) {
+	root := project(t)
+
+	tests := []struct {
+		name               string
+		symbol             string
+		includeDeclaration bool
+		want               []string
+	}{
+		{name: "across packages", symbol: "Total", want: []string{"cmd/shop/main.go:6"}},
+		{name: "with the declaration", symbol: "Total", includeDeclaration: true, want: []string{"shop.go:14", "cmd/shop/main.go:6"}},
+		{name: "method calls", symbol: "Item.Price", want: []string{"shop.go:17"}},
+		{name: "unexported function", symbol: "discount", want: []string{"shop.go:19"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, err := codenav.FindReferences(root, codenav.Query{Symbol: tt.symbol}, tt.includeDeclaration)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range refs.References {
+				got = append(got, fmt.Sprintf("%s:%d", r.Path, r.Line))
+			}
+			if !sameSet(got, tt.want) {
+				t.Errorf("references = %v, want %v", got, tt.want)
+			}
+			if refs.Total != len(tt.want) {
+				t.Errorf("total = %d, want %d", refs.Total, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestPackageAPI(t *testing.T) {
+	root := project(t)
+
+	api, err := codenav.PackageAPI(root, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.Name != "shop" || api.ImportPath != "example.com/shop" {
+		t.Errorf("package = %s %s, want shop example.com/shop", api.Name, api.ImportPath)
+	}
+	got := symbolNames(api.Symbols)
+	if !sameSet(got, []string{"struct Item", "method Item.Price", "func Total"}) {
+		t.Errorf("symbols = %v, want the exported Item, Item.Price and Total", got)
+	}
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]int{}
+	for _, s := range a {
+		seen[s]++
+	}
+	for _, s := range b {
+		if seen[s] == 0 {
+			return false
+		}
+		seen[s]--
+	}
+	return true
+}
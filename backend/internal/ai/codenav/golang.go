@@ -0,0 +1,927 @@
+package codenav
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxModulePackages = 2000
+	goListTimeout     = 60 * time.Second
+)
+
+// goIndex caches type-checked packages of one Go module. A package is
+// checked again when its files, or a module package it imports, change.
+// Packages outside the module are loaded from compiler export data.
+type goIndex struct {
+	mu       sync.Mutex
+	dir      string
+	path     string
+	modStamp string
+
+	fset     *token.FileSet
+	external types.Importer
+	exports  map[string]string
+	primed   bool
+	pkgs     map[string]*goPackage
+	// verified holds the packages found fresh during the current query.
+	verified map[string]bool
+}
+
+type goPackage struct {
+	dir        string
+	importPath string
+	stamp      string
+	files      []*ast.File
+	pkg        *types.Package
+	info       *types.Info
+	imports    []*goPackage
+	checking   bool
+}
+
+var (
+	indexesMu sync.Mutex
+	indexes   = map[string]*goIndex{}
+)
+
+// moduleFor returns the index of the module containing dir.
+func moduleFor(root, dir string) (*goIndex, error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return indexFor(d)
+		}
+		if d == root || d == filepath.Dir(d) || !strings.HasPrefix(d, root) {
+			return nil, fmt.Errorf("no go.mod found above %s", relPath(root, dir))
+		}
+	}
+}
+
+func indexFor(modDir string) (*goIndex, error) {
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+
+	stamp := fileStamp(filepath.Join(modDir, "go.mod")) + fileStamp(filepath.Join(modDir, "go.sum"))
+	if idx := indexes[modDir]; idx != nil && idx.modStamp == stamp {
+		return idx, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(modDir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	var modPath string
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			modPath = strings.Trim(strings.TrimSpace(rest), `"`)
+			break
+		}
+	}
+	if modPath == "" {
+		return nil, fmt.Errorf("no module line in %s", filepath.Join(modDir, "go.mod"))
+	}
+
+	idx := &goIndex{
+		dir:      modDir,
+		path:     modPath,
+		modStamp: stamp,
+		fset:     token.NewFileSet(),
+		exports:  map[string]string{},
+		pkgs:     map[string]*goPackage{},
+	}
+	idx.external = importer.ForCompiler(idx.fset, "gc", idx.lookupExport)
+	indexes[modDir] = idx
+	return idx, nil
+}
+
+// findModules returns the directories under root that hold a go.mod.
+func findModules(root string) []string {
+	var mods []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			if rel, _ := filepath.Rel(root, path); strings.Count(rel, string(filepath.Separator)) >= 3 {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "go.mod" {
+			mods = append(mods, filepath.Dir(path))
+		}
+		return nil
+	})
+	return mods
+}
+
+func (idx *goIndex) begin() {
+	idx.mu.Lock()
+	idx.verified = map[string]bool{}
+}
+
+func (idx *goIndex) end() {
+	idx.verified = nil
+	idx.mu.Unlock()
+}
+
+// load returns the type-checked package in dir. Type errors are tolerated so
+// that code in the middle of an edit can still be navigated.
+func (idx *goIndex) load(dir string) (*goPackage, error) {
+	if p := idx.pkgs[dir]; p != nil && (p.checking || idx.fresh(p)) {
+		return p, nil
+	}
+
+	names, stamp := goFiles(dir)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	p := &goPackage{dir: dir, importPath: idx.path, stamp: stamp, checking: true}
+	if rel, _ := filepath.Rel(idx.dir, dir); rel != "." {
+		p.importPath = idx.path + "/" + filepath.ToSlash(rel)
+	}
+	idx.pkgs[dir] = p
+	defer func() { p.checking = false }()
+
+	for _, name := range names {
+		f, err := parser.ParseFile(idx.fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if f != nil {
+			p.files = append(p.files, f)
+		} else if err != nil {
+			delete(idx.pkgs, dir)
+			return nil, err
+		}
+	}
+
+	p.info = &types.Info{
+		Defs: map[*ast.Ident]types.Object{},
+		Uses: map[*ast.Ident]types.Object{},
+	}
+	conf := types.Config{
+		Importer:    importerFunc(func(path string) (*types.Package, error) { return idx.importFor(p, path) }),
+		Error:       func(error) {},
+		FakeImportC: true,
+	}
+	p.pkg, _ = conf.Check(p.importPath, idx.fset, p.files, p.info)
+	idx.verified[dir] = true
+	return p, nil
+}
+
+// fresh reports whether p and the module packages it imports are unchanged.
+func (idx *goIndex) fresh(p *goPackage) bool {
+	if ok, seen := idx.verified[p.dir]; seen {
+		return ok
+	}
+	idx.verified[p.dir] = true
+	_, stamp := goFiles(p.dir)
+	ok := stamp == p.stamp
+	for _, dep := range p.imports {
+		if !ok {
+			break
+		}
+		// A reloaded import means p was checked against old types.
+		ok = idx.pkgs[dep.dir] == dep && idx.fresh(dep)
+	}
+	idx.verified[p.dir] = ok
+	return ok
+}
+
+func (idx *goIndex) importFor(p *goPackage, path string) (*types.Package, error) {
+	if path == idx.path || strings.HasPrefix(path, idx.path+"/") {
+		dir := filepath.Join(idx.dir, filepath.FromSlash(strings.TrimPrefix(path, idx.path)))
+		dep, err := idx.load(dir)
+		if err != nil {
+			return nil, err
+		}
+		if dep.checking {
+			return nil, fmt.Errorf("import cycle through %s", path)
+		}
+		p.imports = append(p.imports, dep)
+		return dep.pkg, nil
+	}
+	return idx.external.Import(path)
+}
+
+// lookupExport opens the export data of an external package. The first
+// lookup asks the go command for every dependency of the module at once;
+// packages it missed are asked for one by one.
+func (idx *goIndex) lookupExport(path string) (io.ReadCloser, error) {
+	if !idx.primed {
+		idx.primed = true
+		idx.listExports("./...")
+	}
+	if _, ok := idx.exports[path]; !ok {
+		idx.listExports(path)
+		if _, ok := idx.exports[path]; !ok {
+			idx.exports[path] = ""
+		}
+	}
+	file := idx.exports[path]
+	if file == "" {
+		return nil, fmt.Errorf("no export data for %s", path)
+	}
+	return os.Open(file)
+}
+
+func (idx *goIndex) listExports(pattern string) {
+	ctx, cancel := context.WithTimeout(context.Background(), goListTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "go", "list", "-e", "-export", "-deps", "-f", "{{.ImportPath}}\t{{.Export}}", pattern)
+	cmd.Dir = idx.dir
+	// Never reach the network for a navigation query.
+	cmd.Env = append(os.Environ(), "GOPROXY=off")
+	out, _ := cmd.Output()
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if pkg, export, found := strings.Cut(scanner.Text(), "\t"); found && export != "" {
+			idx.exports[pkg] = export
+		}
+	}
+}
+
+// packages loads every package of the module.
+func (idx *goIndex) packages() []*goPackage {
+	var pkgs []*goPackage
+	filepath.WalkDir(idx.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != idx.dir {
+			if skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		if len(pkgs) >= maxModulePackages {
+			return filepath.SkipAll
+		}
+		if p, err := idx.load(path); err == nil {
+			pkgs = append(pkgs, p)
+		}
+		return nil
+	})
+	return pkgs
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// goFiles lists the non-test Go files of dir that build on this platform,
+// with a stamp that changes when any of them does.
+func goFiles(dir string) ([]string, string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, ""
+	}
+	var names []string
+	var stamp strings.Builder
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		names = append(names, name)
+		stamp.WriteString(name + fileStamp(filepath.Join(dir, name)) + ";")
+	}
+	return names, stamp.String()
+}
+
+func fileStamp(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf(":%d:%d", info.Size(), info.ModTime().UnixNano())
+}
+
+// goOutline lists the declarations of one file. It only parses, so it works
+// on files that do not compile.
+func goOutline(absPath string, src []byte, rel string) ([]Symbol, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, absPath, src, parser.ParseComments)
+	if f == nil {
+		return nil, err
+	}
+	return outlineFile(fset, f, rel), nil
+}
+
+func outlineFile(fset *token.FileSet, f *ast.File, rel string) []Symbol {
+	var symbols []Symbol
+	add := func(node ast.Node, name, kind, recv, sig string, doc *ast.CommentGroup) {
+		symbols = append(symbols, Symbol{
+			Name:      name,
+			Kind:      kind,
+			Receiver:  recv,
+			Exported:  ast.IsExported(name),
+			Path:      rel,
+			Line:      fset.Position(node.Pos()).Line,
+			EndLine:   fset.Position(node.End()).Line,
+			Signature: sig,
+			Doc:       firstSentence(doc.Text()),
+		})
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			kind, recv := "func", ""
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				kind, recv = "method", recvName(d.Recv.List[0].Type)
+			}
+			add(d, d.Name.Name, kind, recv, nodeString(fset, &ast.FuncDecl{Recv: d.Recv, Name: d.Name, Type: d.Type}), d.Doc)
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					doc := s.Doc
+					if doc == nil && len(d.Specs) == 1 {
+						doc = d.Doc
+					}
+					kind := "type"
+					switch t := s.Type.(type) {
+					case *ast.StructType:
+						kind = "struct"
+						for _, field := range t.Fields.List {
+							for _, n := range field.Names {
+								add(n, n.Name, "field", s.Name.Name, n.Name+" "+nodeString(fset, field.Type), field.Doc)
+							}
+						}
+					case *ast.InterfaceType:
+						kind = "interface"
+						for _, m := range t.Methods.List {
+							for _, n := range m.Names {
+								add(n, n.Name, "method", s.Name.Name, n.Name+strings.TrimPrefix(nodeString(fset, m.Type), "func"), m.Doc)
+							}
+						}
+					}
+					add(s, s.Name.Name, kind, "", typeSignature(fset, s), doc)
+
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					doc := s.Doc
+					if doc == nil {
+						doc = d.Doc
+					}
+					for _, n := range s.Names {
+						sig := kind + " " + n.Name
+						if s.Type != nil {
+							sig += " " + nodeString(fset, s.Type)
+						}
+						add(n, n.Name, kind, "", sig, doc)
+					}
+				}
+			}
+		}
+	}
+	return symbols
+}
+
+func recvName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return recvName(t.X)
+	case *ast.IndexExpr:
+		return recvName(t.X)
+	case *ast.IndexListExpr:
+		return recvName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+func typeSignature(fset *token.FileSet, s *ast.TypeSpec) string {
+	sig := "type " + s.Name.Name
+	if s.TypeParams != nil {
+		sig += nodeString(fset, s.TypeParams)
+	}
+	if s.Assign.IsValid() {
+		sig += " ="
+	}
+	switch s.Type.(type) {
+	case *ast.StructType:
+		return sig + " struct"
+	case *ast.InterfaceType:
+		return sig + " interface"
+	}
+	return sig + " " + nodeString(fset, s.Type)
+}
+
+func nodeString(fset *token.FileSet, node interface{}) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, node)
+	s := strings.Join(strings.Fields(buf.String()), " ")
+	if len(s) > 300 {
+		s = s[:300] + "..."
+	}
+	return s
+}
+
+// goTargets resolves a query to the objects it names, loading the packages
+// it needs. The caller holds idx.mu.
+func goTargets(root string, idx *goIndex, q Query) ([]types.Object, error) {
+	if q.Path != "" && q.Line > 0 {
+		info, err := os.Stat(q.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			p, err := idx.load(filepath.Dir(q.Path))
+			if err != nil {
+				return nil, err
+			}
+			obj := objectAt(idx.fset, p, q)
+			if obj == nil {
+				return nil, ErrNotFound
+			}
+			return []types.Object{obj}, nil
+		}
+	}
+
+	parts := strings.Split(q.Symbol, ".")
+	var pkgs []*goPackage
+	if q.Path != "" {
+		dir := q.Path
+		if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+		p, err := idx.load(dir)
+		if err != nil {
+			return nil, err
+		}
+		pkgs = []*goPackage{p}
+	} else {
+		pkgs = idx.packages()
+	}
+
+	var objs []types.Object
+	for _, p := range pkgs {
+		if p.pkg == nil {
+			continue
+		}
+		names := parts
+		if len(names) > 1 && (p.pkg.Name() == names[0] || strings.HasSuffix(p.importPath, "/"+names[0])) {
+			names = names[1:]
+		}
+		objs = append(objs, lookupInPackage(p.pkg, names)...)
+		if len(objs) >= MaxDefinitions {
+			break
+		}
+	}
+	// "pkg.Name" may also name a package outside the module that one of
+	// these packages imports.
+	if len(objs) == 0 && len(parts) > 1 {
+	imports:
+		for _, p := range pkgs {
+			if p.pkg == nil {
+				continue
+			}
+			for _, imp := range p.pkg.Imports() {
+				if imp.Name() == parts[0] || strings.HasSuffix(imp.Path(), "/"+parts[0]) {
+					if objs = lookupInPackage(imp, parts[1:]); len(objs) > 0 {
+						break imports
+					}
+				}
+			}
+		}
+	}
+	if len(objs) == 0 {
+		return nil, ErrNotFound
+	}
+	return objs, nil
+}
+
+func lookupInPackage(pkg *types.Package, names []string) []types.Object {
+	scope := pkg.Scope()
+	switch len(names) {
+	case 1:
+		var objs []types.Object
+		if obj := scope.Lookup(names[0]); obj != nil {
+			objs = append(objs, obj)
+		}
+		// A bare name also finds methods declared in the package.
+		for _, n := range scope.Names() {
+			tn, ok := scope.Lookup(n).(*types.TypeName)
+			if !ok {
+				continue
+			}
+			if named, ok := tn.Type().(*types.Named); ok {
+				for i := 0; i < named.NumMethods(); i++ {
+					if m := named.Method(i); m.Name() == names[0] {
+						objs = append(objs, m)
+					}
+				}
+			}
+		}
+		return objs
+	case 2:
+		tn, ok := scope.Lookup(names[0]).(*types.TypeName)
+		if !ok {
+			return nil
+		}
+		if obj, _, _ := types.LookupFieldOrMethod(tn.Type(), true, pkg, names[1]); obj != nil {
+			return []types.Object{obj}
+		}
+	}
+	return nil
+}
+
+// objectAt finds the object denoted by the identifier at the query position.
+func objectAt(fset *token.FileSet, p *goPackage, q Query) types.Object {
+	var file *ast.File
+	for _, f := range p.files {
+		if fset.Position(f.Pos()).Filename == q.Path {
+			file = f
+		}
+	}
+	if file == nil {
+		return nil
+	}
+
+	var found types.Object
+	ast.Inspect(file, func(n ast.Node) bool {
+		if found != nil {
+			return false
+		}
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		pos := fset.Position(id.Pos())
+		if pos.Line != q.Line {
+			return true
+		}
+		if q.Column > 0 && (q.Column < pos.Column || q.Column > pos.Column+len(id.Name)) {
+			return true
+		}
+		if q.Column == 0 && q.Symbol != "" && id.Name != lastPart(q.Symbol) {
+			return true
+		}
+		if obj := p.info.Uses[id]; obj != nil {
+			found = obj
+		} else if obj := p.info.Defs[id]; obj != nil {
+			found = obj
+		}
+		return true
+	})
+	return found
+}
+
+func lastPart(symbol string) string {
+	parts := strings.Split(symbol, ".")
+	return parts[len(parts)-1]
+}
+
+func goDefinitions(root string, q Query) ([]Location, error) {
+	dir := root
+	if q.Path != "" {
+		dir = q.Path
+		if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+	}
+
+	var defs []Location
+	lines := map[string][]string{}
+	for _, idx := range queryIndexes(root, dir, q.Path == "") {
+		idx.begin()
+		objs, err := goTargets(root, idx, q)
+		if err == nil {
+			for _, obj := range objs {
+				defs = append(defs, objectLocation(root, idx.fset, obj, lines))
+			}
+		}
+		idx.end()
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+	}
+	if len(defs) == 0 {
+		return nil, ErrNotFound
+	}
+	if len(defs) > MaxDefinitions {
+		defs = defs[:MaxDefinitions]
+	}
+	return defs, nil
+}
+
+func goReferences(root string, q Query, includeDeclaration bool) (*References, error) {
+	dir := root
+	if q.Path != "" {
+		dir = q.Path
+		if info, err := os.Stat(dir); err == nil && !info.IsDir() {
+			dir = filepath.Dir(dir)
+		}
+	}
+
+	// References are searched in every module of the project, since one
+	// module may use another through a replace directive.
+	var targets map[string]bool
+	refs := &References{Definitions: []Location{}, References: []Location{}}
+	lines := map[string][]string{}
+	for _, idx := range queryIndexes(root, dir, q.Path == "") {
+		idx.begin()
+		objs, err := goTargets(root, idx, q)
+		if err == nil {
+			targets = map[string]bool{}
+			for _, obj := range objs {
+				targets[objectKey(idx.fset, obj)] = true
+				refs.Definitions = append(refs.Definitions, objectLocation(root, idx.fset, obj, lines))
+			}
+		}
+		idx.end()
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if targets != nil {
+			break
+		}
+	}
+	if targets == nil {
+		return nil, ErrNotFound
+	}
+
+	for _, modDir := range findModules(root) {
+		idx, err := indexFor(modDir)
+		if err != nil {
+			continue
+		}
+		idx.begin()
+		for _, p := range idx.packages() {
+			collect := func(idents map[*ast.Ident]types.Object, kind string) {
+				for id, obj := range idents {
+					if obj == nil || !targets[objectKey(idx.fset, obj)] {
+						continue
+					}
+					pos := idx.fset.Position(id.Pos())
+					rel := relPath(root, pos.Filename)
+					if rel == "" {
+						continue
+					}
+					refs.References = append(refs.References, Location{
+						Path:   rel,
+						Line:   pos.Line,
+						Column: pos.Column,
+						Text:   lineText(lines, pos.Filename, pos.Line),
+						Name:   id.Name,
+						Kind:   kind,
+					})
+				}
+			}
+			collect(p.info.Uses, "use")
+			if includeDeclaration {
+				collect(p.info.Defs, "declaration")
+			}
+		}
+		idx.end()
+	}
+
+	sort.Slice(refs.References, func(i, j int) bool {
+		a, b := refs.References[i], refs.References[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return refs, nil
+}
+
+// queryIndexes returns the module index for dir, or with all set the index
+// of every module in the project.
+func queryIndexes(root, dir string, all bool) []*goIndex {
+	var idxs []*goIndex
+	if all {
+		for _, modDir := range findModules(root) {
+			if idx, err := indexFor(modDir); err == nil {
+				idxs = append(idxs, idx)
+			}
+		}
+		return idxs
+	}
+	if idx, err := moduleFor(root, dir); err == nil {
+		idxs = append(idxs, idx)
+	}
+	return idxs
+}
+
+// objectKey identifies an object across packages and modules: package-level
+// objects and methods by name, anything else by its declaration position.
+func objectKey(fset *token.FileSet, obj types.Object) string {
+	switch o := obj.(type) {
+	case *types.Func:
+		obj = o.Origin()
+	case *types.Var:
+		obj = o.Origin()
+	}
+	if obj.Pkg() == nil {
+		return "builtin." + obj.Name()
+	}
+	if pn, ok := obj.(*types.PkgName); ok {
+		return "package " + pn.Imported().Path()
+	}
+	if obj.Parent() == obj.Pkg().Scope() {
+		return obj.Pkg().Path() + "." + obj.Name()
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		if sig, ok := fn.Type().(*types.Signature); ok && sig.Recv() != nil {
+			return obj.Pkg().Path() + "." + recvTypeName(sig.Recv().Type()) + "." + obj.Name()
+		}
+	}
+	pos := fset.Position(obj.Pos())
+	return fmt.Sprintf("%s:%d:%d", pos.Filename, pos.Line, pos.Column)
+}
+
+func recvTypeName(t types.Type) string {
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	switch n := t.(type) {
+	case *types.Named:
+		return n.Obj().Name()
+	case *types.Alias:
+		return n.Obj().Name()
+	}
+	return t.String()
+}
+
+func objectLocation(root string, fset *token.FileSet, obj types.Object, lines map[string][]string) Location {
+	loc := Location{Name: obj.Name(), Kind: objectKind(obj)}
+	if pn, ok := obj.(*types.PkgName); ok {
+		loc.Package = pn.Imported().Path()
+		loc.Signature = "package " + pn.Imported().Name()
+		loc.External = true
+		return loc
+	}
+	if obj.Pkg() != nil {
+		loc.Package = obj.Pkg().Path()
+		loc.Signature = objectSignature(obj)
+	}
+
+	pos := fset.Position(obj.Pos())
+	rel := relPath(root, pos.Filename)
+	if !pos.IsValid() || rel == "" {
+		loc.External = true
+		return loc
+	}
+	loc.Path = rel
+	loc.Line = pos.Line
+	loc.Column = pos.Column
+	loc.Text = lineText(lines, pos.Filename, pos.Line)
+	return loc
+}
+
+// objectSignature is the declaration of obj, leaving out the bodies of
+// struct and interface types.
+func objectSignature(obj types.Object) string {
+	if tn, ok := obj.(*types.TypeName); ok && !tn.IsAlias() {
+		switch tn.Type().Underlying().(type) {
+		case *types.Struct:
+			return "type " + tn.Name() + " struct"
+		case *types.Interface:
+			return "type " + tn.Name() + " interface"
+		}
+	}
+	return types.ObjectString(obj, types.RelativeTo(obj.Pkg()))
+}
+
+func objectKind(obj types.Object) string {
+	switch o := obj.(type) {
+	case *types.Func:
+		if sig, ok := o.Type().(*types.Signature); ok && sig.Recv() != nil {
+			return "method"
+		}
+		return "func"
+	case *types.TypeName:
+		switch o.Type().Underlying().(type) {
+		case *types.Struct:
+			return "struct"
+		case *types.Interface:
+			return "interface"
+		}
+		return "type"
+	case *types.Const:
+		return "const"
+	case *types.Var:
+		if o.IsField() {
+			return "field"
+		}
+		return "var"
+	case *types.PkgName:
+		return "package"
+	}
+	return "object"
+}
+
+func goPackageAPI(root, dir string) (*API, error) {
+	idx, err := moduleFor(root, dir)
+	if err != nil {
+		return nil, err
+	}
+	idx.begin()
+	defer idx.end()
+
+	p, err := idx.load(dir)
+	if err != nil {
+		return nil, err
+	}
+	api := &API{Path: relPath(root, dir), ImportPath: p.importPath, Symbols: []Symbol{}}
+	if p.pkg == nil {
+		return api, nil
+	}
+	api.Name = p.pkg.Name()
+
+	// Doc comments, and type signatures without their bodies, come from
+	// the syntax.
+	outlined := map[string]Symbol{}
+	for _, f := range p.files {
+		if f.Doc != nil && api.Doc == "" {
+			api.Doc = firstSentence(f.Doc.Text())
+		}
+		for _, s := range outlineFile(idx.fset, f, relPath(root, idx.fset.Position(f.Pos()).Filename)) {
+			key := s.Name
+			if s.Receiver != "" {
+				key = s.Receiver + "." + s.Name
+			}
+			outlined[key] = s
+		}
+	}
+
+	qualifier := types.RelativeTo(p.pkg)
+	add := func(obj types.Object, recv string) {
+		pos := idx.fset.Position(obj.Pos())
+		key := obj.Name()
+		if recv != "" {
+			key = recv + "." + key
+		}
+		sig := types.ObjectString(obj, qualifier)
+		if _, ok := obj.(*types.TypeName); ok && outlined[key].Signature != "" {
+			sig = outlined[key].Signature
+		}
+		api.Symbols = append(api.Symbols, Symbol{
+			Name:      obj.Name(),
+			Kind:      objectKind(obj),
+			Receiver:  recv,
+			Exported:  true,
+			Path:      relPath(root, pos.Filename),
+			Line:      pos.Line,
+			Signature: sig,
+			Doc:       outlined[key].Doc,
+		})
+	}
+
+	scope := p.pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		add(obj, "")
+		tn, ok := obj.(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if named, ok := tn.Type().(*types.Named); ok {
+			for i := 0; i < named.NumMethods(); i++ {
+				if m := named.Method(i); m.Exported() {
+					add(m, name)
+				}
+			}
+		}
+	}
+	return api, nil
+}
@@ -0,0 +1,306 @@
+package codenav
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxHeuristicFiles     = 10000
+	maxHeuristicFileBytes = 1024 * 1024
+)
+
+type symbolPattern struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+// symbolPatterns match declarations line by line; the "name" group is the
+// declared name.
+var symbolPatterns = map[string][]symbolPattern{
+	"javascript": {
+		{"func", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\*?\s+(?P<name>[A-Za-z_$][\w$]*)`)},
+		{"class", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+(?P<name>[A-Za-z_$][\w$]*)`)},
+		{"interface", regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?interface\s+(?P<name>[A-Za-z_$][\w$]*)`)},
+		{"type", regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?type\s+(?P<name>[A-Za-z_$][\w$]*)\s*[=<]`)},
+		{"enum", regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?(?:const\s+)?enum\s+(?P<name>[A-Za-z_$][\w$]*)`)},
+		{"const", regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+(?P<name>[A-Za-z_$][\w$]*)\s*[:=]`)},
+		{"method", regexp.MustCompile(`^\s+(?:(?:public|private|protected|static|async|readonly|override)\s+)*(?P<name>[A-Za-z_$][\w$]*)\s*\([^)]*\)\s*(?::\s*[^{]+)?\{\s*$`)},
+	},
+	"python": {
+		{"func", regexp.MustCompile(`^\s*(?:async\s+)?def\s+(?P<name>\w+)`)},
+		{"class", regexp.MustCompile(`^\s*class\s+(?P<name>\w+)`)},
+	},
+	"rust": {
+		{"func", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?fn\s+(?P<name>\w+)`)},
+		{"struct", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?struct\s+(?P<name>\w+)`)},
+		{"enum", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?enum\s+(?P<name>\w+)`)},
+		{"trait", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?trait\s+(?P<name>\w+)`)},
+		{"type", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?type\s+(?P<name>\w+)`)},
+		{"const", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const|static)\s+(?P<name>\w+)\s*:`)},
+	},
+	"java": {
+		{"class", regexp.MustCompile(`^\s*(?:(?:public|private|protected|abstract|final|static|sealed|data|open|internal)\s+)*(?:class|interface|enum|record|object)\s+(?P<name>\w+)`)},
+		{"method", regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|final|abstract|synchronized|override|suspend)\s+)+[\w<>\[\], ?]+\s+(?P<name>\w+)\s*\(`)},
+		{"func", regexp.MustCompile(`^\s*(?:(?:public|private|protected|internal|override|suspend|inline)\s+)*fun\s+(?:<[^>]+>\s*)?(?:\w+\.)?(?P<name>\w+)\s*\(`)},
+	},
+	"ruby": {
+		{"func", regexp.MustCompile(`^\s*def\s+(?:self\.)?(?P<name>\w+[?!=]?)`)},
+		{"class", regexp.MustCompile(`^\s*(?:class|module)\s+(?P<name>[A-Z]\w*)`)},
+	},
+	"php": {
+		{"func", regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|abstract|final)\s+)*function\s+(?P<name>\w+)`)},
+		{"class", regexp.MustCompile(`^\s*(?:(?:abstract|final)\s+)?(?:class|interface|trait|enum)\s+(?P<name>\w+)`)},
+	},
+	"c": {
+		{"struct", regexp.MustCompile(`^\s*(?:typedef\s+)?(?:struct|union|enum|class)\s+(?P<name>\w+)\s*[{:]?\s*$`)},
+		{"func", regexp.MustCompile(`^[A-Za-z_][\w\s\*&:<>,]*?\b(?P<name>[A-Za-z_]\w*)\s*\([^;]*\)\s*(?:const\s*)?\{?\s*$`)},
+		{"macro", regexp.MustCompile(`^\s*#\s*define\s+(?P<name>\w+)`)},
+	},
+}
+
+var languageByExt = map[string]string{
+	".go":   "go",
+	".js":   "javascript",
+	".jsx":  "javascript",
+	".mjs":  "javascript",
+	".cjs":  "javascript",
+	".ts":   "javascript",
+	".tsx":  "javascript",
+	".vue":  "javascript",
+	".py":   "python",
+	".rs":   "rust",
+	".java": "java",
+	".kt":   "java",
+	".cs":   "java",
+	".rb":   "ruby",
+	".php":  "php",
+	".c":    "c",
+	".h":    "c",
+	".cc":   "c",
+	".cpp":  "c",
+	".hpp":  "c",
+}
+
+func language(path string) string {
+	if lang, ok := languageByExt[strings.ToLower(filepath.Ext(path))]; ok {
+		return lang
+	}
+	return "text"
+}
+
+func heuristicOutline(absPath string, src []byte, rel string) []Symbol {
+	lang := language(absPath)
+	patterns := symbolPatterns[lang]
+	var symbols []Symbol
+
+	// The enclosing class, for methods of indented languages.
+	var class string
+	classIndent := -1
+	for i, line := range strings.Split(string(src), "\n") {
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if class != "" && strings.TrimSpace(line) != "" && indent <= classIndent {
+			class, classIndent = "", -1
+		}
+		for _, p := range patterns {
+			m := p.re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			name := m[p.re.SubexpIndex("name")]
+			if isKeyword(name) {
+				continue
+			}
+			sym := Symbol{
+				Name:      name,
+				Kind:      p.kind,
+				Exported:  heuristicExported(lang, line, name),
+				Path:      rel,
+				Line:      i + 1,
+				Signature: strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), "{")),
+			}
+			if len(sym.Signature) > 200 {
+				sym.Signature = sym.Signature[:200] + "..."
+			}
+			if class != "" && indent > classIndent && (p.kind == "func" || p.kind == "method") {
+				sym.Kind, sym.Receiver = "method", class
+			}
+			if p.kind == "class" {
+				class, classIndent = name, indent
+			}
+			symbols = append(symbols, sym)
+			break
+		}
+	}
+	return symbols
+}
+
+func isKeyword(name string) bool {
+	switch name {
+	case "if", "for", "while", "switch", "catch", "return", "function", "else", "new", "sizeof", "do":
+		return true
+	}
+	return false
+}
+
+func heuristicExported(lang, line, name string) bool {
+	trimmed := strings.TrimSpace(line)
+	switch lang {
+	case "javascript":
+		return strings.HasPrefix(trimmed, "export ")
+	case "python", "ruby":
+		return !strings.HasPrefix(name, "_")
+	case "rust":
+		return strings.HasPrefix(trimmed, "pub")
+	case "java", "php":
+		return !strings.Contains(trimmed, "private ") && !strings.Contains(trimmed, "protected ")
+	}
+	return true
+}
+
+// walkSource calls fn for each source file of a known language under root,
+// limited to lang when it is set.
+func walkSource(root, lang string, fn func(path string, src []byte)) {
+	files := 0
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		l := language(path)
+		if l == "text" || (lang != "" && l != lang) {
+			return nil
+		}
+		if files++; files > maxHeuristicFiles {
+			return filepath.SkipAll
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxHeuristicFileBytes {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err == nil {
+			fn(path, src)
+		}
+		return nil
+	})
+}
+
+// queryLanguage limits heuristic searches to the language of the query
+// path, if any.
+func queryLanguage(q Query) string {
+	if q.Path == "" {
+		return ""
+	}
+	if info, err := os.Stat(q.Path); err == nil && info.IsDir() {
+		return ""
+	}
+	if lang := language(q.Path); lang != "text" {
+		return lang
+	}
+	return ""
+}
+
+func heuristicDefinitions(root string, q Query) []Location {
+	name := q.Symbol
+	if name == "" {
+		return nil
+	}
+	parts := strings.Split(name, ".")
+	name = parts[len(parts)-1]
+	receiver := ""
+	if len(parts) > 1 {
+		receiver = parts[len(parts)-2]
+	}
+
+	var defs []Location
+	walkSource(root, queryLanguage(q), func(path string, src []byte) {
+		if len(defs) >= MaxDefinitions || !strings.Contains(string(src), name) {
+			return
+		}
+		for _, s := range heuristicOutline(path, src, relPath(root, path)) {
+			if s.Name != name || (receiver != "" && s.Receiver != "" && s.Receiver != receiver) {
+				continue
+			}
+			defs = append(defs, Location{
+				Path:      s.Path,
+				Line:      s.Line,
+				Text:      s.Signature,
+				Name:      s.Name,
+				Kind:      s.Kind,
+				Signature: s.Signature,
+			})
+		}
+	})
+	if len(defs) > MaxDefinitions {
+		defs = defs[:MaxDefinitions]
+	}
+	return defs
+}
+
+// heuristicReferences finds whole-word occurrences of the symbol name.
+func heuristicReferences(root string, q Query) *References {
+	name := lastPart(q.Symbol)
+	refs := &References{
+		Definitions: heuristicDefinitions(root, q),
+		References:  []Location{},
+		Heuristic:   true,
+	}
+	if refs.Definitions == nil {
+		refs.Definitions = []Location{}
+	}
+	word := regexp.MustCompile(`(^|[^\w$])` + regexp.QuoteMeta(name) + `($|[^\w$])`)
+	walkSource(root, queryLanguage(q), func(path string, src []byte) {
+		if !strings.Contains(string(src), name) {
+			return
+		}
+		rel := relPath(root, path)
+		for i, line := range strings.Split(string(src), "\n") {
+			loc := word.FindStringIndex(line)
+			if loc == nil {
+				continue
+			}
+			column := loc[0] + 1
+			if line[loc[0]] != name[0] {
+				column++
+			}
+			text := strings.TrimSpace(line)
+			if len(text) > 200 {
+				text = text[:200] + "..."
+			}
+			refs.References = append(refs.References, Location{Path: rel, Line: i + 1, Column: column, Text: text, Name: name, Kind: "use"})
+		}
+	})
+	return refs
+}
+
+func heuristicPackageAPI(root, dir string) (*API, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	api := &API{Path: relPath(root, dir), Symbols: []Symbol{}, Heuristic: true}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if e.IsDir() || language(path) == "text" {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, s := range heuristicOutline(path, src, relPath(root, path)) {
+			if s.Exported && s.Receiver == "" {
+				api.Symbols = append(api.Symbols, s)
+			}
+		}
+	}
+	return api, nil
+}
@@ -0,0 +1,226 @@
+package ai
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/db"
+	"github.com/webide/ide/backend/internal/git"
+)
+
+const (
+	ContextActiveFile = "active_file"
+	ContextSelection  = "selection"
+	ContextOpenFiles  = "open_files"
+	ContextGitChanges = "git_changes"
+
+	editorContextBudget = 12 * 1024
+	maxSelectionBytes   = 6 * 1024
+	maxOpenFilesBytes   = 1536
+	maxGitChangesBytes  = 4 * 1024
+	maxOpenFiles        = 40
+)
+
+var defaultContextItems = []string{ContextActiveFile, ContextSelection, ContextOpenFiles, ContextGitChanges}
+
+// EditorContextRequest is sent by the client with a message. Enabled is the
+// per-message toggle; Items narrows what gets attached. ActiveFile and
+// OpenFiles override workspace_state, which may lag behind the editor.
+type EditorContextRequest struct {
+	Enabled    bool             `json:"enabled"`
+	Items      []string         `json:"items,omitempty"`
+	ActiveFile string           `json:"active_file,omitempty"`
+	OpenFiles  []string         `json:"open_files,omitempty"`
+	Selection  *EditorSelection `json:"selection,omitempty"`
+}
+
+type EditorSelection struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Text      string `json:"text"`
+}
+
+type ContextItem struct {
+	Kind      string `json:"kind"`
+	Label     string `json:"label"`
+	Content   string `json:"content"`
+	Bytes     int    `json:"bytes"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// EditorContext is what was actually attached to a message, after budgets.
+type EditorContext struct {
+	Items  []ContextItem `json:"items"`
+	Bytes  int           `json:"bytes"`
+	Budget int           `json:"budget"`
+}
+
+func BuildEditorContext(ctx context.Context, userID, projectID uuid.UUID, projectRoot string, req *EditorContextRequest) *EditorContext {
+	if req == nil || !req.Enabled {
+		return nil
+	}
+
+	wanted := make(map[string]bool)
+	items := req.Items
+	if len(items) == 0 {
+		items = defaultContextItems
+	}
+	for _, k := range items {
+		wanted[k] = true
+	}
+
+	activeFile := req.ActiveFile
+	openFiles := req.OpenFiles
+	if activeFile == "" || openFiles == nil {
+		stateActive, stateOpen := loadWorkspaceState(ctx, userID, projectID)
+		if activeFile == "" {
+			activeFile = stateActive
+		}
+		if openFiles == nil {
+			openFiles = stateOpen
+		}
+	}
+
+	ec := &EditorContext{Budget: editorContextBudget}
+	add := func(kind, label, content string, limit int) {
+		content = strings.TrimRight(content, "\n")
+		if content == "" {
+			return
+		}
+		if remaining := ec.Budget - ec.Bytes; limit > remaining {
+			limit = remaining
+		}
+		if limit <= 0 {
+			return
+		}
+		item := ContextItem{Kind: kind, Label: label, Content: content}
+		if len(content) > limit {
+			item.Content = truncateUTF8(content, limit) + "\n... (truncated)"
+			item.Truncated = true
+		}
+		item.Bytes = len(item.Content)
+		ec.Items = append(ec.Items, item)
+		ec.Bytes += item.Bytes
+	}
+
+	if wanted[ContextActiveFile] && activeFile != "" {
+		add(ContextActiveFile, "Active file", relProjectPath(projectRoot, activeFile), 512)
+	}
+
+	if wanted[ContextSelection] && req.Selection != nil && strings.TrimSpace(req.Selection.Text) != "" {
+		sel := req.Selection
+		path := relProjectPath(projectRoot, sel.Path)
+		if path == "" {
+			path = relProjectPath(projectRoot, activeFile)
+		}
+		label := "Selection in " + path
+		if sel.StartLine > 0 {
+			label = fmt.Sprintf("Selection %s:%d-%d", path, sel.StartLine, max(sel.EndLine, sel.StartLine))
+		}
+		add(ContextSelection, label, sel.Text, maxSelectionBytes)
+	}
+
+	if wanted[ContextOpenFiles] && len(openFiles) > 0 {
+		var b strings.Builder
+		for i, f := range openFiles {
+			if i == maxOpenFiles {
+				fmt.Fprintf(&b, "... and %d more\n", len(openFiles)-maxOpenFiles)
+				break
+			}
+			b.WriteString(relProjectPath(projectRoot, f) + "\n")
+		}
+		add(ContextOpenFiles, fmt.Sprintf("Open files (%d)", len(openFiles)), b.String(), maxOpenFilesBytes)
+	}
+
+	if wanted[ContextGitChanges] && projectRoot != "" && git.IsGitRepo(projectRoot) {
+		add(ContextGitChanges, "Recent git changes", recentGitChanges(projectRoot), maxGitChangesBytes)
+	}
+
+	if len(ec.Items) == 0 {
+		return nil
+	}
+	return ec
+}
+
+func loadWorkspaceState(ctx context.Context, userID, projectID uuid.UUID) (string, []string) {
+	var activeFile, openFilesJSON sql.NullString
+	err := db.GetDB().QueryRowContext(ctx,
+		"SELECT active_file, open_files_json FROM workspace_state WHERE user_id = ? AND project_id = ?",
+		userID.String(), projectID.String(),
+	).Scan(&activeFile, &openFilesJSON)
+	if err != nil {
+		return "", nil
+	}
+
+	var openFiles []string
+	json.Unmarshal([]byte(openFilesJSON.String), &openFiles)
+	return activeFile.String, openFiles
+}
+
+func recentGitChanges(projectRoot string) string {
+	var b strings.Builder
+	if res, err := git.RunGit(projectRoot, 5*time.Second, "status", "--porcelain"); err == nil && res.Stdout != "" {
+		b.WriteString("$ git status --porcelain\n" + res.Stdout + "\n")
+	}
+	if res, err := git.RunGit(projectRoot, 5*time.Second, "log", "-3", "--oneline"); err == nil && res.Stdout != "" {
+		b.WriteString("\n$ git log -3 --oneline\n" + res.Stdout + "\n")
+	}
+	if res, err := git.RunGit(projectRoot, 10*time.Second, "diff", "-U2"); err == nil && res.Stdout != "" {
+		b.WriteString("\n$ git diff\n" + res.Stdout + "\n")
+	}
+	return b.String()
+}
+
+func relProjectPath(projectRoot, path string) string {
+	if path == "" || projectRoot == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	rel, err := filepath.Rel(projectRoot, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
+
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && n < len(s) && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
+
+// Render formats the context as a labelled block that is prepended to the
+// user's message for the model.
+func (ec *EditorContext) Render() string {
+	var b strings.Builder
+	b.WriteString("<editor_context>\n")
+	b.WriteString("The user's editor state when they sent the message below. Use it to resolve references like \"this file\" or \"the selection\".\n")
+	for _, item := range ec.Items {
+		fmt.Fprintf(&b, "\n### %s\n", item.Label)
+		if item.Kind == ContextActiveFile || item.Kind == ContextOpenFiles {
+			b.WriteString(item.Content + "\n")
+			continue
+		}
+		fmt.Fprintf(&b, "```\n%s\n```\n", item.Content)
+	}
+	b.WriteString("</editor_context>")
+	return b.String()
+}
+
+func (ec *EditorContext) Summary() string {
+	labels := make([]string, 0, len(ec.Items))
+	for _, item := range ec.Items {
+		labels = append(labels, item.Label)
+	}
+	return strings.Join(labels, ", ")
+}
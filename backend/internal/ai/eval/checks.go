@@ -0,0 +1,152 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/tools/builtin"
+)
+
+const defaultCheckTimeout = 2 * time.Minute
+
+type CheckResult struct {
+	Check  string `json:"check"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func runCheck(ctx context.Context, task *Task, c Check, workDir, baseDir string) CheckResult {
+	res := CheckResult{Check: c.Label()}
+
+	var err error
+	switch c.Type {
+	case CheckCommand:
+		err = checkCommand(ctx, c, workDir)
+	case CheckFile:
+		err = checkFile(c, workDir)
+	case CheckDiff:
+		err = checkDiff(task, c, workDir, baseDir)
+	default:
+		err = fmt.Errorf("unknown check type %q", c.Type)
+	}
+
+	if err != nil {
+		res.Detail = err.Error()
+		return res
+	}
+	res.Passed = true
+	return res
+}
+
+func checkCommand(ctx context.Context, c Check, workDir string) error {
+	timeout := defaultCheckTimeout
+	if c.TimeoutMs > 0 {
+		timeout = time.Duration(c.TimeoutMs) * time.Millisecond
+	}
+
+	proc, err := builtin.ExecCommand(ctx, builtin.ExecSpec{
+		Cmd:            c.Cmd,
+		Dir:            workDir,
+		Env:            os.Environ(),
+		Timeout:        timeout,
+		MaxOutputBytes: 16 * 1024,
+		Stream:         true,
+	})
+	if err != nil {
+		return err
+	}
+	if proc.Cancelled {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	if proc.ExitCode != c.ExitCode {
+		return fmt.Errorf("exit code %d, want %d\n%s", proc.ExitCode, c.ExitCode, tail(proc.Output.Text(), 2000))
+	}
+	return nil
+}
+
+func checkFile(c Check, workDir string) error {
+	data, err := os.ReadFile(filepath.Join(workDir, filepath.FromSlash(c.Path)))
+	if c.Absent {
+		if err == nil {
+			return fmt.Errorf("%s exists", c.Path)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	content := string(data)
+	if c.Equals != nil && normalizeText(content) != normalizeText(*c.Equals) {
+		return fmt.Errorf("%s content does not match", c.Path)
+	}
+	for _, sub := range c.Contains {
+		if !strings.Contains(content, sub) {
+			return fmt.Errorf("%s does not contain %q", c.Path, sub)
+		}
+	}
+	if c.Matches != "" {
+		re, err := regexp.Compile(c.Matches)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+		if !re.MatchString(content) {
+			return fmt.Errorf("%s does not match /%s/", c.Path, c.Matches)
+		}
+	}
+	return nil
+}
+
+func checkDiff(task *Task, c Check, workDir, baseDir string) error {
+	diff, changed, err := workspaceDiff(baseDir, workDir)
+	if err != nil {
+		return err
+	}
+
+	if c.Expected != "" {
+		want, err := os.ReadFile(task.resolve(c.Expected))
+		if err != nil {
+			return err
+		}
+		if normalizeText(diff) != normalizeText(string(want)) {
+			return fmt.Errorf("diff does not match %s\n%s", c.Expected, tail(diff, 2000))
+		}
+	}
+
+	if c.ChangedFiles != nil {
+		want := append([]string{}, c.ChangedFiles...)
+		sort.Strings(want)
+		if strings.Join(want, ",") != strings.Join(changed, ",") {
+			return fmt.Errorf("changed files %v, want %v", changed, want)
+		}
+	}
+
+	for _, sub := range c.Contains {
+		if !strings.Contains(diff, sub) {
+			return fmt.Errorf("diff does not contain %q", sub)
+		}
+	}
+	return nil
+}
+
+func normalizeText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "..." + s[len(s)-n:]
+}
@@ -0,0 +1,250 @@
+package eval
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// extractFixture unpacks a .tar, .tar.gz or .tgz archive into dest. A
+// directory fixture is copied instead, which is handy while writing tasks.
+func extractFixture(src, dest string) error {
+	dest = filepath.Clean(dest)
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return copyTree(src, dest)
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(src, ".gz") || strings.HasSuffix(src, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dest, filepath.Clean(hdr.Name))
+		if !within(dest, target) {
+			return fmt.Errorf("fixture entry escapes workspace: %s", hdr.Name)
+		}
+		// An earlier entry may have been a symlink; never create anything
+		// through one, or a later entry could land outside dest.
+		if err := checkNoSymlinks(dest, target); err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|syscall.O_NOFOLLOW, os.FileMode(hdr.Mode)&0777)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			out.Close()
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if !linkWithin(dest, target, hdr.Linkname) {
+				return fmt.Errorf("fixture symlink escapes workspace: %s -> %s", hdr.Name, hdr.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// within reports whether target is dest or below it.
+func within(dest, target string) bool {
+	return target == dest || strings.HasPrefix(target, dest+string(os.PathSeparator))
+}
+
+// linkWithin reports whether a symlink at target pointing to linkname stays
+// inside dest. Absolute link targets are always rejected.
+func linkWithin(dest, target, linkname string) bool {
+	if linkname == "" || filepath.IsAbs(linkname) {
+		return false
+	}
+	return within(dest, filepath.Join(filepath.Dir(target), linkname))
+}
+
+// checkNoSymlinks fails if target or any directory between dest and target
+// is a symlink.
+func checkNoSymlinks(dest, target string) error {
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == "." {
+		return err
+	}
+	cur := dest
+	for _, part := range strings.Split(rel, string(os.PathSeparator)) {
+		cur = filepath.Join(cur, part)
+		info, err := os.Lstat(cur)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("fixture entry would write through symlink: %s", rel)
+		}
+	}
+	return nil
+}
+
+func copyTree(src, dest string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dest, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if !linkWithin(dest, target, link) {
+				return fmt.Errorf("fixture symlink escapes workspace: %s -> %s", rel, link)
+			}
+			return os.Symlink(link, target)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, info.Mode().Perm())
+	})
+}
+
+// workspaceDiff returns a unified diff from base to work and the list of
+// changed paths, ignoring .git.
+func workspaceDiff(base, work string) (string, []string, error) {
+	baseFiles, err := listFiles(base)
+	if err != nil {
+		return "", nil, err
+	}
+	workFiles, err := listFiles(work)
+	if err != nil {
+		return "", nil, err
+	}
+
+	all := make(map[string]bool)
+	for p := range baseFiles {
+		all[p] = true
+	}
+	for p := range workFiles {
+		all[p] = true
+	}
+	paths := make([]string, 0, len(all))
+	for p := range all {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	var changed []string
+	for _, p := range paths {
+		before, after := "", ""
+		fromFile, toFile := "a/"+p, "b/"+p
+		if baseFiles[p] {
+			data, _ := os.ReadFile(filepath.Join(base, p))
+			before = string(data)
+		} else {
+			fromFile = "/dev/null"
+		}
+		if workFiles[p] {
+			data, _ := os.ReadFile(filepath.Join(work, p))
+			after = string(data)
+		} else {
+			toFile = "/dev/null"
+		}
+		if before == after && baseFiles[p] == workFiles[p] {
+			continue
+		}
+
+		changed = append(changed, p)
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(before),
+			B:        difflib.SplitLines(after),
+			FromFile: fromFile,
+			ToFile:   toFile,
+			Context:  3,
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		b.WriteString(diff)
+	}
+	return b.String(), changed, nil
+}
+
+func listFiles(root string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
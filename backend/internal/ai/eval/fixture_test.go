@@ -0,0 +1,135 @@
+package eval
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type tarEntry struct {
+	name, link, body string
+	typ              byte
+}
+
+func writeTar(t *testing.T, entries []tarEntry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Linkname: e.link, Typeflag: e.typ, Mode: 0644, Size: int64(len(e.body))}
+		if e.typ != tar.TypeReg {
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if e.typ == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fixture.tar")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtractFixture(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		wantErr bool
+		want    map[string]string
+	}{
+		{
+			name: "regular files and dirs",
+			entries: []tarEntry{
+				{name: "src/", typ: tar.TypeDir},
+				{name: "src/main.go", typ: tar.TypeReg, body: "package main\n"},
+			},
+			want: map[string]string{"src/main.go": "package main\n"},
+		},
+		{
+			name: "symlink inside workspace",
+			entries: []tarEntry{
+				{name: "a.txt", typ: tar.TypeReg, body: "a"},
+				{name: "b.txt", typ: tar.TypeSymlink, link: "a.txt"},
+			},
+			want: map[string]string{"b.txt": "a"},
+		},
+		{
+			name:    "dot-dot entry name",
+			entries: []tarEntry{{name: "../evil.txt", typ: tar.TypeReg, body: "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "absolute symlink",
+			entries: []tarEntry{{name: "out", typ: tar.TypeSymlink, link: "/tmp"}},
+			wantErr: true,
+		},
+		{
+			name:    "relative symlink escaping",
+			entries: []tarEntry{{name: "sub/out", typ: tar.TypeSymlink, link: "../../outside"}},
+			wantErr: true,
+		},
+		{
+			name: "write through symlinked dir",
+			entries: []tarEntry{
+				{name: "inner/", typ: tar.TypeDir},
+				{name: "link", typ: tar.TypeSymlink, link: "inner"},
+				{name: "link/file.txt", typ: tar.TypeReg, body: "x"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "overwrite symlinked file",
+			entries: []tarEntry{
+				{name: "a.txt", typ: tar.TypeReg, body: "a"},
+				{name: "b.txt", typ: tar.TypeSymlink, link: "a.txt"},
+				{name: "b.txt", typ: tar.TypeReg, body: "b"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := writeTar(t, tt.entries)
+			parent := t.TempDir()
+			dest := filepath.Join(parent, "work")
+			if err := os.Mkdir(dest, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := extractFixture(src, dest)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if _, err := os.Lstat(filepath.Join(parent, "evil.txt")); err == nil {
+					t.Fatal("entry was written outside the workspace")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for rel, body := range tt.want {
+				data, err := os.ReadFile(filepath.Join(dest, rel))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != body {
+					t.Errorf("%s = %q, want %q", rel, data, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Report struct {
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	Mode       string       `json:"mode"`
+	Provider   string       `json:"provider,omitempty"`
+	Model      string       `json:"model,omitempty"`
+	Summary    Summary      `json:"summary"`
+	Tasks      []TaskResult `json:"tasks"`
+}
+
+type Summary struct {
+	Total      int     `json:"total"`
+	Passed     int     `json:"passed"`
+	PassRate   float64 `json:"pass_rate"`
+	Steps      int     `json:"steps"`
+	AvgSteps   float64 `json:"avg_steps"`
+	Tokens     int     `json:"tokens"`
+	ToolCalls  int     `json:"tool_calls"`
+	ToolErrors int     `json:"tool_errors"`
+}
+
+func summarize(tasks []TaskResult) Summary {
+	s := Summary{Total: len(tasks)}
+	for _, t := range tasks {
+		if t.Passed {
+			s.Passed++
+		}
+		s.Steps += t.Steps
+		s.Tokens += t.Usage.TotalTokens
+		s.ToolCalls += t.ToolCalls
+		s.ToolErrors += t.ToolErrors
+	}
+	if s.Total > 0 {
+		s.PassRate = float64(s.Passed) / float64(s.Total)
+		s.AvgSteps = float64(s.Steps) / float64(s.Total)
+	}
+	return s
+}
+
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	s := r.Summary
+
+	b.WriteString("# Agent eval report\n\n")
+	fmt.Fprintf(&b, "- Started: %s\n", r.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Mode: %s\n", r.Mode)
+	if r.Provider != "" {
+		fmt.Fprintf(&b, "- Provider: %s\n", r.Provider)
+	}
+	if r.Model != "" {
+		fmt.Fprintf(&b, "- Model: %s\n", r.Model)
+	}
+	fmt.Fprintf(&b, "- Pass rate: %d/%d (%.1f%%)\n", s.Passed, s.Total, s.PassRate*100)
+	fmt.Fprintf(&b, "- Steps: %d (avg %.1f)\n", s.Steps, s.AvgSteps)
+	fmt.Fprintf(&b, "- Tokens: %d\n", s.Tokens)
+	fmt.Fprintf(&b, "- Tool calls: %d, errors: %d\n\n", s.ToolCalls, s.ToolErrors)
+
+	b.WriteString("| Task | Result | Steps | Tokens | Tool calls | Tool errors | Time |\n")
+	b.WriteString("|------|--------|-------|--------|------------|-------------|------|\n")
+	for _, t := range r.Tasks {
+		result := "FAIL"
+		if t.Passed {
+			result = "PASS"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %d | %d | %d | %d | %.1fs |\n",
+			t.Name, result, t.Steps, t.Usage.TotalTokens, t.ToolCalls, t.ToolErrors, float64(t.DurationMs)/1000)
+	}
+
+	for _, t := range r.Tasks {
+		if t.Passed {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", t.Name)
+		if t.Error != "" {
+			fmt.Fprintf(&b, "Error: %s\n\n", t.Error)
+		}
+		for _, c := range t.Checks {
+			mark := "x"
+			if !c.Passed {
+				mark = " "
+			}
+			fmt.Fprintf(&b, "- [%s] %s\n", mark, c.Check)
+			if c.Detail != "" {
+				fmt.Fprintf(&b, "\n```\n%s\n```\n", strings.TrimSpace(c.Detail))
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// Write stores report.json and report.md in dir.
+func (r *Report) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := r.JSON()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "report.json"), data, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "report.md"), []byte(r.Markdown()), 0644)
+}
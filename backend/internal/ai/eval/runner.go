@@ -0,0 +1,245 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/ai/provider"
+	"github.com/webide/ide/backend/internal/ai/tools"
+	_ "github.com/webide/ide/backend/internal/ai/tools/builtin"
+)
+
+const (
+	ModeLive   = "live"
+	ModeRecord = "record"
+	ModeReplay = "replay"
+)
+
+type Options struct {
+	Mode        string
+	Provider    provider.Provider
+	Model       string
+	CassetteDir string
+	Timeout     time.Duration
+	KeepWorkDir bool
+}
+
+type TaskResult struct {
+	Name       string               `json:"name"`
+	Passed     bool                 `json:"passed"`
+	Steps      int                  `json:"steps"`
+	ToolCalls  int                  `json:"tool_calls"`
+	ToolErrors int                  `json:"tool_errors"`
+	Usage      provider.TokenUsage  `json:"usage"`
+	DurationMs int64                `json:"duration_ms"`
+	FinalMsg   string               `json:"final_message,omitempty"`
+	Verify     *agent.ChecksSummary `json:"project_checks,omitempty"`
+	Error      string               `json:"error,omitempty"`
+	Checks     []CheckResult        `json:"checks"`
+	WorkDir    string               `json:"work_dir,omitempty"`
+}
+
+// runStats collects what the orchestrator reports through its event stream.
+type runStats struct {
+	mu         sync.Mutex
+	steps      int
+	toolCalls  int
+	toolErrors int
+	usage      provider.TokenUsage
+	finalMsg   string
+	verify     *agent.ChecksSummary
+	agentErr   string
+}
+
+func (s *runStats) send(ev agent.WSEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch ev.Type {
+	case agent.EventToolResult:
+		s.toolCalls++
+		if payload, ok := ev.Payload.(map[string]interface{}); ok {
+			if ok, _ := payload["ok"].(bool); !ok {
+				s.toolErrors++
+			}
+		}
+	case agent.EventToolError:
+		s.toolCalls++
+		s.toolErrors++
+	case agent.EventAgentDone:
+		if payload, ok := ev.Payload.(agent.AgentDonePayload); ok {
+			s.steps = payload.Steps
+			s.usage = payload.Usage
+			s.finalMsg = payload.FinalMsg
+			s.verify = payload.Checks
+		}
+	case agent.EventAgentError:
+		if payload, ok := ev.Payload.(agent.AgentErrorPayload); ok {
+			s.agentErr = payload.Code + ": " + payload.Message
+		}
+	}
+	return nil
+}
+
+func RunTask(ctx context.Context, task *Task, opts Options) (result TaskResult) {
+	start := time.Now()
+	result = TaskResult{Name: task.Name, Checks: []CheckResult{}}
+	defer func() {
+		result.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	tmp, err := os.MkdirTemp("", "agent-eval-*")
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if opts.KeepWorkDir {
+		result.WorkDir = tmp
+	} else {
+		defer os.RemoveAll(tmp)
+	}
+
+	baseDir := filepath.Join(tmp, "base")
+	workDir := filepath.Join(tmp, "work")
+	fixture := task.resolve(task.Fixture)
+	for _, dir := range []string{baseDir, workDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if err := extractFixture(fixture, dir); err != nil {
+			result.Error = "fixture: " + err.Error()
+			return result
+		}
+	}
+
+	llm, recorder, err := taskProvider(task, opts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	cfg := agent.DefaultConfig()
+	cfg.Mode = agent.ModeExec
+	cfg.ProjectRoot = workDir
+	cfg.Model = opts.Model
+	cfg.AutoApprove = true
+	if task.MaxSteps > 0 {
+		cfg.Limits.MaxSteps = task.MaxSteps
+	}
+
+	session := agent.NewSession(uuid.New(), uuid.New(), uuid.Nil, cfg)
+	orchestrator := agent.NewOrchestrator(tools.GlobalRegistry, llm)
+
+	runCtx := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	stats := &runStats{}
+	if err := orchestrator.Run(runCtx, session, task.Prompt, stats.send); err != nil {
+		result.Error = err.Error()
+	}
+	if runCtx.Err() != nil && result.Error == "" {
+		result.Error = runCtx.Err().Error()
+	}
+	if stats.agentErr != "" && result.Error == "" {
+		result.Error = stats.agentErr
+	}
+
+	if recorder != nil {
+		path := cassettePath(task, opts)
+		if err := recorder.Cassette().Save(path); err != nil {
+			log.Printf("[Eval] Failed to save cassette %s: %v", path, err)
+		}
+	}
+
+	result.Steps = stats.steps
+	result.ToolCalls = stats.toolCalls
+	result.ToolErrors = stats.toolErrors
+	result.Usage = stats.usage
+	result.FinalMsg = stats.finalMsg
+	result.Verify = stats.verify
+	if result.Usage.TotalTokens == 0 {
+		result.Usage = session.Usage()
+	}
+
+	result.Passed = result.Error == ""
+	for _, c := range task.Checks {
+		cr := runCheck(ctx, task, c, workDir, baseDir)
+		if !cr.Passed {
+			result.Passed = false
+		}
+		result.Checks = append(result.Checks, cr)
+	}
+
+	return result
+}
+
+func taskProvider(task *Task, opts Options) (provider.Provider, *provider.Recorder, error) {
+	switch opts.Mode {
+	case ModeReplay:
+		cassette, err := provider.LoadCassette(cassettePath(task, opts))
+		if err != nil {
+			return nil, nil, fmt.Errorf("cassette: %w", err)
+		}
+		return provider.NewReplayer(cassette), nil, nil
+	case ModeRecord:
+		if opts.Provider == nil {
+			return nil, nil, fmt.Errorf("record mode needs a live provider")
+		}
+		rec := provider.NewRecorder(opts.Provider)
+		return rec, rec, nil
+	default:
+		if opts.Provider == nil {
+			return nil, nil, fmt.Errorf("no provider configured")
+		}
+		return opts.Provider, nil, nil
+	}
+}
+
+func cassettePath(task *Task, opts Options) string {
+	if task.Cassette != "" {
+		return task.resolve(task.Cassette)
+	}
+	dir := opts.CassetteDir
+	if dir == "" {
+		dir = filepath.Join(task.dir, "cassettes")
+	}
+	return filepath.Join(dir, task.Name+".json")
+}
+
+func Run(ctx context.Context, tasks []*Task, opts Options) *Report {
+	report := &Report{
+		StartedAt: time.Now(),
+		Mode:      opts.Mode,
+		Model:     opts.Model,
+	}
+	if opts.Provider != nil {
+		report.Provider = opts.Provider.Name()
+	}
+
+	for _, task := range tasks {
+		log.Printf("[Eval] Running task %s", task.Name)
+		res := RunTask(ctx, task, opts)
+		status := "FAIL"
+		if res.Passed {
+			status = "PASS"
+		}
+		log.Printf("[Eval] %s %s (steps=%d, tokens=%d, tool_errors=%d)", status, task.Name, res.Steps, res.Usage.TotalTokens, res.ToolErrors)
+		report.Tasks = append(report.Tasks, res)
+	}
+
+	report.FinishedAt = time.Now()
+	report.Summary = summarize(report.Tasks)
+	return report
+}
@@ -0,0 +1,132 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	CheckCommand = "command"
+	CheckFile    = "file"
+	CheckDiff    = "diff"
+)
+
+// Task is a single evaluation case, loaded from a JSON file.
+type Task struct {
+	Name     string  `json:"name"`
+	Prompt   string  `json:"prompt"`
+	Fixture  string  `json:"fixture"`
+	Cassette string  `json:"cassette,omitempty"`
+	MaxSteps int     `json:"max_steps,omitempty"`
+	Checks   []Check `json:"checks"`
+
+	dir string
+}
+
+// Check describes a success condition evaluated in the task workspace after
+// the agent has finished.
+//
+//	command: Cmd must exit with ExitCode (default 0)
+//	file:    Path must exist (or not, with Absent) and match Equals/Contains/Matches
+//	diff:    the workspace diff must equal Expected, touch exactly ChangedFiles,
+//	         and contain every Contains entry
+type Check struct {
+	Type         string   `json:"type"`
+	Name         string   `json:"name,omitempty"`
+	Cmd          string   `json:"cmd,omitempty"`
+	ExitCode     int      `json:"exit_code,omitempty"`
+	TimeoutMs    int      `json:"timeout_ms,omitempty"`
+	Path         string   `json:"path,omitempty"`
+	Absent       bool     `json:"absent,omitempty"`
+	Equals       *string  `json:"equals,omitempty"`
+	Contains     []string `json:"contains,omitempty"`
+	Matches      string   `json:"matches,omitempty"`
+	Expected     string   `json:"expected,omitempty"`
+	ChangedFiles []string `json:"changed_files,omitempty"`
+}
+
+func (c Check) Label() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	switch c.Type {
+	case CheckCommand:
+		return "command: " + c.Cmd
+	case CheckFile:
+		return "file: " + c.Path
+	}
+	return c.Type
+}
+
+// LoadTasks reads a single task file or every *.json file in a directory.
+func LoadTasks(path string) ([]*Task, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	if info.IsDir() {
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		files = matches
+	} else {
+		files = []string{path}
+	}
+	sort.Strings(files)
+
+	var tasks []*Task
+	for _, f := range files {
+		task, err := loadTask(f)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func loadTask(path string) (*Task, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var task Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	task.dir = filepath.Dir(path)
+	if task.Name == "" {
+		task.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if task.Prompt == "" {
+		return nil, fmt.Errorf("%s: prompt is required", path)
+	}
+	if task.Fixture == "" {
+		return nil, fmt.Errorf("%s: fixture is required", path)
+	}
+	for i, c := range task.Checks {
+		switch c.Type {
+		case CheckCommand, CheckFile, CheckDiff:
+		default:
+			return nil, fmt.Errorf("%s: check %d has unknown type %q", path, i, c.Type)
+		}
+	}
+	return &task, nil
+}
+
+// resolve returns p relative to the task file's directory.
+func (t *Task) resolve(p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(t.dir, p)
+}
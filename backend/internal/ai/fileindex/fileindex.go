@@ -0,0 +1,250 @@
+// Package fileindex keeps an in-memory list of the paths in each project for
+// find_files. Before each query the index re-reads only the directories
+// whose modification time changed, so it stays current without a watcher.
+package fileindex
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/ignore"
+)
+
+const (
+	MaxLimit = 200
+	// maxFiles bounds the index of one project.
+	maxFiles = 200000
+)
+
+var ErrInvalidQuery = errors.New("give a query or at least one glob")
+
+type Match struct {
+	Path    string    `json:"path"`
+	Score   int       `json:"score,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+}
+
+type Result struct {
+	Matches   []Match `json:"matches"`
+	Total     int     `json:"total"`
+	Truncated bool    `json:"truncated"`
+	Indexed   int     `json:"indexed"`
+	// Incomplete is set when the project has more files than the index holds.
+	Incomplete bool `json:"incomplete"`
+}
+
+type dirEntry struct {
+	modTime time.Time
+	// matcher holds the ignore rules for the directory's entries. It is kept
+	// while the parent's matcher and the directory's .gitignore are unchanged.
+	parent      *ignore.Matcher
+	ignoreStamp string
+	matcher     *ignore.Matcher
+	files       []string
+	dirs        []string
+}
+
+// Index lists the files of one project, relative to its root.
+type Index struct {
+	root string
+
+	mu         sync.Mutex
+	rootStamp  string
+	dirs       map[string]*dirEntry
+	paths      []string
+	incomplete bool
+}
+
+var (
+	indexesMu sync.Mutex
+	indexes   = map[string]*Index{}
+)
+
+// For returns the index of the project at root, creating it on first use.
+func For(root string) *Index {
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+	idx := indexes[root]
+	if idx == nil {
+		idx = &Index{root: root, dirs: map[string]*dirEntry{}}
+		indexes[root] = idx
+	}
+	return idx
+}
+
+// Find ranks the indexed paths against a fuzzy query, keeping only those that
+// match one of globs when any are given. Without a query, glob matches are
+// listed in path order.
+func (idx *Index) Find(query string, globs []string, limit int) (*Result, error) {
+	query = strings.TrimSpace(query)
+	if query == "" && len(globs) == 0 {
+		return nil, ErrInvalidQuery
+	}
+	var compiled []*ignore.Glob
+	for _, g := range globs {
+		glob, err := ignore.CompileGlob(g)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, glob)
+	}
+	if limit <= 0 || limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	idx.mu.Lock()
+	idx.refresh()
+	paths, incomplete := idx.paths, idx.incomplete
+	idx.mu.Unlock()
+
+	var matches []Match
+	for _, p := range paths {
+		if len(compiled) > 0 && !anyGlob(compiled, p) {
+			continue
+		}
+		score := 0
+		if query != "" {
+			var ok bool
+			if score, ok = fuzzyScore(query, p); !ok {
+				continue
+			}
+		}
+		matches = append(matches, Match{Path: p, Score: score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if len(a.Path) != len(b.Path) && query != "" {
+			return len(a.Path) < len(b.Path)
+		}
+		return a.Path < b.Path
+	})
+
+	res := &Result{Total: len(matches), Indexed: len(paths), Incomplete: incomplete}
+	if len(matches) > limit {
+		matches, res.Truncated = matches[:limit], true
+	}
+	res.Matches = make([]Match, 0, len(matches))
+	for _, m := range matches {
+		info, err := os.Stat(filepath.Join(idx.root, filepath.FromSlash(m.Path)))
+		if err != nil {
+			continue
+		}
+		m.Size, m.ModTime = info.Size(), info.ModTime()
+		res.Matches = append(res.Matches, m)
+	}
+	return res, nil
+}
+
+func anyGlob(globs []*ignore.Glob, p string) bool {
+	for _, g := range globs {
+		if g.Match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// refresh brings the index up to date. A directory is read again when its
+// modification time or its .gitignore changed, or when the ignore rules
+// above it did. The caller holds idx.mu.
+func (idx *Index) refresh() {
+	rootStamp := stamp(idx.root, ".gitignore") + stamp(idx.root, ".git/info/exclude") + stamp(idx.root, ".webide/ignore")
+	if rootStamp != idx.rootStamp {
+		idx.rootStamp = rootStamp
+		idx.dirs = map[string]*dirEntry{}
+	}
+
+	seen := map[string]*dirEntry{}
+	var paths []string
+	incomplete := false
+
+	var visit func(rel string, parent *ignore.Matcher)
+	visit = func(rel string, parent *ignore.Matcher) {
+		if len(paths) >= maxFiles {
+			incomplete = true
+			return
+		}
+		abs := filepath.Join(idx.root, filepath.FromSlash(rel))
+		info, err := os.Stat(abs)
+		if err != nil || !info.IsDir() {
+			return
+		}
+
+		ignoreStamp := ""
+		if rel != "" {
+			ignoreStamp = stamp(abs, ".gitignore")
+		}
+		d := idx.dirs[rel]
+		sameRules := d != nil && d.parent == parent && d.ignoreStamp == ignoreStamp
+		if !sameRules || !d.modTime.Equal(info.ModTime()) {
+			next := &dirEntry{modTime: info.ModTime(), parent: parent, ignoreStamp: ignoreStamp}
+			switch {
+			case sameRules:
+				next.matcher = d.matcher
+			case rel == "":
+				next.matcher = ignore.Load(idx.root)
+			default:
+				next.matcher = parent.Child(idx.root, rel)
+			}
+			idx.readDir(next, rel, abs)
+			d = next
+		}
+		seen[rel] = d
+
+		paths = append(paths, d.files...)
+		for _, sub := range d.dirs {
+			visit(sub, d.matcher)
+		}
+	}
+	visit("", nil)
+
+	if len(paths) > maxFiles {
+		paths, incomplete = paths[:maxFiles], true
+	}
+	idx.dirs = seen
+	idx.paths = paths
+	idx.incomplete = incomplete
+}
+
+func (idx *Index) readDir(d *dirEntry, rel, abs string) {
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		name := e.Name()
+		childRel := name
+		if rel != "" {
+			childRel = rel + "/" + name
+		}
+		if e.IsDir() {
+			if name == ".git" || ignore.DefaultDirs[name] || d.matcher.Match(childRel, true) {
+				continue
+			}
+			d.dirs = append(d.dirs, childRel)
+			continue
+		}
+		if e.Type().IsRegular() && !d.matcher.Match(childRel, false) {
+			d.files = append(d.files, childRel)
+		}
+	}
+}
+
+func stamp(dir, name string) string {
+	info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d;", info.Size(), info.ModTime().UnixNano())
+}
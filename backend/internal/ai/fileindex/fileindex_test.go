@@ -0,0 +1,162 @@
+package fileindex_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/fileindex"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func paths(res *fileindex.Result) []string {
+	out := []string{}
+	for _, m := range res.Matches {
+		out = append(out, m.Path)
+	}
+	return out
+}
+
+func TestIndex_Find(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"cmd/server/main.go":            "",
+		"internal/domain/order.go":      "",
+		"internal/user/service.go":      "",
+		"internal/user/service_test.go": "",
+		"web/src/UserProfile.vue":       "",
+		"README.md":                     "",
+		".gitignore":                    "dist/\n*.tmp\n",
+		"dist/bundle.js":                "",
+		"scratch.tmp":                   "",
+		"node_modules/lib/index.js":     "",
+		".git/HEAD":                     "",
+	})
+	idx := fileindex.For(root)
+
+	tests := []struct {
+		name      string
+		query     string
+		globs     []string
+		limit     int
+		want      []string
+		wantFirst string
+		wantTotal int
+		wantErr   bool
+	}{
+		{name: "subsequence across segments", query: "usrsvc", wantFirst: "internal/user/service.go"},
+		{name: "segment start beats a match inside a word", query: "main", wantFirst: "cmd/server/main.go"},
+		{name: "camel case", query: "uprof", wantFirst: "web/src/UserProfile.vue"},
+		{name: "no match", query: "zzz", want: []string{}},
+		{
+			name:  "globs alone list in path order",
+			globs: []string{"*.go"},
+			want:  []string{"cmd/server/main.go", "internal/domain/order.go", "internal/user/service.go", "internal/user/service_test.go"},
+		},
+		{name: "query and glob", query: "service", globs: []string{"**/*_test.go"}, want: []string{"internal/user/service_test.go"}},
+		{name: "ignored files are not indexed", globs: []string{"**/*.js", "*.tmp", "HEAD"}, want: []string{}},
+		{name: "limit keeps the total", globs: []string{"*.go"}, limit: 1, want: []string{"cmd/server/main.go"}, wantTotal: 4},
+		{name: "query or glob required", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := idx.Find(tt.query, tt.globs, tt.limit)
+			if tt.wantErr {
+				if !errors.Is(err, fileindex.ErrInvalidQuery) {
+					t.Fatalf("err = %v, want ErrInvalidQuery", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := paths(res)
+			if tt.wantFirst != "" && (len(got) == 0 || got[0] != tt.wantFirst) {
+				t.Errorf("matches = %v, want %s first", got, tt.wantFirst)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+			if tt.wantTotal != 0 && (res.Total != tt.wantTotal || !res.Truncated) {
+				t.Errorf("total = %d truncated = %v, want %d truncated", res.Total, res.Truncated, tt.wantTotal)
+			}
+			if res.Indexed != 7 {
+				t.Errorf("indexed = %d, want 7", res.Indexed)
+			}
+		})
+	}
+}
+
+func TestIndex_Refresh(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"src/a.go": ""})
+	idx := fileindex.For(root)
+
+	// touch moves a directory's modification time forward, so a change is
+	// seen even on file systems with coarse timestamps.
+	touch := func(dir string) {
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(filepath.Join(root, dir), later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	steps := []struct {
+		name   string
+		change func()
+		want   []string
+	}{
+		{name: "initial", change: func() {}, want: []string{"src/a.go"}},
+		{
+			name: "file added",
+			change: func() {
+				writeFiles(t, root, map[string]string{"src/b.go": ""})
+				touch("src")
+			},
+			want: []string{"src/a.go", "src/b.go"},
+		},
+		{
+			name: "file removed",
+			change: func() {
+				os.Remove(filepath.Join(root, "src", "a.go"))
+				touch("src")
+			},
+			want: []string{"src/b.go"},
+		},
+		{
+			name: "ignore rule added",
+			change: func() {
+				writeFiles(t, root, map[string]string{"src/.gitignore": "b.go\n", "src/c.go": ""})
+				touch("src")
+			},
+			want: []string{"src/c.go"},
+		},
+	}
+
+	for _, step := range steps {
+		step.change()
+		res, err := idx.Find("", []string{"*.go"}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := paths(res); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: matches = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
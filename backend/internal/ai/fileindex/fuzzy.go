@@ -0,0 +1,124 @@
+package fileindex
+
+import "unicode"
+
+// Scores for fuzzyScore. A matched character earns scoreMatch plus a bonus
+// when it starts a path segment or word; gaps between matched characters
+// cost a penalty.
+const (
+	scoreMatch       = 16
+	bonusSegment     = 10
+	bonusWord        = 8
+	bonusCamel       = 7
+	bonusConsecutive = 5
+	bonusBaseName    = 2
+	bonusCase        = 1
+	penaltyGapStart  = 3
+	penaltyGapExtend = 1
+)
+
+// fuzzyScore matches query as a case-insensitive subsequence of path, so
+// "usrsvc" finds "internal/user/service.go". It returns the best alignment
+// score, preferring matches that start segments and words, run together and
+// fall in the file name.
+func fuzzyScore(query, path string) (int, bool) {
+	q := []rune(query)
+	p := []rune(path)
+	if len(q) > len(p) || !isSubsequence(q, p) {
+		return 0, false
+	}
+
+	baseStart := 0
+	for i, r := range p {
+		if r == '/' {
+			baseStart = i + 1
+		}
+	}
+
+	bonus := make([]int, len(p))
+	for j := range p {
+		switch {
+		case j == 0 || p[j-1] == '/':
+			bonus[j] = bonusSegment
+		case isSeparator(p[j-1]):
+			bonus[j] = bonusWord
+		case unicode.IsLower(p[j-1]) && unicode.IsUpper(p[j]):
+			bonus[j] = bonusCamel
+		}
+		if j >= baseStart {
+			bonus[j] += bonusBaseName
+		}
+	}
+
+	const none = -1 << 30
+	// match[j] is the best score for the query so far with its last
+	// character matched at p[j]; prev holds the row for the previous query
+	// character.
+	prev := make([]int, len(p))
+	match := make([]int, len(p))
+	for j := range prev {
+		prev[j] = none
+	}
+
+	for i, qr := range q {
+		// best is the best prev score before j, less the gap penalty.
+		best := none
+		for j, pr := range p {
+			score := none
+			if unicode.ToLower(qr) == unicode.ToLower(pr) {
+				s := scoreMatch + bonus[j]
+				if qr == pr {
+					s += bonusCase
+				}
+				switch {
+				case i == 0:
+					score = s
+				default:
+					if j > 0 && prev[j-1] > none {
+						score = prev[j-1] + s + bonusConsecutive
+					}
+					if best > none && best+s > score {
+						score = best + s
+					}
+				}
+			}
+			match[j] = score
+
+			// Extend the gap: a match at j-1 that is skipped now costs the
+			// gap start penalty, any older one one more per character.
+			if best > none {
+				best -= penaltyGapExtend
+			}
+			if j > 0 && prev[j-1] > none && prev[j-1]-penaltyGapStart > best {
+				best = prev[j-1] - penaltyGapStart
+			}
+		}
+		prev, match = match, prev
+	}
+
+	result := none
+	for _, s := range prev {
+		if s > result {
+			result = s
+		}
+	}
+	return result, result > none
+}
+
+func isSubsequence(q, p []rune) bool {
+	i := 0
+	for _, r := range p {
+		if i < len(q) && unicode.ToLower(r) == unicode.ToLower(q[i]) {
+			i++
+		}
+	}
+	return i == len(q)
+}
+
+func isSeparator(r rune) bool {
+	switch r {
+	case '_', '-', '.', ' ':
+		return true
+	}
+	return false
+}
@@ -6,8 +6,11 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
 	"github.com/webide/ide/backend/internal/db"
+	"github.com/webide/ide/backend/internal/git"
 	"github.com/webide/ide/backend/internal/models"
+	"github.com/webide/ide/backend/internal/projects"
 )
 
 func RegisterRoutes(router fiber.Router) {
@@ -186,7 +189,7 @@ func HandleGetChangeSetFiber(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid changeset_id"})
 	}
 
-	rows, err := db.Query(ctx, "SELECT id, project_id, COALESCE(job_id, ''), title, base_ref, COALESCE(target_ref, ''), apply_mode, status, COALESCE(summary_text, ''), created_at, updated_at FROM changesets WHERE id = $1", csID.String())
+	rows, err := db.Query(ctx, "SELECT id, project_id, COALESCE(job_id, ''), title, base_ref, COALESCE(target_ref, ''), apply_mode, status, COALESCE(summary_text, ''), COALESCE(diff, ''), created_at, updated_at FROM changesets WHERE id = $1", csID.String())
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to query changeset"})
 	}
@@ -198,7 +201,7 @@ func HandleGetChangeSetFiber(c *fiber.Ctx) error {
 
 	var cs models.ChangeSet
 	var jobID, targetRef, summaryText sql.NullString
-	err = rows.Scan(&cs.ID, &cs.ProjectID, &jobID, &cs.Title, &cs.BaseRef, &targetRef, &cs.ApplyMode, &cs.Status, &summaryText, &cs.CreatedAt, &cs.UpdatedAt)
+	err = rows.Scan(&cs.ID, &cs.ProjectID, &jobID, &cs.Title, &cs.BaseRef, &targetRef, &cs.ApplyMode, &cs.Status, &summaryText, &cs.Diff, &cs.CreatedAt, &cs.UpdatedAt)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to scan changeset"})
 	}
@@ -223,6 +226,43 @@ func HandleApplyChangeSetFiber(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid changeset_id"})
 	}
 
+	// Changesets from scheduled runs carry their files and have not touched
+	// the working tree yet.
+	var projectID, status, diff string
+	if err := db.GetDB().QueryRowContext(ctx, "SELECT project_id, status, COALESCE(diff, '') FROM changesets WHERE id = ?", csID.String()).Scan(&projectID, &status, &diff); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "changeset not found"})
+	}
+	if status != "applied" {
+		files, err := loadChangeSetFiles(ctx, csID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load changeset files"})
+		}
+		if len(files) > 0 || diff != "" {
+			projUUID, err := uuid.Parse(projectID)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+			}
+			project, err := projects.GetProject(projUUID)
+			if err != nil {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+			}
+			if len(files) > 0 {
+				conflicts, err := agent.ApplyFiles(project.RootPath, files)
+				if err != nil {
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+				}
+				if len(conflicts) > 0 {
+					return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+						"error":     "files changed since the run",
+						"conflicts": conflicts,
+					})
+				}
+			} else if err := git.ApplyPatch(project.RootPath, diff); err != nil {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+	}
+
 	_, err = db.Exec(ctx, "UPDATE changesets SET status = 'applied', updated_at = $1 WHERE id = $2",
 		time.Now(), csID.String())
 	if err != nil {
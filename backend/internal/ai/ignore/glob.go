@@ -0,0 +1,78 @@
+package ignore
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Glob matches slash-separated paths against a pattern where "*" and "?"
+// stay within one path segment, "**" crosses segments and [...] is a
+// character class. A pattern without a slash matches the base name.
+type Glob struct {
+	re       *regexp.Regexp
+	baseName bool
+}
+
+func CompileGlob(pattern string) (*Glob, error) {
+	pattern = strings.TrimPrefix(pattern, "./")
+	re, err := regexp.Compile("^" + globExpr(strings.TrimPrefix(pattern, "/")) + "$")
+	if err != nil {
+		return nil, err
+	}
+	return &Glob{re: re, baseName: !strings.Contains(pattern, "/")}, nil
+}
+
+func (g *Glob) Match(rel string) bool {
+	if g.baseName {
+		rel = rel[strings.LastIndex(rel, "/")+1:]
+	}
+	return g.re.MatchString(rel)
+}
+
+// globExpr translates a glob to a regular expression.
+func globExpr(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				switch {
+				case i+1 < len(glob) && glob[i+1] == '/':
+					// "**/" matches zero or more directories.
+					i++
+					b.WriteString("(?:.*/)?")
+				case i+1 == len(glob):
+					b.WriteString(".*")
+				default:
+					b.WriteString("[^/]*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,134 @@
+// Package ignore decides which project files the agent's search tools skip,
+// following .gitignore files, .git/info/exclude and .webide/ignore (which uses
+// the same syntax).
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultDirs are skipped even without an ignore file, unless a search asks
+// for ignored files too. .git is always skipped.
+var DefaultDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"__pycache__":  true,
+	".venv":        true,
+}
+
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher holds the rules of one directory's ignore file and points to the
+// matcher of its parent, so a tree walk can share it between goroutines.
+type Matcher struct {
+	parent *Matcher
+	// base is the directory of the rules, relative to the project root, with
+	// a trailing slash ("" for the root).
+	base  string
+	rules []rule
+}
+
+// Load returns the matcher for the project root.
+func Load(root string) *Matcher {
+	m := &Matcher{}
+	for _, name := range []string{".git/info/exclude", ".gitignore", ".webide/ignore"} {
+		m.rules = append(m.rules, readRules(filepath.Join(root, filepath.FromSlash(name)))...)
+	}
+	return m
+}
+
+// Child returns the matcher for the directory at rel (slash-separated,
+// relative to the project root), adding its .gitignore if it has one.
+func (m *Matcher) Child(root, rel string) *Matcher {
+	rules := readRules(filepath.Join(root, filepath.FromSlash(rel), ".gitignore"))
+	if len(rules) == 0 {
+		return m
+	}
+	return &Matcher{parent: m, base: rel + "/", rules: rules}
+}
+
+// Match reports whether the project-relative path is ignored. The deepest
+// ignore file with a matching rule decides, and within a file the last
+// matching rule.
+func (m *Matcher) Match(rel string, isDir bool) bool {
+	for cur := m; cur != nil; cur = cur.parent {
+		if !strings.HasPrefix(rel, cur.base) {
+			continue
+		}
+		sub := rel[len(cur.base):]
+		for i := len(cur.rules) - 1; i >= 0; i-- {
+			r := cur.rules[i]
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if r.re.MatchString(sub) {
+				return !r.negate
+			}
+		}
+	}
+	return false
+}
+
+func readRules(path string) []rule {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var rules []rule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if r, ok := parseRule(scanner.Text()); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+func parseRule(line string) (rule, bool) {
+	line = strings.TrimRight(line, "\r")
+	if !strings.HasSuffix(line, `\ `) {
+		line = strings.TrimRight(line, " \t")
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+
+	var r rule
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+
+	// A pattern with a slash before its end is relative to the ignore
+	// file's directory; otherwise it matches a name at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	expr := globExpr(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return rule{}, false
+	}
+	r.re = re
+	return r, true
+}
@@ -0,0 +1,321 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/db"
+	"github.com/webide/ide/backend/internal/models"
+)
+
+const (
+	SourceAgent = "agent"
+	SourceUser  = "user"
+
+	MirrorPath      = ".webide/memory.md"
+	MaxContentBytes = 1000
+	MaxEntries      = 200
+	PromptBudget    = 4 * 1024
+
+	configPath = ".webide/config.json"
+)
+
+var (
+	ErrNotFound  = errors.New("memory not found")
+	ErrAmbiguous = errors.New("memory id prefix matches more than one entry")
+	ErrEmpty     = errors.New("memory content is empty")
+	ErrTooLong   = fmt.Errorf("memory content exceeds %d bytes", MaxContentBytes)
+	ErrFull      = fmt.Errorf("project already has %d memories; update or delete some first", MaxEntries)
+)
+
+// Config is the "memory" section of .webide/config.json.
+type Config struct {
+	Disabled bool `json:"disabled,omitempty"`
+	Mirror   bool `json:"mirror,omitempty"`
+}
+
+// LoadConfig reads only the memory section so the tool does not depend on
+// the agent package.
+func LoadConfig(projectRoot string) Config {
+	var file struct {
+		Memory Config `json:"memory"`
+	}
+	if projectRoot == "" {
+		return file.Memory
+	}
+	data, err := os.ReadFile(filepath.Join(projectRoot, configPath))
+	if err != nil {
+		return file.Memory
+	}
+	json.Unmarshal(data, &file)
+	return file.Memory
+}
+
+const selectColumns = "SELECT id, project_id, content, source, created_at, updated_at FROM project_memories"
+
+func List(ctx context.Context, projectID uuid.UUID) ([]models.ProjectMemory, error) {
+	rows, err := db.Query(ctx, selectColumns+" WHERE project_id = $1 ORDER BY created_at ASC", projectID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.ProjectMemory{}
+	for rows.Next() {
+		var m models.ProjectMemory
+		var id, project string
+		if err := rows.Scan(&id, &project, &m.Content, &m.Source, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			continue
+		}
+		m.ID, _ = uuid.Parse(id)
+		m.ProjectID, _ = uuid.Parse(project)
+		entries = append(entries, m)
+	}
+	return entries, nil
+}
+
+// Get resolves a full id or a unique prefix of at least 4 characters, which
+// is what the agent sees in its prompt.
+func Get(ctx context.Context, projectID uuid.UUID, id string) (*models.ProjectMemory, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if len(id) < 4 {
+		return nil, ErrNotFound
+	}
+
+	entries, err := List(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *models.ProjectMemory
+	for i := range entries {
+		if !strings.HasPrefix(entries[i].ID.String(), id) {
+			continue
+		}
+		if found != nil {
+			return nil, ErrAmbiguous
+		}
+		found = &entries[i]
+	}
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	return found, nil
+}
+
+func Save(ctx context.Context, projectID uuid.UUID, content, source string) (*models.ProjectMemory, error) {
+	content, err := normalize(content)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := List(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if strings.EqualFold(entries[i].Content, content) {
+			return &entries[i], nil
+		}
+	}
+	if len(entries) >= MaxEntries {
+		return nil, ErrFull
+	}
+
+	if source == "" {
+		source = SourceAgent
+	}
+	now := time.Now()
+	m := &models.ProjectMemory{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		Content:   content,
+		Source:    source,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	_, err = db.Exec(ctx,
+		"INSERT INTO project_memories (id, project_id, content, source, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		m.ID.String(), projectID.String(), m.Content, m.Source, m.CreatedAt, m.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func Update(ctx context.Context, projectID uuid.UUID, id, content string) (*models.ProjectMemory, error) {
+	content, err := normalize(content)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Content = content
+	m.UpdatedAt = time.Now()
+	_, err = db.Exec(ctx,
+		"UPDATE project_memories SET content = $1, updated_at = $2 WHERE id = $3",
+		m.Content, m.UpdatedAt, m.ID.String(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func Delete(ctx context.Context, projectID uuid.UUID, id string) (*models.ProjectMemory, error) {
+	m, err := Get(ctx, projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(ctx, "DELETE FROM project_memories WHERE id = $1", m.ID.String()); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func normalize(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	switch {
+	case content == "":
+		return "", ErrEmpty
+	case len(content) > MaxContentBytes:
+		return "", ErrTooLong
+	}
+	return content, nil
+}
+
+func ShortID(id uuid.UUID) string {
+	return id.String()[:8]
+}
+
+// Relevant returns the memories to inject into the system prompt. When all
+// of them fit the budget they are all included; otherwise entries sharing
+// words with query win, then the most recently updated.
+func Relevant(ctx context.Context, projectID uuid.UUID, query string, budget int) []models.ProjectMemory {
+	if db.GetDB() == nil || projectID == uuid.Nil {
+		return nil
+	}
+
+	entries, err := List(ctx, projectID)
+	if err != nil {
+		log.Printf("[Memory] Failed to load memories: %v", err)
+		return nil
+	}
+	if len(Render(entries)) <= budget {
+		return entries
+	}
+
+	terms := keywords(query)
+	scores := make(map[uuid.UUID]int, len(entries))
+	for _, m := range entries {
+		content := strings.ToLower(m.Content)
+		for _, t := range terms {
+			if strings.Contains(content, t) {
+				scores[m.ID]++
+			}
+		}
+	}
+
+	ranked := append([]models.ProjectMemory{}, entries...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		si, sj := scores[ranked[i].ID], scores[ranked[j].ID]
+		if si != sj {
+			return si > sj
+		}
+		return ranked[i].UpdatedAt.After(ranked[j].UpdatedAt)
+	})
+
+	var picked []models.ProjectMemory
+	used := 0
+	for _, m := range ranked {
+		size := len(renderEntry(m))
+		if used+size > budget {
+			continue
+		}
+		picked = append(picked, m)
+		used += size
+	}
+	sort.Slice(picked, func(i, j int) bool {
+		return picked[i].CreatedAt.Before(picked[j].CreatedAt)
+	})
+	return picked
+}
+
+func keywords(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !(r == '_' || r == '-' || r == '.' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	}) {
+		if len(w) < 3 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+func renderEntry(m models.ProjectMemory) string {
+	return "- [" + ShortID(m.ID) + "] " + strings.ReplaceAll(m.Content, "\n", " ") + "\n"
+}
+
+// Render lists memories one per line, prefixed with their short id so the
+// agent can update or delete them.
+func Render(entries []models.ProjectMemory) string {
+	var b strings.Builder
+	for _, m := range entries {
+		b.WriteString(renderEntry(m))
+	}
+	return b.String()
+}
+
+// Sync rewrites the mirror file when the project opted in. The file is
+// output only; edits to it are overwritten on the next change.
+func Sync(ctx context.Context, projectID uuid.UUID, projectRoot string) {
+	if projectRoot == "" || !LoadConfig(projectRoot).Mirror {
+		return
+	}
+
+	entries, err := List(ctx, projectID)
+	if err != nil {
+		log.Printf("[Memory] Failed to load memories for mirror: %v", err)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("# Project memory\n\n")
+	b.WriteString("<!-- Generated by WebIDE. Manage entries with the agent or the memory API; edits here are overwritten. -->\n\n")
+	if len(entries) == 0 {
+		b.WriteString("_No memories yet._\n")
+	}
+	b.WriteString(Render(entries))
+
+	path := filepath.Join(projectRoot, MirrorPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("[Memory] Failed to create %s: %v", filepath.Dir(path), err)
+		return
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		log.Printf("[Memory] Failed to write %s: %v", MirrorPath, err)
+	}
+}
+
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, sql.ErrNoRows)
+}
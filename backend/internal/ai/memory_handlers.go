@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/memory"
+	"github.com/webide/ide/backend/internal/projects"
+)
+
+type MemoryRequest struct {
+	Content string `json:"content"`
+}
+
+func HandleListMemories(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+
+	entries, err := memory.List(c.Context(), projectID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list memories"})
+	}
+	return c.JSON(entries)
+}
+
+func HandleCreateMemory(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+
+	var req MemoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	entry, err := memory.Save(c.Context(), projectID, req.Content, memory.SourceUser)
+	if err != nil {
+		return memoryError(c, err)
+	}
+	syncMemoryMirror(c, projectID)
+	return c.Status(fiber.StatusCreated).JSON(entry)
+}
+
+func HandleUpdateMemory(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+
+	var req MemoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	entry, err := memory.Update(c.Context(), projectID, c.Params("memoryId"), req.Content)
+	if err != nil {
+		return memoryError(c, err)
+	}
+	syncMemoryMirror(c, projectID)
+	return c.JSON(entry)
+}
+
+func HandleDeleteMemory(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+
+	if _, err := memory.Delete(c.Context(), projectID, c.Params("memoryId")); err != nil {
+		return memoryError(c, err)
+	}
+	syncMemoryMirror(c, projectID)
+	return c.JSON(fiber.Map{"success": true})
+}
+
+func memoryError(c *fiber.Ctx, err error) error {
+	switch {
+	case memory.IsNotFound(err):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "memory not found"})
+	case errors.Is(err, memory.ErrAmbiguous), errors.Is(err, memory.ErrEmpty), errors.Is(err, memory.ErrTooLong), errors.Is(err, memory.ErrFull):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to save memory"})
+}
+
+func syncMemoryMirror(c *fiber.Ctx, projectID uuid.UUID) {
+	if project, err := projects.GetProject(projectID); err == nil {
+		memory.Sync(c.Context(), projectID, project.RootPath)
+	}
+}
@@ -28,6 +28,7 @@ func (a *Anthropic) Complete(ctx context.Context, messages []Message, cfg Config
 	msg, err := a.client.Messages.New(ctx, anthropic.MessageNewParams{
 		Model:     anthropic.Model(cfg.Model),
 		Messages:  apiMessages,
+		System:    convertSystem(messages),
 		MaxTokens: int64(cfg.MaxTokens),
 	})
 	if err != nil {
@@ -58,6 +59,7 @@ func (a *Anthropic) Stream(ctx context.Context, messages []Message, cfg Config)
 	stream := a.client.Messages.NewStreaming(ctx, anthropic.MessageNewParams{
 		Model:     anthropic.Model(cfg.Model),
 		Messages:  apiMessages,
+		System:    convertSystem(messages),
 		MaxTokens: int64(cfg.MaxTokens),
 	})
 
@@ -106,6 +108,7 @@ func (a *Anthropic) StreamWithTools(ctx context.Context, messages []Message, cfg
 		stream := a.client.Messages.NewStreaming(ctx, anthropic.MessageNewParams{
 			Model:       anthropic.Model(cfg.Model),
 			Messages:    apiMessages,
+			System:      convertSystem(messages),
 			MaxTokens:   int64(cfg.MaxTokens),
 			Tools:       apiTools,
 			Temperature: anthropic.Float(cfg.Temperature),
@@ -113,11 +116,19 @@ func (a *Anthropic) StreamWithTools(ctx context.Context, messages []Message, cfg
 
 		pendingToolCalls := make(map[int]ToolCall)
 		var thinking strings.Builder
+		var usage TokenUsage
 
 		for stream.Next() {
 			event := stream.Current()
 
 			switch ev := event.AsAny().(type) {
+			case anthropic.MessageStartEvent:
+				usage.PromptTokens = int(ev.Message.Usage.InputTokens)
+			case anthropic.MessageDeltaEvent:
+				if ev.Usage.InputTokens > 0 {
+					usage.PromptTokens = int(ev.Usage.InputTokens)
+				}
+				usage.CompletionTokens = int(ev.Usage.OutputTokens)
 			case anthropic.ContentBlockStartEvent:
 				switch block := ev.ContentBlock.AsAny().(type) {
 				case anthropic.TextBlock:
@@ -183,7 +194,8 @@ func (a *Anthropic) StreamWithTools(ctx context.Context, messages []Message, cfg
 			}
 		}
 
-		ch <- StreamChunk{Done: true}
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		ch <- StreamChunk{Usage: &usage, Done: true}
 	}()
 
 	return ch, nil
@@ -232,6 +244,16 @@ func convertMessages(messages []Message) []anthropic.MessageParam {
 	return result
 }
 
+func convertSystem(messages []Message) []anthropic.TextBlockParam {
+	var blocks []anthropic.TextBlockParam
+	for _, m := range messages {
+		if m.Role == "system" && m.Content != "" {
+			blocks = append(blocks, anthropic.TextBlockParam{Text: m.Content})
+		}
+	}
+	return blocks
+}
+
 func convertTools(tools []ToolDefinition) []anthropic.ToolUnionParam {
 	result := make([]anthropic.ToolUnionParam, len(tools))
 	for i, t := range tools {
@@ -0,0 +1,186 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var ErrCassetteExhausted = errors.New("cassette has no more recorded responses")
+
+// Cassette is a recorded sequence of model responses that can be replayed
+// without network access.
+type Cassette struct {
+	Model        string        `json:"model,omitempty"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	LastMessage Message       `json:"last_message"`
+	Messages    int           `json:"messages"`
+	Chunks      []StreamChunk `json:"chunks,omitempty"`
+	Response    *Response     `json:"response,omitempty"`
+}
+
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+func (c *Cassette) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Recorder wraps a provider and appends every response to a cassette.
+type Recorder struct {
+	inner    Provider
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+func NewRecorder(inner Provider) *Recorder {
+	return &Recorder{inner: inner, cassette: &Cassette{}}
+}
+
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cassette
+}
+
+func (r *Recorder) Complete(ctx context.Context, messages []Message, cfg Config) (*Response, error) {
+	resp, err := r.inner.Complete(ctx, messages, cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.record(messages, cfg, Interaction{Response: resp})
+	return resp, nil
+}
+
+func (r *Recorder) Stream(ctx context.Context, messages []Message, cfg Config) (<-chan Chunk, error) {
+	return r.inner.Stream(ctx, messages, cfg)
+}
+
+func (r *Recorder) StreamWithTools(ctx context.Context, messages []Message, cfg Config, tools []ToolDefinition, toolChoice string) (<-chan StreamChunk, error) {
+	in, err := r.inner.StreamWithTools(ctx, messages, cfg, tools, toolChoice)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan StreamChunk, 100)
+	go func() {
+		defer close(out)
+		var chunks []StreamChunk
+		for chunk := range in {
+			chunks = append(chunks, chunk)
+			out <- chunk
+		}
+		r.record(messages, cfg, Interaction{Chunks: chunks})
+	}()
+	return out, nil
+}
+
+func (r *Recorder) Name() string {
+	return "recorder:" + r.inner.Name()
+}
+
+func (r *Recorder) record(messages []Message, cfg Config, in Interaction) {
+	in.Messages = len(messages)
+	if len(messages) > 0 {
+		in.LastMessage = messages[len(messages)-1]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cassette.Model == "" {
+		r.cassette.Model = cfg.Model
+	}
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+}
+
+// Replayer serves recorded responses in order.
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	next     int
+}
+
+func NewReplayer(c *Cassette) *Replayer {
+	return &Replayer{cassette: c}
+}
+
+func (r *Replayer) nextInteraction() (Interaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next >= len(r.cassette.Interactions) {
+		return Interaction{}, ErrCassetteExhausted
+	}
+	in := r.cassette.Interactions[r.next]
+	r.next++
+	return in, nil
+}
+
+func (r *Replayer) Complete(ctx context.Context, messages []Message, cfg Config) (*Response, error) {
+	in, err := r.nextInteraction()
+	if err != nil {
+		return nil, err
+	}
+	if in.Response == nil {
+		return nil, fmt.Errorf("cassette interaction %d is not a completion", r.next-1)
+	}
+	return in.Response, nil
+}
+
+func (r *Replayer) Stream(ctx context.Context, messages []Message, cfg Config) (<-chan Chunk, error) {
+	in, err := r.nextInteraction()
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan Chunk, len(in.Chunks)+1)
+	for _, c := range in.Chunks {
+		ch <- Chunk{Content: c.Content, Done: c.Done}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (r *Replayer) StreamWithTools(ctx context.Context, messages []Message, cfg Config, tools []ToolDefinition, toolChoice string) (<-chan StreamChunk, error) {
+	in, err := r.nextInteraction()
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan StreamChunk, len(in.Chunks)+1)
+	for _, c := range in.Chunks {
+		ch <- c
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (r *Replayer) Name() string {
+	return "replay"
+}
+
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.cassette.Interactions) - r.next
+}
@@ -48,11 +48,12 @@ type ToolCall struct {
 }
 
 type StreamChunk struct {
-	Content       string     `json:"content,omitempty"`
-	Thinking      string     `json:"thinking,omitempty"`
-	ToolCalls     []ToolCall `json:"tool_calls,omitempty"`
-	ToolCallIndex int        `json:"tool_call_index,omitempty"`
-	Done          bool       `json:"done"`
+	Content       string      `json:"content,omitempty"`
+	Thinking      string      `json:"thinking,omitempty"`
+	ToolCalls     []ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallIndex int         `json:"tool_call_index,omitempty"`
+	Usage         *TokenUsage `json:"usage,omitempty"`
+	Done          bool        `json:"done"`
 }
 
 type Provider interface {
@@ -0,0 +1,93 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+)
+
+const Placeholder = "[REDACTED]"
+
+type rule struct {
+	name string
+	re   *regexp.Regexp
+	// group is the submatch holding the secret; 0 replaces the whole match.
+	group int
+}
+
+var rules = []rule{
+	{name: "private_key", re: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{name: "aws_access_key", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "github_token", re: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{name: "slack_token", re: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{name: "api_key", re: regexp.MustCompile(`\bsk-(?:ant-)?[A-Za-z0-9_-]{20,}\b`)},
+	{name: "google_api_key", re: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{name: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\b`)},
+	{name: "bearer", re: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{16,})`), group: 1},
+	{name: "url_credentials", re: regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@"']+:([^/\s@"']+)@`), group: 1},
+	{name: "assignment", re: regexp.MustCompile(`(?i)\b[A-Z0-9_]*(?:password|passwd|secret|token|api_?key|access_?key|private_?key)[A-Z0-9_]*\s*[:=]\s*['"]?([^\s'"\\,;]{6,})`), group: 1},
+}
+
+// String replaces anything that looks like a credential with Placeholder.
+func String(s string) string {
+	return applyRules(s, func(string, string) string { return Placeholder })
+}
+
+// applyRules runs every rule over s, replacing each secret with the
+// placeholder returned for its rule name and value.
+func applyRules(s string, placeholder func(name, secret string) string) string {
+	for _, r := range rules {
+		s = replaceGroup(s, r.re, r.group, func(secret string) string {
+			return placeholder(r.name, secret)
+		})
+	}
+	return s
+}
+
+func replaceGroup(s string, re *regexp.Regexp, group int, placeholder func(string) string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	out := make([]byte, 0, len(s))
+	last := 0
+	for _, m := range matches {
+		start, end := m[2*group], m[2*group+1]
+		if start < 0 || start < last || strings.HasPrefix(s[start:end], "[REDACTED") || insidePlaceholder(s, start) {
+			continue
+		}
+		out = append(out, s[last:start]...)
+		out = append(out, placeholder(s[start:end])...)
+		last = end
+	}
+	out = append(out, s[last:]...)
+	return string(out)
+}
+
+// insidePlaceholder reports whether i falls within an earlier placeholder, so
+// rules do not match on names like "[REDACTED:github_token:...]".
+func insidePlaceholder(s string, i int) bool {
+	open := strings.LastIndex(s[:i], "[REDACTED")
+	return open >= 0 && !strings.Contains(s[open:i], "]")
+}
+
+// Value redacts every string inside a decoded JSON value.
+func Value(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		return String(t)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = Value(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = Value(val)
+		}
+		return out
+	}
+	return v
+}
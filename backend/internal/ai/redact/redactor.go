@@ -0,0 +1,359 @@
+package redact
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultDenyGlobs are files the agent may only read with explicit approval.
+var DefaultDenyGlobs = []string{
+	"*.pem",
+	"*.key",
+	"*.p12",
+	"*.pfx",
+	"*.jks",
+	"*.keystore",
+	"id_rsa",
+	"id_dsa",
+	"id_ecdsa",
+	"id_ed25519",
+	".netrc",
+	".pgpass",
+}
+
+// Config is the "redact" section of .webide/config.json.
+type Config struct {
+	Disabled bool `json:"disabled,omitempty"`
+	// SecretNames are environment variable names whose values are always
+	// secret, whether they come from an env file or the server environment.
+	SecretNames []string `json:"secret_names,omitempty"`
+	// EnvFiles are read for secret values. Defaults to .env and .env.*
+	// except example and template files.
+	EnvFiles []string `json:"env_files,omitempty"`
+	// DenyGlobs are added to DefaultDenyGlobs.
+	DenyGlobs      []string `json:"deny_globs,omitempty"`
+	DisableEntropy bool     `json:"disable_entropy,omitempty"`
+}
+
+var (
+	secretNamePattern = regexp.MustCompile(`(?i)(key|secret|token|passw|pwd|credential|auth|private|dsn|database_url|conn)`)
+	envLinePattern    = regexp.MustCompile(`^\s*(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+	entropyToken      = regexp.MustCompile(`[A-Za-z0-9+_=-]{24,}`)
+	hexOrUUID         = regexp.MustCompile(`^[0-9a-fA-F-]+$`)
+)
+
+const (
+	minSecretValueLen = 6
+	minEntropyBits    = 4.2
+)
+
+// Redactor hides one project's secrets. Each secret gets a stable
+// placeholder so the model can still tell values apart, and Restore puts the
+// real value back into arguments the model sends to tools.
+type Redactor struct {
+	disabled  bool
+	entropy   bool
+	denyGlobs []string
+
+	mu     sync.Mutex
+	known  []knownSecret
+	values map[string]string
+}
+
+type knownSecret struct {
+	value, placeholder string
+}
+
+func New(projectRoot string, cfg Config) *Redactor {
+	r := &Redactor{
+		disabled:  cfg.Disabled,
+		entropy:   !cfg.DisableEntropy,
+		denyGlobs: append(append([]string{}, DefaultDenyGlobs...), cfg.DenyGlobs...),
+		values:    make(map[string]string),
+	}
+	if r.disabled {
+		return r
+	}
+
+	declared := make(map[string]bool, len(cfg.SecretNames))
+	for _, n := range cfg.SecretNames {
+		declared[n] = true
+	}
+
+	for _, file := range envFiles(projectRoot, cfg.EnvFiles) {
+		for name, value := range readEnvFile(file) {
+			if declared[name] || secretNamePattern.MatchString(name) || highEntropy(value) {
+				r.addKnown(name, value)
+			}
+		}
+	}
+	// Commands inherit the server environment, so its secrets can show up
+	// in tool output too.
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		if declared[name] || (strings.HasPrefix(name, "IDE_") && secretNamePattern.MatchString(name)) {
+			r.addKnown(name, value)
+		}
+	}
+
+	sort.Slice(r.known, func(i, j int) bool { return len(r.known[i].value) > len(r.known[j].value) })
+	return r
+}
+
+func (r *Redactor) addKnown(name, value string) {
+	if len(value) < minSecretValueLen || isPlainValue(value) {
+		return
+	}
+	for _, k := range r.known {
+		if k.value == value {
+			return
+		}
+	}
+	placeholder := "[REDACTED:" + name + "]"
+	r.known = append(r.known, knownSecret{value: value, placeholder: placeholder})
+	r.values[placeholder] = value
+}
+
+// isPlainValue filters env values that are configuration rather than
+// secrets and would otherwise be redacted everywhere they appear.
+func isPlainValue(v string) bool {
+	switch strings.ToLower(v) {
+	case "true", "false", "yes", "no", "on", "off", "null", "none", "changeme", "localhost", "development", "production":
+		return true
+	}
+	for _, c := range v {
+		if (c < '0' || c > '9') && c != '.' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// String redacts s. A nil or disabled Redactor returns s unchanged.
+func (r *Redactor) String(s string) string {
+	if r == nil || r.disabled || s == "" {
+		return s
+	}
+	for _, k := range r.known {
+		s = strings.ReplaceAll(s, k.value, k.placeholder)
+	}
+	s = applyRules(s, r.placeholder)
+	if r.entropy {
+		s = entropyToken.ReplaceAllStringFunc(s, func(tok string) string {
+			if !highEntropy(tok) {
+				return tok
+			}
+			return r.placeholder("entropy", tok)
+		})
+	}
+	return s
+}
+
+// placeholder names a secret by rule and a short hash so the same value
+// always gets the same placeholder.
+func (r *Redactor) placeholder(name, secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	p := "[REDACTED:" + name + ":" + hex.EncodeToString(sum[:3]) + "]"
+	r.mu.Lock()
+	r.values[p] = secret
+	r.mu.Unlock()
+	return p
+}
+
+// Value redacts every string inside a decoded JSON value.
+func (r *Redactor) Value(v interface{}) interface{} {
+	if r == nil || r.disabled {
+		return v
+	}
+	switch t := v.(type) {
+	case string:
+		return r.String(t)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = r.Value(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = r.Value(val)
+		}
+		return out
+	case []string:
+		out := make([]string, len(t))
+		for i, val := range t {
+			out[i] = r.String(val)
+		}
+		return out
+	}
+	return v
+}
+
+// Data redacts an arbitrary tool result. Typed values such as structs or
+// slices of structs are converted to their JSON form first, since Value only
+// walks decoded JSON.
+func (r *Redactor) Data(v interface{}) interface{} {
+	if r == nil || r.disabled || v == nil {
+		return v
+	}
+	if s, ok := v.(string); ok {
+		return r.String(s)
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return r.Value(v)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return r.Value(v)
+	}
+	return r.Value(generic)
+}
+
+// Restore replaces placeholders this Redactor produced with the original
+// values, so edits the model makes around a secret do not overwrite it.
+func (r *Redactor) Restore(v interface{}) interface{} {
+	if r == nil || r.disabled {
+		return v
+	}
+	switch t := v.(type) {
+	case string:
+		if !strings.Contains(t, "[REDACTED:") {
+			return t
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for p, secret := range r.values {
+			t = strings.ReplaceAll(t, p, secret)
+		}
+		return t
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			out[k] = r.Restore(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = r.Restore(val)
+		}
+		return out
+	}
+	return v
+}
+
+// Denied reports the deny glob matching a project-relative path, if any.
+func (r *Redactor) Denied(relPath string) (string, bool) {
+	if r == nil || r.disabled || relPath == "" {
+		return "", false
+	}
+	relPath = filepath.ToSlash(filepath.Clean(relPath))
+	base := path.Base(relPath)
+	for _, g := range r.denyGlobs {
+		if ok, _ := path.Match(g, base); ok {
+			return g, true
+		}
+		if ok, _ := path.Match(g, relPath); ok {
+			return g, true
+		}
+	}
+	return "", false
+}
+
+func envFiles(projectRoot string, configured []string) []string {
+	if projectRoot == "" {
+		return nil
+	}
+	if len(configured) > 0 {
+		files := make([]string, 0, len(configured))
+		for _, f := range configured {
+			files = append(files, filepath.Join(projectRoot, f))
+		}
+		return files
+	}
+	matches, _ := filepath.Glob(filepath.Join(projectRoot, ".env*"))
+	var files []string
+	for _, m := range matches {
+		name := strings.ToLower(filepath.Base(m))
+		if strings.Contains(name, "example") || strings.Contains(name, "sample") || strings.Contains(name, "template") {
+			continue
+		}
+		files = append(files, m)
+	}
+	return files
+}
+
+func readEnvFile(file string) map[string]string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := envLinePattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		value := strings.TrimSpace(m[2])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		values[m[1]] = value
+	}
+	return values
+}
+
+// highEntropy flags random-looking tokens: long, mixed case with digits and
+// a high Shannon entropy. Hex digests and UUIDs are left alone since tools
+// report them as file hashes and handles.
+func highEntropy(s string) bool {
+	if len(s) < 24 || hexOrUUID.MatchString(s) {
+		return false
+	}
+	var upper, lower, digit bool
+	for _, c := range s {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		}
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+	return shannon(s) >= minEntropyBits
+}
+
+func shannon(s string) float64 {
+	counts := make(map[rune]int)
+	for _, c := range s {
+		counts[c]++
+	}
+	var h float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
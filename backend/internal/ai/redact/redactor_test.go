@@ -0,0 +1,139 @@
+package redact_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/redact"
+)
+
+const testSecret = "sk_live_4f9a8b7c6d5e4f3a2b1c"
+
+type searchMatch struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+func newTestRedactor(t *testing.T) *redact.Redactor {
+	t.Helper()
+	projectDir := t.TempDir()
+	os.WriteFile(filepath.Join(projectDir, ".env"), []byte("STRIPE_KEY="+testSecret+"\nDEBUG=true\n"), 0644)
+	return redact.New(projectDir, redact.Config{})
+}
+
+func TestRedactor_Data(t *testing.T) {
+	r := newTestRedactor(t)
+
+	tests := []struct {
+		name  string
+		input interface{}
+	}{
+		{
+			name:  "string",
+			input: "key is " + testSecret,
+		},
+		{
+			name:  "map",
+			input: map[string]interface{}{"content": "STRIPE_KEY=" + testSecret},
+		},
+		{
+			name:  "typed slice",
+			input: []searchMatch{{File: ".env", Line: 1, Text: "STRIPE_KEY=" + testSecret}},
+		},
+		{
+			name:  "typed slice in map",
+			input: map[string]interface{}{"matches": []searchMatch{{File: "config.go", Line: 3, Text: testSecret}}},
+		},
+		{
+			name:  "pointer to struct",
+			input: &searchMatch{File: "a.txt", Text: testSecret},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := r.Data(tt.input)
+			text := stringify(out)
+			if strings.Contains(text, testSecret) {
+				t.Errorf("secret not redacted: %s", text)
+			}
+			if !strings.Contains(text, "[REDACTED:STRIPE_KEY]") {
+				t.Errorf("expected placeholder in %s", text)
+			}
+		})
+	}
+}
+
+func TestRedactor_Restore(t *testing.T) {
+	r := newTestRedactor(t)
+
+	redacted := r.String("token=" + testSecret)
+	if redacted != "token=[REDACTED:STRIPE_KEY]" {
+		t.Fatalf("unexpected redaction: %q", redacted)
+	}
+	if restored := r.Restore(redacted); restored != "token="+testSecret {
+		t.Errorf("restore returned %q", restored)
+	}
+}
+
+func TestRedactor_Denied(t *testing.T) {
+	r := redact.New(t.TempDir(), redact.Config{DenyGlobs: []string{"secrets/*.json"}})
+
+	tests := []struct {
+		name   string
+		path   string
+		denied bool
+	}{
+		{name: "pem anywhere", path: "deploy/server.pem", denied: true},
+		{name: "ssh key", path: "id_rsa", denied: true},
+		{name: "configured glob", path: "secrets/prod.json", denied: true},
+		{name: "plain source", path: "main.go", denied: false},
+		{name: "env file is redacted not denied", path: ".env", denied: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, denied := r.Denied(tt.path)
+			if denied != tt.denied {
+				t.Errorf("Denied(%q) = %v, want %v", tt.path, denied, tt.denied)
+			}
+		})
+	}
+}
+
+func TestRedactor_Disabled(t *testing.T) {
+	projectDir := t.TempDir()
+	os.WriteFile(filepath.Join(projectDir, ".env"), []byte("STRIPE_KEY="+testSecret+"\n"), 0644)
+	r := redact.New(projectDir, redact.Config{Disabled: true})
+
+	if out := r.String(testSecret); out != testSecret {
+		t.Errorf("disabled redactor changed %q to %q", testSecret, out)
+	}
+}
+
+func stringify(v interface{}) string {
+	var b strings.Builder
+	var walk func(interface{})
+	walk = func(v interface{}) {
+		switch x := v.(type) {
+		case string:
+			b.WriteString(x)
+			b.WriteByte('\n')
+		case map[string]interface{}:
+			for _, e := range x {
+				walk(e)
+			}
+		case []interface{}:
+			for _, e := range x {
+				walk(e)
+			}
+		default:
+			b.WriteString("<unwalked>")
+		}
+	}
+	walk(v)
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/schedule"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "steps and ranges", expr: "*/15 9-17 * * 1-5"},
+		{name: "lists", expr: "0,30 8 1,15 1-6/2 *"},
+		{name: "macro", expr: "@nightly"},
+		{name: "macro is case-insensitive", expr: " @Daily "},
+		{name: "sunday as 7", expr: "0 0 * * 7"},
+		{name: "too few fields", expr: "* * * *", wantErr: true},
+		{name: "too many fields", expr: "0 * * * * *", wantErr: true},
+		{name: "unknown macro", expr: "@fortnightly", wantErr: true},
+		{name: "minute out of range", expr: "60 * * * *", wantErr: true},
+		{name: "day of month zero", expr: "0 0 0 * *", wantErr: true},
+		{name: "month out of range", expr: "0 0 * 13 *", wantErr: true},
+		{name: "weekday out of range", expr: "0 0 * * 8", wantErr: true},
+		{name: "zero step", expr: "*/0 * * * *", wantErr: true},
+		{name: "negative step", expr: "*/-5 * * * *", wantErr: true},
+		{name: "reversed range", expr: "0 17-9 * * *", wantErr: true},
+		{name: "not a number", expr: "a * * * *", wantErr: true},
+		{name: "empty list item", expr: "1,,2 * * * *", wantErr: true},
+		{name: "names are not supported", expr: "0 0 * * mon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := schedule.Parse(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCron_Next(t *testing.T) {
+	// A Saturday.
+	from := time.Date(2026, 3, 14, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{name: "next step", expr: "*/15 * * * *", from: from, want: time.Date(2026, 3, 14, 10, 15, 0, 0, time.UTC)},
+		{name: "weekdays skip the weekend", expr: "0 9 * * 1-5", from: from, want: time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)},
+		{name: "daily macro", expr: "@daily", from: from, want: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "sunday as 7", expr: "0 0 * * 7", from: from, want: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "either day field matches", expr: "0 0 20 * 0", from: from, want: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "day of month and star weekday", expr: "0 0 20 * *", from: from, want: time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)},
+		{name: "strictly after a match", expr: "30 10 14 3 *", from: time.Date(2026, 3, 14, 10, 30, 0, 0, time.UTC), want: time.Date(2027, 3, 14, 10, 30, 0, 0, time.UTC)},
+		{name: "year rollover", expr: "0 0 1 * *", from: time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC), want: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "leap day", expr: "0 12 29 2 *", from: from, want: time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{name: "impossible date", expr: "0 0 31 2 *", from: from, want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := schedule.Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			if got := c.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
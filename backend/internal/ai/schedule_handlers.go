@@ -0,0 +1,242 @@
+package ai
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/schedule"
+	"github.com/webide/ide/backend/internal/db"
+	"github.com/webide/ide/backend/internal/models"
+	"github.com/webide/ide/backend/internal/projects"
+)
+
+const maxScheduleRuns = 100
+
+type ScheduleRequest struct {
+	Name     *string `json:"name"`
+	Cron     *string `json:"cron"`
+	Prompt   *string `json:"prompt"`
+	Enabled  *bool   `json:"enabled"`
+	MaxSteps *int    `json:"max_steps"`
+}
+
+// ScheduledRun is one entry in a schedule's run history.
+type ScheduledRun struct {
+	ID         uuid.UUID           `json:"id"`
+	Status     string              `json:"status"`
+	Trigger    string              `json:"trigger"`
+	Error      string              `json:"error,omitempty"`
+	Result     *ScheduledRunResult `json:"result,omitempty"`
+	StartedAt  *time.Time          `json:"started_at"`
+	FinishedAt *time.Time          `json:"finished_at"`
+}
+
+func HandleListSchedules(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+
+	schedules, err := querySchedules(c.Context(), " WHERE project_id = $1", projectID.String())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to list schedules"})
+	}
+	return c.JSON(schedules)
+}
+
+func HandleCreateSchedule(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+	if _, err := projects.GetProject(projectID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	}
+
+	var req ScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	sched := models.AgentSchedule{ProjectID: projectID, Enabled: true, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	if msg := applyScheduleRequest(&sched, req); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	sched.NextRunAt = nextRun(sched.Cron, sched.Enabled, time.Now())
+
+	if err := db.Insert(c.Context(), "agent_schedules", &sched); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to create schedule"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(sched)
+}
+
+func HandleGetSchedule(c *fiber.Ctx) error {
+	sched, err := scheduleFromParams(c)
+	if sched == nil {
+		return err
+	}
+	return c.JSON(sched)
+}
+
+func HandleUpdateSchedule(c *fiber.Ctx) error {
+	sched, err := scheduleFromParams(c)
+	if sched == nil {
+		return err
+	}
+
+	var req ScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid request body"})
+	}
+	if msg := applyScheduleRequest(sched, req); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	sched.NextRunAt = nextRun(sched.Cron, sched.Enabled, time.Now())
+	sched.UpdatedAt = time.Now()
+
+	if _, err := db.Exec(c.Context(),
+		"UPDATE agent_schedules SET name = $1, cron = $2, prompt = $3, enabled = $4, max_steps = $5, next_run_at = $6, updated_at = $7 WHERE id = $8",
+		sched.Name, sched.Cron, sched.Prompt, sched.Enabled, sched.MaxSteps, sched.NextRunAt, sched.UpdatedAt, sched.ID.String()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to update schedule"})
+	}
+	return c.JSON(sched)
+}
+
+func HandleDeleteSchedule(c *fiber.Ctx) error {
+	sched, err := scheduleFromParams(c)
+	if sched == nil {
+		return err
+	}
+	if _, err := db.Exec(c.Context(), "DELETE FROM agent_schedules WHERE id = $1", sched.ID.String()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to delete schedule"})
+	}
+	return c.JSON(fiber.Map{"success": true})
+}
+
+// HandleRunSchedule starts a run now, independent of the cron timing.
+func HandleRunSchedule(c *fiber.Ctx) error {
+	sched, err := scheduleFromParams(c)
+	if sched == nil {
+		return err
+	}
+
+	jobID, err := Scheduler.Start(sched, "manual")
+	if errors.Is(err, ErrScheduleRunning) {
+		running, _ := Scheduler.Running(sched.ID)
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error(), "job_id": running})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to start run"})
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{"job_id": jobID, "status": RunStatusRunning})
+}
+
+func HandleListScheduleRuns(c *fiber.Ctx) error {
+	sched, err := scheduleFromParams(c)
+	if sched == nil {
+		return err
+	}
+
+	rows, err := db.Query(c.Context(),
+		"SELECT id, status, COALESCE(payload_json, ''), COALESCE(result_json, ''), COALESCE(error_text, ''), started_at, finished_at FROM jobs WHERE project_id = $1 AND type = $2 AND json_extract(payload_json, '$.schedule_id') = $3 ORDER BY created_at DESC LIMIT $4",
+		sched.ProjectID.String(), ScheduledRunJobType, sched.ID.String(), maxScheduleRuns)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to query runs"})
+	}
+	defer rows.Close()
+
+	runs := []ScheduledRun{}
+	for rows.Next() {
+		var run ScheduledRun
+		var id, payloadJSON, resultJSON string
+		var startedAt, finishedAt sql.NullTime
+		if err := rows.Scan(&id, &run.Status, &payloadJSON, &resultJSON, &run.Error, &startedAt, &finishedAt); err != nil {
+			continue
+		}
+		run.ID, _ = uuid.Parse(id)
+		var payload struct {
+			Trigger string `json:"trigger"`
+		}
+		json.Unmarshal([]byte(payloadJSON), &payload)
+		run.Trigger = payload.Trigger
+		if resultJSON != "" {
+			var result ScheduledRunResult
+			if json.Unmarshal([]byte(resultJSON), &result) == nil {
+				run.Result = &result
+			}
+		}
+		if startedAt.Valid {
+			run.StartedAt = &startedAt.Time
+		}
+		if finishedAt.Valid {
+			run.FinishedAt = &finishedAt.Time
+		}
+		runs = append(runs, run)
+	}
+	return c.JSON(runs)
+}
+
+// scheduleFromParams loads the schedule named in the route. When it returns
+// nil it has already written the error response, and the handler returns
+// err as is.
+func scheduleFromParams(c *fiber.Ctx) (*models.AgentSchedule, error) {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+	scheduleID, err := uuid.Parse(c.Params("scheduleId"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid schedule_id"})
+	}
+
+	schedules, err := querySchedules(c.Context(), " WHERE id = $1 AND project_id = $2", scheduleID.String(), projectID.String())
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to load schedule"})
+	}
+	if len(schedules) == 0 {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "schedule not found"})
+	}
+	return &schedules[0], nil
+}
+
+// applyScheduleRequest copies the fields set in req and returns a validation
+// message, or "" when the schedule is valid.
+func applyScheduleRequest(sched *models.AgentSchedule, req ScheduleRequest) string {
+	if req.Name != nil {
+		sched.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Cron != nil {
+		sched.Cron = strings.TrimSpace(*req.Cron)
+	}
+	if req.Prompt != nil {
+		sched.Prompt = strings.TrimSpace(*req.Prompt)
+	}
+	if req.Enabled != nil {
+		sched.Enabled = *req.Enabled
+	}
+	if req.MaxSteps != nil {
+		sched.MaxSteps = *req.MaxSteps
+	}
+
+	switch {
+	case sched.Name == "":
+		return "name is required"
+	case sched.Prompt == "":
+		return "prompt is required"
+	case sched.MaxSteps < 0 || sched.MaxSteps > 100:
+		return "max_steps must be between 0 and 100"
+	}
+	c, err := schedule.Parse(sched.Cron)
+	if err != nil {
+		return err.Error()
+	}
+	if c.Next(time.Now()).IsZero() {
+		return "cron expression never fires"
+	}
+	return ""
+}
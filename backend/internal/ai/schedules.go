@@ -0,0 +1,378 @@
+package ai
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/ai/provider"
+	"github.com/webide/ide/backend/internal/ai/schedule"
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/config"
+	"github.com/webide/ide/backend/internal/db"
+	"github.com/webide/ide/backend/internal/git"
+	"github.com/webide/ide/backend/internal/models"
+	"github.com/webide/ide/backend/internal/projects"
+)
+
+const (
+	ScheduledRunJobType = "scheduled_agent_run"
+
+	schedulerInterval       = 30 * time.Second
+	scheduledRunTimeout     = 30 * time.Minute
+	defaultScheduleMaxSteps = 30
+)
+
+var ErrScheduleRunning = errors.New("schedule already has a run in progress")
+
+const selectScheduleColumns = "SELECT id, project_id, name, cron, prompt, enabled, max_steps, next_run_at, last_run_at, created_at, updated_at FROM agent_schedules"
+
+// ScheduledRunResult is stored in the run's job result_json.
+type ScheduledRunResult struct {
+	ScheduleID  uuid.UUID            `json:"schedule_id"`
+	ChangeSetID *uuid.UUID           `json:"changeset_id,omitempty"`
+	Files       []agent.ShadowChange `json:"files"`
+	FinalMsg    string               `json:"final_message,omitempty"`
+	Steps       int                  `json:"steps"`
+	ToolCalls   int                  `json:"tool_calls"`
+	DeniedTools int                  `json:"denied_tools"`
+	Usage       provider.TokenUsage  `json:"usage"`
+}
+
+type scheduler struct {
+	mu      sync.Mutex
+	running map[uuid.UUID]uuid.UUID
+}
+
+// Scheduler starts scheduled agent runs, at most one per schedule at a time.
+var Scheduler = &scheduler{running: make(map[uuid.UUID]uuid.UUID)}
+
+// StartScheduler fails runs interrupted by a restart and then checks for due
+// schedules until ctx is done.
+func StartScheduler(ctx context.Context) {
+	res, err := db.Exec(ctx,
+		"UPDATE jobs SET status = $1, error_text = $2, finished_at = $3 WHERE type = $4 AND status = $5",
+		RunStatusFailed, "interrupted by server restart", time.Now(), ScheduledRunJobType, RunStatusRunning)
+	if err != nil {
+		log.Printf("[Scheduler] Failed to recover interrupted runs: %v", err)
+	} else if n, _ := res.RowsAffected(); n > 0 {
+		log.Printf("[Scheduler] Marked %d interrupted scheduled runs as failed", n)
+	}
+
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		Scheduler.tick(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *scheduler) tick(ctx context.Context, now time.Time) {
+	schedules, err := querySchedules(ctx, " WHERE enabled = 1")
+	if err != nil {
+		log.Printf("[Scheduler] Failed to load schedules: %v", err)
+		return
+	}
+	for i := range schedules {
+		sched := &schedules[i]
+		if sched.NextRunAt == nil || sched.NextRunAt.After(now) {
+			continue
+		}
+		// Advance first so a slow or failing run is not retried every tick.
+		setNextRun(ctx, sched, now)
+		if _, err := s.Start(sched, "schedule"); err != nil {
+			log.Printf("[Scheduler] Skipping schedule %s (%s): %v", sched.ID, sched.Name, err)
+		}
+	}
+}
+
+// Start runs a schedule now, in the background, and returns the run's job id.
+func (s *scheduler) Start(sched *models.AgentSchedule, trigger string) (uuid.UUID, error) {
+	s.mu.Lock()
+	if _, ok := s.running[sched.ID]; ok {
+		s.mu.Unlock()
+		return uuid.Nil, ErrScheduleRunning
+	}
+	jobID := uuid.New()
+	s.running[sched.ID] = jobID
+	s.mu.Unlock()
+
+	ctx := context.Background()
+	now := time.Now()
+	job := &models.Job{
+		ID:        jobID,
+		ProjectID: sched.ProjectID,
+		Type:      ScheduledRunJobType,
+		Status:    RunStatusRunning,
+		PayloadJSON: mustMarshal(map[string]interface{}{
+			"schedule_id": sched.ID,
+			"name":        sched.Name,
+			"prompt":      sched.Prompt,
+			"trigger":     trigger,
+		}),
+		CreatedAt: now,
+		StartedAt: &now,
+	}
+	if err := db.Insert(ctx, "jobs", job); err != nil {
+		s.release(sched.ID)
+		return uuid.Nil, err
+	}
+	db.Exec(ctx, "UPDATE agent_schedules SET last_run_at = $1 WHERE id = $2", now, sched.ID.String())
+	BroadcastJobUpdate(sched.ProjectID.String(), jobID.String(), RunStatusRunning, "", map[string]interface{}{
+		"type":        ScheduledRunJobType,
+		"schedule_id": sched.ID,
+	})
+
+	log.Printf("[Scheduler] Starting run %s for schedule %s (%s, %s)", jobID, sched.ID, sched.Name, trigger)
+	go s.run(jobID, *sched)
+	return jobID, nil
+}
+
+func (s *scheduler) release(scheduleID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.running, scheduleID)
+	s.mu.Unlock()
+}
+
+// run executes the prompt headlessly in a shadow copy of the project. The
+// working tree is never touched: whatever the agent changed becomes a
+// changeset that waits for review.
+func (s *scheduler) run(jobID uuid.UUID, sched models.AgentSchedule) {
+	defer s.release(sched.ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), scheduledRunTimeout)
+	defer cancel()
+
+	result := ScheduledRunResult{ScheduleID: sched.ID, Files: []agent.ShadowChange{}}
+	err := s.execute(ctx, jobID, sched, &result)
+
+	status, errText := RunStatusSucceeded, ""
+	if err != nil {
+		status, errText = RunStatusFailed, err.Error()
+		log.Printf("[Scheduler] Run %s failed: %v", jobID, err)
+	}
+	if _, dbErr := db.Exec(context.Background(),
+		"UPDATE jobs SET status = $1, error_text = $2, result_json = $3, finished_at = $4 WHERE id = $5",
+		status, errText, mustMarshal(result), time.Now(), jobID.String()); dbErr != nil {
+		log.Printf("[Scheduler] Failed to save run %s: %v", jobID, dbErr)
+	}
+	BroadcastJobUpdate(sched.ProjectID.String(), jobID.String(), status, errText, result)
+}
+
+func (s *scheduler) execute(ctx context.Context, jobID uuid.UUID, sched models.AgentSchedule, result *ScheduledRunResult) error {
+	project, err := projects.GetProject(sched.ProjectID)
+	if err != nil {
+		return errors.New("project not found")
+	}
+	cfg, err := config.Load()
+	if err != nil || cfg == nil {
+		return errors.New("failed to load config")
+	}
+
+	shadow, err := agent.NewShadow(project.RootPath)
+	if err != nil {
+		return fmt.Errorf("failed to create shadow copy: %w", err)
+	}
+	defer shadow.Close()
+
+	agentCfg := agent.DefaultConfig()
+	agentCfg.Mode = agent.ModeExec
+	agentCfg.ProjectRoot = shadow.Root
+	agentCfg.Model = cfg.MiniMaxModel
+	agentCfg.DenyConfirm = true
+	agentCfg.Limits.MaxSteps = defaultScheduleMaxSteps
+	if sched.MaxSteps > 0 {
+		agentCfg.Limits.MaxSteps = sched.MaxSteps
+	}
+
+	session := agent.NewSession(sched.ProjectID, uuid.Nil, uuid.Nil, agentCfg)
+	orchestrator := agent.NewOrchestrator(tools.GlobalRegistry, provider.NewAnthropic(cfg.MiniMaxAPIKey, cfg.MiniMaxURL))
+	// The allowlist comes from the real tree, which the agent cannot edit.
+	scheduleCfg := agent.LoadProjectConfig(project.RootPath).Schedule
+	orchestrator.SetPolicy(agent.NewScheduledPolicyEngine(tools.GlobalRegistry, scheduleCfg))
+
+	var agentErr string
+	runErr := orchestrator.Run(ctx, session, sched.Prompt, func(ev agent.WSEvent) error {
+		switch ev.Type {
+		case agent.EventToolResult:
+			result.ToolCalls++
+			if payload, ok := ev.Payload.(map[string]interface{}); ok {
+				if e, ok := payload["error"].(*tools.ToolError); ok && e != nil && e.Code == tools.ErrCodePermission {
+					result.DeniedTools++
+				}
+			}
+		case agent.EventToolError:
+			result.ToolCalls++
+		case agent.EventAgentDone:
+			if payload, ok := ev.Payload.(agent.AgentDonePayload); ok {
+				result.Steps = payload.Steps
+				result.FinalMsg = payload.FinalMsg
+				result.Usage = payload.Usage
+			}
+		case agent.EventAgentError:
+			if payload, ok := ev.Payload.(agent.AgentErrorPayload); ok {
+				agentErr = payload.Code + ": " + payload.Message
+			}
+		}
+		return nil
+	})
+	if result.Usage.TotalTokens == 0 {
+		result.Usage = session.Usage()
+	}
+
+	// Keep whatever was done before a failure or timeout; it is only a
+	// proposal until someone applies it.
+	changes, err := shadow.Changes()
+	if err != nil {
+		return fmt.Errorf("failed to collect changes: %w", err)
+	}
+	if len(changes) > 0 {
+		for _, c := range changes {
+			result.Files = append(result.Files, agent.ShadowChange{Path: c.Path, Status: c.Status, Binary: c.Binary, Conflict: c.Conflict})
+		}
+		files, err := shadow.Files(changes)
+		if err != nil {
+			return fmt.Errorf("failed to collect changed files: %w", err)
+		}
+		cs, err := createScheduledChangeSet(ctx, jobID, sched, project.RootPath, changes, files, result.FinalMsg)
+		if err != nil {
+			return fmt.Errorf("failed to create changeset: %w", err)
+		}
+		result.ChangeSetID = &cs.ID
+	}
+
+	switch {
+	case runErr != nil:
+		return runErr
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case agentErr != "":
+		return errors.New(agentErr)
+	}
+	return nil
+}
+
+// createScheduledChangeSet saves the run's changes for review. The diff is
+// for reading; applying writes the saved file contents, which also covers
+// binary files and diffs too large to keep.
+func createScheduledChangeSet(ctx context.Context, jobID uuid.UUID, sched models.AgentSchedule, projectRoot string, changes []agent.ShadowChange, files []agent.ShadowFile, summary string) (*models.ChangeSet, error) {
+	headRef, _ := git.GetHeadCommit(projectRoot)
+	cs := &models.ChangeSet{
+		ID:          uuid.New(),
+		ProjectID:   sched.ProjectID,
+		JobID:       &jobID,
+		Title:       "Scheduled: " + sched.Name,
+		BaseRef:     headRef,
+		ApplyMode:   "working_tree",
+		Status:      "needs_review",
+		SummaryText: summary,
+		Diff:        agent.CombinedDiff(changes),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := db.Insert(ctx, "changesets", cs); err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if _, err := db.Exec(ctx,
+			"INSERT INTO changeset_files (id, changeset_id, path, status, content, mode, base_sha) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			uuid.New().String(), cs.ID.String(), f.Path, f.Status, f.Content, int(f.Mode), f.BaseSHA); err != nil {
+			return nil, err
+		}
+	}
+	BroadcastChangeSetCreated(sched.ProjectID.String(), cs.ID.String(), cs.Title, cs.Status, cs.SummaryText)
+	return cs, nil
+}
+
+// loadChangeSetFiles returns the file contents saved with a changeset, if
+// any.
+func loadChangeSetFiles(ctx context.Context, csID uuid.UUID) ([]agent.ShadowFile, error) {
+	rows, err := db.Query(ctx, "SELECT path, status, content, mode, COALESCE(base_sha, '') FROM changeset_files WHERE changeset_id = $1 ORDER BY path", csID.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []agent.ShadowFile
+	for rows.Next() {
+		var f agent.ShadowFile
+		var mode int
+		if err := rows.Scan(&f.Path, &f.Status, &f.Content, &mode, &f.BaseSHA); err != nil {
+			return nil, err
+		}
+		f.Mode = os.FileMode(mode)
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// Running reports the job id of the schedule's active run, if any.
+func (s *scheduler) Running(scheduleID uuid.UUID) (uuid.UUID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.running[scheduleID]
+	return id, ok
+}
+
+// setNextRun stores the next time the schedule fires after now.
+func setNextRun(ctx context.Context, sched *models.AgentSchedule, now time.Time) {
+	sched.NextRunAt = nextRun(sched.Cron, sched.Enabled, now)
+	if _, err := db.Exec(ctx, "UPDATE agent_schedules SET next_run_at = $1 WHERE id = $2", sched.NextRunAt, sched.ID.String()); err != nil {
+		log.Printf("[Scheduler] Failed to update next run of %s: %v", sched.ID, err)
+	}
+}
+
+func nextRun(expr string, enabled bool, now time.Time) *time.Time {
+	if !enabled {
+		return nil
+	}
+	c, err := schedule.Parse(expr)
+	if err != nil {
+		return nil
+	}
+	next := c.Next(now)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+func querySchedules(ctx context.Context, where string, args ...interface{}) ([]models.AgentSchedule, error) {
+	rows, err := db.Query(ctx, selectScheduleColumns+where+" ORDER BY created_at ASC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []models.AgentSchedule{}
+	for rows.Next() {
+		var sc models.AgentSchedule
+		var id, projectID string
+		var nextRunAt, lastRunAt sql.NullTime
+		if err := rows.Scan(&id, &projectID, &sc.Name, &sc.Cron, &sc.Prompt, &sc.Enabled, &sc.MaxSteps, &nextRunAt, &lastRunAt, &sc.CreatedAt, &sc.UpdatedAt); err != nil {
+			log.Printf("[Scheduler] Failed to scan schedule: %v", err)
+			continue
+		}
+		sc.ID, _ = uuid.Parse(id)
+		sc.ProjectID, _ = uuid.Parse(projectID)
+		if nextRunAt.Valid {
+			sc.NextRunAt = &nextRunAt.Time
+		}
+		if lastRunAt.Valid {
+			sc.LastRunAt = &lastRunAt.Time
+		}
+		schedules = append(schedules, sc)
+	}
+	return schedules, rows.Err()
+}
@@ -0,0 +1,139 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/agent"
+	"github.com/webide/ide/backend/internal/db"
+	"github.com/webide/ide/backend/internal/models"
+)
+
+func TestHandleApplyChangeSet(t *testing.T) {
+	if err := db.Init(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	app := fiber.New()
+	app.Post("/changesets/:csId/apply", HandleApplyChangeSetFiber)
+	apply := func(csID string) int {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("POST", "/changesets/"+csID+"/apply", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	// newChangeSet runs a fake scheduled edit in a shadow of a fresh project.
+	newChangeSet := func(t *testing.T) (string, uuid.UUID) {
+		project := t.TempDir()
+		writeTestFile(t, project, "main.go", "package main\n")
+		writeTestFile(t, project, "old.txt", "remove me\n")
+		writeTestFile(t, project, "logo.png", "\x89PNG\x00\x01")
+
+		projectID := uuid.New()
+		if _, err := db.Exec(ctx, "INSERT INTO projects (id, name, root_path, created_at, last_opened_at) VALUES ($1, $2, $3, $4, $4)",
+			projectID.String(), "test", project, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		shadow, err := agent.NewShadow(project)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer shadow.Close()
+		writeTestFile(t, shadow.Root, "main.go", "package main\n\nfunc main() {}\n")
+		writeTestFile(t, shadow.Root, "logo.png", "\x89PNG\x00\x02\x03")
+		writeTestFile(t, shadow.Root, "pkg/new.go", "package pkg\n")
+		os.Remove(filepath.Join(shadow.Root, "old.txt"))
+
+		changes, err := shadow.Changes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files, err := shadow.Files(changes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sched := models.AgentSchedule{ID: uuid.New(), ProjectID: projectID, Name: "nightly"}
+		cs, err := createScheduledChangeSet(ctx, uuid.New(), sched, project, changes, files, "done")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return project, cs.ID
+	}
+
+	t.Run("applies text, binary, added and deleted files", func(t *testing.T) {
+		project, csID := newChangeSet(t)
+		if code := apply(csID.String()); code != fiber.StatusOK {
+			t.Fatalf("apply returned %d", code)
+		}
+		want := map[string]string{
+			"main.go":    "package main\n\nfunc main() {}\n",
+			"logo.png":   "\x89PNG\x00\x02\x03",
+			"pkg/new.go": "package pkg\n",
+		}
+		for rel, content := range want {
+			data, err := os.ReadFile(filepath.Join(project, rel))
+			if err != nil || !bytes.Equal(data, []byte(content)) {
+				t.Errorf("%s = %q (%v), want %q", rel, data, err, content)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(project, "old.txt")); !os.IsNotExist(err) {
+			t.Errorf("old.txt should be deleted, stat err = %v", err)
+		}
+	})
+
+	t.Run("refuses files changed since the run", func(t *testing.T) {
+		project, csID := newChangeSet(t)
+		writeTestFile(t, project, "main.go", "package main // edited by the user\n")
+
+		if code := apply(csID.String()); code != fiber.StatusConflict {
+			t.Fatalf("apply returned %d, want 409", code)
+		}
+		data, _ := os.ReadFile(filepath.Join(project, "main.go"))
+		if string(data) != "package main // edited by the user\n" {
+			t.Errorf("user edit was overwritten: %q", data)
+		}
+		if _, err := os.Stat(filepath.Join(project, "pkg/new.go")); !os.IsNotExist(err) {
+			t.Error("no file should be written when one conflicts")
+		}
+	})
+
+	t.Run("invalid project id", func(t *testing.T) {
+		csID := uuid.New()
+		if _, err := db.Exec(ctx, "INSERT INTO changesets (id, project_id, title, base_ref, apply_mode, status, diff) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			csID.String(), "not-a-uuid", "broken", "", "working_tree", "needs_review", "diff --git a/x b/x\n"); err != nil {
+			t.Fatal(err)
+		}
+		if code := apply(csID.String()); code != fiber.StatusBadRequest {
+			t.Errorf("apply returned %d, want 400", code)
+		}
+	})
+
+	t.Run("invalid changeset id", func(t *testing.T) {
+		if code := apply("nope"); code != fiber.StatusBadRequest {
+			t.Errorf("apply returned %d, want 400", code)
+		}
+	})
+}
+
+func writeTestFile(t *testing.T, root, rel, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/skills"
+	"github.com/webide/ide/backend/internal/projects"
+)
+
+func HandleListSkills(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+
+	project, err := projects.GetProject(projectID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	}
+
+	list := skills.List(project.RootPath)
+	if list == nil {
+		list = []skills.Skill{}
+	}
+	return c.JSON(list)
+}
+
+// HandleGetSkill previews a skill exactly as use_skill would load it.
+func HandleGetSkill(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+
+	project, err := projects.GetProject(projectID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "project not found"})
+	}
+
+	skill, err := skills.Load(project.RootPath, c.Params("name"))
+	switch {
+	case errors.Is(err, skills.ErrInvalidName):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, skills.ErrNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to read skill"})
+	}
+	return c.JSON(skill)
+}
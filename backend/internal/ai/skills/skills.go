@@ -0,0 +1,156 @@
+package skills
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	Dir      = ".webide/skills"
+	FileName = "SKILL.md"
+
+	MaxSkillBytes       = 32 * 1024
+	MaxDescriptionBytes = 300
+	// MaxListed bounds how many skills are named in the system prompt.
+	MaxListed = 50
+	maxFiles  = 100
+)
+
+var (
+	ErrNotFound    = errors.New("skill not found")
+	ErrInvalidName = errors.New("invalid skill name")
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Skill is a packaged workflow in .webide/skills/<name>/SKILL.md. Only the
+// name and description are listed to the model; the rest of SKILL.md is
+// loaded through use_skill.
+type Skill struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Path        string `json:"path"`
+	Bytes       int    `json:"bytes"`
+}
+
+// Loaded is a skill with its instructions and the helper files shipped
+// next to SKILL.md.
+type Loaded struct {
+	Skill
+	Content   string   `json:"content"`
+	Files     []string `json:"files"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+// List returns the project's skills sorted by name. Directories without a
+// SKILL.md are ignored.
+func List(projectRoot string) []Skill {
+	if projectRoot == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(filepath.Join(projectRoot, Dir))
+	if err != nil {
+		return nil
+	}
+	var list []Skill
+	for _, e := range entries {
+		if !e.IsDir() || !namePattern.MatchString(e.Name()) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(projectRoot, Dir, e.Name(), FileName))
+		if err != nil {
+			continue
+		}
+		list = append(list, parse(e.Name(), data).Skill)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Load reads a skill by name, as listed by List.
+func Load(projectRoot, name string) (*Loaded, error) {
+	if !namePattern.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+	dir := filepath.Join(projectRoot, Dir, name)
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	skill := parse(name, data)
+	if len(skill.Content) > MaxSkillBytes {
+		skill.Content = skill.Content[:MaxSkillBytes]
+		skill.Truncated = true
+	}
+	skill.Files = helperFiles(projectRoot, dir)
+	return &skill, nil
+}
+
+// parse reads the description from an optional front matter block, or
+// else from the first paragraph line. The directory name is always the
+// skill's name.
+func parse(dirName string, data []byte) Loaded {
+	skill := Loaded{
+		Skill: Skill{
+			Name:  dirName,
+			Path:  filepath.ToSlash(filepath.Join(Dir, dirName, FileName)),
+			Bytes: len(data),
+		},
+	}
+	body := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	if rest, ok := strings.CutPrefix(body, "---\n"); ok {
+		if front, after, found := strings.Cut(rest, "\n---"); found {
+			for _, line := range strings.Split(front, "\n") {
+				key, value, ok := strings.Cut(line, ":")
+				if ok && strings.TrimSpace(key) == "description" {
+					skill.Description = strings.Trim(strings.TrimSpace(value), `"'`)
+				}
+			}
+			body = strings.TrimPrefix(strings.TrimLeft(after, "-"), "\n")
+		}
+	}
+	skill.Content = strings.TrimSpace(body)
+
+	if skill.Description == "" {
+		for _, line := range strings.Split(skill.Content, "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				skill.Description = line
+				break
+			}
+		}
+	}
+	if len(skill.Description) > MaxDescriptionBytes {
+		skill.Description = skill.Description[:MaxDescriptionBytes] + "..."
+	}
+	return skill
+}
+
+// helperFiles lists the files next to SKILL.md relative to the project root
+// so the model can read or run them with the usual tools.
+func helperFiles(projectRoot, dir string) []string {
+	files := []string{}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == FileName {
+			return nil
+		}
+		if len(files) >= maxFiles {
+			return filepath.SkipAll
+		}
+		if rel, err := filepath.Rel(projectRoot, path); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files
+}
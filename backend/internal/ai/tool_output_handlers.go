@@ -0,0 +1,28 @@
+package ai
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+// HandleGetToolOutput pages through the full output behind a summarized tool
+// result.
+func HandleGetToolOutput(c *fiber.Ctx) error {
+	projectID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid project_id"})
+	}
+
+	handle := c.Params("handle")
+	out, ok := tools.Outputs.Get(handle)
+	if !ok || out.ProjectID != projectID {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "output not found or expired"})
+	}
+
+	page, _ := tools.Outputs.Page(handle, c.QueryInt("from", 0), c.QueryInt("limit", 1000))
+	return c.JSON(fiber.Map{
+		"output": out,
+		"page":   page,
+	})
+}
@@ -58,6 +58,16 @@ func ApplyPatch() tools.Tool {
 				}), nil
 			}
 
+			// The diff and rejects would show a protected file's content.
+			for _, patch := range patchSet {
+				absPath := filepath.Join(tc.ProjectRoot, patch.File)
+				if glob, denied := tc.Protected(absPath); denied {
+					if _, err := os.Stat(absPath); err == nil {
+						return tools.NewProtectedResult(patch.File, glob), nil
+					}
+				}
+			}
+
 			var applied []FileChange
 			var rejects []Reject
 
@@ -0,0 +1,245 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/codenav"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+var symbolQueryProperties = map[string]interface{}{
+	"symbol": map[string]interface{}{
+		"type":        "string",
+		"description": "Name to look up: Name, pkg.Name or Type.Method. With path and line, picks the identifier on that line",
+	},
+	"path": map[string]interface{}{
+		"type":        "string",
+		"description": "File (with line) or package directory the symbol is in",
+	},
+	"line": map[string]interface{}{
+		"type": "integer",
+	},
+	"column": map[string]interface{}{
+		"type": "integer",
+	},
+}
+
+func ListSymbols() tools.Tool {
+	return tools.Tool{
+		Name:        "list_symbols",
+		Description: "Outline a source file: functions, methods, types, fields, constants and variables with line numbers and signatures. Exact for Go, heuristic for other languages.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type": "string",
+				},
+			},
+			"required": []string{"path"},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			p, _ := args["path"].(string)
+			abs, errResult := codenavPath(p, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			if info, err := os.Stat(abs); err == nil && info.IsDir() {
+				return tools.NewErrorResult(tools.ErrCodeValidation, "path is a directory; use package_api to list a package", nil), nil
+			}
+
+			outline, err := codenav.ListSymbols(tc.ProjectRoot, abs)
+			if err != nil {
+				return codenavError(err), nil
+			}
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"path":      outline.Path,
+				"language":  outline.Language,
+				"symbols":   outline.Symbols,
+				"truncated": outline.Truncated,
+				"heuristic": outline.Heuristic,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds(), Truncated: outline.Truncated}), nil
+		},
+	}
+}
+
+func FindDefinition() tools.Tool {
+	return tools.Tool{
+		Name:        "find_definition",
+		Description: "Find where a symbol is declared, by name or by the position of a use (path, line, column). Go code is resolved with type information; other languages are matched by name.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": symbolQueryProperties,
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			q, errResult := symbolQuery(args, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			defs, err := codenav.FindDefinition(tc.ProjectRoot, q)
+			if err != nil {
+				return codenavError(err), nil
+			}
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"definitions": dropProtected(defs.Definitions, tc),
+				"heuristic":   defs.Heuristic,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds()}), nil
+		},
+	}
+}
+
+func FindReferences() tools.Tool {
+	props := map[string]interface{}{
+		"include_declaration": map[string]interface{}{
+			"type":        "boolean",
+			"description": "Also list the declaration itself",
+		},
+	}
+	for k, v := range symbolQueryProperties {
+		props[k] = v
+	}
+
+	return tools.Tool{
+		Name:        "find_references",
+		Description: "List every use of a symbol across the project, by name or by position. Go references are resolved with type information; other languages fall back to a whole-word search.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": props,
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			q, errResult := symbolQuery(args, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			includeDecl, _ := args["include_declaration"].(bool)
+			refs, err := codenav.FindReferences(tc.ProjectRoot, q, includeDecl)
+			if err != nil {
+				return codenavError(err), nil
+			}
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"definitions": dropProtected(refs.Definitions, tc),
+				"references":  dropProtected(refs.References, tc),
+				"total":       refs.Total,
+				"truncated":   refs.Truncated,
+				"heuristic":   refs.Heuristic,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds(), Truncated: refs.Truncated}), nil
+		},
+	}
+}
+
+func PackageAPI() tools.Tool {
+	return tools.Tool{
+		Name:        "package_api",
+		Description: "List the exported API of a package directory: types, functions, methods, constants and variables with signatures and doc summaries",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Package directory, or a file in it",
+				},
+			},
+			"required": []string{"path"},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			p, _ := args["path"].(string)
+			abs, errResult := codenavPath(p, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			api, err := codenav.PackageAPI(tc.ProjectRoot, abs)
+			if err != nil {
+				return codenavError(err), nil
+			}
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"path":        api.Path,
+				"import_path": api.ImportPath,
+				"name":        api.Name,
+				"doc":         api.Doc,
+				"symbols":     api.Symbols,
+				"truncated":   api.Truncated,
+				"heuristic":   api.Heuristic,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds(), Truncated: api.Truncated}), nil
+		},
+	}
+}
+
+func codenavPath(p string, tc tools.ToolContext) (string, *tools.ToolResult) {
+	if p == "" {
+		r := tools.NewErrorResult(tools.ErrCodeValidation, "path is required", nil)
+		return "", &r
+	}
+	abs, err := tools.NewPathGuard(tc.ProjectRoot, tc.Limits).ResolveProjectPath(p)
+	if err != nil {
+		r := tools.NewErrorResult(tools.ErrCodeInvalidPath, err.Error(), map[string]interface{}{"path": p})
+		return "", &r
+	}
+	if glob, denied := tc.Protected(abs); denied {
+		rel, _ := filepath.Rel(tc.ProjectRoot, abs)
+		r := tools.NewProtectedResult(filepath.ToSlash(rel), glob)
+		return "", &r
+	}
+	return abs, nil
+}
+
+// dropProtected leaves out locations in protected files, whose line text
+// could hold a secret.
+func dropProtected(locs []codenav.Location, tc tools.ToolContext) []codenav.Location {
+	if tc.Denied == nil {
+		return locs
+	}
+	kept := locs[:0]
+	for _, l := range locs {
+		if !l.External {
+			if _, denied := tc.Protected(l.Path); denied {
+				continue
+			}
+		}
+		kept = append(kept, l)
+	}
+	return kept
+}
+
+func symbolQuery(args map[string]interface{}, tc tools.ToolContext) (codenav.Query, *tools.ToolResult) {
+	q := codenav.Query{}
+	q.Symbol, _ = args["symbol"].(string)
+	if l, ok := args["line"].(float64); ok {
+		q.Line = int(l)
+	}
+	if c, ok := args["column"].(float64); ok {
+		q.Column = int(c)
+	}
+	if p, _ := args["path"].(string); p != "" {
+		abs, errResult := codenavPath(p, tc)
+		if errResult != nil {
+			return q, errResult
+		}
+		q.Path = abs
+	}
+	return q, nil
+}
+
+func codenavError(err error) tools.ToolResult {
+	switch {
+	case errors.Is(err, codenav.ErrNotFound), errors.Is(err, os.ErrNotExist):
+		return tools.NewErrorResult(tools.ErrCodeNotFound, err.Error(), nil)
+	case errors.Is(err, codenav.ErrInvalidQuery):
+		return tools.NewErrorResult(tools.ErrCodeValidation, err.Error(), nil)
+	}
+	return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil)
+}
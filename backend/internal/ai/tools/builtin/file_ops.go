@@ -0,0 +1,463 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+// maxCopyEntries caps how many files and directories copy_path creates.
+const maxCopyEntries = 5000
+
+func MovePath() tools.Tool {
+	return tools.Tool{
+		Name:        "move_path",
+		Description: "Move or rename a file or directory inside the project. Missing parent directories of the destination are created.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"source": map[string]interface{}{
+					"type": "string",
+				},
+				"destination": map[string]interface{}{
+					"type": "string",
+				},
+				"overwrite": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Replace an existing destination file",
+				},
+			},
+			"required": []string{"source", "destination"},
+		},
+		Policy: tools.PolicyConfirm,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			src, dst, overwritten, errResult := prepareTransfer(args, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			if err := os.MkdirAll(filepath.Dir(dst.abs), 0755); err != nil {
+				return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil), nil
+			}
+			if err := os.Rename(src.abs, dst.abs); err != nil {
+				return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil), nil
+			}
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"source":      src.rel,
+				"destination": dst.rel,
+				"type":        pathType(src.info),
+				"overwritten": overwritten,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds()}), nil
+		},
+	}
+}
+
+func CopyPath() tools.Tool {
+	return tools.Tool{
+		Name:        "copy_path",
+		Description: "Copy a file or directory (recursively) inside the project. Symlinks are copied as links.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"source": map[string]interface{}{
+					"type": "string",
+				},
+				"destination": map[string]interface{}{
+					"type": "string",
+				},
+				"overwrite": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Replace an existing destination file",
+				},
+			},
+			"required": []string{"source", "destination"},
+		},
+		Policy: tools.PolicyConfirm,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			src, dst, overwritten, errResult := prepareTransfer(args, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+
+			if !src.info.IsDir() {
+				err := os.MkdirAll(filepath.Dir(dst.abs), 0755)
+				if err == nil && overwritten {
+					err = os.Remove(dst.abs)
+				}
+				if err != nil {
+					return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil), nil
+				}
+			}
+
+			var files int
+			var bytes int64
+			var err error
+			switch {
+			case src.info.IsDir():
+				files, bytes, err = copyDir(ctx, src.abs, dst.abs)
+			case src.info.Mode()&os.ModeSymlink != 0:
+				// A link is copied as a link, as copyDir does.
+				err = copyLink(src.abs, dst.abs)
+			default:
+				bytes, err = copyFile(src.abs, dst.abs, src.info.Mode().Perm())
+				files = 1
+			}
+			if err != nil {
+				// Leave nothing half-copied behind.
+				os.RemoveAll(dst.abs)
+			}
+			if errors.Is(err, errTooManyEntries) {
+				return tools.NewErrorResult(tools.ErrCodeSizeLimit, err.Error(), map[string]interface{}{"max_entries": maxCopyEntries}), nil
+			}
+			if err != nil {
+				return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil), nil
+			}
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"source":      src.rel,
+				"destination": dst.rel,
+				"type":        pathType(src.info),
+				"files":       files,
+				"bytes":       bytes,
+				"overwritten": overwritten,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds()}), nil
+		},
+	}
+}
+
+func DeletePath() tools.Tool {
+	return tools.Tool{
+		Name:        "delete_path",
+		Description: "Delete a file or directory inside the project. A non-empty directory needs recursive=true.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type": "string",
+				},
+				"recursive": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Delete a directory and everything in it",
+				},
+			},
+			"required": []string{"path"},
+		},
+		Policy: tools.PolicyConfirm,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			p, _ := args["path"].(string)
+			recursive, _ := args["recursive"].(bool)
+			target, errResult := resolveOpPath(p, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			if target.info == nil {
+				return tools.NewErrorResult(tools.ErrCodeNotFound, "path not found: "+target.rel, nil), nil
+			}
+
+			files := 1
+			if target.info.IsDir() {
+				entries, _ := os.ReadDir(target.abs)
+				if len(entries) > 0 && !recursive {
+					return tools.NewErrorResult(tools.ErrCodeValidation, "directory is not empty; set recursive to delete it with its contents", map[string]interface{}{
+						"path":    target.rel,
+						"entries": len(entries),
+					}), nil
+				}
+				files = 0
+				filepath.WalkDir(target.abs, func(_ string, d fs.DirEntry, err error) error {
+					if err == nil && !d.IsDir() {
+						files++
+					}
+					return nil
+				})
+			}
+
+			if err := os.RemoveAll(target.abs); err != nil {
+				return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil), nil
+			}
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"path":          target.rel,
+				"type":          pathType(target.info),
+				"files_deleted": files,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds()}), nil
+		},
+	}
+}
+
+func MakeDir() tools.Tool {
+	return tools.Tool{
+		Name:        "make_dir",
+		Description: "Create a directory inside the project, including missing parents.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type": "string",
+				},
+			},
+			"required": []string{"path"},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			p, _ := args["path"].(string)
+			target, errResult := resolveOpPath(p, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			if target.info != nil && !target.info.IsDir() {
+				return tools.NewErrorResult(tools.ErrCodeAlreadyExists, "a file already exists at "+target.rel, nil), nil
+			}
+			if target.info == nil {
+				if err := os.MkdirAll(target.abs, 0755); err != nil {
+					return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil), nil
+				}
+			}
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"path":    target.rel,
+				"created": target.info == nil,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds()}), nil
+		},
+	}
+}
+
+type opPath struct {
+	abs, rel string
+	// info is nil when nothing exists at the path.
+	info os.FileInfo
+}
+
+// resolveOpPath resolves a path for a file operation. The project root itself
+// and anything under .git are refused.
+func resolveOpPath(p string, tc tools.ToolContext) (*opPath, *tools.ToolResult) {
+	fail := func(code, msg string, details interface{}) (*opPath, *tools.ToolResult) {
+		r := tools.NewErrorResult(code, msg, details)
+		return nil, &r
+	}
+
+	if strings.TrimSpace(p) == "" {
+		return fail(tools.ErrCodeValidation, "path is required", nil)
+	}
+	guard := tools.NewPathGuard(tc.ProjectRoot, tc.Limits)
+	abs, err := guard.ResolveProjectPath(p)
+	if err != nil {
+		return fail(tools.ErrCodeInvalidPath, err.Error(), map[string]interface{}{"path": p})
+	}
+	rel, _ := filepath.Rel(tc.ProjectRoot, abs)
+	rel = filepath.ToSlash(rel)
+
+	if rel == "." {
+		return fail(tools.ErrCodePermission, "refusing to operate on the project root", nil)
+	}
+	// A symlinked directory can lead into .git under another name, so the
+	// resolved path is checked as well.
+	if inGitDir(rel) || inGitDir(realRel(tc.ProjectRoot, abs)) {
+		return fail(tools.ErrCodePermission, "refusing to modify .git", map[string]interface{}{"path": rel})
+	}
+
+	op := &opPath{abs: abs, rel: rel}
+	info, err := os.Lstat(abs)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fail(tools.ErrCodeExecution, err.Error(), nil)
+	}
+	if err == nil {
+		op.info = info
+	}
+	return op, nil
+}
+
+func inGitDir(rel string) bool {
+	for _, part := range strings.Split(rel, "/") {
+		if part == ".git" {
+			return true
+		}
+	}
+	return false
+}
+
+// realRel returns abs relative to root after resolving symlinks in both. A
+// path that does not exist yet is resolved through its nearest existing
+// parent.
+func realRel(root, abs string) string {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		realRoot = root
+	}
+	rest := ""
+	for p := abs; ; p = filepath.Dir(p) {
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			rel, err := filepath.Rel(realRoot, filepath.Join(real, rest))
+			if err != nil {
+				return ""
+			}
+			return filepath.ToSlash(rel)
+		}
+		if p == filepath.Dir(p) {
+			return ""
+		}
+		rest = filepath.Join(filepath.Base(p), rest)
+	}
+}
+
+// prepareTransfer checks the source and destination of a move or copy. The
+// bool reports whether an existing destination file will be replaced.
+func prepareTransfer(args map[string]interface{}, tc tools.ToolContext) (*opPath, *opPath, bool, *tools.ToolResult) {
+	fail := func(code, msg string, details interface{}) (*opPath, *opPath, bool, *tools.ToolResult) {
+		r := tools.NewErrorResult(code, msg, details)
+		return nil, nil, false, &r
+	}
+
+	srcArg, _ := args["source"].(string)
+	dstArg, _ := args["destination"].(string)
+	overwrite, _ := args["overwrite"].(bool)
+
+	src, errResult := resolveOpPath(srcArg, tc)
+	if errResult != nil {
+		return nil, nil, false, errResult
+	}
+	dst, errResult := resolveOpPath(dstArg, tc)
+	if errResult != nil {
+		return nil, nil, false, errResult
+	}
+	if src.info == nil {
+		return fail(tools.ErrCodeNotFound, "source not found: "+src.rel, nil)
+	}
+	if src.abs == dst.abs {
+		return fail(tools.ErrCodeValidation, "source and destination are the same", nil)
+	}
+	if src.info.IsDir() && strings.HasPrefix(dst.abs, src.abs+string(filepath.Separator)) {
+		return fail(tools.ErrCodeValidation, "destination is inside the source directory", nil)
+	}
+
+	if rel, glob, exposed := exposesProtected(src, dst, tc); exposed {
+		r := tools.NewProtectedResult(rel, glob)
+		return nil, nil, false, &r
+	}
+
+	if dst.info == nil {
+		return src, dst, false, nil
+	}
+	if !overwrite || dst.info.IsDir() || src.info.IsDir() {
+		return fail(tools.ErrCodeAlreadyExists, "destination already exists: "+dst.rel, map[string]interface{}{
+			"destination": dst.rel,
+			"type":        pathType(dst.info),
+			"hint":        "only a file can replace a file, with overwrite=true",
+		})
+	}
+	return src, dst, true, nil
+}
+
+// exposesProtected reports a protected file under src that would no longer
+// be protected at its new path under dst, where read_file would return it.
+func exposesProtected(src, dst *opPath, tc tools.ToolContext) (string, string, bool) {
+	if tc.Denied == nil {
+		return "", "", false
+	}
+	var rel, glob string
+	filepath.WalkDir(src.abs, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		g, denied := tc.Protected(path)
+		if !denied {
+			return nil
+		}
+		suffix, _ := filepath.Rel(src.abs, path)
+		if _, still := tc.Protected(filepath.Join(dst.abs, suffix)); still {
+			return nil
+		}
+		rel, glob = filepath.ToSlash(filepath.Join(src.rel, suffix)), g
+		return filepath.SkipAll
+	})
+	return rel, glob, glob != ""
+}
+
+func pathType(info os.FileInfo) string {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return "symlink"
+	case info.IsDir():
+		return "dir"
+	}
+	return "file"
+}
+
+var errTooManyEntries = errors.New("directory has too many entries to copy")
+
+func copyDir(ctx context.Context, src, dst string) (int, int64, error) {
+	var files, entries int
+	var bytes int64
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entries++; entries > maxCopyEntries {
+			return errTooManyEntries
+		}
+
+		rel, _ := filepath.Rel(src, path)
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.Type()&os.ModeSymlink != 0:
+			return copyLink(path, target)
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		n, err := copyFile(path, target, info.Mode().Perm())
+		files++
+		bytes += n
+		return err
+	})
+	return files, bytes, err
+}
+
+// copyLink creates dst as a symlink with the same target as src.
+func copyLink(src, dst string) error {
+	link, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	return os.Symlink(link, dst)
+}
+
+func copyFile(src, dst string, mode os.FileMode) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
@@ -0,0 +1,258 @@
+package builtin_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/ai/tools/builtin"
+)
+
+// fileOpsProject creates a small project for the file operation tools.
+func fileOpsProject(t *testing.T) (string, tools.ToolContext) {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"a.txt":           "a\n",
+		"b.txt":           "b\n",
+		"dir/one.txt":     "one\n",
+		"dir/sub/two.txt": "two\n",
+		".git/config":     "[core]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(root, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{"link.txt": "a.txt", "gitlink": ".git"}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, tools.ToolContext{ProjectRoot: root}
+}
+
+func TestFileOps_Refusals(t *testing.T) {
+	tests := []struct {
+		name     string
+		tool     tools.Tool
+		args     map[string]interface{}
+		wantCode string
+	}{
+		{name: "delete .git", tool: builtin.DeletePath(), args: map[string]interface{}{"path": ".git", "recursive": true}, wantCode: tools.ErrCodePermission},
+		{name: "delete inside .git", tool: builtin.DeletePath(), args: map[string]interface{}{"path": ".git/config"}, wantCode: tools.ErrCodePermission},
+		{name: "delete project root", tool: builtin.DeletePath(), args: map[string]interface{}{"path": ".", "recursive": true}, wantCode: tools.ErrCodePermission},
+		{name: "move into .git", tool: builtin.MovePath(), args: map[string]interface{}{"source": "a.txt", "destination": ".git/hooks/pre-commit"}, wantCode: tools.ErrCodePermission},
+		{name: "copy out of nested .git", tool: builtin.CopyPath(), args: map[string]interface{}{"source": "dir/.git/HEAD", "destination": "HEAD"}, wantCode: tools.ErrCodePermission},
+		{name: "make dir in .git", tool: builtin.MakeDir(), args: map[string]interface{}{"path": ".git/refs/x"}, wantCode: tools.ErrCodePermission},
+		{name: "delete through a link to .git", tool: builtin.DeletePath(), args: map[string]interface{}{"path": "gitlink/config"}, wantCode: tools.ErrCodePermission},
+		{name: "copy into .git through a link", tool: builtin.CopyPath(), args: map[string]interface{}{"source": "a.txt", "destination": "gitlink/hooks/pre-commit"}, wantCode: tools.ErrCodePermission},
+		{name: "path outside the project", tool: builtin.DeletePath(), args: map[string]interface{}{"path": "../a.txt"}, wantCode: tools.ErrCodeInvalidPath},
+		{name: "missing source", tool: builtin.MovePath(), args: map[string]interface{}{"source": "nope.txt", "destination": "c.txt"}, wantCode: tools.ErrCodeNotFound},
+		{name: "copy a directory into itself", tool: builtin.CopyPath(), args: map[string]interface{}{"source": "dir", "destination": "dir/sub/copy"}, wantCode: tools.ErrCodeValidation},
+		{name: "same source and destination", tool: builtin.MovePath(), args: map[string]interface{}{"source": "a.txt", "destination": "./a.txt"}, wantCode: tools.ErrCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, tc := fileOpsProject(t)
+
+			result, err := tt.tool.Execute(t.Context(), tt.args, tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.OK || result.Error == nil || result.Error.Code != tt.wantCode {
+				t.Fatalf("result = %+v, want error %s", result, tt.wantCode)
+			}
+			if _, err := os.Stat(filepath.Join(root, ".git", "config")); err != nil {
+				t.Errorf(".git/config is gone: %v", err)
+			}
+		})
+	}
+}
+
+func TestFileOps_Overwrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		tool     tools.Tool
+		args     map[string]interface{}
+		wantCode string
+		// files are the contents expected afterwards; "" means no file.
+		files map[string]string
+	}{
+		{
+			name:     "move onto a file without overwrite",
+			tool:     builtin.MovePath(),
+			args:     map[string]interface{}{"source": "a.txt", "destination": "b.txt"},
+			wantCode: tools.ErrCodeAlreadyExists,
+			files:    map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+		},
+		{
+			name:  "move onto a file with overwrite",
+			tool:  builtin.MovePath(),
+			args:  map[string]interface{}{"source": "a.txt", "destination": "b.txt", "overwrite": true},
+			files: map[string]string{"a.txt": "", "b.txt": "a\n"},
+		},
+		{
+			name:     "move onto a directory with overwrite",
+			tool:     builtin.MovePath(),
+			args:     map[string]interface{}{"source": "a.txt", "destination": "dir", "overwrite": true},
+			wantCode: tools.ErrCodeAlreadyExists,
+			files:    map[string]string{"a.txt": "a\n", "dir/one.txt": "one\n"},
+		},
+		{
+			name:  "move creates parents",
+			tool:  builtin.MovePath(),
+			args:  map[string]interface{}{"source": "a.txt", "destination": "new/deep/a.txt"},
+			files: map[string]string{"a.txt": "", "new/deep/a.txt": "a\n"},
+		},
+		{
+			name:     "copy onto a file without overwrite",
+			tool:     builtin.CopyPath(),
+			args:     map[string]interface{}{"source": "a.txt", "destination": "b.txt"},
+			wantCode: tools.ErrCodeAlreadyExists,
+			files:    map[string]string{"a.txt": "a\n", "b.txt": "b\n"},
+		},
+		{
+			name:  "copy onto a file with overwrite",
+			tool:  builtin.CopyPath(),
+			args:  map[string]interface{}{"source": "a.txt", "destination": "b.txt", "overwrite": true},
+			files: map[string]string{"a.txt": "a\n", "b.txt": "a\n"},
+		},
+		{
+			name:     "copy a directory onto a directory",
+			tool:     builtin.CopyPath(),
+			args:     map[string]interface{}{"source": "dir", "destination": "empty", "overwrite": true},
+			wantCode: tools.ErrCodeAlreadyExists,
+		},
+		{
+			name:  "copy a directory recursively",
+			tool:  builtin.CopyPath(),
+			args:  map[string]interface{}{"source": "dir", "destination": "copy"},
+			files: map[string]string{"dir/one.txt": "one\n", "copy/one.txt": "one\n", "copy/sub/two.txt": "two\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, tc := fileOpsProject(t)
+
+			result, err := tt.tool.Execute(t.Context(), tt.args, tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCode != "" {
+				if result.OK || result.Error == nil || result.Error.Code != tt.wantCode {
+					t.Fatalf("result = %+v, want error %s", result, tt.wantCode)
+				}
+			} else if !result.OK {
+				t.Fatalf("%s failed: %+v", tt.tool.Name, result.Error)
+			}
+
+			for name, want := range tt.files {
+				got, err := os.ReadFile(filepath.Join(root, name))
+				switch {
+				case want == "" && !os.IsNotExist(err):
+					t.Errorf("%s exists with %q, want none", name, got)
+				case want != "" && string(got) != want:
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCopyPath_Symlink(t *testing.T) {
+	root, tc := fileOpsProject(t)
+
+	result, err := builtin.CopyPath().Execute(t.Context(), map[string]interface{}{"source": "link.txt", "destination": "copy.txt"}, tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.OK {
+		t.Fatalf("copy_path failed: %+v", result.Error)
+	}
+	if target, err := os.Readlink(filepath.Join(root, "copy.txt")); err != nil || target != "a.txt" {
+		t.Errorf("copy.txt links to %q (%v), want a link to a.txt", target, err)
+	}
+}
+
+func TestCopyPath_RemovesPartialCopy(t *testing.T) {
+	root, tc := fileOpsProject(t)
+	// A socket cannot be opened for reading, so the copy fails after
+	// one.txt has already been copied.
+	l, err := net.Listen("unix", filepath.Join(root, "dir", "sock"))
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer l.Close()
+
+	result, err := builtin.CopyPath().Execute(t.Context(), map[string]interface{}{"source": "dir", "destination": "copy"}, tc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.OK {
+		t.Fatalf("copy_path succeeded: %+v", result.Data)
+	}
+	if _, err := os.Lstat(filepath.Join(root, "copy")); !os.IsNotExist(err) {
+		t.Errorf("partial copy left behind: %v", err)
+	}
+}
+
+func TestDeletePath_Recursive(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		recursive bool
+		wantCode  string
+		wantFiles int
+	}{
+		{name: "file", path: "a.txt", wantFiles: 1},
+		{name: "empty directory", path: "empty", wantFiles: 0},
+		{name: "non-empty directory without recursive", path: "dir", wantCode: tools.ErrCodeValidation},
+		{name: "non-empty directory with recursive", path: "dir", recursive: true, wantFiles: 2},
+		{name: "missing path", path: "nope", wantCode: tools.ErrCodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, tc := fileOpsProject(t)
+
+			result, err := builtin.DeletePath().Execute(t.Context(), map[string]interface{}{"path": tt.path, "recursive": tt.recursive}, tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, statErr := os.Lstat(filepath.Join(root, tt.path))
+			if tt.wantCode != "" {
+				if result.OK || result.Error == nil || result.Error.Code != tt.wantCode {
+					t.Fatalf("result = %+v, want error %s", result, tt.wantCode)
+				}
+				if tt.wantCode != tools.ErrCodeNotFound && statErr != nil {
+					t.Errorf("%s was deleted after an error: %v", tt.path, statErr)
+				}
+				return
+			}
+			if !result.OK {
+				t.Fatalf("delete_path failed: %+v", result.Error)
+			}
+			if !os.IsNotExist(statErr) {
+				t.Errorf("%s still exists", tt.path)
+			}
+			data, _ := result.Data.(map[string]interface{})
+			if data["files_deleted"] != tt.wantFiles {
+				t.Errorf("files_deleted = %v, want %d", data["files_deleted"], tt.wantFiles)
+			}
+		})
+	}
+}
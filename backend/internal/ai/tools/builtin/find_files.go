@@ -0,0 +1,74 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/fileindex"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+func FindFiles() tools.Tool {
+	return tools.Tool{
+		Name:        "find_files",
+		Description: "Find files by fuzzy path query (e.g. \"usrsvc\" finds internal/user/service.go) and/or glob patterns, instead of listing directories. Returns ranked paths with size and modification time; ignored files are skipped.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Characters that appear in order in the path",
+				},
+				"globs": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+					"description": "Only return paths matching one of these globs; a glob without a slash matches the file name, \"**\" crosses directories",
+				},
+				"limit": map[string]interface{}{
+					"type":    "integer",
+					"default": 20,
+					"minimum": 1,
+					"maximum": fileindex.MaxLimit,
+				},
+			},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			query, _ := args["query"].(string)
+			var globs []string
+			if g, ok := args["globs"].([]interface{}); ok {
+				for _, item := range g {
+					if s, ok := item.(string); ok && s != "" {
+						globs = append(globs, s)
+					}
+				}
+			}
+			limit := 20
+			if l, ok := args["limit"].(float64); ok {
+				limit = int(l)
+			}
+
+			res, err := fileindex.For(tc.ProjectRoot).Find(query, globs, limit)
+			if errors.Is(err, fileindex.ErrInvalidQuery) {
+				return tools.NewErrorResult(tools.ErrCodeValidation, err.Error(), nil), nil
+			}
+			if err != nil {
+				return tools.NewErrorResult(tools.ErrCodeValidation, "invalid glob: "+err.Error(), nil), nil
+			}
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"query":      query,
+				"matches":    res.Matches,
+				"total":      res.Total,
+				"truncated":  res.Truncated,
+				"indexed":    res.Indexed,
+				"incomplete": res.Incomplete,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds(), Truncated: res.Truncated}), nil
+		},
+	}
+}
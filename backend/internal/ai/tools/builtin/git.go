@@ -0,0 +1,682 @@
+package builtin
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/git"
+)
+
+const (
+	gitToolTimeout      = 30 * time.Second
+	maxGitOutputBytes   = 64 * 1024
+	maxGitStatusEntries = 500
+	maxGitLogEntries    = 100
+	maxBlameLines       = 400
+)
+
+func GitStatus() tools.Tool {
+	return tools.Tool{
+		Name:        "git_status",
+		Description: "Show the current branch, its upstream, and staged, unstaged, untracked and conflicted files",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			out, errResult := runGitTool(tc, "status", "--porcelain=v1", "--branch", "-z")
+			if errResult != nil {
+				return *errResult, nil
+			}
+
+			data := map[string]interface{}{}
+			staged := []map[string]interface{}{}
+			unstaged := []map[string]interface{}{}
+			untracked := []string{}
+			conflicted := []string{}
+			entries := 0
+			truncated := false
+
+			fields := strings.Split(out, "\x00")
+			for i := 0; i < len(fields); i++ {
+				f := fields[i]
+				if strings.HasPrefix(f, "## ") {
+					parseBranchHeader(f[3:], data)
+					continue
+				}
+				if len(f) < 4 {
+					continue
+				}
+				x, y, path := f[0], f[1], f[3:]
+				origPath := ""
+				if x == 'R' || x == 'C' {
+					if i+1 < len(fields) {
+						origPath = fields[i+1]
+					}
+					i++
+				}
+
+				if entries >= maxGitStatusEntries {
+					truncated = true
+					continue
+				}
+				entries++
+
+				switch {
+				case x == '?' && y == '?':
+					untracked = append(untracked, path)
+				case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+					conflicted = append(conflicted, path)
+				default:
+					if x != ' ' {
+						entry := map[string]interface{}{"path": path, "status": string(x)}
+						if origPath != "" {
+							entry["orig_path"] = origPath
+						}
+						staged = append(staged, entry)
+					}
+					if y != ' ' {
+						unstaged = append(unstaged, map[string]interface{}{"path": path, "status": string(y)})
+					}
+				}
+			}
+
+			data["staged"] = staged
+			data["unstaged"] = unstaged
+			data["untracked"] = untracked
+			data["conflicted"] = conflicted
+			data["clean"] = entries == 0 && !truncated
+			data["truncated"] = truncated
+			return tools.NewSuccessResultWithMeta(data, tools.ResultMeta{
+				DurationMs: time.Since(startTime).Milliseconds(),
+				Truncated:  truncated,
+			}), nil
+		},
+	}
+}
+
+// parseBranchHeader reads a porcelain "## main...origin/main [ahead 1]" line.
+func parseBranchHeader(h string, data map[string]interface{}) {
+	if i := strings.Index(h, " ["); i >= 0 && strings.HasSuffix(h, "]") {
+		for _, part := range strings.Split(h[i+2:len(h)-1], ", ") {
+			if n, ok := strings.CutPrefix(part, "ahead "); ok {
+				data["ahead"], _ = strconv.Atoi(n)
+			}
+			if n, ok := strings.CutPrefix(part, "behind "); ok {
+				data["behind"], _ = strconv.Atoi(n)
+			}
+		}
+		h = h[:i]
+	}
+	h = strings.TrimPrefix(h, "No commits yet on ")
+	branch, upstream, _ := strings.Cut(h, "...")
+	data["branch"] = branch
+	if upstream != "" {
+		data["upstream"] = upstream
+	}
+}
+
+func GitDiff() tools.Tool {
+	return tools.Tool{
+		Name:        "git_diff",
+		Description: "Show changes as a unified diff with per-file line counts: unstaged changes by default, staged changes with staged=true, or between refs with from (and optionally to)",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"staged": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Diff the index against HEAD",
+				},
+				"from": map[string]interface{}{
+					"type":        "string",
+					"description": "Ref to diff from; without to, diffs it against the working tree",
+				},
+				"to": map[string]interface{}{
+					"type":        "string",
+					"description": "Ref to diff to",
+				},
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Limit the diff to a file or directory",
+				},
+			},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			staged, _ := args["staged"].(bool)
+			from, _ := args["from"].(string)
+			to, _ := args["to"].(string)
+
+			diffArgs := []string{"diff", "--no-color", "--no-ext-diff"}
+			switch {
+			case to != "" && from == "":
+				return tools.NewErrorResult(tools.ErrCodeValidation, "to needs from", nil), nil
+			case staged && to != "":
+				return tools.NewErrorResult(tools.ErrCodeValidation, "staged cannot be combined with to", nil), nil
+			}
+			if staged {
+				diffArgs = append(diffArgs, "--cached")
+			}
+			for _, ref := range []string{from, to} {
+				if ref == "" {
+					continue
+				}
+				if !validGitRef(ref) {
+					return tools.NewErrorResult(tools.ErrCodeValidation, "invalid ref: "+ref, nil), nil
+				}
+				diffArgs = append(diffArgs, ref)
+			}
+
+			pathArgs, errResult := gitPathArgs(args, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+
+			numstat, errResult := runGitTool(tc, append(append(append([]string{}, diffArgs...), "--numstat"), pathArgs...)...)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			patch, errResult := runGitTool(tc, append(diffArgs, pathArgs...)...)
+			if errResult != nil {
+				return *errResult, nil
+			}
+
+			patch, protected := dropProtectedDiffs(patch, tc)
+			files, additions, deletions := parseNumstat(dropProtectedNumstat(numstat, protected))
+			patch, truncated := capGitOutput(patch, tc)
+			data := map[string]interface{}{
+				"files":     files,
+				"additions": additions,
+				"deletions": deletions,
+				"diff":      patch,
+				"truncated": truncated,
+			}
+			if len(protected) > 0 {
+				data["protected_files"] = protected
+			}
+			return tools.NewSuccessResultWithMeta(data, tools.ResultMeta{
+				DurationMs: time.Since(startTime).Milliseconds(),
+				Truncated:  truncated,
+			}), nil
+		},
+	}
+}
+
+func GitLog() tools.Tool {
+	return tools.Tool{
+		Name:        "git_log",
+		Description: "List commits, newest first, optionally from a ref and touching a path",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"ref": map[string]interface{}{
+					"type":        "string",
+					"description": "Branch, tag or commit to start from (default HEAD)",
+				},
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Only commits that touch this file or directory",
+				},
+				"limit": map[string]interface{}{
+					"type":    "integer",
+					"default": 20,
+					"minimum": 1,
+					"maximum": maxGitLogEntries,
+				},
+			},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			limit := 20
+			if l, ok := args["limit"].(float64); ok {
+				limit = int(l)
+			}
+			if limit < 1 {
+				limit = 1
+			}
+			if limit > maxGitLogEntries {
+				limit = maxGitLogEntries
+			}
+
+			logArgs := []string{"log", "--no-color", "--format=%H%x1f%h%x1f%an%x1f%ae%x1f%aI%x1f%s%x1e", "-n", strconv.Itoa(limit + 1)}
+			if ref, _ := args["ref"].(string); ref != "" {
+				if !validGitRef(ref) {
+					return tools.NewErrorResult(tools.ErrCodeValidation, "invalid ref: "+ref, nil), nil
+				}
+				logArgs = append(logArgs, ref)
+			}
+			pathArgs, errResult := gitPathArgs(args, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+
+			out, errResult := runGitTool(tc, append(logArgs, pathArgs...)...)
+			if errResult != nil {
+				return *errResult, nil
+			}
+
+			commits := []map[string]interface{}{}
+			for _, rec := range strings.Split(out, "\x1e") {
+				parts := strings.Split(strings.TrimSpace(rec), "\x1f")
+				if len(parts) < 6 {
+					continue
+				}
+				commits = append(commits, map[string]interface{}{
+					"hash":    parts[0],
+					"short":   parts[1],
+					"author":  parts[2],
+					"email":   parts[3],
+					"date":    parts[4],
+					"subject": parts[5],
+				})
+			}
+			truncated := len(commits) > limit
+			if truncated {
+				commits = commits[:limit]
+			}
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"commits":   commits,
+				"truncated": truncated,
+			}, tools.ResultMeta{
+				DurationMs: time.Since(startTime).Milliseconds(),
+				Truncated:  truncated,
+			}), nil
+		},
+	}
+}
+
+func GitShow() tools.Tool {
+	return tools.Tool{
+		Name:        "git_show",
+		Description: "Show a commit (metadata, changed files and patch), or with path the content of a file at that commit",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"ref": map[string]interface{}{
+					"type":    "string",
+					"default": "HEAD",
+				},
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Show this file as it was at ref",
+				},
+			},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			ref, _ := args["ref"].(string)
+			if ref == "" {
+				ref = "HEAD"
+			}
+			if !validGitRef(ref) {
+				return tools.NewErrorResult(tools.ErrCodeValidation, "invalid ref: "+ref, nil), nil
+			}
+
+			if p, _ := args["path"].(string); p != "" {
+				rel, errResult := gitRelPath(p, tc)
+				if errResult != nil {
+					return *errResult, nil
+				}
+				if glob, denied := tc.Protected(rel); denied {
+					return tools.NewProtectedResult(rel, glob), nil
+				}
+				content, errResult := runGitTool(tc, "show", ref+":"+rel)
+				if errResult != nil {
+					return *errResult, nil
+				}
+				data := map[string]interface{}{"ref": ref, "path": rel}
+				if strings.IndexByte(content, 0) >= 0 {
+					data["binary"] = true
+					return tools.NewSuccessResultWithMeta(data, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds()}), nil
+				}
+				content, truncated := capGitOutput(content, tc)
+				data["content"] = content
+				data["truncated"] = truncated
+				return tools.NewSuccessResultWithMeta(data, tools.ResultMeta{
+					DurationMs: time.Since(startTime).Milliseconds(),
+					Truncated:  truncated,
+				}), nil
+			}
+
+			meta, errResult := runGitTool(tc, "show", "-s", "--format=%H%x1f%P%x1f%an%x1f%ae%x1f%aI%x1f%s%x1f%b", ref)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			numstat, errResult := runGitTool(tc, "show", "--format=", "--numstat", ref)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			patch, errResult := runGitTool(tc, "show", "--format=", "--no-color", "--no-ext-diff", ref)
+			if errResult != nil {
+				return *errResult, nil
+			}
+
+			parts := strings.SplitN(meta, "\x1f", 7)
+			for len(parts) < 7 {
+				parts = append(parts, "")
+			}
+			patch, protected := dropProtectedDiffs(patch, tc)
+			files, additions, deletions := parseNumstat(dropProtectedNumstat(numstat, protected))
+			patch, truncated := capGitOutput(patch, tc)
+			data := map[string]interface{}{
+				"hash":      parts[0],
+				"parents":   strings.Fields(parts[1]),
+				"author":    parts[2],
+				"email":     parts[3],
+				"date":      parts[4],
+				"subject":   parts[5],
+				"body":      strings.TrimSpace(parts[6]),
+				"files":     files,
+				"additions": additions,
+				"deletions": deletions,
+				"diff":      patch,
+				"truncated": truncated,
+			}
+			if len(protected) > 0 {
+				data["protected_files"] = protected
+			}
+			return tools.NewSuccessResultWithMeta(data, tools.ResultMeta{
+				DurationMs: time.Since(startTime).Milliseconds(),
+				Truncated:  truncated,
+			}), nil
+		},
+	}
+}
+
+func GitBlame() tools.Tool {
+	return tools.Tool{
+		Name:        "git_blame",
+		Description: "Show which commit last changed each line of a file, optionally for a line range",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type": "string",
+				},
+				"start_line": map[string]interface{}{
+					"type":    "integer",
+					"minimum": 1,
+				},
+				"end_line": map[string]interface{}{
+					"type":    "integer",
+					"minimum": 1,
+				},
+			},
+			"required": []string{"path"},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			p, _ := args["path"].(string)
+			if p == "" {
+				return tools.NewErrorResult(tools.ErrCodeValidation, "path is required", nil), nil
+			}
+			rel, errResult := gitRelPath(p, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			if glob, denied := tc.Protected(rel); denied {
+				return tools.NewProtectedResult(rel, glob), nil
+			}
+
+			blameArgs := []string{"blame", "--porcelain"}
+			start, hasStart := args["start_line"].(float64)
+			end, hasEnd := args["end_line"].(float64)
+			if hasStart || hasEnd {
+				if !hasStart || start < 1 {
+					start = 1
+				}
+				if !hasEnd || end < start || end-start >= maxBlameLines {
+					end = start + maxBlameLines - 1
+				}
+				blameArgs = append(blameArgs, "-L", strconv.Itoa(int(start))+","+strconv.Itoa(int(end)))
+			}
+
+			out, errResult := runGitTool(tc, append(blameArgs, "--", rel)...)
+			if errResult != nil {
+				return *errResult, nil
+			}
+
+			commits, lines := parseBlame(out)
+			truncated := len(lines) > maxBlameLines
+			if truncated {
+				lines = lines[:maxBlameLines]
+			}
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"path":      rel,
+				"commits":   commits,
+				"lines":     lines,
+				"truncated": truncated,
+			}, tools.ResultMeta{
+				DurationMs: time.Since(startTime).Milliseconds(),
+				Truncated:  truncated,
+			}), nil
+		},
+	}
+}
+
+// parseBlame reads `git blame --porcelain` output. Commit details are
+// reported once per commit rather than on every line.
+func parseBlame(out string) (map[string]map[string]interface{}, []map[string]interface{}) {
+	commits := map[string]map[string]interface{}{}
+	lines := []map[string]interface{}{}
+
+	var hash string
+	var lineNo int
+	for _, l := range strings.Split(out, "\n") {
+		if text, ok := strings.CutPrefix(l, "\t"); ok {
+			lines = append(lines, map[string]interface{}{"line": lineNo, "hash": hash, "text": text})
+			continue
+		}
+		fields := strings.Fields(l)
+		if len(fields) >= 3 && (len(fields[0]) == 40 || len(fields[0]) == 64) {
+			hash = fields[0]
+			lineNo, _ = strconv.Atoi(fields[2])
+			if commits[hash] == nil {
+				commits[hash] = map[string]interface{}{}
+			}
+			continue
+		}
+		key, value, _ := strings.Cut(l, " ")
+		switch key {
+		case "author", "summary":
+			commits[hash][key] = value
+		case "author-time":
+			if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+				commits[hash]["date"] = time.Unix(sec, 0).UTC().Format(time.RFC3339)
+			}
+		}
+	}
+	return commits, lines
+}
+
+// runGitTool runs git in the project root and turns failures into tool
+// errors.
+func runGitTool(tc tools.ToolContext, args ...string) (string, *tools.ToolResult) {
+	fail := func(code, msg string, details interface{}) (string, *tools.ToolResult) {
+		r := tools.NewErrorResult(code, msg, details)
+		return "", &r
+	}
+
+	if !git.IsGitRepo(tc.ProjectRoot) {
+		return fail(tools.ErrCodeValidation, "project is not a git repository", nil)
+	}
+	res, err := git.RunGit(tc.ProjectRoot, gitToolTimeout, args...)
+	if err != nil {
+		return fail(tools.ErrCodeExecution, err.Error(), nil)
+	}
+	if res.ExitCode == -1 && res.Stderr == "timeout" {
+		return fail(tools.ErrCodeTimeout, "git timed out", nil)
+	}
+	if res.ExitCode != 0 {
+		msg := strings.TrimSpace(res.Stderr)
+		if len(msg) > 2000 {
+			msg = msg[:2000]
+		}
+		return fail(tools.ErrCodeExecution, msg, map[string]interface{}{"exit_code": res.ExitCode})
+	}
+	return res.Stdout, nil
+}
+
+// gitRelPath resolves p through PathGuard and returns it relative to the
+// project root, as git expects.
+func gitRelPath(p string, tc tools.ToolContext) (string, *tools.ToolResult) {
+	guard := tools.NewPathGuard(tc.ProjectRoot, tc.Limits)
+	abs, err := guard.ResolveProjectPath(p)
+	if err != nil {
+		r := tools.NewErrorResult(tools.ErrCodeInvalidPath, err.Error(), map[string]interface{}{"path": p})
+		return "", &r
+	}
+	rel, _ := filepath.Rel(tc.ProjectRoot, abs)
+	return filepath.ToSlash(rel), nil
+}
+
+// gitPathArgs returns the "-- path" suffix for the optional path argument.
+func gitPathArgs(args map[string]interface{}, tc tools.ToolContext) ([]string, *tools.ToolResult) {
+	p, _ := args["path"].(string)
+	if p == "" {
+		return nil, nil
+	}
+	rel, errResult := gitRelPath(p, tc)
+	if errResult != nil {
+		return nil, errResult
+	}
+	return []string{"--", rel}, nil
+}
+
+// validGitRef rejects refs that git would read as options.
+func validGitRef(ref string) bool {
+	return ref != "" && len(ref) <= 256 && !strings.HasPrefix(ref, "-") && !strings.ContainsAny(ref, " \t\n\x00")
+}
+
+func parseNumstat(out string) ([]map[string]interface{}, int, int) {
+	files := []map[string]interface{}{}
+	var additions, deletions int
+	for _, l := range strings.Split(out, "\n") {
+		parts := strings.SplitN(l, "\t", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		file := map[string]interface{}{"path": parts[2]}
+		if parts[0] == "-" {
+			file["binary"] = true
+		} else {
+			add, _ := strconv.Atoi(parts[0])
+			del, _ := strconv.Atoi(parts[1])
+			file["additions"] = add
+			file["deletions"] = del
+			additions += add
+			deletions += del
+		}
+		files = append(files, file)
+	}
+	return files, additions, deletions
+}
+
+// dropProtectedDiffs removes the sections of a unified diff for files whose
+// old or new path is protected, and returns those paths.
+func dropProtectedDiffs(patch string, tc tools.ToolContext) (string, []string) {
+	if tc.Denied == nil {
+		return patch, nil
+	}
+	var out strings.Builder
+	var protected []string
+	skip := false
+	for _, line := range strings.SplitAfter(patch, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			skip = false
+			for _, p := range diffHeaderPaths(strings.TrimSuffix(line, "\n")) {
+				if _, denied := tc.Protected(p); denied {
+					skip = true
+					protected = append(protected, p)
+					break
+				}
+			}
+		}
+		if !skip {
+			out.WriteString(line)
+		}
+	}
+	return out.String(), protected
+}
+
+// diffHeaderPaths returns the old and new path of a "diff --git a/x b/y"
+// line. Paths with special characters are quoted by git.
+func diffHeaderPaths(header string) []string {
+	rest := strings.TrimPrefix(header, "diff --git ")
+	var paths []string
+	for rest != "" {
+		var p string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				break
+			}
+			p, _ = strconv.Unquote(quoted)
+			rest = strings.TrimPrefix(rest[len(quoted):], " ")
+		} else if len(paths) == 0 {
+			// Unquoted paths may contain spaces; the new path starts at
+			// the last " b/".
+			i := max(strings.LastIndex(rest, " b/"), strings.LastIndex(rest, ` "b/`))
+			if i < 0 {
+				break
+			}
+			p, rest = rest[:i], rest[i+1:]
+		} else {
+			p, rest = rest, ""
+		}
+		if len(p) > 2 && (strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/")) {
+			paths = append(paths, p[2:])
+		}
+	}
+	return paths
+}
+
+// dropProtectedNumstat removes --numstat lines for the given paths.
+func dropProtectedNumstat(numstat string, protected []string) string {
+	if len(protected) == 0 {
+		return numstat
+	}
+	var kept []string
+	for _, l := range strings.Split(numstat, "\n") {
+		parts := strings.SplitN(l, "\t", 3)
+		if len(parts) == 3 && slices.Contains(protected, parts[2]) {
+			continue
+		}
+		kept = append(kept, l)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// capGitOutput cuts s at a line boundary below the output limit.
+func capGitOutput(s string, tc tools.ToolContext) (string, bool) {
+	max := maxGitOutputBytes
+	if tc.Limits.MaxOutputBytes > 0 && tc.Limits.MaxOutputBytes < int64(max) {
+		max = int(tc.Limits.MaxOutputBytes)
+	}
+	if len(s) <= max {
+		return s, false
+	}
+	s = s[:max]
+	if i := strings.LastIndexByte(s, '\n'); i > 0 {
+		s = s[:i+1]
+	}
+	return s, true
+}
@@ -0,0 +1,125 @@
+package builtin_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/ai/tools/builtin"
+)
+
+// gitProject creates a repository with one commit of a.txt and an unstaged
+// change to it.
+func gitProject(t *testing.T) (string, tools.ToolContext) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(content string) {
+		if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q")
+	write("one\n")
+	run("add", "a.txt")
+	run("commit", "-q", "-m", "first")
+	write("one\ntwo\n")
+	return root, tools.ToolContext{ProjectRoot: root}
+}
+
+func TestGitTools_Refs(t *testing.T) {
+	tests := []struct {
+		name     string
+		tool     tools.Tool
+		args     map[string]interface{}
+		wantCode string
+	}{
+		{name: "log from HEAD", tool: builtin.GitLog(), args: map[string]interface{}{"ref": "HEAD"}},
+		{name: "show HEAD", tool: builtin.GitShow(), args: map[string]interface{}{"ref": "HEAD"}},
+		{name: "diff from HEAD", tool: builtin.GitDiff(), args: map[string]interface{}{"from": "HEAD"}},
+		{name: "log ref as option", tool: builtin.GitLog(), args: map[string]interface{}{"ref": "--output=leak.txt"}, wantCode: tools.ErrCodeValidation},
+		{name: "show ref as option", tool: builtin.GitShow(), args: map[string]interface{}{"ref": "--output=leak.txt"}, wantCode: tools.ErrCodeValidation},
+		{name: "diff from as option", tool: builtin.GitDiff(), args: map[string]interface{}{"from": "-p"}, wantCode: tools.ErrCodeValidation},
+		{name: "diff to as option", tool: builtin.GitDiff(), args: map[string]interface{}{"from": "HEAD", "to": "--output=leak.txt"}, wantCode: tools.ErrCodeValidation},
+		{name: "ref with a space", tool: builtin.GitLog(), args: map[string]interface{}{"ref": "HEAD --all"}, wantCode: tools.ErrCodeValidation},
+		{name: "ref with a newline", tool: builtin.GitShow(), args: map[string]interface{}{"ref": "HEAD\n--stat"}, wantCode: tools.ErrCodeValidation},
+		{name: "diff to without from", tool: builtin.GitDiff(), args: map[string]interface{}{"to": "HEAD"}, wantCode: tools.ErrCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, tc := gitProject(t)
+
+			result, err := tt.tool.Execute(t.Context(), tt.args, tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCode != "" {
+				if result.OK || result.Error == nil || result.Error.Code != tt.wantCode {
+					t.Fatalf("result = %+v, want error %s", result, tt.wantCode)
+				}
+			} else if !result.OK {
+				t.Fatalf("%s failed: %+v", tt.tool.Name, result.Error)
+			}
+			if _, err := os.Stat(filepath.Join(root, "leak.txt")); err == nil {
+				t.Error("git wrote leak.txt")
+			}
+		})
+	}
+}
+
+func TestGitTools_PathsAfterSeparator(t *testing.T) {
+	tests := []struct {
+		name      string
+		tool      tools.Tool
+		args      map[string]interface{}
+		wantFiles int
+		wantError bool
+	}{
+		{name: "diff of a file", tool: builtin.GitDiff(), args: map[string]interface{}{"path": "a.txt"}, wantFiles: 1},
+		{name: "diff of an option-like path", tool: builtin.GitDiff(), args: map[string]interface{}{"path": "--output=leak.txt"}, wantFiles: 0},
+		{name: "log of an option-like path", tool: builtin.GitLog(), args: map[string]interface{}{"path": "--output=leak.txt"}},
+		{name: "blame of an option-like path", tool: builtin.GitBlame(), args: map[string]interface{}{"path": "--output=leak.txt"}, wantError: true},
+		{name: "path outside the project", tool: builtin.GitDiff(), args: map[string]interface{}{"path": "../a.txt"}, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, tc := gitProject(t)
+
+			result, err := tt.tool.Execute(t.Context(), tt.args, tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(root, "leak.txt")); err == nil {
+				t.Fatal("git read the path as an option and wrote leak.txt")
+			}
+			if tt.wantError {
+				if result.OK {
+					t.Fatalf("result = %+v, want an error", result)
+				}
+				return
+			}
+			if !result.OK {
+				t.Fatalf("%s failed: %+v", tt.tool.Name, result.Error)
+			}
+			data, _ := result.Data.(map[string]interface{})
+			if files, ok := data["files"].([]map[string]interface{}); ok && len(files) != tt.wantFiles {
+				t.Errorf("files = %v, want %d", files, tt.wantFiles)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/memory"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+func Memory() tools.Tool {
+	return tools.Tool{
+		Name:        "memory",
+		Description: "Save, update or delete a short fact about this project that should be remembered in future chats (build quirks, conventions, decisions). Existing memories are listed in the system prompt with their ids. Do not store secrets or anything that is obvious from the code.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"action": map[string]interface{}{
+					"type": "string",
+					"enum": []string{"save", "update", "delete"},
+				},
+				"id": map[string]interface{}{
+					"type":        "string",
+					"description": "Memory id (or its 8-character prefix) for update and delete",
+				},
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "One self-contained fact, at most 1000 bytes",
+				},
+			},
+			"required": []string{"action"},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			if memory.LoadConfig(tc.ProjectRoot).Disabled {
+				return tools.NewErrorResult(tools.ErrCodePermission, "memory is disabled for this project", nil), nil
+			}
+
+			action, _ := args["action"].(string)
+			id, _ := args["id"].(string)
+			content, _ := args["content"].(string)
+
+			var (
+				entry interface{}
+				err   error
+			)
+			switch action {
+			case "save":
+				entry, err = memory.Save(ctx, tc.ProjectID, content, memory.SourceAgent)
+			case "update":
+				if strings.TrimSpace(id) == "" {
+					return tools.NewErrorResult(tools.ErrCodeValidation, "id is required for update", nil), nil
+				}
+				entry, err = memory.Update(ctx, tc.ProjectID, id, content)
+			case "delete":
+				if strings.TrimSpace(id) == "" {
+					return tools.NewErrorResult(tools.ErrCodeValidation, "id is required for delete", nil), nil
+				}
+				entry, err = memory.Delete(ctx, tc.ProjectID, id)
+			default:
+				return tools.NewErrorResult(tools.ErrCodeValidation, "action must be save, update or delete", nil), nil
+			}
+			if err != nil {
+				switch {
+				case memory.IsNotFound(err):
+					return tools.NewErrorResult(tools.ErrCodeNotFound, err.Error(), map[string]interface{}{"id": id}), nil
+				case errors.Is(err, memory.ErrAmbiguous), errors.Is(err, memory.ErrEmpty), errors.Is(err, memory.ErrTooLong), errors.Is(err, memory.ErrFull):
+					return tools.NewErrorResult(tools.ErrCodeValidation, err.Error(), nil), nil
+				}
+				return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil), nil
+			}
+
+			memory.Sync(ctx, tc.ProjectID, tc.ProjectRoot)
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"action": action,
+				"memory": entry,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds()}), nil
+		},
+	}
+}
@@ -0,0 +1,191 @@
+package builtin_test
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/redact"
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/ai/tools/builtin"
+)
+
+const keyMaterial = "key-material-do-not-share"
+
+// protectedProject creates a repository with a committed private key, an
+// unstaged change to it and a symlink with a harmless name pointing at it.
+func protectedProject(t *testing.T) (string, tools.ToolContext) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q")
+	write("main.go", "package main\n")
+	write("certs/server.key", "old "+keyMaterial+"\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "first")
+	write("main.go", "package main\n\nfunc main() {}\n")
+	write("certs/server.key", "new "+keyMaterial+"\n")
+	if err := os.Symlink(filepath.Join("certs", "server.key"), filepath.Join(root, "notes.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	return root, tools.ToolContext{
+		ProjectRoot: root,
+		Limits:      tools.ToolLimits{MaxFileBytes: 1 << 20},
+		Denied:      redact.New(root, redact.Config{}).Denied,
+	}
+}
+
+func TestProtectedFiles(t *testing.T) {
+	tests := []struct {
+		name          string
+		tool          tools.Tool
+		args          func(root string) map[string]interface{}
+		approved      bool
+		wantCode      string
+		wantProtected bool
+	}{
+		{
+			name: "read key",
+			tool: builtin.ReadFile(),
+			args: func(root string) map[string]interface{} {
+				return map[string]interface{}{"path": filepath.Join(root, "certs/server.key")}
+			},
+			wantCode: tools.ErrCodeProtected,
+		},
+		{
+			name: "read key through a symlink",
+			tool: builtin.ReadFile(),
+			args: func(root string) map[string]interface{} {
+				return map[string]interface{}{"path": filepath.Join(root, "notes.txt")}
+			},
+			wantCode: tools.ErrCodeProtected,
+		},
+		{
+			name: "approved read",
+			tool: builtin.ReadFile(),
+			args: func(root string) map[string]interface{} {
+				return map[string]interface{}{"path": filepath.Join(root, "certs/server.key")}
+			},
+			approved: true,
+		},
+		{
+			name:          "search without globs",
+			tool:          builtin.SearchInFiles(),
+			args:          func(string) map[string]interface{} { return map[string]interface{}{"query": "key-material"} },
+			wantProtected: true,
+		},
+		{
+			name:          "diff without a path",
+			tool:          builtin.GitDiff(),
+			args:          func(string) map[string]interface{} { return map[string]interface{}{} },
+			wantProtected: true,
+		},
+		{
+			name:          "show a commit",
+			tool:          builtin.GitShow(),
+			args:          func(string) map[string]interface{} { return map[string]interface{}{"ref": "HEAD"} },
+			wantProtected: true,
+		},
+		{
+			name:     "show the key at a commit",
+			tool:     builtin.GitShow(),
+			args:     func(string) map[string]interface{} { return map[string]interface{}{"path": "certs/server.key"} },
+			wantCode: tools.ErrCodeProtected,
+		},
+		{
+			name:     "blame the key",
+			tool:     builtin.GitBlame(),
+			args:     func(string) map[string]interface{} { return map[string]interface{}{"path": "certs/server.key"} },
+			wantCode: tools.ErrCodeProtected,
+		},
+		{
+			name: "move the key to a harmless name",
+			tool: builtin.MovePath(),
+			args: func(string) map[string]interface{} {
+				return map[string]interface{}{"source": "certs/server.key", "destination": "key.txt"}
+			},
+			wantCode: tools.ErrCodeProtected,
+		},
+		{
+			name: "copy a directory holding the key",
+			tool: builtin.CopyPath(),
+			args: func(string) map[string]interface{} {
+				return map[string]interface{}{"source": "certs", "destination": "backup"}
+			},
+		},
+		{
+			name: "write over the key",
+			tool: builtin.WriteFile(),
+			args: func(string) map[string]interface{} {
+				return map[string]interface{}{"path": "certs/server.key", "content": "replaced\n"}
+			},
+			wantCode: tools.ErrCodeProtected,
+		},
+		{
+			name: "edit the key",
+			tool: builtin.StrReplace(),
+			args: func(string) map[string]interface{} {
+				return map[string]interface{}{"path": "certs/server.key", "old_string": "missing", "new_string": "x"}
+			},
+			wantCode: tools.ErrCodeProtected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, tc := protectedProject(t)
+			if tt.approved {
+				tc.Denied = nil
+			}
+			result, err := tt.tool.Execute(t.Context(), tt.args(root), tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCode != "" {
+				if result.OK || result.Error == nil || result.Error.Code != tt.wantCode {
+					t.Fatalf("result = %+v, want error %s", result, tt.wantCode)
+				}
+				if data, _ := os.ReadFile(filepath.Join(root, "certs", "server.key")); !strings.Contains(string(data), "new "+keyMaterial) {
+					t.Errorf("key changed to %q by a refused call", data)
+				}
+				return
+			}
+			if !result.OK {
+				t.Fatalf("%s failed: %+v", tt.tool.Name, result.Error)
+			}
+
+			raw, _ := json.Marshal(result.Data)
+			if strings.Contains(string(raw), keyMaterial) != tt.approved {
+				t.Errorf("result contains the key = %v, want %v: %s", !tt.approved, tt.approved, raw)
+			}
+			data, _ := result.Data.(map[string]interface{})
+			if _, listed := data["protected_files"]; listed != tt.wantProtected {
+				t.Errorf("protected_files listed = %v, want %v: %s", listed, tt.wantProtected, raw)
+			}
+		})
+	}
+}
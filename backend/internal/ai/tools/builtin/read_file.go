@@ -56,6 +56,10 @@ func ReadFile() tools.Tool {
 			if !strings.HasPrefix(path, tc.ProjectRoot) {
 				return tools.NewErrorResult(tools.ErrCodePermission, "path outside project", nil), nil
 			}
+			if glob, denied := tc.Protected(path); denied {
+				relPath, _ := filepath.Rel(tc.ProjectRoot, path)
+				return tools.NewProtectedResult(relPath, glob), nil
+			}
 
 			maxBytes := 65536
 			if mb, ok := args["max_bytes"].(float64); ok {
@@ -131,6 +135,7 @@ func ReadFile() tools.Tool {
 				Data: result,
 				Meta: &tools.ResultMeta{
 					DurationMs: time.Since(startTime).Milliseconds(),
+					SHA:        sha,
 				},
 			}, nil
 		},
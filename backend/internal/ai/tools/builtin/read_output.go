@@ -0,0 +1,100 @@
+package builtin
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+func ReadOutput() tools.Tool {
+	return tools.Tool{
+		Name:        "read_output",
+		Description: "Page through the full output of an earlier tool call that was summarized. Use the output_handle from that result; lines are numbered from 0.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"handle": map[string]interface{}{
+					"type": "string",
+				},
+				"from": map[string]interface{}{
+					"type":    "integer",
+					"default": 0,
+					"minimum": 0,
+				},
+				"limit": map[string]interface{}{
+					"type":    "integer",
+					"default": 200,
+					"minimum": 1,
+					"maximum": 2000,
+				},
+				"grep": map[string]interface{}{
+					"type":        "string",
+					"description": "Only return lines containing this text (case-insensitive)",
+				},
+			},
+			"required": []string{"handle"},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			handle, _ := args["handle"].(string)
+			if handle == "" {
+				return tools.NewErrorResult(tools.ErrCodeValidation, "handle is required", nil), nil
+			}
+			out, ok := tools.Outputs.Get(handle)
+			if !ok || out.ProjectID != tc.ProjectID {
+				return tools.NewErrorResult(tools.ErrCodeNotFound, "output not found or expired", map[string]interface{}{"handle": handle}), nil
+			}
+
+			from := 0
+			if f, ok := args["from"].(float64); ok {
+				from = int(f)
+			}
+			limit := 200
+			if l, ok := args["limit"].(float64); ok {
+				limit = int(l)
+			}
+			grep, _ := args["grep"].(string)
+
+			var page tools.OutputPage
+			if grep == "" {
+				page, _ = tools.Outputs.Page(handle, from, limit)
+			} else {
+				page = grepOutput(handle, strings.ToLower(grep), from, limit)
+			}
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"handle":      handle,
+				"tool":        out.Tool,
+				"lines":       page.Lines,
+				"from":        page.From,
+				"next":        page.Next,
+				"total_lines": page.Total,
+				"done":        page.Done,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds()}), nil
+		},
+	}
+}
+
+// grepOutput prefixes each matching line with its number so the model can
+// page to the surrounding lines.
+func grepOutput(handle, needle string, from, limit int) tools.OutputPage {
+	all, _ := tools.Outputs.Page(handle, from, 0)
+	page := tools.OutputPage{Handle: handle, From: from, Total: all.Total, Next: all.Total, Done: true}
+	for i, line := range all.Lines {
+		if !strings.Contains(strings.ToLower(line), needle) {
+			continue
+		}
+		if len(page.Lines) >= limit {
+			page.Next = from + i
+			page.Done = false
+			break
+		}
+		page.Lines = append(page.Lines, strconv.Itoa(from+i)+": "+line)
+	}
+	return page
+}
@@ -8,8 +8,30 @@ func init() {
 	tools.GlobalRegistry.Register(ListDir())
 	tools.GlobalRegistry.Register(ReadFile())
 	tools.GlobalRegistry.Register(SearchInFiles())
+	tools.GlobalRegistry.Register(FindFiles())
 	tools.GlobalRegistry.Register(ApplyPatch())
+	tools.GlobalRegistry.Register(WriteFile())
+	tools.GlobalRegistry.Register(StrReplace())
+	tools.GlobalRegistry.Register(MovePath())
+	tools.GlobalRegistry.Register(CopyPath())
+	tools.GlobalRegistry.Register(DeletePath())
+	tools.GlobalRegistry.Register(MakeDir())
+	tools.GlobalRegistry.Register(GitStatus())
+	tools.GlobalRegistry.Register(GitDiff())
+	tools.GlobalRegistry.Register(GitLog())
+	tools.GlobalRegistry.Register(GitShow())
+	tools.GlobalRegistry.Register(GitBlame())
+	tools.GlobalRegistry.Register(ListSymbols())
+	tools.GlobalRegistry.Register(FindDefinition())
+	tools.GlobalRegistry.Register(FindReferences())
+	tools.GlobalRegistry.Register(PackageAPI())
 	tools.GlobalRegistry.Register(RunCommand())
 	tools.GlobalRegistry.Register(GetCommandOutput())
 	tools.GlobalRegistry.Register(CancelCommand())
+	tools.GlobalRegistry.Register(RunTests())
+	tools.GlobalRegistry.Register(ReadTerminal())
+	tools.GlobalRegistry.Register(SendToTerminal())
+	tools.GlobalRegistry.Register(ReadOutput())
+	tools.GlobalRegistry.Register(Memory())
+	tools.GlobalRegistry.Register(UseSkill())
 }
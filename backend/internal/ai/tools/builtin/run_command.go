@@ -3,12 +3,14 @@ package builtin
 import (
 	"bufio"
 	"context"
+	"errors"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -26,6 +28,7 @@ type TrackedProcess struct {
 	StartedAt time.Time
 	Output    *OutputBuffer
 	Done      bool
+	Cancelled bool
 	ExitCode  int
 	mu        sync.Mutex
 }
@@ -34,6 +37,7 @@ type OutputBuffer struct {
 	mu      sync.Mutex
 	entries []OutputEntry
 	maxSize int
+	dropped int
 }
 
 type OutputEntry struct {
@@ -44,6 +48,10 @@ type OutputEntry struct {
 
 var CmdManager *CommandManager
 
+// maxStreamLineBytes is the longest output line kept; go test -json, for
+// one, can print long lines.
+const maxStreamLineBytes = 1024 * 1024
+
 func init() {
 	CmdManager = &CommandManager{
 		procs: make(map[string]*TrackedProcess),
@@ -131,100 +139,177 @@ func RunCommand() tools.Tool {
 				stream = s
 			}
 
-			handle := uuid.New().String()
-
-			cmd := exec.CommandContext(ctx, "sh", "-c", cmdStr)
-			cmd.Dir = absCwd
-			cmd.Env = env
-
-			outputBuf := &OutputBuffer{
-				maxSize: int(tc.Limits.MaxOutputBytes),
-			}
-
-			tracked := &TrackedProcess{
-				Cmd:       cmd,
-				Handle:    handle,
-				StartedAt: time.Now(),
-				Output:    outputBuf,
-				Done:      false,
-			}
-
-			CmdManager.mu.Lock()
-			CmdManager.procs[handle] = tracked
-			CmdManager.mu.Unlock()
-
-			stdout, err := cmd.StdoutPipe()
-			if err != nil {
-				return tools.NewErrorResult(tools.ErrCodeExecution, "stdout pipe error", nil), nil
-			}
-
-			stderr, err := cmd.StderrPipe()
-			if err != nil {
-				return tools.NewErrorResult(tools.ErrCodeExecution, "stderr pipe error", nil), nil
-			}
-
-			err = cmd.Start()
+			tracked, err := ExecCommand(ctx, ExecSpec{
+				Cmd:            cmdStr,
+				Dir:            absCwd,
+				Env:            env,
+				Timeout:        time.Duration(timeout) * time.Millisecond,
+				MaxOutputBytes: int(tc.Limits.MaxOutputBytes),
+				Stream:         stream,
+			})
 			if err != nil {
 				return tools.NewErrorResult(tools.ErrCodeExecution, "command start error", err.Error()), nil
 			}
-
-			if stream {
-				go streamOutput(stdout, outputBuf, "stdout", int(tc.Limits.MaxOutputBytes))
-				go streamOutput(stderr, outputBuf, "stderr", int(tc.Limits.MaxOutputBytes))
+			if tracked.Cancelled {
+				return tools.NewErrorResult(tools.ErrCodeTimeout, "command cancelled", nil), nil
 			}
 
-			done := make(chan error, 1)
-			go func() {
-				done <- cmd.Wait()
-			}()
+			output := tracked.Output.Text()
 
-			select {
-			case <-ctx.Done():
-				cmd.Cancel()
-				tracked.mu.Lock()
-				tracked.Done = true
-				tracked.ExitCode = -1
-				tracked.mu.Unlock()
-				return tools.NewErrorResult(tools.ErrCodeTimeout, "command cancelled", nil), nil
-			case err = <-done:
-				tracked.mu.Lock()
-				tracked.Done = true
-				if err != nil {
-					if exitErr, ok := err.(*exec.ExitError); ok {
-						tracked.ExitCode = exitErr.ExitCode()
-					} else {
-						tracked.ExitCode = -1
-					}
-				} else {
-					tracked.ExitCode = 0
-				}
-				tracked.mu.Unlock()
+			data := map[string]interface{}{
+				"handle":    tracked.Handle,
+				"started":   tracked.StartedAt.Unix(),
+				"cwd":       absCwd,
+				"exit_code": tracked.ExitCode,
+				"output":    output,
+			}
+			if dropped := tracked.Output.Dropped(); dropped > 0 {
+				data["dropped_lines"] = dropped
 			}
-
-			CmdManager.mu.Lock()
-			delete(CmdManager.procs, handle)
-			CmdManager.mu.Unlock()
 
 			return tools.ToolResult{
-				OK: true,
-				Data: map[string]interface{}{
-					"handle":    handle,
-					"started":   tracked.StartedAt.Unix(),
-					"cwd":       absCwd,
-					"exit_code": tracked.ExitCode,
-				},
+				OK:   true,
+				Data: data,
 				Meta: &tools.ResultMeta{
 					DurationMs: time.Since(startTime).Milliseconds(),
+					Truncated:  tracked.Output.Dropped() > 0,
 				},
 			}, nil
 		},
 	}
 }
 
-func streamOutput(rd io.ReadCloser, buf *OutputBuffer, stream string, maxBytes int) {
+type ExecSpec struct {
+	Cmd            string
+	Dir            string
+	Env            []string
+	Stdin          io.Reader
+	Timeout        time.Duration
+	MaxOutputBytes int
+	Stream         bool
+	// OnLine, if set, sees every output line, including those later evicted
+	// from the capped buffer.
+	OnLine func(stream, text string)
+}
+
+// commandWaitDelay bounds how long ExecCommand waits for output after the
+// shell exits or is killed. A background child that inherited stdout would
+// otherwise keep the pipe, and the call, open forever.
+const commandWaitDelay = 2 * time.Second
+
+// ExecCommand runs spec.Cmd through "sh -c" and blocks until it exits. The
+// shell gets its own process group so a timeout or cancel kills everything
+// it started.
+func ExecCommand(ctx context.Context, spec ExecSpec) (*TrackedProcess, error) {
+	if spec.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, spec.Timeout)
+		defer cancel()
+	}
+
+	handle := uuid.New().String()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", spec.Cmd)
+	cmd.Dir = spec.Dir
+	cmd.Env = spec.Env
+	if spec.Stdin != nil {
+		cmd.Stdin = spec.Stdin
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = commandWaitDelay
+
+	outputBuf := &OutputBuffer{
+		maxSize: spec.MaxOutputBytes,
+	}
+
+	tracked := &TrackedProcess{
+		Cmd:       cmd,
+		Handle:    handle,
+		StartedAt: time.Now(),
+		Output:    outputBuf,
+		Done:      false,
+	}
+
+	// The pipes are written by exec's copy goroutines, which Wait stops
+	// after WaitDelay, so closing the writers below always ends the readers.
+	var readers sync.WaitGroup
+	var writers []*io.PipeWriter
+	if spec.Stream {
+		for _, stream := range []string{"stdout", "stderr"} {
+			pr, pw := io.Pipe()
+			writers = append(writers, pw)
+			if stream == "stdout" {
+				cmd.Stdout = pw
+			} else {
+				cmd.Stderr = pw
+			}
+			readers.Add(1)
+			go func() {
+				defer readers.Done()
+				streamOutput(pr, outputBuf, stream, spec.OnLine)
+			}()
+		}
+	}
+	closeWriters := func() {
+		for _, pw := range writers {
+			pw.Close()
+		}
+		readers.Wait()
+	}
+
+	if err := cmd.Start(); err != nil {
+		closeWriters()
+		return nil, err
+	}
+
+	CmdManager.mu.Lock()
+	CmdManager.procs[handle] = tracked
+	CmdManager.mu.Unlock()
+
+	err := cmd.Wait()
+	// Background children outlive the shell; nothing reads their output
+	// any more, so stop them rather than leave them running unattended.
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	closeWriters()
+
+	tracked.mu.Lock()
+	tracked.Done = true
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		tracked.Cancelled = true
+		tracked.ExitCode = -1
+	case err == nil:
+		tracked.ExitCode = 0
+	case errors.As(err, &exitErr):
+		tracked.ExitCode = exitErr.ExitCode()
+	case cmd.ProcessState != nil:
+		// exec.ErrWaitDelay: the shell exited but a child kept its output
+		// open.
+		tracked.ExitCode = cmd.ProcessState.ExitCode()
+	default:
+		tracked.ExitCode = -1
+	}
+	tracked.mu.Unlock()
+
+	CmdManager.mu.Lock()
+	delete(CmdManager.procs, handle)
+	CmdManager.mu.Unlock()
+
+	return tracked, nil
+}
+
+func streamOutput(rd io.ReadCloser, buf *OutputBuffer, stream string, onLine func(stream, text string)) {
 	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineBytes)
 	for scanner.Scan() {
 		text := scanner.Text()
+		if onLine != nil {
+			onLine(stream, text)
+		}
 		buf.mu.Lock()
 		if buf.entries == nil {
 			buf.entries = make([]OutputEntry, 0)
@@ -233,8 +318,9 @@ func streamOutput(rd io.ReadCloser, buf *OutputBuffer, stream string, maxBytes i
 		for _, e := range buf.entries {
 			totalSize += len(e.Text)
 		}
-		if totalSize+len(text) > buf.maxSize {
+		if totalSize+len(text) > buf.maxSize && len(buf.entries) > 0 {
 			buf.entries = buf.entries[1:]
+			buf.dropped++
 		}
 		buf.entries = append(buf.entries, OutputEntry{
 			Stream: stream,
@@ -243,9 +329,32 @@ func streamOutput(rd io.ReadCloser, buf *OutputBuffer, stream string, maxBytes i
 		})
 		buf.mu.Unlock()
 	}
+	// Drain what is left after an over-long line so the process never
+	// blocks writing to a full pipe.
+	io.Copy(io.Discard, rd)
 	rd.Close()
 }
 
+func (b *OutputBuffer) Text() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var sb strings.Builder
+	for i, e := range b.entries {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(e.Text)
+	}
+	return sb.String()
+}
+
+// Dropped is the number of leading lines evicted to stay under the byte cap.
+func (b *OutputBuffer) Dropped() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
 func GetCommandOutput() tools.Tool {
 	return tools.Tool{
 		Name:        "get_command_output",
@@ -278,10 +387,6 @@ func GetCommandOutput() tools.Tool {
 			tracked, ok := CmdManager.procs[handle]
 			CmdManager.mu.RUnlock()
 
-			if !ok {
-				return tools.NewErrorResult(tools.ErrCodeNotFound, "command not found", nil), nil
-			}
-
 			from := 0
 			if f, ok := args["from"].(float64); ok {
 				from = int(f)
@@ -292,6 +397,18 @@ func GetCommandOutput() tools.Tool {
 				limit = int(l)
 			}
 
+			if !ok {
+				page, found := tools.Outputs.Page(handle, from, limit)
+				if !found {
+					return tools.NewErrorResult(tools.ErrCodeNotFound, "command not found", nil), nil
+				}
+				return tools.NewSuccessResult(map[string]interface{}{
+					"lines": page.Lines,
+					"next":  page.Next,
+					"done":  true,
+				}), nil
+			}
+
 			tracked.mu.Lock()
 			entries := tracked.Output.entries
 			done := tracked.Done
@@ -0,0 +1,127 @@
+package builtin_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/tools/builtin"
+)
+
+func TestExecCommand(t *testing.T) {
+	tests := []struct {
+		name      string
+		cmd       string
+		timeout   time.Duration
+		stream    bool
+		exitCode  int
+		cancelled bool
+		output    []string
+		maxTime   time.Duration
+	}{
+		{
+			name:     "exit code and both streams",
+			cmd:      "echo out; echo err >&2; exit 3",
+			stream:   true,
+			exitCode: 3,
+			output:   []string{"out", "err"},
+			maxTime:  5 * time.Second,
+		},
+		{
+			name:      "timeout with a child holding stdout",
+			cmd:       "sleep 30 & echo started; sleep 30",
+			timeout:   200 * time.Millisecond,
+			stream:    true,
+			exitCode:  -1,
+			cancelled: true,
+			output:    []string{"started"},
+			maxTime:   5 * time.Second,
+		},
+		{
+			name:     "shell exits while a child holds stdout",
+			cmd:      "sleep 30 & echo done",
+			stream:   true,
+			exitCode: 0,
+			output:   []string{"done"},
+			maxTime:  5 * time.Second,
+		},
+		{
+			name:     "output not streamed",
+			cmd:      "yes | head -c 1000000; exit 0",
+			stream:   false,
+			exitCode: 0,
+			maxTime:  5 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			proc, err := builtin.ExecCommand(context.Background(), builtin.ExecSpec{
+				Cmd:            tt.cmd,
+				Dir:            t.TempDir(),
+				Timeout:        tt.timeout,
+				MaxOutputBytes: 64 * 1024,
+				Stream:         tt.stream,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(start); elapsed > tt.maxTime {
+				t.Errorf("ExecCommand took %s", elapsed)
+			}
+			if proc.ExitCode != tt.exitCode {
+				t.Errorf("exit code = %d, want %d", proc.ExitCode, tt.exitCode)
+			}
+			if proc.Cancelled != tt.cancelled {
+				t.Errorf("cancelled = %v, want %v", proc.Cancelled, tt.cancelled)
+			}
+			text := proc.Output.Text()
+			for _, want := range tt.output {
+				if !strings.Contains(text, want) {
+					t.Errorf("output %q missing %q", text, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExecCommand_KillsChildrenOnTimeout(t *testing.T) {
+	pidFile := t.TempDir() + "/pid"
+	proc, err := builtin.ExecCommand(context.Background(), builtin.ExecSpec{
+		Cmd:     "sleep 30 & echo $! > " + pidFile + "; wait",
+		Dir:     t.TempDir(),
+		Timeout: 200 * time.Millisecond,
+		Stream:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !proc.Cancelled {
+		t.Fatal("expected the command to time out")
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The child is gone, or a zombie waiting for init to reap it.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+		if err != nil || strings.Contains(string(stat), ") Z ") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("child %d still running: %s", pid, stat)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,556 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+const (
+	maxListedTests     = 200
+	maxFailureLines    = 40
+	maxTestOutputLines = 400
+	maxRawOutputLines  = 40
+)
+
+var testFrameworks = []string{"go", "jest", "vitest", "pytest"}
+
+// TestCase is one test in a run_tests result. Suite is the Go package or
+// the test file.
+type TestCase struct {
+	Name       string `json:"name"`
+	Suite      string `json:"suite,omitempty"`
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	Location   string `json:"location,omitempty"`
+	Output     string `json:"output,omitempty"`
+}
+
+// testRun is one invocation of a test framework. parse reads the results
+// once the command has exited; go test results are collected while it runs.
+type testRun struct {
+	framework string
+	dir       string
+	cmd       string
+	report    string
+	onLine    func(stream, text string)
+	parse     func() ([]TestCase, error)
+}
+
+func RunTests() tools.Tool {
+	return tools.Tool{
+		Name:        "run_tests",
+		Description: "Run tests and get per-test results instead of raw logs. Detects go test, jest, vitest or pytest from the path; runs a package, directory, file or a single test; returns pass/fail/skip status, durations, and trimmed failure output with file:line locations.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Package directory or test file; the whole project by default",
+					"default":     ".",
+				},
+				"test": map[string]interface{}{
+					"type":        "string",
+					"description": "Name or pattern of the test(s) to run",
+				},
+				"framework": map[string]interface{}{
+					"type":    "string",
+					"enum":    append([]string{"auto"}, testFrameworks...),
+					"default": "auto",
+				},
+				"timeout_ms": map[string]interface{}{
+					"type":    "integer",
+					"default": 600000,
+					"minimum": 1000,
+					"maximum": 1800000,
+				},
+			},
+		},
+		Policy: tools.PolicyConfirm,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			run, errResult := planTestRun(args, tc.ProjectRoot, tc.Limits)
+			if errResult != nil {
+				return *errResult, nil
+			}
+			framework := run.framework
+			root := filepath.Clean(tc.ProjectRoot)
+			if run.report != "" {
+				defer os.Remove(run.report)
+			}
+
+			timeout := 600000
+			if t, ok := args["timeout_ms"].(float64); ok {
+				timeout = int(t)
+			}
+			if timeout > 1800000 {
+				timeout = 1800000
+			}
+
+			env := os.Environ()
+			if framework != "go" {
+				env = append(env, "CI=true")
+			}
+			tracked, err := ExecCommand(ctx, ExecSpec{
+				Cmd:            run.cmd,
+				Dir:            run.dir,
+				Env:            env,
+				Timeout:        time.Duration(timeout) * time.Millisecond,
+				MaxOutputBytes: int(tc.Limits.MaxOutputBytes),
+				Stream:         true,
+				OnLine:         run.onLine,
+			})
+			if err != nil {
+				return tools.NewErrorResult(tools.ErrCodeExecution, "command start error", err.Error()), nil
+			}
+			if tracked.Cancelled {
+				return tools.NewErrorResult(tools.ErrCodeTimeout, "tests cancelled or timed out", map[string]interface{}{"command": run.cmd}), nil
+			}
+
+			output := tracked.Output.Text()
+			stored := output
+			if tc.Redact != nil {
+				stored = tc.Redact(output)
+			}
+			tools.Outputs.Put(tc.ProjectID, "run_tests", tracked.Handle, stored)
+
+			cases, parseErr := run.parse()
+			for i := range cases {
+				cases[i].Output = trimTestOutput(cases[i].Output)
+				if cases[i].Status != "fail" {
+					cases[i].Output = ""
+				}
+			}
+
+			counts := map[string]int{}
+			for _, c := range cases {
+				counts[c.Status]++
+			}
+			status := "passed"
+			switch {
+			case counts["fail"] > 0:
+				status = "failed"
+			case tracked.ExitCode != 0:
+				status = "error"
+			case len(cases) == 0:
+				status = "no_tests"
+			}
+
+			// Failures first, then skips, in run order.
+			rank := map[string]int{"fail": 0, "skip": 1, "pass": 2}
+			sort.SliceStable(cases, func(i, j int) bool { return rank[cases[i].Status] < rank[cases[j].Status] })
+			listed := cases
+			if len(listed) > maxListedTests {
+				listed = listed[:maxListedTests]
+			}
+			if listed == nil {
+				listed = []TestCase{}
+			}
+
+			data := map[string]interface{}{
+				"framework":       framework,
+				"command":         run.cmd,
+				"cwd":             relOrDot(root, run.dir),
+				"handle":          tracked.Handle,
+				"exit_code":       tracked.ExitCode,
+				"status":          status,
+				"total":           len(cases),
+				"passed":          counts["pass"],
+				"failed":          counts["fail"],
+				"skipped":         counts["skip"],
+				"tests":           listed,
+				"tests_truncated": len(listed) < len(cases),
+			}
+			// Without per-test failures the raw output is the only clue, e.g.
+			// a compile error or a missing framework.
+			if status == "error" || status == "no_tests" {
+				data["output"] = stripANSI(lastLines(output, maxRawOutputLines))
+			}
+			if parseErr != nil {
+				data["parse_error"] = parseErr.Error()
+			}
+
+			return tools.NewSuccessResultWithMeta(data, tools.ResultMeta{
+				DurationMs: time.Since(startTime).Milliseconds(),
+				Truncated:  len(listed) < len(cases),
+			}), nil
+		},
+	}
+}
+
+// TestCommand returns the shell command run_tests would run for args, so a
+// policy can check it against an allowlist before the tool runs.
+func TestCommand(projectRoot string, args map[string]interface{}) (string, error) {
+	run, errResult := planTestRun(args, projectRoot, tools.ToolLimits{})
+	if errResult != nil {
+		return "", errors.New(errResult.Error.Message)
+	}
+	if run.report != "" {
+		os.Remove(run.report)
+	}
+	return run.cmd, nil
+}
+
+// planTestRun picks the framework for the target in args and builds its
+// command. The caller removes run.report once the results are read.
+func planTestRun(args map[string]interface{}, projectRoot string, limits tools.ToolLimits) (*testRun, *tools.ToolResult) {
+	fail := func(code, msg string, details interface{}) (*testRun, *tools.ToolResult) {
+		r := tools.NewErrorResult(code, msg, details)
+		return nil, &r
+	}
+
+	p, _ := args["path"].(string)
+	if p == "" {
+		p = "."
+	}
+	abs, err := tools.NewPathGuard(projectRoot, limits).ResolveProjectPath(p)
+	if err != nil {
+		return fail(tools.ErrCodeInvalidPath, err.Error(), map[string]interface{}{"path": p})
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return fail(tools.ErrCodeNotFound, "path not found: "+p, nil)
+	}
+	root := filepath.Clean(projectRoot)
+
+	framework, _ := args["framework"].(string)
+	test, _ := args["test"].(string)
+	if framework == "" || framework == "auto" {
+		framework = detectTestFramework(root, abs, info.IsDir())
+		if framework == "" {
+			return fail(tools.ErrCodeValidation, "no test framework found; set framework to one of go, jest, vitest, pytest", nil)
+		}
+	}
+
+	var run *testRun
+	switch framework {
+	case "go":
+		run, err = goTestRun(root, abs, info.IsDir(), test)
+	case "jest", "vitest":
+		run, err = jsTestRun(framework, root, abs, test)
+	case "pytest":
+		run, err = pytestRun(root, abs, info.IsDir(), test)
+	default:
+		return fail(tools.ErrCodeValidation, "framework must be one of auto, go, jest, vitest, pytest", nil)
+	}
+	if err != nil {
+		return fail(tools.ErrCodeExecution, err.Error(), nil)
+	}
+	return run, nil
+}
+
+// detectTestFramework looks for the nearest project marker at or above the
+// target, preferring one that fits the target's file type.
+func detectTestFramework(root, abs string, isDir bool) string {
+	want := ""
+	if !isDir {
+		switch ext := filepath.Ext(abs); ext {
+		case ".go":
+			return "go"
+		case ".py":
+			want = "pytest"
+		case ".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".mts", ".cts":
+			want = "js"
+		}
+		abs = filepath.Dir(abs)
+	}
+
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		found := dirTestFrameworks(dir)
+		for _, f := range found {
+			if want == "" || f == want || (want == "js" && (f == "jest" || f == "vitest")) {
+				return f
+			}
+		}
+		if dir == root || !strings.HasPrefix(dir, root) || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	if want == "pytest" {
+		return "pytest"
+	}
+	return ""
+}
+
+// dirTestFrameworks lists the frameworks whose markers are in dir.
+func dirTestFrameworks(dir string) []string {
+	var found []string
+	if fileExists(filepath.Join(dir, "go.mod")) {
+		found = append(found, "go")
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "package.json")); err == nil {
+		switch {
+		case strings.Contains(string(data), `"vitest"`) || strings.Contains(string(data), "vitest "):
+			found = append(found, "vitest")
+		case strings.Contains(string(data), `"jest"`) || strings.Contains(string(data), "jest "):
+			found = append(found, "jest")
+		}
+	}
+	for _, marker := range []string{"pytest.ini", "conftest.py", "pyproject.toml", "setup.cfg", "tox.ini"} {
+		if fileExists(filepath.Join(dir, marker)) {
+			found = append(found, "pytest")
+			break
+		}
+	}
+	return found
+}
+
+// nearestDir returns the closest directory at or above start, within root,
+// that holds marker, or root.
+func nearestDir(root, start, marker string) string {
+	for dir := start; strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if fileExists(filepath.Join(dir, marker)) {
+			return dir
+		}
+		if dir == root || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	return root
+}
+
+func goTestRun(root, abs string, isDir bool, test string) (*testRun, error) {
+	start := abs
+	if !isDir {
+		start = filepath.Dir(abs)
+	}
+	modDir := nearestDir(root, start, "go.mod")
+	modPath := goModulePath(modDir)
+
+	// A directory without Go files of its own runs every package below it.
+	pkg := "."
+	if rel := mustRel(modDir, start); rel != "." {
+		pkg = "./" + rel
+	}
+	if !hasGoFile(start) {
+		pkg += "/..."
+	}
+
+	run := ""
+	switch {
+	case test != "":
+		run = goRunPattern(test)
+	case !isDir && strings.HasSuffix(abs, "_test.go"):
+		// A test file runs the tests declared in it.
+		if names := goTestNames(abs); len(names) > 0 {
+			run = "^(" + strings.Join(names, "|") + ")$"
+		}
+	}
+
+	cmd := "go test -json"
+	if run != "" {
+		cmd += " -run " + shellQuote(run)
+	}
+	cmd += " " + shellQuote(pkg)
+
+	p := newGoTestParser(root, modDir, modPath)
+	return &testRun{framework: "go", dir: modDir, cmd: cmd, onLine: p.line, parse: p.results}, nil
+}
+
+// goRunPattern anchors each level of a plain test name such as
+// "TestFoo/sub case"; anything that looks like a regexp is passed through.
+func goRunPattern(test string) string {
+	if strings.ContainsAny(test, `^$|*+?()[]\`) {
+		return test
+	}
+	parts := strings.Split(test, "/")
+	for i, part := range parts {
+		parts[i] = "^" + regexp.QuoteMeta(strings.ReplaceAll(part, " ", "_")) + "$"
+	}
+	return strings.Join(parts, "/")
+}
+
+var goTestFuncPattern = regexp.MustCompile(`(?m)^func\s+((?:Test|Example|Fuzz)\w*)\s*\(`)
+
+func goTestNames(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, m := range goTestFuncPattern.FindAllStringSubmatch(string(data), -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+func goModulePath(modDir string) string {
+	data, err := os.ReadFile(filepath.Join(modDir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+func hasGoFile(dir string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	return len(matches) > 0
+}
+
+func jsTestRun(framework, root, abs, test string) (*testRun, error) {
+	dir := nearestDir(root, abs, "package.json")
+	report, err := testReportFile(".json")
+	if err != nil {
+		return nil, err
+	}
+
+	bin := filepath.Join(dir, "node_modules", ".bin", framework)
+	cmd := "npx --no-install " + framework
+	if fileExists(bin) {
+		cmd = shellQuote(bin)
+	}
+	if framework == "vitest" {
+		cmd += " run --reporter=json --outputFile=" + shellQuote(report)
+	} else {
+		cmd += " --json --testLocationInResults --outputFile=" + shellQuote(report)
+	}
+	if test != "" {
+		cmd += " -t " + shellQuote(test)
+	}
+	if abs != dir {
+		cmd += " " + shellQuote(mustRel(dir, abs))
+	}
+
+	return &testRun{
+		framework: framework,
+		dir:       dir,
+		cmd:       cmd,
+		report:    report,
+		parse:     func() ([]TestCase, error) { return parseJestReport(root, report) },
+	}, nil
+}
+
+func pytestRun(root, abs string, isDir bool, test string) (*testRun, error) {
+	start := abs
+	if !isDir {
+		start = filepath.Dir(abs)
+	}
+	dir := root
+	for _, marker := range []string{"pytest.ini", "pyproject.toml", "setup.cfg", "tox.ini"} {
+		if d := nearestDir(root, start, marker); d != root || fileExists(filepath.Join(root, marker)) {
+			dir = d
+			break
+		}
+	}
+	report, err := testReportFile(".xml")
+	if err != nil {
+		return nil, err
+	}
+
+	python := "python3"
+	if _, err := exec.LookPath(python); err != nil {
+		python = "python"
+	}
+	cmd := python + " -m pytest -q -o junit_family=xunit1 --junitxml=" + shellQuote(report)
+	target := ""
+	if abs != dir {
+		target = mustRel(dir, abs)
+	}
+	switch {
+	case test != "" && !isDir:
+		target += "::" + test
+	case test != "":
+		cmd += " -k " + shellQuote(test)
+	}
+	if target != "" {
+		cmd += " " + shellQuote(target)
+	}
+
+	return &testRun{
+		framework: "pytest",
+		dir:       dir,
+		cmd:       cmd,
+		report:    report,
+		parse:     func() ([]TestCase, error) { return parseJUnitReport(root, dir, report) },
+	}, nil
+}
+
+func testReportFile(ext string) (string, error) {
+	f, err := os.CreateTemp("", "webide-tests-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	return f.Name(), nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func mustRel(base, target string) string {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return target
+	}
+	return filepath.ToSlash(rel)
+}
+
+func relOrDot(root, dir string) string {
+	if rel := mustRel(root, dir); !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return dir
+}
+
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`|&;<>()*?[]{}~#!") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?<=>]*[ -/]*[@-~]|\x1b[\]PX^_][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[()*+][0-9A-Za-z]|\x1b[ -/]*[0-~]`)
+
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) <= n {
+		return strings.Join(lines, "\n")
+	}
+	return strings.Join(lines[len(lines)-n:], "\n")
+}
+
+// trimTestOutput keeps the start of a failure, where the assertion usually
+// is, and its last lines, dropping stack frames inside dependencies.
+func trimTestOutput(out string) string {
+	out = stripANSI(strings.TrimRight(out, "\n"))
+	if out == "" {
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "node_modules/") || strings.Contains(line, "node:internal") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \r"))
+	}
+	if len(lines) <= maxFailureLines {
+		return strings.Join(lines, "\n")
+	}
+	head := maxFailureLines / 4
+	tail := maxFailureLines - head
+	omitted := len(lines) - head - tail
+	return strings.Join(lines[:head], "\n") +
+		"\n... (" + strconv.Itoa(omitted) + " lines omitted)\n" +
+		strings.Join(lines[len(lines)-tail:], "\n")
+}
@@ -0,0 +1,116 @@
+package builtin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectTestFramework(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":                    "module example.com/app\n",
+		"pkg/app.go":                "package pkg\n",
+		"web/package.json":          `{"devDependencies": {"jest": "^29.0.0"}}`,
+		"web/src/app.test.ts":       "",
+		"ui/package.json":           `{"scripts": {"test": "vitest run"}}`,
+		"ui/src/button.test.tsx":    "",
+		"py/pyproject.toml":         "[tool.pytest.ini_options]\n",
+		"py/tests/test_app.py":      "",
+		"scripts/test_tool.py":      "",
+		"docs/notes/guide.md":       "",
+		"mixed/package.json":        `{"devDependencies": {"jest": "^29.0.0"}}`,
+		"mixed/conftest.py":         "",
+		"mixed/tests/test_mixed.py": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: ".", want: "go"},
+		{target: "pkg", want: "go"},
+		{target: "pkg/app.go", want: "go"},
+		{target: "web", want: "jest"},
+		{target: "web/src/app.test.ts", want: "jest"},
+		{target: "ui/src/button.test.tsx", want: "vitest"},
+		{target: "py/tests/test_app.py", want: "pytest"},
+		{target: "py", want: "pytest"},
+		// A Python file with no marker above it still runs under pytest.
+		{target: "scripts/test_tool.py", want: "pytest"},
+		{target: "mixed/tests/test_mixed.py", want: "pytest"},
+		{target: "docs/notes", want: "go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			abs := filepath.Join(root, filepath.FromSlash(tt.target))
+			info, err := os.Stat(abs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := detectTestFramework(root, abs, info.IsDir()); got != tt.want {
+				t.Errorf("detectTestFramework(%s) = %q, want %q", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoTestRun_Command(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":                    "module example.com/app\n",
+		"pkg/app.go":                "package pkg\n",
+		"pkg/app_test.go":           "package pkg\n\nfunc TestOne(t *testing.T) {}\n\nfunc TestTwo(t *testing.T) {}\n\nfunc helper() {}\n",
+		"internal/store/db.go":      "package store\n",
+		"internal/store/db_test.go": "package store\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		isDir  bool
+		test   string
+		want   string
+	}{
+		{name: "module root without go files", target: ".", isDir: true, want: "go test -json ./..."},
+		{name: "directory without go files", target: "internal", isDir: true, want: "go test -json ./internal/..."},
+		{name: "package", target: "pkg", isDir: true, want: "go test -json ./pkg"},
+		{name: "test file runs its tests", target: "pkg/app_test.go", want: "go test -json -run '^(TestOne|TestTwo)$' ./pkg"},
+		{name: "named subtest", target: "pkg", isDir: true, test: "TestOne/empty input", want: "go test -json -run '^TestOne$/^empty_input$' ./pkg"},
+		{name: "regexp passes through", target: "pkg", isDir: true, test: "TestO.*", want: "go test -json -run 'TestO.*' ./pkg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run, err := goTestRun(root, filepath.Join(root, filepath.FromSlash(tt.target)), tt.isDir, tt.test)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if run.cmd != tt.want {
+				t.Errorf("cmd = %s, want %s", run.cmd, tt.want)
+			}
+			if run.dir != root {
+				t.Errorf("dir = %s, want the module root", run.dir)
+			}
+		})
+	}
+}
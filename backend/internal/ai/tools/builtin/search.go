@@ -1,31 +1,76 @@
 package builtin
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/webide/ide/backend/internal/ai/ignore"
 	"github.com/webide/ide/backend/internal/ai/tools"
 )
 
+const (
+	maxSearchContext   = 10
+	maxSearchFileBytes = 4 * 1024 * 1024
+	// binarySniffBytes is how much of a file is checked for NUL bytes, as
+	// git does.
+	binarySniffBytes = 8000
+)
+
 func SearchInFiles() tools.Tool {
 	return tools.Tool{
 		Name:        "search_in_files",
-		Description: "Search for text patterns in files with optional glob filtering",
+		Description: "Search file contents for a literal string or a regular expression, with optional context lines and glob filtering. Files ignored by .gitignore or .webide/ignore and binary files are skipped; protected files that may hold secrets are listed in protected_files instead of searched. Returns matching lines and the total number of matching lines.",
 		Parameters: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"query": map[string]interface{}{
 					"type": "string",
 				},
+				"mode": map[string]interface{}{
+					"type":    "string",
+					"enum":    []string{"literal", "regex"},
+					"default": "literal",
+				},
+				"case_sensitive": map[string]interface{}{
+					"type":    "boolean",
+					"default": false,
+				},
+				"whole_word": map[string]interface{}{
+					"type":    "boolean",
+					"default": false,
+				},
+				"context": map[string]interface{}{
+					"type":        "integer",
+					"description": "Lines of context before and after each match",
+					"minimum":     0,
+					"maximum":     maxSearchContext,
+				},
+				"before": map[string]interface{}{
+					"type":        "integer",
+					"description": "Lines of context before each match, overriding context",
+					"minimum":     0,
+					"maximum":     maxSearchContext,
+				},
+				"after": map[string]interface{}{
+					"type":        "integer",
+					"description": "Lines of context after each match, overriding context",
+					"minimum":     0,
+					"maximum":     maxSearchContext,
+				},
 				"globs": map[string]interface{}{
 					"type": "array",
 					"items": map[string]interface{}{
 						"type": "string",
 					},
+					"description": "Only search files matching one of these globs; a glob without a slash matches the file name, \"**\" crosses directories",
 				},
 				"max_results": map[string]interface{}{
 					"type":    "integer",
@@ -33,6 +78,16 @@ func SearchInFiles() tools.Tool {
 					"minimum": 1,
 					"maximum": 200,
 				},
+				"max_per_file": map[string]interface{}{
+					"type":    "integer",
+					"default": 20,
+					"minimum": 1,
+					"maximum": 200,
+				},
+				"include_ignored": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Also search ignored files and dependency directories (never .git)",
+				},
 			},
 			"required": []string{"query"},
 		},
@@ -45,42 +100,99 @@ func SearchInFiles() tools.Tool {
 				return tools.NewErrorResult(tools.ErrCodeValidation, "query is required", nil), nil
 			}
 
-			maxResults := 50
+			mode, _ := args["mode"].(string)
+			if mode == "" {
+				mode = "literal"
+			}
+			expr := query
+			switch mode {
+			case "literal":
+				expr = regexp.QuoteMeta(query)
+			case "regex":
+			default:
+				return tools.NewErrorResult(tools.ErrCodeValidation, "mode must be literal or regex", nil), nil
+			}
+			if wholeWord, _ := args["whole_word"].(bool); wholeWord {
+				expr = `\b(?:` + expr + `)\b`
+			}
+			if caseSensitive, _ := args["case_sensitive"].(bool); !caseSensitive {
+				expr = "(?i)" + expr
+			}
+			pattern, err := regexp.Compile(expr)
+			if err != nil {
+				return tools.NewErrorResult(tools.ErrCodeValidation, "invalid regex pattern: "+err.Error(), nil), nil
+			}
+
+			s := &searcher{
+				root:       tc.ProjectRoot,
+				denied:     tc.Denied,
+				pattern:    pattern,
+				maxResults: 50,
+				maxPerFile: 20,
+			}
 			if mr, ok := args["max_results"].(float64); ok {
-				maxResults = int(mr)
+				s.maxResults = int(mr)
+			}
+			if tc.Limits.MaxSearchResults > 0 && s.maxResults > tc.Limits.MaxSearchResults {
+				s.maxResults = tc.Limits.MaxSearchResults
+			}
+			if mp, ok := args["max_per_file"].(float64); ok {
+				s.maxPerFile = int(mp)
+			}
+			if s.maxResults < 1 || s.maxPerFile < 1 {
+				return tools.NewErrorResult(tools.ErrCodeValidation, "max_results and max_per_file must be positive", nil), nil
+			}
+			if c, ok := args["context"].(float64); ok {
+				s.before, s.after = int(c), int(c)
 			}
-			if maxResults > tc.Limits.MaxSearchResults {
-				maxResults = tc.Limits.MaxSearchResults
+			if b, ok := args["before"].(float64); ok {
+				s.before = int(b)
 			}
+			if a, ok := args["after"].(float64); ok {
+				s.after = int(a)
+			}
+			s.before = max(0, min(s.before, maxSearchContext))
+			s.after = max(0, min(s.after, maxSearchContext))
+			s.includeIgnored, _ = args["include_ignored"].(bool)
 
-			var globs []string
 			if g, ok := args["globs"].([]interface{}); ok {
 				for _, item := range g {
-					if s, ok := item.(string); ok {
-						globs = append(globs, s)
+					str, ok := item.(string)
+					if !ok || str == "" {
+						continue
+					}
+					glob, err := ignore.CompileGlob(str)
+					if err != nil {
+						return tools.NewErrorResult(tools.ErrCodeValidation, "invalid glob "+str+": "+err.Error(), nil), nil
 					}
+					s.globs = append(s.globs, glob)
 				}
 			}
 
-			pattern, err := regexp.Compile("(?i)" + regexp.QuoteMeta(query))
-			if err != nil {
-				return tools.NewErrorResult(tools.ErrCodeValidation, "invalid regex pattern", nil), nil
+			if err := s.run(ctx); err != nil {
+				return tools.NewErrorResult(tools.ErrCodeTimeout, "search cancelled: "+err.Error(), nil), nil
 			}
 
-			matches, truncated, err := searchInDir(tc.ProjectRoot, pattern, globs, maxResults, tc.ProjectRoot)
-			if err != nil {
-				return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil), nil
+			truncated := s.total > len(s.matches)
+			data := map[string]interface{}{
+				"query":          query,
+				"mode":           mode,
+				"matches":        s.matches,
+				"total":          s.total,
+				"files_matched":  s.filesMatched,
+				"files_searched": s.filesSearched,
+				"truncated":      truncated,
+			}
+			if len(s.protected) > 0 {
+				sort.Strings(s.protected)
+				data["protected_files"] = s.protected
 			}
-
 			return tools.ToolResult{
-				OK: true,
-				Data: map[string]interface{}{
-					"query":     query,
-					"matches":   matches,
-					"truncated": truncated,
-				},
+				OK:   true,
+				Data: data,
 				Meta: &tools.ResultMeta{
 					DurationMs: time.Since(startTime).Milliseconds(),
+					Truncated:  truncated,
 				},
 			}, nil
 		},
@@ -88,113 +200,203 @@ func SearchInFiles() tools.Tool {
 }
 
 type SearchMatch struct {
-	Path    string `json:"path"`
-	Line    int    `json:"line"`
-	Col     int    `json:"col"`
-	Preview string `json:"preview"`
+	Path    string   `json:"path"`
+	Line    int      `json:"line"`
+	Col     int      `json:"col"`
+	Preview string   `json:"preview"`
+	Before  []string `json:"before,omitempty"`
+	After   []string `json:"after,omitempty"`
 }
 
-func searchInDir(root string, pattern *regexp.Regexp, globs []string, maxResults int, projectRoot string) ([]SearchMatch, bool, error) {
-	var matches []SearchMatch
-	var truncated bool
+// searcher walks the project in parallel. Every matching line is counted,
+// but only the first maxResults in path order are kept.
+type searcher struct {
+	root           string
+	pattern        *regexp.Regexp
+	globs          []*ignore.Glob
+	before, after  int
+	maxResults     int
+	maxPerFile     int
+	includeIgnored bool
+	// denied files are not read; their names are listed in protected.
+	denied func(relPath string) (string, bool)
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
+	mu            sync.Mutex
+	matches       []SearchMatch
+	total         int
+	filesMatched  int
+	filesSearched int
+	protected     []string
 
-		if info.IsDir() {
-			return nil
-		}
+	wg  sync.WaitGroup
+	sem chan struct{}
+}
 
-		if len(globs) > 0 {
-			matched := false
-			for _, g := range globs {
-				if matched, _ = filepath.Match(g, filepath.Base(path)); matched {
-					break
-				}
-				if matched, _ = filepath.Match(g, path); matched {
-					break
-				}
+func (s *searcher) run(ctx context.Context) error {
+	s.matches = []SearchMatch{}
+	s.sem = make(chan struct{}, min(runtime.NumCPU(), 8))
+	s.wg.Add(1)
+	go s.walkDir(ctx, "", ignore.Load(s.root))
+	s.wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.sortMatches()
+	if len(s.matches) > s.maxResults {
+		s.matches = s.matches[:s.maxResults]
+	}
+	return nil
+}
+
+// walkDir searches the files of one directory and starts a goroutine for
+// each subdirectory. File reads are limited by s.sem.
+func (s *searcher) walkDir(ctx context.Context, rel string, m *ignore.Matcher) {
+	defer s.wg.Done()
+	if ctx.Err() != nil {
+		return
+	}
+
+	dir := filepath.Join(s.root, filepath.FromSlash(rel))
+	s.sem <- struct{}{}
+	entries, err := os.ReadDir(dir)
+	<-s.sem
+	if err != nil {
+		return
+	}
+	if rel != "" && !s.includeIgnored {
+		m = m.Child(s.root, rel)
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		childRel := name
+		if rel != "" {
+			childRel = rel + "/" + name
+		}
+		if e.IsDir() {
+			if name == ".git" {
+				continue
 			}
-			if !matched {
-				return nil
+			if !s.includeIgnored && (ignore.DefaultDirs[name] || m.Match(childRel, true)) {
+				continue
 			}
+			s.wg.Add(1)
+			go s.walkDir(ctx, childRel, m)
+			continue
 		}
-
-		fileMatches, err := searchInFile(path, pattern, projectRoot, maxResults-len(matches))
-		if err != nil {
-			return nil
+		if !e.Type().IsRegular() {
+			continue
 		}
-
-		for _, m := range fileMatches {
-			if len(matches) >= maxResults {
-				truncated = true
-				return filepath.SkipDir
+		if !s.includeIgnored && m.Match(childRel, false) {
+			continue
+		}
+		if !s.matchesGlobs(childRel) {
+			continue
+		}
+		if s.denied != nil {
+			if _, denied := s.denied(childRel); denied {
+				s.mu.Lock()
+				s.protected = append(s.protected, childRel)
+				s.mu.Unlock()
+				continue
 			}
-			matches = append(matches, m)
 		}
 
-		return nil
-	})
+		s.sem <- struct{}{}
+		s.searchFile(childRel)
+		<-s.sem
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
 
-	return matches, truncated, err
+func (s *searcher) matchesGlobs(rel string) bool {
+	if len(s.globs) == 0 {
+		return true
+	}
+	for _, g := range s.globs {
+		if g.Match(rel) {
+			return true
+		}
+	}
+	return false
 }
 
-func searchInFile(path string, pattern *regexp.Regexp, projectRoot string, limit int) ([]SearchMatch, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+func (s *searcher) searchFile(rel string) {
+	path := filepath.Join(s.root, filepath.FromSlash(rel))
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxSearchFileBytes {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(data[:min(len(data), binarySniffBytes)], 0) >= 0 {
+		return
 	}
-	defer file.Close()
 
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
 	var matches []SearchMatch
-
-	lineNum := 0
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-
-		indices := pattern.FindAllStringIndex(line, -1)
-		for _, idx := range indices {
-			if len(matches) >= limit {
-				return matches, nil
-			}
-
-			preview := line
-			if len(preview) > 200 {
-				col := idx[0]
-				start := 0
-				if col > 100 {
-					start = col - 100
-				}
-				preview = "..." + line[start:min(len(line), start+200)] + "..."
-			}
-
-			matches = append(matches, SearchMatch{
-				Path:    getRelativePath(path, projectRoot),
-				Line:    lineNum,
-				Col:     idx[0] + 1,
-				Preview: preview,
-			})
+	count := 0
+	for i, line := range lines {
+		idx := s.pattern.FindStringIndex(strings.TrimSuffix(line, "\r"))
+		if idx == nil {
+			continue
+		}
+		count++
+		if len(matches) >= s.maxPerFile {
+			continue
 		}
+		m := SearchMatch{
+			Path:    rel,
+			Line:    i + 1,
+			Col:     idx[0] + 1,
+			Preview: searchPreview(line, idx[0]),
+		}
+		for j := max(0, i-s.before); j < i; j++ {
+			m.Before = append(m.Before, searchPreview(lines[j], 0))
+		}
+		for j := i + 1; j <= min(len(lines)-1, i+s.after); j++ {
+			m.After = append(m.After, searchPreview(lines[j], 0))
+		}
+		matches = append(matches, m)
 	}
 
-	return matches, scanner.Err()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filesSearched++
+	if count == 0 {
+		return
+	}
+	s.filesMatched++
+	s.total += count
+	s.matches = append(s.matches, matches...)
+	// Keep memory bounded: only the first maxResults in path order can be
+	// returned.
+	if len(s.matches) > 2*s.maxResults {
+		s.sortMatches()
+		s.matches = s.matches[:s.maxResults]
+	}
 }
 
-func getRelativePath(absPath, projectRoot string) string {
-	rel, err := filepath.Rel(projectRoot, absPath)
-	if err != nil {
-		return absPath
-	}
-	return rel
+func (s *searcher) sortMatches() {
+	sort.Slice(s.matches, func(i, j int) bool {
+		a, b := s.matches[i], s.matches[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Line < b.Line
+	})
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
+// searchPreview trims a line to at most 200 bytes around col.
+func searchPreview(line string, col int) string {
+	line = strings.TrimSuffix(line, "\r")
+	if len(line) <= 200 {
+		return line
+	}
+	start := 0
+	if col > 100 {
+		start = col - 100
 	}
-	return b
+	return "..." + line[start:min(len(line), start+200)] + "..."
 }
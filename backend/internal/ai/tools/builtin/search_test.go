@@ -0,0 +1,204 @@
+package builtin_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/ai/tools/builtin"
+)
+
+// searchProject creates files with ignore rules at several levels.
+func searchProject(t *testing.T) tools.ToolContext {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"main.go":                   "package main\n\nfunc Hello() {}\nfunc helloWorld() {}\n",
+		"lib/util.js":               "export function hello() {}\nconst x = 1;\n",
+		".gitignore":                "build/\n*.log\n",
+		"build/out.js":              "hello()\n",
+		"debug.log":                 "hello log\n",
+		"node_modules/dep/index.js": "hello dep\n",
+		"sub/.gitignore":            "generated.go\n",
+		"sub/generated.go":          "hello gen\n",
+		"sub/real.go":               "hello real\n",
+		".webide/ignore":            "secret/\n",
+		"secret/a.txt":              "hello secret\n",
+		"bin.dat":                   "hello\x00binary\n",
+		"notes.txt":                 "one\ntwo\nthree\nfour\nfive\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tools.ToolContext{ProjectRoot: root}
+}
+
+func TestSearchInFiles(t *testing.T) {
+	tc := searchProject(t)
+
+	tests := []struct {
+		name      string
+		args      map[string]interface{}
+		want      []string
+		wantTotal int
+		wantCode  string
+	}{
+		{
+			name: "literal ignores case by default",
+			args: map[string]interface{}{"query": "hello"},
+			want: []string{"lib/util.js:1", "main.go:3", "main.go:4", "sub/real.go:1"},
+		},
+		{
+			name: "case sensitive",
+			args: map[string]interface{}{"query": "Hello", "case_sensitive": true},
+			want: []string{"main.go:3"},
+		},
+		{
+			name: "whole word",
+			args: map[string]interface{}{"query": "hello", "whole_word": true},
+			want: []string{"lib/util.js:1", "main.go:3", "sub/real.go:1"},
+		},
+		{
+			name: "literal escapes regex characters",
+			args: map[string]interface{}{"query": "hello()"},
+			want: []string{"lib/util.js:1", "main.go:3"},
+		},
+		{
+			name: "regex",
+			args: map[string]interface{}{"query": `func(tion)? \w+\(`, "mode": "regex"},
+			want: []string{"lib/util.js:1", "main.go:3", "main.go:4"},
+		},
+		{
+			name: "file name glob",
+			args: map[string]interface{}{"query": "hello", "globs": []interface{}{"*.go"}},
+			want: []string{"main.go:3", "main.go:4", "sub/real.go:1"},
+		},
+		{
+			name: "directory glob",
+			args: map[string]interface{}{"query": "hello", "globs": []interface{}{"lib/**"}},
+			want: []string{"lib/util.js:1"},
+		},
+		{
+			name: "include ignored still skips binary files",
+			args: map[string]interface{}{"query": "hello", "include_ignored": true},
+			want: []string{
+				"build/out.js:1", "debug.log:1", "lib/util.js:1", "main.go:3", "main.go:4",
+				"node_modules/dep/index.js:1", "secret/a.txt:1", "sub/generated.go:1", "sub/real.go:1",
+			},
+		},
+		{
+			name:      "max results keeps the total",
+			args:      map[string]interface{}{"query": "hello", "max_results": float64(2)},
+			want:      []string{"lib/util.js:1", "main.go:3"},
+			wantTotal: 4,
+		},
+		{
+			name:     "invalid regex",
+			args:     map[string]interface{}{"query": "(", "mode": "regex"},
+			wantCode: tools.ErrCodeValidation,
+		},
+		{
+			name:     "unknown mode",
+			args:     map[string]interface{}{"query": "hello", "mode": "glob"},
+			wantCode: tools.ErrCodeValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := builtin.SearchInFiles().Execute(t.Context(), tt.args, tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantCode != "" {
+				if result.OK || result.Error == nil || result.Error.Code != tt.wantCode {
+					t.Fatalf("result = %+v, want error %s", result, tt.wantCode)
+				}
+				return
+			}
+			if !result.OK {
+				t.Fatalf("search_in_files failed: %+v", result.Error)
+			}
+
+			data := result.Data.(map[string]interface{})
+			var got []string
+			for _, m := range data["matches"].([]builtin.SearchMatch) {
+				got = append(got, fmt.Sprintf("%s:%d", m.Path, m.Line))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+			wantTotal := tt.wantTotal
+			if wantTotal == 0 {
+				wantTotal = len(tt.want)
+			}
+			if data["total"] != wantTotal {
+				t.Errorf("total = %v, want %d", data["total"], wantTotal)
+			}
+		})
+	}
+}
+
+func TestSearchInFiles_Context(t *testing.T) {
+	tc := searchProject(t)
+
+	tests := []struct {
+		name       string
+		args       map[string]interface{}
+		wantBefore []string
+		wantAfter  []string
+	}{
+		{
+			name:       "context on both sides",
+			args:       map[string]interface{}{"query": "three", "context": float64(1)},
+			wantBefore: []string{"two"},
+			wantAfter:  []string{"four"},
+		},
+		{
+			name:       "before overrides context",
+			args:       map[string]interface{}{"query": "three", "context": float64(1), "before": float64(2), "after": float64(0)},
+			wantBefore: []string{"one", "two"},
+		},
+		{
+			name:      "first line",
+			args:      map[string]interface{}{"query": "one", "context": float64(2)},
+			wantAfter: []string{"two", "three"},
+		},
+		{
+			name:       "last line",
+			args:       map[string]interface{}{"query": "five", "context": float64(3)},
+			wantBefore: []string{"two", "three", "four"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args["globs"] = []interface{}{"notes.txt"}
+			result, err := builtin.SearchInFiles().Execute(t.Context(), tt.args, tc)
+			if err != nil || !result.OK {
+				t.Fatalf("search_in_files: %v %+v", err, result.Error)
+			}
+			matches := result.Data.(map[string]interface{})["matches"].([]builtin.SearchMatch)
+			if len(matches) != 1 {
+				t.Fatalf("matches = %+v, want one", matches)
+			}
+			if m := matches[0]; len(m.Before)+len(tt.wantBefore) > 0 && !reflect.DeepEqual(m.Before, tt.wantBefore) {
+				t.Errorf("before = %q, want %q", m.Before, tt.wantBefore)
+			}
+			if m := matches[0]; len(m.After)+len(tt.wantAfter) > 0 && !reflect.DeepEqual(m.After, tt.wantAfter) {
+				t.Errorf("after = %q, want %q", m.After, tt.wantAfter)
+			}
+		})
+	}
+}
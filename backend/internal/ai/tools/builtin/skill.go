@@ -0,0 +1,58 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/webide/ide/backend/internal/ai/skills"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+func UseSkill() tools.Tool {
+	return tools.Tool{
+		Name:        "use_skill",
+		Description: "Load the full instructions of a project skill listed in the system prompt, plus the paths of its helper files. Call it before starting a task the skill describes, then follow the instructions.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Skill name as listed under Skills",
+				},
+			},
+			"required": []string{"name"},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			name, _ := args["name"].(string)
+			if name == "" {
+				return tools.NewErrorResult(tools.ErrCodeValidation, "name is required", nil), nil
+			}
+
+			skill, err := skills.Load(tc.ProjectRoot, name)
+			if err != nil {
+				switch {
+				case errors.Is(err, skills.ErrNotFound), errors.Is(err, skills.ErrInvalidName):
+					available := []string{}
+					for _, s := range skills.List(tc.ProjectRoot) {
+						available = append(available, s.Name)
+					}
+					return tools.NewErrorResult(tools.ErrCodeNotFound, err.Error(), map[string]interface{}{"name": name, "available": available}), nil
+				}
+				return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil), nil
+			}
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"name":         skill.Name,
+				"description":  skill.Description,
+				"path":         skill.Path,
+				"instructions": skill.Content,
+				"files":        skill.Files,
+				"truncated":    skill.Truncated,
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds(), Truncated: skill.Truncated}), nil
+		},
+	}
+}
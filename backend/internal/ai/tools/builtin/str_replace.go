@@ -0,0 +1,240 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+const maxReplaceCandidates = 3
+
+func StrReplace() tools.Tool {
+	return tools.Tool{
+		Name:        "str_replace",
+		Description: "Replace an exact string in a file. old_string must match the file exactly, including indentation, and must be unique unless replace_all is set; include a few surrounding lines to make it unique. Prefer this over apply_patch for edits to existing files.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type": "string",
+				},
+				"old_string": map[string]interface{}{
+					"type":        "string",
+					"description": "Exact text to replace",
+				},
+				"new_string": map[string]interface{}{
+					"type":        "string",
+					"description": "Replacement text",
+				},
+				"replace_all": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Replace every occurrence instead of requiring a unique match",
+				},
+			},
+			"required": []string{"path", "old_string", "new_string"},
+		},
+		Policy:  tools.PolicyConfirm,
+		Preview: previewStrReplace,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			w, count, errResult := prepareReplace(args, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+
+			if err := writeFileAtomic(w.absPath, []byte(w.content), w.mode); err != nil {
+				return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil), nil
+			}
+
+			sha := computeSHA(w.content)
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"path":         w.relPath,
+				"replacements": count,
+				"sha_before":   w.shaBefore,
+				"sha":          sha,
+				"diff":         unifiedDiff(w.relPath, w.before, w.content),
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds(), SHA: sha}), nil
+		},
+	}
+}
+
+// prepareReplace validates a str_replace call and works out the new content
+// and the number of replacements without touching the file.
+func prepareReplace(args map[string]interface{}, tc tools.ToolContext) (*pendingWrite, int, *tools.ToolResult) {
+	fail := func(code, msg string, details interface{}) (*pendingWrite, int, *tools.ToolResult) {
+		r := tools.NewErrorResult(code, msg, details)
+		return nil, 0, &r
+	}
+
+	path, _ := args["path"].(string)
+	oldStr, _ := args["old_string"].(string)
+	newStr, ok := args["new_string"].(string)
+	replaceAll, _ := args["replace_all"].(bool)
+	if path == "" || oldStr == "" || !ok {
+		return fail(tools.ErrCodeValidation, "path, old_string and new_string are required", nil)
+	}
+	if oldStr == newStr {
+		return fail(tools.ErrCodeValidation, "old_string and new_string are identical", nil)
+	}
+
+	guard := tools.NewPathGuard(tc.ProjectRoot, tc.Limits)
+	absPath, err := guard.ResolveProjectPath(path)
+	if err != nil {
+		return fail(tools.ErrCodeInvalidPath, err.Error(), map[string]interface{}{"path": path})
+	}
+	relPath, _ := filepath.Rel(tc.ProjectRoot, absPath)
+	relPath = filepath.ToSlash(relPath)
+	// The diff and closest lines would show the content of a protected file.
+	if glob, denied := tc.Protected(absPath); denied {
+		r := tools.NewProtectedResult(relPath, glob)
+		return nil, 0, &r
+	}
+
+	info, err := os.Stat(absPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fail(tools.ErrCodeNotFound, "file not found: "+relPath, nil)
+	}
+	if err != nil {
+		return fail(tools.ErrCodeExecution, err.Error(), nil)
+	}
+	if info.IsDir() {
+		return fail(tools.ErrCodeInvalidPath, "path is a directory", map[string]interface{}{"path": relPath})
+	}
+	if tc.Limits.MaxFileBytes > 0 && info.Size() > tc.Limits.MaxFileBytes {
+		return fail(tools.ErrCodeSizeLimit, "file too large", map[string]interface{}{
+			"size":     info.Size(),
+			"max_size": tc.Limits.MaxFileBytes,
+		})
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return fail(tools.ErrCodeExecution, err.Error(), nil)
+	}
+	before := string(data)
+
+	// Models write LF; match and replace in the file's own line endings.
+	if strings.Contains(before, "\r\n") && !strings.Contains(oldStr, "\r\n") {
+		oldStr = strings.ReplaceAll(oldStr, "\n", "\r\n")
+		newStr = strings.ReplaceAll(strings.ReplaceAll(newStr, "\r\n", "\n"), "\n", "\r\n")
+	}
+
+	count := strings.Count(before, oldStr)
+	switch {
+	case count == 0:
+		return fail(tools.ErrCodeValidation, "old_string not found in "+relPath+"; check whitespace and indentation against the closest lines", map[string]interface{}{
+			"path":    relPath,
+			"closest": closestLines(before, oldStr),
+		})
+	case count > 1 && !replaceAll:
+		return fail(tools.ErrCodeValidation, fmt.Sprintf("old_string matches %d times in %s; include more surrounding context or set replace_all", count, relPath), map[string]interface{}{
+			"path":    relPath,
+			"matches": count,
+			"lines":   matchLines(before, oldStr),
+		})
+	}
+
+	content := strings.Replace(before, oldStr, newStr, -1)
+	if tc.Limits.MaxFileBytes > 0 && int64(len(content)) > tc.Limits.MaxFileBytes {
+		return fail(tools.ErrCodeSizeLimit, "result too large", map[string]interface{}{
+			"size":     len(content),
+			"max_size": tc.Limits.MaxFileBytes,
+		})
+	}
+
+	return &pendingWrite{
+		absPath:   absPath,
+		relPath:   relPath,
+		exists:    true,
+		before:    before,
+		content:   content,
+		shaBefore: computeSHA(before),
+		mode:      info.Mode().Perm(),
+	}, count, nil
+}
+
+func previewStrReplace(args map[string]interface{}, tc tools.ToolContext) string {
+	w, _, errResult := prepareReplace(args, tc)
+	if errResult != nil {
+		return errResult.Error.Message
+	}
+	return unifiedDiff(w.relPath, w.before, w.content)
+}
+
+// closestLines returns the file lines most similar to the first non-blank
+// line of needle.
+func closestLines(content, needle string) []map[string]interface{} {
+	var first string
+	for _, l := range strings.Split(needle, "\n") {
+		if strings.TrimSpace(l) != "" {
+			first = strings.TrimSpace(l)
+			break
+		}
+	}
+	if first == "" {
+		return nil
+	}
+
+	type candidate struct {
+		line  int
+		text  string
+		score float64
+	}
+	var candidates []candidate
+	matcher := difflib.NewMatcher(nil, strings.Split(first, ""))
+	for i, l := range strings.Split(content, "\n") {
+		text := strings.TrimRight(l, "\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			continue
+		}
+		matcher.SetSeq1(strings.Split(trimmed, ""))
+		if matcher.RealQuickRatio() < 0.6 || matcher.QuickRatio() < 0.6 {
+			continue
+		}
+		if score := matcher.Ratio(); score >= 0.6 {
+			candidates = append(candidates, candidate{line: i + 1, text: text, score: score})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	if len(candidates) > maxReplaceCandidates {
+		candidates = candidates[:maxReplaceCandidates]
+	}
+
+	out := make([]map[string]interface{}, 0, len(candidates))
+	for _, c := range candidates {
+		if len(c.text) > 200 {
+			c.text = c.text[:200] + "..."
+		}
+		out = append(out, map[string]interface{}{
+			"line":       c.line,
+			"text":       c.text,
+			"similarity": float64(int(c.score*100)) / 100,
+		})
+	}
+	return out
+}
+
+// matchLines returns the 1-based line numbers where needle starts.
+func matchLines(content, needle string) []int {
+	var lines []int
+	offset := 0
+	for {
+		i := strings.Index(content[offset:], needle)
+		if i < 0 || len(lines) >= 20 {
+			return lines
+		}
+		offset += i
+		lines = append(lines, strings.Count(content[:offset], "\n")+1)
+		offset += len(needle)
+	}
+}
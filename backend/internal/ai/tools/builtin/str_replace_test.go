@@ -0,0 +1,135 @@
+package builtin_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/ai/tools/builtin"
+)
+
+func TestStrReplace(t *testing.T) {
+	const src = "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n\nfunc other() {\n\tprintln(\"hi\")\n}\n"
+
+	tests := []struct {
+		name     string
+		file     string
+		args     map[string]interface{}
+		wantCode string
+		want     string
+		details  func(t *testing.T, details map[string]interface{})
+	}{
+		{
+			name: "unique match",
+			file: src,
+			args: map[string]interface{}{"old_string": "func main() {\n\tprintln(\"hi\")", "new_string": "func main() {\n\tprintln(\"bye\")"},
+			want: "package main\n\nfunc main() {\n\tprintln(\"bye\")\n}\n\nfunc other() {\n\tprintln(\"hi\")\n}\n",
+		},
+		{
+			name: "replace all",
+			file: src,
+			args: map[string]interface{}{"old_string": "\"hi\"", "new_string": "\"bye\"", "replace_all": true},
+			want: "package main\n\nfunc main() {\n\tprintln(\"bye\")\n}\n\nfunc other() {\n\tprintln(\"bye\")\n}\n",
+		},
+		{
+			name:     "ambiguous match lists the lines",
+			file:     src,
+			args:     map[string]interface{}{"old_string": "println(\"hi\")", "new_string": "println(\"bye\")"},
+			wantCode: tools.ErrCodeValidation,
+			details: func(t *testing.T, details map[string]interface{}) {
+				if got := details["lines"]; !reflect.DeepEqual(got, []int{4, 8}) {
+					t.Errorf("lines = %v, want [4 8]", got)
+				}
+			},
+		},
+		{
+			name:     "miss suggests the closest lines",
+			file:     src,
+			args:     map[string]interface{}{"old_string": "    println(\"hi\")\n}\n\nfunc main2", "new_string": "x"},
+			wantCode: tools.ErrCodeValidation,
+			details: func(t *testing.T, details map[string]interface{}) {
+				closest, _ := details["closest"].([]map[string]interface{})
+				if len(closest) == 0 || closest[0]["line"] != 4 {
+					t.Errorf("closest = %v, want line 4 first", closest)
+				}
+			},
+		},
+		{
+			name: "CRLF file keeps its line endings",
+			file: "a\r\nb\r\nc\r\n",
+			args: map[string]interface{}{"old_string": "a\nb\n", "new_string": "a\nB\n"},
+			want: "a\r\nB\r\nc\r\n",
+		},
+		{
+			name:     "identical strings",
+			file:     src,
+			args:     map[string]interface{}{"old_string": "main", "new_string": "main"},
+			wantCode: tools.ErrCodeValidation,
+		},
+		{
+			name:     "empty old_string",
+			file:     src,
+			args:     map[string]interface{}{"old_string": "", "new_string": "x"},
+			wantCode: tools.ErrCodeValidation,
+		},
+		{
+			name:     "missing file",
+			args:     map[string]interface{}{"old_string": "a", "new_string": "b"},
+			wantCode: tools.ErrCodeNotFound,
+		},
+		{
+			name:     "outside the project",
+			args:     map[string]interface{}{"path": "../main.go", "old_string": "a", "new_string": "b"},
+			wantCode: tools.ErrCodeInvalidPath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			path := filepath.Join(root, "main.go")
+			if tt.file != "" {
+				if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, ok := tt.args["path"]; !ok {
+				tt.args["path"] = "main.go"
+			}
+
+			result, err := builtin.StrReplace().Execute(t.Context(), tt.args, tools.ToolContext{ProjectRoot: root})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantCode != "" {
+				if result.OK || result.Error == nil || result.Error.Code != tt.wantCode {
+					t.Fatalf("result = %+v, want error %s", result, tt.wantCode)
+				}
+				if tt.details != nil {
+					details, _ := result.Error.Details.(map[string]interface{})
+					tt.details(t, details)
+				}
+				if tt.file != "" {
+					if got, _ := os.ReadFile(path); string(got) != tt.file {
+						t.Errorf("file changed on a failed replace:\n%q", got)
+					}
+				}
+				return
+			}
+
+			if !result.OK {
+				t.Fatalf("str_replace failed: %+v", result.Error)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("file = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
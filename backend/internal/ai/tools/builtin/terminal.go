@@ -0,0 +1,335 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/terminal"
+)
+
+const (
+	defaultTerminalLines = 100
+	maxTerminalLines     = 1000
+	maxTerminalWaitMs    = 10000
+)
+
+type TerminalInfo struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title"`
+	Cwd          string    `json:"cwd,omitempty"`
+	Shell        string    `json:"shell"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	LastOutputAt time.Time `json:"last_output_at"`
+}
+
+func ReadTerminal() tools.Tool {
+	return tools.Tool{
+		Name:        "read_terminal",
+		Description: "List the user's open terminals in this project and read the recent output of one, with colors and cursor movement removed. Use it when the user refers to an error or a server in their terminal.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"terminal": map[string]interface{}{
+					"type":        "string",
+					"description": "Terminal id or title; optional when the project has a single terminal. Omit to only list terminals when there are several",
+				},
+				"lines": map[string]interface{}{
+					"type":    "integer",
+					"default": defaultTerminalLines,
+					"minimum": 1,
+					"maximum": maxTerminalLines,
+				},
+			},
+		},
+		Policy: tools.PolicyAllow,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			sessions := projectTerminals(tc.ProjectID)
+			infos := make([]TerminalInfo, 0, len(sessions))
+			for _, s := range sessions {
+				infos = append(infos, terminalInfo(s, tc.ProjectRoot))
+			}
+
+			name, _ := args["terminal"].(string)
+			if name == "" && len(sessions) != 1 {
+				return tools.NewSuccessResultWithMeta(map[string]interface{}{
+					"terminals": infos,
+				}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds()}), nil
+			}
+			session, errResult := findTerminal(sessions, name)
+			if session == nil {
+				return errResult, nil
+			}
+
+			n := defaultTerminalLines
+			if l, ok := args["lines"].(float64); ok {
+				n = int(l)
+			}
+			n = max(1, min(n, maxTerminalLines))
+
+			lines := cleanTerminalOutput(session.GetBacklog())
+			total := len(lines)
+			if total > n {
+				lines = lines[total-n:]
+			}
+			if name, glob, denied := protectedTerminalOutput(lines, tc); denied {
+				return tools.NewProtectedResult(name, glob), nil
+			}
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"terminals":   infos,
+				"terminal":    terminalInfo(session, tc.ProjectRoot),
+				"output":      strings.Join(lines, "\n"),
+				"lines":       len(lines),
+				"total_lines": total,
+				"truncated":   total > len(lines),
+			}, tools.ResultMeta{
+				DurationMs: time.Since(startTime).Milliseconds(),
+				Truncated:  total > len(lines),
+			}), nil
+		},
+	}
+}
+
+func SendToTerminal() tools.Tool {
+	return tools.Tool{
+		Name:        "send_to_terminal",
+		Description: "Type a command into one of the user's terminals and press Enter, so it runs in their shell (e.g. restarting a dev server) instead of as a separate process. Returns the output printed within wait_ms.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"terminal": map[string]interface{}{
+					"type":        "string",
+					"description": "Terminal id or title; optional when the project has a single terminal",
+				},
+				"command": map[string]interface{}{
+					"type": "string",
+				},
+				"interrupt": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Press Ctrl-C first to stop whatever is running",
+					"default":     false,
+				},
+				"enter": map[string]interface{}{
+					"type":    "boolean",
+					"default": true,
+				},
+				"wait_ms": map[string]interface{}{
+					"type":    "integer",
+					"default": 1000,
+					"minimum": 0,
+					"maximum": maxTerminalWaitMs,
+				},
+			},
+			"required": []string{"command"},
+		},
+		Policy: tools.PolicyConfirm,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			command, _ := args["command"].(string)
+			interrupt, _ := args["interrupt"].(bool)
+			if command == "" && !interrupt {
+				return tools.NewErrorResult(tools.ErrCodeValidation, "command is required", nil), nil
+			}
+			// Control characters would type keystrokes the approval card
+			// does not show.
+			if strings.IndexFunc(command, func(r rune) bool { return r < 0x20 && r != '\t' || r == 0x7f }) >= 0 {
+				return tools.NewErrorResult(tools.ErrCodeValidation, "command must be a single line without control characters", nil), nil
+			}
+
+			name, _ := args["terminal"].(string)
+			session, errResult := findTerminal(projectTerminals(tc.ProjectID), name)
+			if session == nil {
+				return errResult, nil
+			}
+			if status, _ := session.GetState(); status != "running" {
+				return tools.NewErrorResult(tools.ErrCodeExecution, "terminal is "+status, map[string]interface{}{"terminal": session.ID.String()}), nil
+			}
+
+			enter := true
+			if e, ok := args["enter"].(bool); ok {
+				enter = e
+			}
+			wait := 1000
+			if w, ok := args["wait_ms"].(float64); ok {
+				wait = int(w)
+			}
+			wait = max(0, min(wait, maxTerminalWaitMs))
+
+			before := session.GetBacklog()
+			var input []byte
+			if interrupt {
+				input = append(input, 0x03)
+			}
+			input = append(input, command...)
+			if enter && command != "" {
+				input = append(input, '\r')
+			}
+			if err := session.Write(input); err != nil {
+				return tools.NewErrorResult(tools.ErrCodeExecution, "failed to write to terminal", err.Error()), nil
+			}
+
+			if wait > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Duration(wait) * time.Millisecond):
+				}
+			}
+			lines := cleanTerminalOutput(newTerminalOutput(before, session.GetBacklog()))
+			truncated := len(lines) > defaultTerminalLines
+			if truncated {
+				lines = lines[len(lines)-defaultTerminalLines:]
+			}
+			if name, glob, denied := protectedTerminalOutput(lines, tc); denied {
+				return tools.NewProtectedResult(name, glob), nil
+			}
+
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"terminal":  terminalInfo(session, tc.ProjectRoot),
+				"sent":      command,
+				"output":    strings.Join(lines, "\n"),
+				"truncated": truncated,
+			}, tools.ResultMeta{
+				DurationMs: time.Since(startTime).Milliseconds(),
+				Truncated:  truncated,
+			}), nil
+		},
+	}
+}
+
+func projectTerminals(projectID uuid.UUID) []*terminal.TerminalSession {
+	sessions, _ := terminal.GetProjectSessions(projectID)
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+	return sessions
+}
+
+func terminalInfo(s *terminal.TerminalSession, projectRoot string) TerminalInfo {
+	status, lastSeen := s.GetState()
+	cwd := s.Cwd
+	if cwd != "" && projectRoot != "" {
+		cwd = relOrDot(projectRoot, cwd)
+	}
+	return TerminalInfo{
+		ID:           s.ID.String(),
+		Title:        s.Title,
+		Cwd:          cwd,
+		Shell:        s.Shell,
+		Status:       status,
+		CreatedAt:    s.CreatedAt,
+		LastOutputAt: lastSeen,
+	}
+}
+
+// findTerminal picks a terminal by id or by title, ignoring case. An empty
+// name is only enough when there is one terminal.
+func findTerminal(sessions []*terminal.TerminalSession, name string) (*terminal.TerminalSession, tools.ToolResult) {
+	if len(sessions) == 0 {
+		return nil, tools.NewErrorResult(tools.ErrCodeNotFound, "the project has no open terminals", nil)
+	}
+	titles := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		titles = append(titles, s.Title)
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		if len(sessions) == 1 {
+			return sessions[0], tools.ToolResult{}
+		}
+		return nil, tools.NewErrorResult(tools.ErrCodeValidation, "the project has several terminals; name one", map[string]interface{}{"terminals": titles})
+	}
+
+	var matches []*terminal.TerminalSession
+	for _, s := range sessions {
+		if s.ID.String() == name {
+			return s, tools.ToolResult{}
+		}
+		if strings.EqualFold(s.Title, name) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, tools.NewErrorResult(tools.ErrCodeNotFound, "terminal not found: "+name, map[string]interface{}{"terminals": titles})
+	case 1:
+		return matches[0], tools.ToolResult{}
+	}
+	return nil, tools.NewErrorResult(tools.ErrCodeValidation, "several terminals are titled "+name+"; use the id", nil)
+}
+
+// newTerminalOutput returns what after holds beyond before. Once the ring
+// buffer is full its start moves, so the tail of before is looked up in after.
+func newTerminalOutput(before, after []byte) []byte {
+	if len(before) == 0 {
+		return after
+	}
+	if len(after) > len(before) && bytes.Equal(after[:len(before)], before) {
+		return after[len(before):]
+	}
+	anchor := before[max(0, len(before)-256):]
+	if i := bytes.LastIndex(after, anchor); i >= 0 {
+		return after[i+len(anchor):]
+	}
+	return after
+}
+
+// protectedTerminalOutput reports a protected file named in terminal output,
+// as by "cat id_rsa", since its content may be printed there too.
+func protectedTerminalOutput(lines []string, tc tools.ToolContext) (string, string, bool) {
+	if tc.Denied == nil {
+		return "", "", false
+	}
+	for _, line := range lines {
+		for _, field := range strings.Fields(line) {
+			field = strings.Trim(field, "\"'`,;:()[]{}<>")
+			if field == "" {
+				continue
+			}
+			if glob, denied := tc.Denied(field); denied {
+				return field, glob, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// cleanTerminalOutput turns raw terminal output into plain lines: escape
+// sequences are removed and carriage returns and backspaces are applied, so
+// progress bars and retyped prompts show their final text.
+func cleanTerminalOutput(data []byte) []string {
+	text := stripANSI(strings.ToValidUTF8(string(data), ""))
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var lines []string
+	for _, raw := range strings.Split(text, "\n") {
+		var line []rune
+		col := 0
+		for _, r := range raw {
+			switch {
+			case r == '\r':
+				col = 0
+			case r == '\b':
+				col = max(0, col-1)
+			case r < 0x20 && r != '\t' || r == 0x7f:
+			case col < len(line):
+				line[col] = r
+				col++
+			default:
+				line = append(line, r)
+				col++
+			}
+		}
+		lines = append(lines, strings.TrimRight(string(line), " \t"))
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,99 @@
+package builtin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/terminal"
+)
+
+func TestNewTerminalOutput(t *testing.T) {
+	long := strings.Repeat("x", 300) + "$ "
+
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   string
+	}{
+		{name: "empty before", before: "", after: "$ ls\nREADME.md\n", want: "$ ls\nREADME.md\n"},
+		{name: "appended output", before: "$ ", after: "$ ls\nREADME.md\n", want: "ls\nREADME.md\n"},
+		{name: "no new output", before: "$ ", after: "$ ", want: ""},
+		{name: "ring buffer moved", before: "old\n" + long, after: long[10:] + "ls\nREADME.md\n", want: "ls\nREADME.md\n"},
+		{name: "tail repeated in the new output", before: "a\n$ ", after: "a\n$ echo\n\n$ ", want: "echo\n\n$ "},
+		{name: "buffer cleared", before: "old output\n$ ", after: "fresh\n", want: "fresh\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(newTerminalOutput([]byte(tt.before), []byte(tt.after))); got != tt.want {
+				t.Errorf("newTerminalOutput = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanTerminalOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{name: "plain lines", data: "one\ntwo\n", want: []string{"one", "two"}},
+		{name: "CRLF", data: "one\r\ntwo\r\n", want: []string{"one", "two"}},
+		{name: "colors", data: "\x1b[31merror\x1b[0m: failed\n", want: []string{"error: failed"}},
+		{name: "progress bar", data: "10%\r50%\r100%\n", want: []string{"100%"}},
+		{name: "shorter rewrite keeps the rest", data: "abcdef\rxy\n", want: []string{"xycdef"}},
+		{name: "backspace", data: "lss\b \b\n", want: []string{"ls"}},
+		{name: "title sequence", data: "\x1b]0;user@host\x07$ ls\n", want: []string{"$ ls"}},
+		{name: "trailing blank lines", data: "done\n\n\n", want: []string{"done"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanTerminalOutput([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cleanTerminalOutput = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindTerminal(t *testing.T) {
+	server := &terminal.TerminalSession{ID: uuid.New(), Title: "Server"}
+	shell1 := &terminal.TerminalSession{ID: uuid.New(), Title: "bash"}
+	shell2 := &terminal.TerminalSession{ID: uuid.New(), Title: "Bash"}
+
+	tests := []struct {
+		name     string
+		sessions []*terminal.TerminalSession
+		query    string
+		want     *terminal.TerminalSession
+		wantCode string
+	}{
+		{name: "no terminals", query: "", wantCode: tools.ErrCodeNotFound},
+		{name: "only terminal", sessions: []*terminal.TerminalSession{server}, want: server},
+		{name: "several without a name", sessions: []*terminal.TerminalSession{server, shell1}, wantCode: tools.ErrCodeValidation},
+		{name: "by title ignoring case", sessions: []*terminal.TerminalSession{server, shell1}, query: " server ", want: server},
+		{name: "by id", sessions: []*terminal.TerminalSession{shell1, shell2}, query: shell2.ID.String(), want: shell2},
+		{name: "ambiguous title", sessions: []*terminal.TerminalSession{shell1, shell2}, query: "bash", wantCode: tools.ErrCodeValidation},
+		{name: "unknown title", sessions: []*terminal.TerminalSession{server}, query: "logs", wantCode: tools.ErrCodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, result := findTerminal(tt.sessions, tt.query)
+			if tt.wantCode != "" {
+				if got != nil || result.Error == nil || result.Error.Code != tt.wantCode {
+					t.Fatalf("findTerminal = %v %+v, want error %s", got, result, tt.wantCode)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("findTerminal = %v, want %s", got, tt.want.Title)
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package builtin
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// goTestEvent is one line of go test -json output.
+type goTestEvent struct {
+	Action     string  `json:"Action"`
+	Package    string  `json:"Package"`
+	ImportPath string  `json:"ImportPath"`
+	Test       string  `json:"Test"`
+	Elapsed    float64 `json:"Elapsed"`
+	Output     string  `json:"Output"`
+}
+
+type goTestEntry struct {
+	pkg     string
+	name    string
+	status  string
+	elapsed float64
+	output  []string
+}
+
+// goTestParser collects go test -json events as the command runs. Lines
+// come from both stdout and stderr readers, hence the lock.
+type goTestParser struct {
+	root, modDir, modPath string
+
+	mu       sync.Mutex
+	tests    map[string]*goTestEntry
+	order    []string
+	pkgs     map[string]*goTestEntry
+	pkgOrder []string
+	build    map[string][]string
+	stray    []string
+}
+
+var (
+	goOutputLocation = regexp.MustCompile(`^\s+([\w./-]+\.go):(\d+):`)
+	goStackLocation  = regexp.MustCompile(`^\s+(/\S+\.go):(\d+)`)
+	goBuildLocation  = regexp.MustCompile(`^(\S+\.go):(\d+)(?::\d+)?:`)
+)
+
+func newGoTestParser(root, modDir, modPath string) *goTestParser {
+	return &goTestParser{
+		root:    root,
+		modDir:  modDir,
+		modPath: modPath,
+		tests:   map[string]*goTestEntry{},
+		pkgs:    map[string]*goTestEntry{},
+		build:   map[string][]string{},
+	}
+}
+
+func (p *goTestParser) line(stream, text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ev goTestEvent
+	if stream != "stdout" || !strings.HasPrefix(text, "{") || json.Unmarshal([]byte(text), &ev) != nil {
+		// Build errors from older go versions and anything printed by a
+		// test binary outside the test framework.
+		p.stray = appendCapped(p.stray, text)
+		return
+	}
+
+	switch ev.Action {
+	case "build-output":
+		pkg, _, _ := strings.Cut(ev.ImportPath, " ")
+		p.build[pkg] = appendCapped(p.build[pkg], strings.TrimSuffix(ev.Output, "\n"))
+		return
+	case "build-fail", "start", "run", "pause", "cont", "bench":
+		return
+	}
+
+	var e *goTestEntry
+	if ev.Test == "" {
+		if e = p.pkgs[ev.Package]; e == nil {
+			e = &goTestEntry{pkg: ev.Package}
+			p.pkgs[ev.Package] = e
+			p.pkgOrder = append(p.pkgOrder, ev.Package)
+		}
+	} else {
+		key := ev.Package + "\x00" + ev.Test
+		if e = p.tests[key]; e == nil {
+			e = &goTestEntry{pkg: ev.Package, name: ev.Test}
+			p.tests[key] = e
+			p.order = append(p.order, key)
+		}
+	}
+
+	switch ev.Action {
+	case "output":
+		out := strings.TrimSuffix(ev.Output, "\n")
+		if strings.HasPrefix(out, "=== ") {
+			return
+		}
+		e.output = appendCapped(e.output, out)
+	case "pass", "fail", "skip":
+		e.status = ev.Action
+		e.elapsed = ev.Elapsed
+	}
+}
+
+func (p *goTestParser) results() ([]TestCase, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Only leaf tests are listed, so a failing subtest is not counted again
+	// for each parent, unless the parent failed on its own account.
+	parents := map[string]bool{}
+	for _, key := range p.order {
+		e := p.tests[key]
+		for name := e.name; strings.Contains(name, "/"); {
+			name = name[:strings.LastIndex(name, "/")]
+			parents[e.pkg+"\x00"+name] = true
+		}
+	}
+
+	var cases []TestCase
+	failedPkgs := map[string]bool{}
+	for _, key := range p.order {
+		e := p.tests[key]
+		status := goTestStatus(e.status)
+		location := p.testLocation(e)
+		if parents[key] && (status != "fail" || location == "") {
+			if status == "fail" {
+				failedPkgs[e.pkg] = true
+			}
+			continue
+		}
+		if status == "fail" {
+			failedPkgs[e.pkg] = true
+		}
+		cases = append(cases, TestCase{
+			Name:       e.name,
+			Suite:      p.pkgRel(e.pkg),
+			Status:     status,
+			DurationMs: int64(e.elapsed * 1000),
+			Location:   location,
+			Output:     strings.Join(e.output, "\n"),
+		})
+	}
+
+	// A package that failed without a failing test did not build or its
+	// binary died outside a test.
+	for _, pkg := range p.pkgOrder {
+		e := p.pkgs[pkg]
+		if e.status != "fail" || failedPkgs[pkg] {
+			continue
+		}
+		output := append(append([]string{}, p.build[pkg]...), e.output...)
+		if len(p.build[pkg]) == 0 {
+			output = append(append([]string{}, p.stray...), output...)
+		}
+		tc := TestCase{
+			Name:       "(package)",
+			Suite:      p.pkgRel(pkg),
+			Status:     "fail",
+			DurationMs: int64(e.elapsed * 1000),
+			Output:     strings.Join(output, "\n"),
+		}
+		for _, line := range output {
+			if m := goBuildLocation.FindStringSubmatch(line); m != nil {
+				tc.Location = p.rel(filepath.Join(p.modDir, m[1])) + ":" + m[2]
+				break
+			}
+		}
+		cases = append(cases, tc)
+	}
+
+	return cases, nil
+}
+
+func goTestStatus(action string) string {
+	switch action {
+	case "pass":
+		return "pass"
+	case "skip":
+		return "skip"
+	}
+	// No final action means the test binary died while it ran.
+	return "fail"
+}
+
+func (p *goTestParser) testLocation(e *goTestEntry) string {
+	for _, line := range e.output {
+		if m := goOutputLocation.FindStringSubmatch(line); m != nil {
+			return p.rel(filepath.Join(p.pkgDir(e.pkg), m[1])) + ":" + m[2]
+		}
+	}
+	for _, line := range e.output {
+		if m := goStackLocation.FindStringSubmatch(line); m != nil && strings.HasPrefix(m[1], p.root+string(filepath.Separator)) {
+			return p.rel(m[1]) + ":" + m[2]
+		}
+	}
+	return ""
+}
+
+func (p *goTestParser) pkgDir(pkg string) string {
+	if p.modPath != "" && (pkg == p.modPath || strings.HasPrefix(pkg, p.modPath+"/")) {
+		return filepath.Join(p.modDir, filepath.FromSlash(strings.TrimPrefix(pkg, p.modPath)))
+	}
+	return p.modDir
+}
+
+func (p *goTestParser) pkgRel(pkg string) string {
+	if p.modPath != "" && (pkg == p.modPath || strings.HasPrefix(pkg, p.modPath+"/")) {
+		return p.rel(p.pkgDir(pkg))
+	}
+	return pkg
+}
+
+func (p *goTestParser) rel(path string) string {
+	return relOrDot(p.root, path)
+}
+
+func appendCapped(lines []string, line string) []string {
+	if len(lines) >= maxTestOutputLines {
+		return lines
+	}
+	return append(lines, line)
+}
+
+// jestReport is the --json report of jest, which vitest also writes.
+type jestReport struct {
+	TestResults []struct {
+		Name             string `json:"name"`
+		Status           string `json:"status"`
+		Message          string `json:"message"`
+		AssertionResults []struct {
+			AncestorTitles  []string `json:"ancestorTitles"`
+			Title           string   `json:"title"`
+			FullName        string   `json:"fullName"`
+			Status          string   `json:"status"`
+			Duration        float64  `json:"duration"`
+			FailureMessages []string `json:"failureMessages"`
+			Location        *struct {
+				Line int `json:"line"`
+			} `json:"location"`
+		} `json:"assertionResults"`
+	} `json:"testResults"`
+}
+
+var jsStackLocation = regexp.MustCompile(`(/[^\s():]+):(\d+):\d+`)
+
+func parseJestReport(root, report string) ([]TestCase, error) {
+	data, err := os.ReadFile(report)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("no test report was written")
+	}
+	var r jestReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, errors.New("invalid test report: " + err.Error())
+	}
+
+	var cases []TestCase
+	for _, file := range r.TestResults {
+		suite := relOrDot(root, file.Name)
+		failed := false
+		for _, a := range file.AssertionResults {
+			name := a.FullName
+			if name == "" {
+				name = strings.Join(append(append([]string{}, a.AncestorTitles...), a.Title), " > ")
+			}
+			tc := TestCase{
+				Name:       name,
+				Suite:      suite,
+				Status:     jestStatus(a.Status),
+				DurationMs: int64(a.Duration),
+			}
+			if a.Location != nil && a.Location.Line > 0 {
+				tc.Location = suite + ":" + strconv.Itoa(a.Location.Line)
+			}
+			if tc.Status == "fail" {
+				failed = true
+				tc.Output = strings.Join(a.FailureMessages, "\n")
+				if loc := jsLocation(root, tc.Output); loc != "" {
+					tc.Location = loc
+				}
+			}
+			cases = append(cases, tc)
+		}
+		// The file failed to load or a hook outside any test threw.
+		if file.Status == "failed" && !failed {
+			cases = append(cases, TestCase{
+				Name:     "(suite)",
+				Suite:    suite,
+				Status:   "fail",
+				Location: jsLocation(root, file.Message),
+				Output:   file.Message,
+			})
+		}
+	}
+	return cases, nil
+}
+
+func jestStatus(status string) string {
+	switch status {
+	case "passed":
+		return "pass"
+	case "failed":
+		return "fail"
+	}
+	return "skip"
+}
+
+// jsLocation returns the first stack frame in the project's own code.
+func jsLocation(root, text string) string {
+	for _, m := range jsStackLocation.FindAllStringSubmatch(stripANSI(text), -1) {
+		if strings.HasPrefix(m[1], root+string(filepath.Separator)) && !strings.Contains(m[1], "/node_modules/") {
+			return relOrDot(root, m[1]) + ":" + m[2]
+		}
+	}
+	return ""
+}
+
+type junitResult struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitCase struct {
+	Name      string       `xml:"name,attr"`
+	Classname string       `xml:"classname,attr"`
+	File      string       `xml:"file,attr"`
+	Line      string       `xml:"line,attr"`
+	Time      float64      `xml:"time,attr"`
+	Failure   *junitResult `xml:"failure"`
+	Error     *junitResult `xml:"error"`
+	Skipped   *junitResult `xml:"skipped"`
+}
+
+var pyTraceLocation = regexp.MustCompile(`(?m)^([^\s:]+\.py):(\d+):`)
+
+// parseJUnitReport reads pytest's xunit1 report, whose testcases carry the
+// file and the 0-based line of each test.
+func parseJUnitReport(root, dir, report string) ([]TestCase, error) {
+	f, err := os.Open(report)
+	if err != nil {
+		return nil, errors.New("no test report was written")
+	}
+	defer f.Close()
+
+	var cases []TestCase
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return nil, errors.New("invalid test report: " + err.Error())
+			}
+			break
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "testcase" {
+			continue
+		}
+		var c junitCase
+		if err := dec.DecodeElement(&c, &start); err != nil {
+			return cases, errors.New("invalid test report: " + err.Error())
+		}
+
+		tc := TestCase{Name: c.Name, Status: "pass", DurationMs: int64(c.Time * 1000)}
+		if c.File != "" {
+			tc.Suite = relOrDot(root, filepath.Join(dir, c.File))
+			// Tests in a class are named Class::test, as pytest does.
+			module := strings.ReplaceAll(strings.TrimSuffix(filepath.ToSlash(c.File), ".py"), "/", ".")
+			if class, ok := strings.CutPrefix(c.Classname, module+"."); ok {
+				tc.Name = class + "::" + c.Name
+			}
+			if line, err := strconv.Atoi(c.Line); err == nil {
+				tc.Location = tc.Suite + ":" + strconv.Itoa(line+1)
+			}
+		}
+
+		result := c.Failure
+		if result == nil {
+			result = c.Error
+		}
+		switch {
+		case result != nil:
+			tc.Status = "fail"
+			tc.Output = strings.TrimSpace(result.Text)
+			if tc.Output == "" {
+				tc.Output = result.Message
+			}
+			if m := pyTraceLocation.FindAllStringSubmatch(tc.Output, -1); len(m) > 0 {
+				last := m[len(m)-1]
+				path := last[1]
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(dir, path)
+				}
+				tc.Location = relOrDot(root, path) + ":" + last[2]
+			}
+		case c.Skipped != nil:
+			tc.Status = "skip"
+		}
+		cases = append(cases, tc)
+	}
+	return cases, nil
+}
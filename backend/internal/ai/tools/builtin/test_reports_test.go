@@ -0,0 +1,237 @@
+package builtin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// caseSummary is the part of a TestCase the parser tests compare.
+type caseSummary struct {
+	name, suite, status, location string
+}
+
+func summarize(cases []TestCase) []caseSummary {
+	out := make([]caseSummary, 0, len(cases))
+	for _, c := range cases {
+		out = append(out, caseSummary{c.Name, c.Suite, c.Status, c.Location})
+	}
+	return out
+}
+
+func writeReport(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGoTestParser(t *testing.T) {
+	root := "/work/project"
+
+	tests := []struct {
+		name   string
+		stdout []string
+		stderr []string
+		want   []caseSummary
+	}{
+		{
+			name: "pass, fail and skip",
+			stdout: []string{
+				`{"Action":"run","Package":"example.com/app/pkg","Test":"TestOK"}`,
+				`{"Action":"pass","Package":"example.com/app/pkg","Test":"TestOK","Elapsed":0.01}`,
+				`{"Action":"run","Package":"example.com/app/pkg","Test":"TestBad"}`,
+				`{"Action":"output","Package":"example.com/app/pkg","Test":"TestBad","Output":"=== RUN   TestBad\n"}`,
+				`{"Action":"output","Package":"example.com/app/pkg","Test":"TestBad","Output":"    app_test.go:12: got 1, want 2\n"}`,
+				`{"Action":"fail","Package":"example.com/app/pkg","Test":"TestBad","Elapsed":0.02}`,
+				`{"Action":"skip","Package":"example.com/app/pkg","Test":"TestLater","Elapsed":0}`,
+				`{"Action":"fail","Package":"example.com/app/pkg","Elapsed":0.1}`,
+			},
+			want: []caseSummary{
+				{"TestOK", "pkg", "pass", ""},
+				{"TestBad", "pkg", "fail", "pkg/app_test.go:12"},
+				{"TestLater", "pkg", "skip", ""},
+			},
+		},
+		{
+			name: "failing subtest is listed once",
+			stdout: []string{
+				`{"Action":"output","Package":"example.com/app","Test":"TestTable/empty","Output":"    table_test.go:30: empty input\n"}`,
+				`{"Action":"fail","Package":"example.com/app","Test":"TestTable/empty","Elapsed":0}`,
+				`{"Action":"pass","Package":"example.com/app","Test":"TestTable/full","Elapsed":0}`,
+				`{"Action":"fail","Package":"example.com/app","Test":"TestTable","Elapsed":0}`,
+			},
+			want: []caseSummary{
+				{"TestTable/empty", ".", "fail", "table_test.go:30"},
+				{"TestTable/full", ".", "pass", ""},
+			},
+		},
+		{
+			name: "test binary died in a test",
+			stdout: []string{
+				`{"Action":"run","Package":"example.com/app/pkg","Test":"TestPanic"}`,
+				`{"Action":"output","Package":"example.com/app/pkg","Test":"TestPanic","Output":"panic: boom\n"}`,
+				`{"Action":"output","Package":"example.com/app/pkg","Test":"TestPanic","Output":"\t/work/project/pkg/app.go:7 +0x1d\n"}`,
+				`{"Action":"fail","Package":"example.com/app/pkg","Elapsed":0.1}`,
+			},
+			want: []caseSummary{
+				{"TestPanic", "pkg", "fail", "pkg/app.go:7"},
+			},
+		},
+		{
+			name: "build failure",
+			stdout: []string{
+				`{"ImportPath":"example.com/app/pkg [example.com/app/pkg.test]","Action":"build-output","Output":"# example.com/app/pkg\n"}`,
+				`{"ImportPath":"example.com/app/pkg [example.com/app/pkg.test]","Action":"build-output","Output":"pkg/app.go:3:2: undefined: missing\n"}`,
+				`{"ImportPath":"example.com/app/pkg [example.com/app/pkg.test]","Action":"build-fail"}`,
+				`{"Action":"fail","Package":"example.com/app/pkg","Elapsed":0}`,
+			},
+			want: []caseSummary{
+				{"(package)", "pkg", "fail", "pkg/app.go:3"},
+			},
+		},
+		{
+			name:   "build failure on stderr",
+			stderr: []string{"# example.com/app/pkg", "pkg/app.go:9:1: syntax error"},
+			stdout: []string{
+				`{"Action":"fail","Package":"example.com/app/pkg","Elapsed":0}`,
+			},
+			want: []caseSummary{
+				{"(package)", "pkg", "fail", "pkg/app.go:9"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newGoTestParser(root, root, "example.com/app")
+			for _, line := range tt.stderr {
+				p.line("stderr", line)
+			}
+			for _, line := range tt.stdout {
+				p.line("stdout", line)
+			}
+			cases, err := p.results()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := summarize(cases)
+			if len(got) != len(tt.want) {
+				t.Fatalf("cases = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("case %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseJestReport(t *testing.T) {
+	root := "/work/web"
+
+	tests := []struct {
+		name   string
+		report string
+		want   []caseSummary
+	}{
+		{
+			name: "jest assertions",
+			report: `{"testResults":[{"name":"/work/web/src/sum.test.js","status":"failed","assertionResults":[
+				{"ancestorTitles":["sum"],"title":"adds","fullName":"sum adds","status":"passed","duration":3},
+				{"ancestorTitles":["sum"],"title":"overflows","fullName":"sum overflows","status":"failed","duration":2,
+				 "failureMessages":["Error: expect(received).toBe(expected)\n    at Object.<anonymous> (/work/web/src/sum.test.js:14:21)\n    at /work/web/node_modules/jest-circus/build/run.js:1:1"]},
+				{"ancestorTitles":[],"title":"later","fullName":"later","status":"pending"}]}]}`,
+			want: []caseSummary{
+				{"sum adds", "src/sum.test.js", "pass", ""},
+				{"sum overflows", "src/sum.test.js", "fail", "src/sum.test.js:14"},
+				{"later", "src/sum.test.js", "skip", ""},
+			},
+		},
+		{
+			name: "vitest locations and titles",
+			report: `{"testResults":[{"name":"/work/web/src/button.test.ts","status":"passed","assertionResults":[
+				{"ancestorTitles":["Button","click"],"title":"fires","status":"passed","duration":1,"location":{"line":8,"column":5}}]}]}`,
+			want: []caseSummary{
+				{"Button > click > fires", "src/button.test.ts", "pass", "src/button.test.ts:8"},
+			},
+		},
+		{
+			name:   "suite that failed to load",
+			report: `{"testResults":[{"name":"/work/web/src/broken.test.js","status":"failed","message":"SyntaxError: Unexpected token\n    at /work/web/src/broken.test.js:3:1","assertionResults":[]}]}`,
+			want: []caseSummary{
+				{"(suite)", "src/broken.test.js", "fail", "src/broken.test.js:3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cases, err := parseJestReport(root, writeReport(t, "report.json", tt.report))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := summarize(cases)
+			if len(got) != len(tt.want) {
+				t.Fatalf("cases = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("case %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := parseJestReport(root, filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("parseJestReport accepted a missing report")
+	}
+}
+
+func TestParseJUnitReport(t *testing.T) {
+	root := "/work/project"
+	report := `<?xml version="1.0" encoding="utf-8"?>
+<testsuites><testsuite name="pytest" tests="4">
+<testcase classname="tests.test_app" name="test_ok" file="tests/test_app.py" line="3" time="0.001"/>
+<testcase classname="tests.test_app.TestCart" name="test_total" file="tests/test_app.py" line="10" time="0.002">
+<failure message="assert 1 == 2">def test_total(self):
+&gt;       assert total() == 2
+E       assert 1 == 2
+
+tests/test_app.py:12: AssertionError</failure>
+</testcase>
+<testcase classname="tests.test_app" name="test_error" file="tests/test_app.py" line="20" time="0">
+<error message="fixture 'db' not found"></error>
+</testcase>
+<testcase classname="tests.test_app" name="test_skip" file="tests/test_app.py" line="30" time="0">
+<skipped message="not ready"/>
+</testcase>
+</testsuite></testsuites>`
+
+	cases, err := parseJUnitReport(root, filepath.Join(root, "py"), writeReport(t, "report.xml", report))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []caseSummary{
+		{"test_ok", "py/tests/test_app.py", "pass", "py/tests/test_app.py:4"},
+		{"TestCart::test_total", "py/tests/test_app.py", "fail", "py/tests/test_app.py:12"},
+		{"test_error", "py/tests/test_app.py", "fail", "py/tests/test_app.py:21"},
+		{"test_skip", "py/tests/test_app.py", "skip", "py/tests/test_app.py:31"},
+	}
+	got := summarize(cases)
+	if len(got) != len(want) {
+		t.Fatalf("cases = %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("case %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if !strings.Contains(cases[2].Output, "fixture 'db' not found") {
+		t.Errorf("error output = %q, want the error message", cases[2].Output)
+	}
+}
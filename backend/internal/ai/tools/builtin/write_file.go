@@ -0,0 +1,218 @@
+package builtin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/webide/ide/backend/internal/ai/tools"
+)
+
+// maxResultDiffBytes caps the diff returned with write results and shown in
+// approval previews.
+const maxResultDiffBytes = 8 * 1024
+
+func WriteFile() tools.Tool {
+	return tools.Tool{
+		Name:        "write_file",
+		Description: "Create a file or replace its whole content. Prefer this over apply_patch for new files and full rewrites. Pass the sha from your last read_file of the path as expected_sha so a file changed in the meantime is not overwritten. Existing line endings and file mode are kept.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type": "string",
+				},
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "The complete new file content",
+				},
+				"expected_sha": map[string]interface{}{
+					"type":        "string",
+					"description": "sha256 of the current content as returned by read_file; omit when creating a file",
+				},
+			},
+			"required": []string{"path", "content"},
+		},
+		Policy:  tools.PolicyConfirm,
+		Preview: previewWriteFile,
+		Execute: func(ctx context.Context, args map[string]interface{}, tc tools.ToolContext) (tools.ToolResult, error) {
+			startTime := time.Now()
+
+			w, errResult := prepareWrite(args, tc)
+			if errResult != nil {
+				return *errResult, nil
+			}
+
+			if err := writeFileAtomic(w.absPath, []byte(w.content), w.mode); err != nil {
+				return tools.NewErrorResult(tools.ErrCodeExecution, err.Error(), nil), nil
+			}
+
+			sha := computeSHA(w.content)
+			return tools.NewSuccessResultWithMeta(map[string]interface{}{
+				"path":         w.relPath,
+				"created":      !w.exists,
+				"bytes":        len(w.content),
+				"sha_before":   w.shaBefore,
+				"sha":          sha,
+				"line_endings": w.lineEndings,
+				"diff":         unifiedDiff(w.relPath, w.before, w.content),
+			}, tools.ResultMeta{DurationMs: time.Since(startTime).Milliseconds(), SHA: sha}), nil
+		},
+	}
+}
+
+type pendingWrite struct {
+	absPath, relPath string
+	exists           bool
+	before, content  string
+	shaBefore        string
+	mode             os.FileMode
+	lineEndings      string
+}
+
+// prepareWrite validates a write_file call and works out the final content
+// without touching the file.
+func prepareWrite(args map[string]interface{}, tc tools.ToolContext) (*pendingWrite, *tools.ToolResult) {
+	fail := func(code, msg string, details interface{}) (*pendingWrite, *tools.ToolResult) {
+		r := tools.NewErrorResult(code, msg, details)
+		return nil, &r
+	}
+
+	path, _ := args["path"].(string)
+	content, ok := args["content"].(string)
+	if path == "" || !ok {
+		return fail(tools.ErrCodeValidation, "path and content are required", nil)
+	}
+	expectedSHA, _ := args["expected_sha"].(string)
+
+	guard := tools.NewPathGuard(tc.ProjectRoot, tc.Limits)
+	absPath, err := guard.ResolveProjectPath(path)
+	if err != nil {
+		return fail(tools.ErrCodeInvalidPath, err.Error(), map[string]interface{}{"path": path})
+	}
+	relPath, _ := filepath.Rel(tc.ProjectRoot, absPath)
+	relPath = filepath.ToSlash(relPath)
+	// The result diff would show the old content of a protected file.
+	if glob, denied := tc.Protected(absPath); denied {
+		if _, err := os.Stat(absPath); err == nil {
+			r := tools.NewProtectedResult(relPath, glob)
+			return nil, &r
+		}
+	}
+
+	if tc.Limits.MaxFileBytes > 0 && int64(len(content)) > tc.Limits.MaxFileBytes {
+		return fail(tools.ErrCodeSizeLimit, "content too large", map[string]interface{}{
+			"size":     len(content),
+			"max_size": tc.Limits.MaxFileBytes,
+		})
+	}
+
+	w := &pendingWrite{absPath: absPath, relPath: relPath, mode: 0644, lineEndings: "lf"}
+	info, err := os.Stat(absPath)
+	switch {
+	case err == nil && info.IsDir():
+		return fail(tools.ErrCodeInvalidPath, "path is a directory", map[string]interface{}{"path": relPath})
+	case err == nil:
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			return fail(tools.ErrCodeExecution, err.Error(), nil)
+		}
+		w.exists = true
+		w.before = string(data)
+		w.shaBefore = computeSHA(w.before)
+		w.mode = info.Mode().Perm()
+		if strings.Contains(w.before, "\r\n") {
+			w.lineEndings = "crlf"
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fail(tools.ErrCodeExecution, err.Error(), nil)
+	}
+
+	if expectedSHA != "" && expectedSHA != w.shaBefore {
+		msg := "file changed since it was read; read it again and redo the edit"
+		if !w.exists {
+			msg = "file no longer exists; it was deleted or moved since it was read"
+		}
+		return fail(tools.ErrCodeConflict, msg, map[string]interface{}{
+			"path":         relPath,
+			"expected_sha": expectedSHA,
+			"current_sha":  w.shaBefore,
+		})
+	}
+
+	w.content = content
+	if w.lineEndings == "crlf" {
+		w.content = strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\n", "\r\n")
+	}
+	return w, nil
+}
+
+func previewWriteFile(args map[string]interface{}, tc tools.ToolContext) string {
+	w, errResult := prepareWrite(args, tc)
+	if errResult != nil {
+		return errResult.Error.Message
+	}
+	if w.before == w.content {
+		return "No changes to " + w.relPath
+	}
+	return unifiedDiff(w.relPath, w.before, w.content)
+}
+
+// writeFileAtomic replaces path through a temporary file in the same
+// directory so readers never see a half-written file.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func unifiedDiff(path, before, after string) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        diffLines(before),
+		B:        diffLines(after),
+		FromFile: "a/" + path,
+		ToFile:   "b/" + path,
+		Context:  3,
+	})
+	if len(diff) > maxResultDiffBytes {
+		diff = diff[:maxResultDiffBytes] + "\n... (diff truncated)\n"
+	}
+	return diff
+}
+
+// diffLines splits s into newline-terminated lines without the empty trailing
+// element difflib.SplitLines adds.
+func diffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
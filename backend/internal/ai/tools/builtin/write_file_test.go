@@ -0,0 +1,115 @@
+package builtin_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/webide/ide/backend/internal/ai/tools"
+	"github.com/webide/ide/backend/internal/ai/tools/builtin"
+)
+
+func TestWriteFile_ExpectedSHA(t *testing.T) {
+	sha := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name string
+		// file is the content read_file saw; "" means there is no file.
+		file string
+		// change runs between the read and the write.
+		change      func(path string) error
+		useSHA      bool
+		expectedSHA string
+		wantCode    string
+		want        string
+		currentSHA  string
+	}{
+		{name: "unchanged file", file: "one\n", useSHA: true, want: "new\n"},
+		{
+			name:       "changed since read",
+			file:       "one\n",
+			change:     func(path string) error { return os.WriteFile(path, []byte("two\n"), 0644) },
+			useSHA:     true,
+			wantCode:   tools.ErrCodeConflict,
+			want:       "two\n",
+			currentSHA: sha("two\n"),
+		},
+		{
+			name:     "deleted since read",
+			file:     "one\n",
+			change:   os.Remove,
+			useSHA:   true,
+			wantCode: tools.ErrCodeConflict,
+		},
+		{
+			name:   "no expected_sha overwrites",
+			file:   "one\n",
+			change: func(path string) error { return os.WriteFile(path, []byte("two\n"), 0644) },
+			want:   "new\n",
+		},
+		{name: "new file without expected_sha", want: "new\n"},
+		{name: "new file with a stale sha", expectedSHA: sha("one\n"), wantCode: tools.ErrCodeConflict},
+		{name: "CRLF file keeps its line endings", file: "one\r\n", useSHA: true, want: "new\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			path := filepath.Join(root, "notes.txt")
+			tc := tools.ToolContext{ProjectRoot: root, Limits: tools.ToolLimits{MaxFileBytes: 1024}}
+
+			expected := tt.expectedSHA
+			if tt.file != "" {
+				if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+				read, err := builtin.ReadFile().Execute(t.Context(), map[string]interface{}{"path": path}, tc)
+				if err != nil || !read.OK {
+					t.Fatalf("read_file: %v %+v", err, read.Error)
+				}
+				if tt.useSHA {
+					expected, _ = read.Data.(map[string]interface{})["sha"].(string)
+				}
+			}
+			if tt.change != nil {
+				if err := tt.change(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			args := map[string]interface{}{"path": "notes.txt", "content": "new\n"}
+			if expected != "" {
+				args["expected_sha"] = expected
+			}
+			result, err := builtin.WriteFile().Execute(t.Context(), args, tc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantCode != "" {
+				if result.OK || result.Error == nil || result.Error.Code != tt.wantCode {
+					t.Fatalf("result = %+v, want error %s", result, tt.wantCode)
+				}
+				details, _ := result.Error.Details.(map[string]interface{})
+				if details["current_sha"] != tt.currentSHA {
+					t.Errorf("current_sha = %v, want %q", details["current_sha"], tt.currentSHA)
+				}
+			} else if !result.OK {
+				t.Fatalf("write_file failed: %+v", result.Error)
+			}
+
+			got, err := os.ReadFile(path)
+			switch {
+			case tt.want == "" && !os.IsNotExist(err):
+				t.Errorf("file exists with %q, want none", got)
+			case tt.want != "" && string(got) != tt.want:
+				t.Errorf("file = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package tools
 
+import "fmt"
+
 type ToolResult struct {
 	OK    bool        `json:"ok"`
 	Data  interface{} `json:"data,omitempty"`
@@ -11,6 +13,9 @@ type ResultMeta struct {
 	DurationMs int64  `json:"duration_ms"`
 	Truncated  bool   `json:"truncated,omitempty"`
 	SHA        string `json:"sha,omitempty"`
+	// CachedStep is set when the result is a note pointing at an identical
+	// result from that step.
+	CachedStep int `json:"cached_step,omitempty"`
 }
 
 type ToolError struct {
@@ -30,6 +35,9 @@ const (
 	ErrCodeInvalidPath   = "INVALID_PATH"
 	ErrCodeNotExecutable = "NOT_EXECUTABLE"
 	ErrCodeAlreadyExists = "ALREADY_EXISTS"
+	ErrCodeHookBlocked   = "HOOK_BLOCKED"
+	ErrCodeConflict      = "CONFLICT"
+	ErrCodeProtected     = "PROTECTED_FILE"
 )
 
 func NewSuccessResult(data interface{}) ToolResult {
@@ -48,6 +56,14 @@ func NewSuccessResultWithMeta(data interface{}, meta ResultMeta) ToolResult {
 	}
 }
 
+// NewProtectedResult refuses to return the content of a file matching a
+// deny glob. The orchestrator asks a person before running the call again.
+func NewProtectedResult(path, glob string) ToolResult {
+	return NewErrorResult(ErrCodeProtected,
+		fmt.Sprintf("%s matches the protected pattern %q and may contain secrets", path, glob),
+		map[string]interface{}{"path": path, "pattern": glob})
+}
+
 func NewErrorResult(code, message string, details interface{}) ToolResult {
 	return ToolResult{
 		OK: false,
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxStoredOutputBytes bounds the memory held by Outputs. The oldest outputs
+// are evicted first.
+const maxStoredOutputBytes = 64 * 1024 * 1024
+
+// StoredOutput is the full output of a tool call that was cut down before it
+// reached the model. It can be paged through by handle.
+type StoredOutput struct {
+	Handle    string    `json:"handle"`
+	ProjectID uuid.UUID `json:"project_id"`
+	Tool      string    `json:"tool"`
+	Bytes     int       `json:"bytes"`
+	Lines     int       `json:"lines"`
+	CreatedAt time.Time `json:"created_at"`
+
+	lines []string
+}
+
+type OutputPage struct {
+	Handle string   `json:"handle"`
+	Lines  []string `json:"lines"`
+	From   int      `json:"from"`
+	Next   int      `json:"next"`
+	Total  int      `json:"total"`
+	Done   bool     `json:"done"`
+}
+
+type OutputStore struct {
+	mu      sync.Mutex
+	outputs map[string]*StoredOutput
+	order   []string
+	bytes   int
+}
+
+var Outputs = &OutputStore{outputs: make(map[string]*StoredOutput)}
+
+// Put stores text under handle, or under a new handle when handle is empty,
+// and returns the handle.
+func (s *OutputStore) Put(projectID uuid.UUID, tool, handle, text string) string {
+	if handle == "" {
+		handle = uuid.New().String()
+	}
+	out := &StoredOutput{
+		Handle:    handle,
+		ProjectID: projectID,
+		Tool:      tool,
+		Bytes:     len(text),
+		CreatedAt: time.Now(),
+		lines:     strings.Split(strings.TrimRight(text, "\n"), "\n"),
+	}
+	out.Lines = len(out.lines)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if old, ok := s.outputs[handle]; ok {
+		s.bytes -= old.Bytes
+	} else {
+		s.order = append(s.order, handle)
+	}
+	s.outputs[handle] = out
+	s.bytes += out.Bytes
+
+	for s.bytes > maxStoredOutputBytes && len(s.order) > 1 {
+		oldest := s.order[0]
+		s.order = s.order[1:]
+		if o, ok := s.outputs[oldest]; ok {
+			s.bytes -= o.Bytes
+			delete(s.outputs, oldest)
+		}
+	}
+	return handle
+}
+
+func (s *OutputStore) Get(handle string) (*StoredOutput, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out, ok := s.outputs[handle]
+	return out, ok
+}
+
+// Page returns up to limit lines starting at line from (0-based).
+func (s *OutputStore) Page(handle string, from, limit int) (OutputPage, bool) {
+	out, ok := s.Get(handle)
+	if !ok {
+		return OutputPage{}, false
+	}
+	if from < 0 {
+		from = 0
+	}
+	if from > len(out.lines) {
+		from = len(out.lines)
+	}
+	end := len(out.lines)
+	if limit > 0 && from+limit < end {
+		end = from + limit
+	}
+	return OutputPage{
+		Handle: handle,
+		Lines:  out.lines[from:end],
+		From:   from,
+		Next:   end,
+		Total:  len(out.lines),
+		Done:   end >= len(out.lines),
+	}, true
+}
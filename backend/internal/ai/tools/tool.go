@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,6 +24,49 @@ type ToolContext struct {
 	ProjectRoot string
 	Mode        string
 	Limits      ToolLimits
+	// Denied reports the deny glob matching a project-relative path. Tools
+	// that return file content refuse such files, or leave them out of
+	// listings like search results and diffs. Nil, as for a call a person
+	// approved, allows every file.
+	Denied func(relPath string) (string, bool)
+	// Redact hides secrets in text a tool keeps outside its result, such as
+	// output stored for read_output. Nil leaves text unchanged.
+	Redact func(text string) string
+}
+
+// Protected reports the deny glob matching path, which is absolute or
+// relative to the project. The path is checked as named and after resolving
+// symlinks, so a link with a harmless name does not expose a protected file.
+func (tc ToolContext) Protected(path string) (string, bool) {
+	if tc.Denied == nil || path == "" {
+		return "", false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(tc.ProjectRoot, path)
+	}
+	paths := []string{filepath.Clean(path)}
+	if real, err := filepath.EvalSymlinks(path); err == nil && real != paths[0] {
+		paths = append(paths, real)
+	}
+	roots := []string{tc.ProjectRoot}
+	if real, err := filepath.EvalSymlinks(tc.ProjectRoot); err == nil && real != tc.ProjectRoot {
+		roots = append(roots, real)
+	}
+
+	for _, p := range paths {
+		// A path outside the project is matched by its base name.
+		rel := filepath.Base(p)
+		for _, root := range roots {
+			if r, err := filepath.Rel(root, p); err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+				rel = r
+				break
+			}
+		}
+		if glob, denied := tc.Denied(rel); denied {
+			return glob, true
+		}
+	}
+	return "", false
 }
 
 type ToolLimits struct {
@@ -38,6 +83,9 @@ type Tool struct {
 	Parameters  map[string]interface{}
 	Policy      ToolPolicy
 	Execute     func(ctx context.Context, args map[string]interface{}, tc ToolContext) (ToolResult, error)
+	// Preview describes what Execute would change, shown when the call needs
+	// approval. Optional.
+	Preview func(args map[string]interface{}, tc ToolContext) string
 }
 
 type ToolDefinition struct {
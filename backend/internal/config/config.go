@@ -18,6 +18,8 @@ type Config struct {
 	MiniMaxAPIKey     string
 	MiniMaxModel      string
 	MiniMaxURL        string
+	// SummaryModel condenses large tool outputs; defaults to MiniMaxModel.
+	SummaryModel string
 }
 
 func init() {
@@ -117,6 +119,7 @@ func Load() (*Config, error) {
 	miniMaxAPIKey := os.Getenv("IDE_MINIMAX_API_KEY")
 	miniMaxModel := getEnv("IDE_MINIMAX_MODEL", "abab6.5s-chat")
 	miniMaxURL := os.Getenv("IDE_MINIMAX_URL")
+	summaryModel := getEnv("IDE_SUMMARY_MODEL", miniMaxModel)
 
 	return &Config{
 		DataDir:           dataDir,
@@ -129,6 +132,7 @@ func Load() (*Config, error) {
 		MiniMaxAPIKey:     miniMaxAPIKey,
 		MiniMaxModel:      miniMaxModel,
 		MiniMaxURL:        miniMaxURL,
+		SummaryModel:      summaryModel,
 	}, nil
 }
 
@@ -142,6 +146,7 @@ func PrintEnvVars() {
 		"IDE_MINIMAX_API_KEY",
 		"IDE_MINIMAX_MODEL",
 		"IDE_MINIMAX_URL",
+		"IDE_SUMMARY_MODEL",
 	}
 
 	log.Println("=== Loaded Environment Variables ===")
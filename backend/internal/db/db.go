@@ -114,6 +114,15 @@ func runMigrations() error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
+		`CREATE TABLE IF NOT EXISTS changeset_files (
+			id TEXT PRIMARY KEY,
+			changeset_id TEXT NOT NULL,
+			path TEXT NOT NULL,
+			status TEXT NOT NULL,
+			content BLOB,
+			mode INTEGER NOT NULL DEFAULT 0,
+			base_sha TEXT
+		)`,
 		`CREATE TABLE IF NOT EXISTS review_threads (
 			id TEXT PRIMARY KEY,
 			changeset_id TEXT NOT NULL,
@@ -134,6 +143,7 @@ func runMigrations() error {
 		`CREATE INDEX IF NOT EXISTS idx_jobs_project ON jobs(project_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_changesets_project ON changesets(project_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_review_threads_changeset ON review_threads(changeset_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_changeset_files_changeset ON changeset_files(changeset_id)`,
 
 		`CREATE TABLE IF NOT EXISTS workspace_state (
 			id TEXT PRIMARY KEY,
@@ -188,6 +198,31 @@ func runMigrations() error {
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_chat_changesets_chat ON chat_changesets(chat_id)`,
 
+		`CREATE TABLE IF NOT EXISTS project_memories (
+			id TEXT PRIMARY KEY,
+			project_id TEXT NOT NULL,
+			content TEXT NOT NULL,
+			source TEXT NOT NULL DEFAULT 'agent',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_project_memories_project ON project_memories(project_id)`,
+
+		`CREATE TABLE IF NOT EXISTS agent_schedules (
+			id TEXT PRIMARY KEY,
+			project_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			cron TEXT NOT NULL,
+			prompt TEXT NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT 1,
+			max_steps INTEGER NOT NULL DEFAULT 0,
+			next_run_at DATETIME,
+			last_run_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_agent_schedules_project ON agent_schedules(project_id)`,
+
 		`CREATE TABLE IF NOT EXISTS user_settings (
 			id TEXT PRIMARY KEY,
 			user_id TEXT NOT NULL UNIQUE,
@@ -243,6 +278,8 @@ func addMissingColumns() error {
 		{"chat_messages", "tool_results_json", "TEXT", ""},
 		{"chat_messages", "thinking", "TEXT", ""},
 		{"chat_messages", "tool_call_id", "TEXT", ""},
+		{"chats", "budget_json", "TEXT", ""},
+		{"changesets", "diff", "TEXT", ""},
 		{"user_settings", "ui_theme_id", "TEXT", "'dark-plus'"},
 		{"user_settings", "editor_theme_id", "TEXT", "'vs-dark'"},
 		{"user_settings", "terminal_theme_id", "TEXT", "'monokai'"},
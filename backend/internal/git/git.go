@@ -2,6 +2,7 @@ package git
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -54,13 +55,21 @@ func RunGit(repoDir string, timeout time.Duration, args ...string) (*GitResult,
 			Stderr:   "timeout",
 		}, nil
 	case runErr = <-done:
-		_ = runErr
+	}
+
+	exitCode := 0
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(runErr, &exitErr) {
+			return nil, runErr
+		}
+		exitCode = exitErr.ExitCode()
 	}
 
 	return &GitResult{
 		Stdout:   strings.TrimSuffix(stdout.String(), "\n"),
 		Stderr:   strings.TrimSuffix(stderr.String(), "\n"),
-		ExitCode: 0,
+		ExitCode: exitCode,
 	}, nil
 }
 
@@ -64,6 +64,7 @@ type ChangeSet struct {
 	ApplyMode   string     `json:"apply_mode" db:"apply_mode"`
 	Status      string     `json:"status" db:"status"`
 	SummaryText string     `json:"summary_text" db:"summary_text"`
+	Diff        string     `json:"diff,omitempty" db:"diff"`
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
 }
@@ -116,3 +117,26 @@ type ChatChangeSet struct {
 	SummaryText string     `json:"summary_text" db:"summary_text"`
 	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
 }
+
+type AgentSchedule struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	ProjectID uuid.UUID  `json:"project_id" db:"project_id"`
+	Name      string     `json:"name" db:"name"`
+	Cron      string     `json:"cron" db:"cron"`
+	Prompt    string     `json:"prompt" db:"prompt"`
+	Enabled   bool       `json:"enabled" db:"enabled"`
+	MaxSteps  int        `json:"max_steps" db:"max_steps"`
+	NextRunAt *time.Time `json:"next_run_at" db:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at" db:"last_run_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type ProjectMemory struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	ProjectID uuid.UUID `json:"project_id" db:"project_id"`
+	Content   string    `json:"content" db:"content"`
+	Source    string    `json:"source" db:"source"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
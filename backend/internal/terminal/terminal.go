@@ -138,6 +138,13 @@ func (s *TerminalSession) GetBacklog() []byte {
 	return s.Buffer.ReadAll()
 }
 
+// GetState returns the session status and when it last produced output.
+func (s *TerminalSession) GetState() (string, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Status, s.LastSeen
+}
+
 func (s *TerminalSession) Close() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -2,6 +2,7 @@ import { defineStore } from 'pinia'
 import { ref } from 'vue'
 import { api } from '../api'
 import { parseMarkdown } from '../utils/markdown'
+import { useEditorStore } from './editor'
 
 export interface Job {
   id: string
@@ -42,13 +43,80 @@ export interface ReviewThread {
 export interface ChatMessage {
   id: string
   chat_id: string
-  role: 'user' | 'assistant' | 'system' | 'tool' | 'thinking' | 'tool_block'
+  role: 'user' | 'assistant' | 'system' | 'tool' | 'thinking' | 'tool_block' | 'hook' | 'checks' | 'context' | 'budget' | 'review'
   content: string
   parsedContent?: string
   created_at: string
   tool_calls?: ToolCall[]
   tool_results?: ToolResult[]
   thinking?: string
+  delivery?: 'queued' | 'dropped'
+  dropReason?: string
+  checks?: CheckOutcome
+  context?: EditorContext
+  budgetExceeded?: boolean
+  review?: ReviewOutcome
+}
+
+export interface ReviewFile {
+  path: string
+  status: 'added' | 'modified' | 'deleted'
+  diff?: string
+  binary?: boolean
+  conflict?: boolean
+}
+
+export interface PendingReview {
+  id: string
+  files: ReviewFile[]
+  diff: string
+}
+
+export interface ReviewOutcome {
+  id: string
+  accepted: string[]
+  rejected: string[]
+  conflicts?: string[]
+  reason?: string
+  error?: string
+}
+
+export interface EditorContextItem {
+  kind: string
+  label: string
+  content: string
+  bytes: number
+  truncated?: boolean
+}
+
+export interface EditorContext {
+  items: EditorContextItem[]
+  bytes: number
+  budget: number
+}
+
+export interface CheckRunResult {
+  name: string
+  cmd: string
+  passed: boolean
+  exit_code: number
+  timed_out?: boolean
+  duration_ms: number
+  output?: string
+}
+
+export interface CheckOutcome {
+  passed: boolean
+  attempt: number
+  results: CheckRunResult[]
+}
+
+export interface PendingApproval {
+  id: string
+  name: string
+  arguments: Record<string, unknown>
+  summary?: string
+  preview?: string
 }
 
 export interface ToolCall {
@@ -110,8 +178,14 @@ export const useAIStore = defineStore('ai', () => {
   const streamingMessageId = ref<string | null>(null)
   const streamingContent = ref('')
   const isStreaming = ref(false)
-  const modelStatus = ref<'idle' | 'thinking' | 'using_tool' | 'editing' | 'planning'>('idle')
+  const modelStatus = ref<'idle' | 'thinking' | 'using_tool' | 'editing' | 'planning' | 'waiting_approval'>('idle')
   const currentToolCall = ref<ToolCall | null>(null)
+  const activeRunId = ref<string | null>(null)
+  const pendingApproval = ref<PendingApproval | null>(null)
+  const pendingReview = ref<PendingReview | null>(null)
+  let runSeq = 0
+  let runSeqChatId: string | null = null
+  const pendingUserMessageIds = new Set<string>()
 
   const usage = ref<{
     remaining_credits: number
@@ -452,6 +526,12 @@ export const useAIStore = defineStore('ai', () => {
       console.log('[CHAT] Loaded messages:', response.data.length)
       chatMessages.value = (response.data || []).map((msg: any) => {
         const toolCalls = msg.tool_calls_json ? JSON.parse(msg.tool_calls_json || '[]') : []
+        if (msg.role === 'context') {
+          return { ...msg, content: '', context: msg.tool_results_json ? JSON.parse(msg.tool_results_json) : undefined }
+        }
+        if (msg.role === 'review') {
+          return { ...msg, content: '', review: msg.tool_results_json ? JSON.parse(msg.tool_results_json) : undefined }
+        }
         const toolResults = msg.tool_results_json ? JSON.parse(msg.tool_results_json || '[]') : []
         console.log('[CHAT] Message:', msg.id, 'tool_calls:', toolCalls.length, 'tool_results:', toolResults.length, 'thinking:', msg.thinking ? msg.thinking.substring(0, 50) + '...' : 'empty')
         return {
@@ -486,11 +566,23 @@ export const useAIStore = defineStore('ai', () => {
 
     console.log('[CHAT] Connecting to:', wsUrl)
 
+    if (runSeqChatId !== chatId) {
+      runSeq = 0
+      runSeqChatId = chatId
+      activeRunId.value = null
+      pendingApproval.value = null
+      pendingReview.value = null
+    }
+
     chatWs.value = new WebSocket(wsUrl)
 
     chatWs.value.onopen = () => {
       console.log('[CHAT] WebSocket connected')
       wsConnected.value = true
+      chatWs.value?.send(JSON.stringify({
+        type: 'attach',
+        payload: { since_seq: runSeq }
+      }))
     }
 
     chatWs.value.onclose = (e) => {
@@ -507,6 +599,9 @@ export const useAIStore = defineStore('ai', () => {
     chatWs.value.onmessage = (event) => {
       try {
         const data = JSON.parse(event.data)
+        if (data.seq) {
+          runSeq = Math.max(runSeq, data.seq)
+        }
         handleChatWSMessage(data)
       } catch (e) {
         console.error('[CHAT] Failed to parse message:', e)
@@ -558,7 +653,34 @@ export const useAIStore = defineStore('ai', () => {
         console.log('[CHAT] Thinking message received')
         currentThinkingMsgId = payload.id
       }
-      const existingIndex = chatMessages.value.findIndex(m => m.id === payload.id)
+      if (payload.role === 'context') {
+        if (chatMessages.value.some(m => m.id === payload.id)) {
+          return
+        }
+        const contextMsg: ChatMessage = {
+          id: payload.id,
+          chat_id: payload.chat_id,
+          role: 'context',
+          content: '',
+          context: payload.tool_results_json ? JSON.parse(payload.tool_results_json) : undefined,
+          created_at: payload.created_at
+        }
+        // Show the context above the optimistic user message it belongs to.
+        const pendingIndex = chatMessages.value.findIndex(m => pendingUserMessageIds.has(m.id))
+        if (pendingIndex !== -1) {
+          chatMessages.value.splice(pendingIndex, 0, contextMsg)
+        } else {
+          chatMessages.value.push(contextMsg)
+        }
+        return
+      }
+      let existingIndex = chatMessages.value.findIndex(m => m.id === payload.id)
+      if (existingIndex === -1 && payload.role === 'user') {
+        existingIndex = chatMessages.value.findIndex(m => pendingUserMessageIds.has(m.id) && m.content === payload.content)
+        if (existingIndex !== -1) {
+          pendingUserMessageIds.delete(chatMessages.value[existingIndex].id)
+        }
+      }
       if (existingIndex !== -1) {
         chatMessages.value[existingIndex] = {
           id: payload.id,
@@ -589,6 +711,9 @@ export const useAIStore = defineStore('ai', () => {
         console.log('[CHAT] tool_call received:', payload.name, payload.id)
         modelStatus.value = 'using_tool'
         const toolMsgId = payload.id + '_tool'
+        if (chatMessages.value.some(m => m.id === toolMsgId)) {
+          return
+        }
         chatMessages.value.push({
           id: toolMsgId,
           chat_id: activeChat.value?.id || '',
@@ -624,6 +749,111 @@ export const useAIStore = defineStore('ai', () => {
           }]
           console.log('[CHAT] Updated tool_block')
         }
+      } else if (data.type === 'hook.result') {
+        const payload = data.payload
+        console.log('[CHAT] hook.result received:', payload.event, payload.hook, payload.decision)
+        if (chatMessages.value.some(m => m.id === `hook_${data.seq}`)) {
+          return
+        }
+        chatMessages.value.push({
+          id: `hook_${data.seq || Date.now()}`,
+          chat_id: activeChat.value?.id || '',
+          role: 'hook',
+          content: payload.output || payload.reason || '',
+          created_at: new Date().toISOString()
+        })
+      } else if (data.type === 'message.queued') {
+        const payload = data.payload
+        let index = chatMessages.value.findIndex(m => m.id === payload.id)
+        if (index === -1) {
+          index = chatMessages.value.findIndex(m => pendingUserMessageIds.has(m.id) && m.content === payload.content)
+          if (index !== -1) {
+            pendingUserMessageIds.delete(chatMessages.value[index].id)
+            chatMessages.value[index].id = payload.id
+          }
+        }
+        if (index === -1) {
+          chatMessages.value.push({
+            id: payload.id,
+            chat_id: activeChat.value?.id || '',
+            role: 'user',
+            content: payload.content,
+            created_at: payload.queued_at
+          })
+          index = chatMessages.value.length - 1
+        }
+        chatMessages.value[index].delivery = 'queued'
+      } else if (data.type === 'message.delivered') {
+        const msg = chatMessages.value.find(m => m.id === data.payload.id)
+        if (msg) {
+          msg.delivery = undefined
+        }
+      } else if (data.type === 'message.dropped') {
+        const msg = chatMessages.value.find(m => m.id === data.payload.id)
+        if (msg) {
+          msg.delivery = 'dropped'
+          msg.dropReason = data.payload.reason
+        }
+      } else if (data.type === 'checks.result') {
+        const id = `checks_${data.seq || Date.now()}`
+        if (chatMessages.value.some(m => m.id === id)) {
+          return
+        }
+        chatMessages.value.push({
+          id,
+          chat_id: activeChat.value?.id || '',
+          role: 'checks',
+          content: '',
+          checks: data.payload,
+          created_at: new Date().toISOString()
+        })
+      } else if (data.type === 'budget.warning' || data.type === 'agent.error') {
+        const id = `budget_${data.seq || Date.now()}`
+        if (chatMessages.value.some(m => m.id === id)) {
+          return
+        }
+        chatMessages.value.push({
+          id,
+          chat_id: activeChat.value?.id || '',
+          role: 'budget',
+          content: data.payload.message,
+          budgetExceeded: data.type === 'agent.error',
+          created_at: new Date().toISOString()
+        })
+      } else if (data.type === 'run.attached') {
+        const payload = data.payload
+        activeRunId.value = payload.run_id
+        if (payload.status === 'running' || payload.status === 'waiting_approval') {
+          isStreaming.value = true
+        }
+        pendingApproval.value = payload.pending_approval || null
+        pendingReview.value = payload.pending_review || null
+      } else if (data.type === 'tool.approval_required') {
+        pendingApproval.value = data.payload
+        modelStatus.value = 'waiting_approval'
+      } else if (data.type === 'review.required') {
+        pendingReview.value = data.payload
+        modelStatus.value = 'waiting_approval'
+      } else if (data.type === 'review.resolved') {
+        pendingReview.value = null
+        const id = `review_${data.payload.id}`
+        if (!chatMessages.value.some(m => m.id === id)) {
+          chatMessages.value.push({
+            id,
+            chat_id: activeChat.value?.id || '',
+            role: 'review',
+            content: '',
+            review: data.payload,
+            created_at: new Date().toISOString()
+          })
+        }
+      } else if (data.type === 'run.error') {
+        error.value = data.payload.error
+      } else if (data.type === 'run.finished') {
+        activeRunId.value = null
+        pendingApproval.value = null
+        pendingReview.value = null
+        isStreaming.value = false
       } else if (data.type === 'status') {
         const payload = data.payload
         if (payload.status) {
@@ -632,7 +862,17 @@ export const useAIStore = defineStore('ai', () => {
       }
     }
 
-  function sendChatMessage(content: string): Promise<void> {
+  function editorContextPayload() {
+    const editorStore = useEditorStore()
+    return {
+      enabled: true,
+      active_file: editorStore.activeFile?.path || '',
+      open_files: editorStore.openFiles.map(f => f.path),
+      selection: editorStore.selection || undefined
+    }
+  }
+
+  function sendChatMessage(content: string, includeContext = false, batchReview?: boolean): Promise<void> {
     return new Promise((resolve) => {
       console.log('[CHAT] sendChatMessage called, readyState:', chatWs.value?.readyState)
 
@@ -643,9 +883,12 @@ export const useAIStore = defineStore('ai', () => {
       }
 
       if (chatWs.value.readyState === WebSocket.OPEN) {
+        if (!isStreaming.value) {
+          modelStatus.value = 'thinking'
+        }
         isStreaming.value = true
-        modelStatus.value = 'thinking'
         const tempId = crypto.randomUUID()
+        pendingUserMessageIds.add(tempId)
         const isFirstMessage = chatMessages.value.length === 0
         chatMessages.value.push({
           id: tempId,
@@ -662,7 +905,7 @@ export const useAIStore = defineStore('ai', () => {
 
         const message = JSON.stringify({
           type: 'send_message',
-          payload: { content }
+          payload: { content, context: includeContext ? editorContextPayload() : undefined, batch_review: batchReview }
         })
         console.log('[CHAT] Sending message:', message)
         chatWs.value.send(message)
@@ -683,6 +926,7 @@ export const useAIStore = defineStore('ai', () => {
             clearInterval(checkOpen)
             isStreaming.value = true
             const tempId = crypto.randomUUID()
+            pendingUserMessageIds.add(tempId)
             const isFirstMessage = chatMessages.value.length === 0
             chatMessages.value.push({
               id: tempId,
@@ -693,7 +937,7 @@ export const useAIStore = defineStore('ai', () => {
             })
             const message = JSON.stringify({
               type: 'send_message',
-              payload: { content }
+              payload: { content, context: includeContext ? editorContextPayload() : undefined, batch_review: batchReview }
             })
             console.log('[CHAT] Sending after connect:', message)
             chatWs.value.send(message)
@@ -726,6 +970,35 @@ export const useAIStore = defineStore('ai', () => {
     })
   }
 
+  function respondToApproval(approved: boolean, reason?: string) {
+    if (!chatWs.value || !pendingApproval.value) {
+      return
+    }
+    chatWs.value.send(JSON.stringify({
+      type: approved ? 'tool.approve' : 'tool.reject',
+      payload: { id: pendingApproval.value.id, reason }
+    }))
+    pendingApproval.value = null
+    modelStatus.value = 'using_tool'
+  }
+
+  function submitReview(accept: string[] | 'all', reason?: string) {
+    if (!chatWs.value || !pendingReview.value) {
+      return
+    }
+    chatWs.value.send(JSON.stringify({
+      type: 'review.submit',
+      payload: {
+        id: pendingReview.value.id,
+        accept_all: accept === 'all',
+        accept: accept === 'all' ? undefined : accept,
+        reason
+      }
+    }))
+    pendingReview.value = null
+    modelStatus.value = 'editing'
+  }
+
   function stopStreaming() {
     if (chatWs.value) {
       chatWs.value.send(JSON.stringify({ type: 'stop' }))
@@ -737,6 +1010,23 @@ export const useAIStore = defineStore('ai', () => {
     currentToolCall.value = null
   }
 
+  async function exportChat(chatId: string, format: 'md' | 'json' | 'html', thinking = false) {
+    try {
+      const response = await api.get(`/api/v1/projects/${currentProjectId}/ai/chats/${chatId}/export`, {
+        params: { format, thinking },
+        responseType: 'blob'
+      })
+      const url = URL.createObjectURL(response.data)
+      const link = document.createElement('a')
+      link.href = url
+      link.download = `chat-${chatId.slice(0, 8)}.${format}`
+      link.click()
+      URL.revokeObjectURL(url)
+    } catch (e: any) {
+      error.value = 'Failed to export chat'
+    }
+  }
+
   async function fetchChatChangeSets(chatId: string) {
     loading.value = true
     error.value = null
@@ -751,6 +1041,18 @@ export const useAIStore = defineStore('ai', () => {
     }
   }
 
+  async function fetchToolOutput(handle: string): Promise<string[] | null> {
+    try {
+      const response = await api.get(`/api/v1/projects/${currentProjectId}/ai/outputs/${handle}`, {
+        params: { limit: 0 }
+      })
+      return response.data.page.lines || []
+    } catch (e: any) {
+      error.value = e.response?.data?.error || 'Failed to fetch output'
+      return null
+    }
+  }
+
   async function fetchUsage() {
     try {
       const response = await api.get('/api/v1/ai/usage')
@@ -802,9 +1104,16 @@ export const useAIStore = defineStore('ai', () => {
     streamingContent,
     streamingMessageId,
     fetchChatChangeSets,
+    fetchToolOutput,
+    exportChat,
     usage,
     fetchUsage,
     modelStatus,
-    currentToolCall
+    currentToolCall,
+    activeRunId,
+    pendingApproval,
+    respondToApproval,
+    pendingReview,
+    submitReview
   }
 })
@@ -18,6 +18,13 @@ interface OpenFile {
   language?: string
 }
 
+export interface EditorSelection {
+  path: string
+  start_line: number
+  end_line: number
+  text: string
+}
+
 interface WorkspaceState {
   open_files: string[]
   expanded_dirs: string[]
@@ -30,6 +37,7 @@ export const useEditorStore = defineStore('editor', () => {
   const fileTree = ref<FileNode | null>(null)
   const openFiles = ref<OpenFile[]>([])
   const activeFile = ref<OpenFile | null>(null)
+  const selection = ref<EditorSelection | null>(null)
   const expandedDirs = ref<Set<string>>(new Set())
   const loading = ref(false)
   const error = ref<string | null>(null)
@@ -265,6 +273,10 @@ export const useEditorStore = defineStore('editor', () => {
     saveWorkspaceState()
   }
 
+  function setSelection(value: EditorSelection | null) {
+    selection.value = value && value.text.trim() ? value : null
+  }
+
   function setActiveFile(path: string) {
     const normalizedPath = path.replace(/\/+/g, '/').replace(/^\//, '/')
     const file = openFiles.value.find(f => f.path === normalizedPath)
@@ -335,6 +347,7 @@ export const useEditorStore = defineStore('editor', () => {
     fileTree,
     openFiles,
     activeFile,
+    selection,
     expandedDirs,
     loading,
     error,
@@ -348,6 +361,7 @@ export const useEditorStore = defineStore('editor', () => {
     saveFile,
     closeFile,
     setActiveFile,
+    setSelection,
     toggleExpandedDir,
     isDirExpanded
   }